@@ -0,0 +1,184 @@
+package sigloop
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+
+	"github.com/sigloop/sdk-go/agent"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/policy"
+	"github.com/sigloop/sdk-go/x402"
+)
+
+type AgentClient struct {
+	client  *SigloopClient
+	agentID string
+}
+
+func (c *SigloopClient) Agent(agentID string) (*AgentClient, error) {
+	if _, err := c.activeAgent(agentID); err != nil {
+		return nil, err
+	}
+	return &AgentClient{client: c, agentID: agentID}, nil
+}
+
+func (c *SigloopClient) activeAgent(agentID string) (*agent.Agent, error) {
+	a, err := c.AgentService.GetAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != agent.AgentStatusActive {
+		return nil, errors.New("agent is not active")
+	}
+	return a, nil
+}
+
+func (a *AgentClient) EffectivePolicy() (*policy.Policy, error) {
+	ag, err := a.client.AgentService.GetAgent(a.agentID)
+	if err != nil {
+		return nil, err
+	}
+	return a.client.PolicyService.EffectivePolicy(ag.ID, ag.WalletAddress)
+}
+
+func (a *AgentClient) Authorize(ctx context.Context, tx *policy.Transaction) (*policy.Decision, error) {
+	ag, err := a.client.activeAgent(a.agentID)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.client.PolicyService.Enforce(ctx, ag.ID, ag.WalletAddress, tx)
+	if err != nil {
+		return nil, err
+	}
+	return d, d.Err()
+}
+
+func (a *AgentClient) Approvals(status policy.ApprovalStatus) ([]*policy.ApprovalRequest, error) {
+	all, err := a.client.PolicyService.Approvals().List(status)
+	if err != nil {
+		return nil, err
+	}
+	var result []*policy.ApprovalRequest
+	for _, r := range all {
+		if r.AgentID == a.agentID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (a *AgentClient) ExecuteSwap(ctx context.Context, params defi.SwapParams) (*defi.DeFiResult, *policy.Reservation, error) {
+	result, err := a.client.DeFiService.ExecuteSwap(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.reserveResult(ctx, result, &policy.Transaction{Token: params.TokenIn, Amount: params.AmountIn})
+}
+
+func (a *AgentClient) Supply(ctx context.Context, params defi.LendingParams) (*defi.DeFiResult, *policy.Reservation, error) {
+	result, err := a.client.DeFiService.Supply(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.reserveResult(ctx, result, &policy.Transaction{Token: params.Token, Amount: params.Amount})
+}
+
+func (a *AgentClient) Borrow(ctx context.Context, params defi.LendingParams) (*defi.DeFiResult, *policy.Reservation, error) {
+	result, err := a.client.DeFiService.Borrow(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.reserveResult(ctx, result, &policy.Transaction{Token: params.Token, Amount: params.Amount})
+}
+
+func (a *AgentClient) Repay(ctx context.Context, params defi.LendingParams) (*defi.DeFiResult, *policy.Reservation, error) {
+	result, err := a.client.DeFiService.Repay(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.reserveResult(ctx, result, &policy.Transaction{Token: params.Token, Amount: params.Amount})
+}
+
+func (a *AgentClient) reserveResult(ctx context.Context, result *defi.DeFiResult, tx *policy.Transaction) (*defi.DeFiResult, *policy.Reservation, error) {
+	ag, err := a.client.activeAgent(a.agentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx.To = result.To
+	tx.Value = result.Value
+	tx.Data = result.Data
+	d, r, err := a.client.PolicyService.EnforceReserve(ctx, ag.ID, ag.WalletAddress, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := d.Err(); err != nil {
+		return nil, nil, err
+	}
+	return result, r, nil
+}
+
+func (a *AgentClient) Commit(r *policy.Reservation) error {
+	if r == nil {
+		return nil
+	}
+	return a.client.PolicyService.Commit(r)
+}
+
+func (a *AgentClient) Release(r *policy.Reservation) error {
+	if r == nil {
+		return nil
+	}
+	return a.client.PolicyService.Release(r)
+}
+
+func (a *AgentClient) X402Transport(base http.RoundTripper, config x402.X402Config) (*x402.X402Transport, error) {
+	ag, err := a.client.activeAgent(a.agentID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := agent.SessionKeySigner(ag.SessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := x402.NewX402Transport(base, s, ag.SessionKey.ChainID, a.client.X402Service, nil, config)
+	transport.Authorize = func(ctx context.Context, req *http.Request, payment *x402.PaymentRequirement, amount *big.Int) (x402.PaymentReservation, error) {
+		ag, err := a.client.activeAgent(a.agentID)
+		if err != nil {
+			return nil, err
+		}
+		d, r, err := a.client.PolicyService.EnforceReserve(ctx, ag.ID, ag.WalletAddress, &policy.Transaction{
+			To:     x402.PaymentToken,
+			Data:   append([]byte(nil), x402.TransferWithAuthorizationSelector[:]...),
+			Token:  x402.PaymentToken,
+			Amount: amount,
+			Payee:  payment.PayTo,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := d.Err(); err != nil {
+			return nil, err
+		}
+		if r == nil {
+			return nil, nil
+		}
+		return &paymentReservation{policies: a.client.PolicyService, r: r}, nil
+	}
+	return transport, nil
+}
+
+type paymentReservation struct {
+	policies *policy.PolicyService
+	r        *policy.Reservation
+}
+
+func (p *paymentReservation) Commit() error {
+	return p.policies.Commit(p.r)
+}
+
+func (p *paymentReservation) Release() error {
+	return p.policies.Release(p.r)
+}
@@ -11,16 +11,29 @@ import (
 )
 
 type AgentService struct {
-	agents map[string]*Agent
-	mu     sync.RWMutex
+	store Store
+	mu    sync.RWMutex
 }
 
-func NewAgentService() *AgentService {
-	return &AgentService{
-		agents: make(map[string]*Agent),
+type AgentServiceOption func(*AgentService)
+
+func WithStore(store Store) AgentServiceOption {
+	return func(s *AgentService) {
+		s.store = store
 	}
 }
 
+func NewAgentService(opts ...AgentServiceOption) *AgentService {
+	s := &AgentService{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	return s
+}
+
 func (s *AgentService) CreateAgent(params CreateAgentParams) (*Agent, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -48,7 +61,9 @@ func (s *AgentService) CreateAgent(params CreateAgentParams) (*Agent, error) {
 		Permissions:   params.Config.Permissions,
 	}
 
-	s.agents[id] = a
+	if err := s.store.Put(a); err != nil {
+		return nil, err
+	}
 	return a, nil
 }
 
@@ -56,44 +71,63 @@ func (s *AgentService) RevokeAgent(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	a, ok := s.agents[id]
+	a, ok, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("agent not found")
 	}
 
 	a.Status = AgentStatusRevoked
-	return nil
+	return s.store.Put(a)
 }
 
 func (s *AgentService) GetAgent(id string) (*Agent, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	a, ok := s.agents[id]
+	a, ok, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
 		return nil, errors.New("agent not found")
 	}
 
-	if a.Status == AgentStatusActive && time.Now().After(a.ExpiresAt) {
-		a.Status = AgentStatusExpired
+	if err := s.expireIfNeeded(a, time.Now()); err != nil {
+		return nil, err
 	}
 
 	return a, nil
 }
 
-func (s *AgentService) ListAgents(walletAddr common.Address) []*Agent {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *AgentService) ListAgents(walletAddr common.Address) ([]*Agent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agents, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
 
 	var result []*Agent
 	now := time.Now()
-	for _, a := range s.agents {
+	for _, a := range agents {
 		if a.WalletAddress == walletAddr {
-			if a.Status == AgentStatusActive && now.After(a.ExpiresAt) {
-				a.Status = AgentStatusExpired
+			if err := s.expireIfNeeded(a, now); err != nil {
+				return nil, err
 			}
 			result = append(result, a)
 		}
 	}
-	return result
+	return result, nil
+}
+
+func (s *AgentService) expireIfNeeded(a *Agent, now time.Time) error {
+	if a.Status == AgentStatusActive && now.After(a.ExpiresAt) {
+		a.Status = AgentStatusExpired
+		return s.store.Put(a)
+	}
+	return nil
 }
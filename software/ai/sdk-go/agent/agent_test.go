@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"errors"
 	"math/big"
 	"sync"
 	"testing"
@@ -26,8 +27,8 @@ func testAgentParams(walletAddr common.Address) CreateAgentParams {
 func TestNewAgentService(t *testing.T) {
 	svc := NewAgentService()
 	assert.NotNil(t, svc)
-	assert.NotNil(t, svc.agents)
-	assert.Empty(t, svc.agents)
+	assert.IsType(t, &MemoryStore{}, svc.store)
+	assert.Empty(t, svc.store.(*MemoryStore).agents)
 }
 
 func TestCreateAgent(t *testing.T) {
@@ -163,7 +164,8 @@ func TestListAgents(t *testing.T) {
 
 	t.Run("empty", func(t *testing.T) {
 		svc := NewAgentService()
-		result := svc.ListAgents(wallet1)
+		result, err := svc.ListAgents(wallet1)
+		require.NoError(t, err)
 		assert.Empty(t, result)
 	})
 
@@ -179,13 +181,15 @@ func TestListAgents(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		result1 := svc.ListAgents(wallet1)
+		result1, err := svc.ListAgents(wallet1)
+		require.NoError(t, err)
 		assert.Len(t, result1, 3)
 		for _, a := range result1 {
 			assert.Equal(t, wallet1, a.WalletAddress)
 		}
 
-		result2 := svc.ListAgents(wallet2)
+		result2, err := svc.ListAgents(wallet2)
+		require.NoError(t, err)
 		assert.Len(t, result2, 2)
 	})
 
@@ -206,10 +210,25 @@ func TestListAgents(t *testing.T) {
 
 		time.Sleep(5 * time.Millisecond)
 
-		result := svc.ListAgents(wallet1)
+		result, err := svc.ListAgents(wallet1)
+		require.NoError(t, err)
 		assert.Len(t, result, 1)
 		assert.Equal(t, AgentStatusExpired, result[0].Status)
 	})
+
+	t.Run("store error", func(t *testing.T) {
+		svc := NewAgentService(WithStore(failingStore{NewMemoryStore()}))
+		_, err := svc.ListAgents(wallet1)
+		assert.EqualError(t, err, "store unavailable")
+	})
+}
+
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) List() ([]*Agent, error) {
+	return nil, errors.New("store unavailable")
 }
 
 func TestAgentServiceConcurrency(t *testing.T) {
@@ -245,11 +264,12 @@ func TestAgentServiceConcurrency(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_ = svc.ListAgents(walletAddr)
+			_, _ = svc.ListAgents(walletAddr)
 		}()
 	}
 	wg.Wait()
 
-	result := svc.ListAgents(walletAddr)
+	result, err := svc.ListAgents(walletAddr)
+	require.NoError(t, err)
 	assert.Len(t, result, 20)
 }
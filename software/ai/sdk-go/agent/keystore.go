@@ -0,0 +1,415 @@
+package agent
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
+	"github.com/sigloop/sdk-go/storage"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keystoreVersion = 3
+	keystoreCipher  = "aes-256-gcm"
+	keystoreKDF     = "scrypt"
+	scryptDKLen     = 32
+)
+
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+var (
+	StandardScryptParams = ScryptParams{N: 1 << 18, R: 8, P: 1}
+	LightScryptParams    = ScryptParams{N: 1 << 12, R: 8, P: 6}
+)
+
+type KeystoreMetadata struct {
+	Address       common.Address `json:"-"`
+	AgentID       string         `json:"agentId,omitempty"`
+	WalletAddress common.Address `json:"walletAddress"`
+	ChainID       *big.Int       `json:"chainId"`
+	ValidAfter    *big.Int       `json:"validAfter"`
+	ValidUntil    *big.Int       `json:"validUntil"`
+}
+
+type encryptedKeyJSON struct {
+	Version int              `json:"version"`
+	ID      string           `json:"id"`
+	Address string           `json:"address"`
+	Crypto  cryptoJSON       `json:"crypto"`
+	Meta    KeystoreMetadata `json:"meta"`
+}
+
+type cryptoJSON struct {
+	Cipher       string           `json:"cipher"`
+	CipherText   string           `json:"ciphertext"`
+	CipherParams cipherParamsJSON `json:"cipherparams"`
+	KDF          string           `json:"kdf"`
+	KDFParams    scryptParamsJSON `json:"kdfparams"`
+}
+
+type cipherParamsJSON struct {
+	Nonce string `json:"nonce"`
+}
+
+type scryptParamsJSON struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+func EncryptSessionKey(sk *SessionKey, meta KeystoreMetadata, password string, params ScryptParams) ([]byte, error) {
+	if sk == nil || sk.PrivateKey == nil {
+		return nil, errors.New("nil session key")
+	}
+	if password == "" {
+		return nil, errors.New("empty password")
+	}
+	if params.N <= 1 || params.N&(params.N-1) != 0 || params.R <= 0 || params.P <= 0 {
+		return nil, errors.New("invalid scrypt params")
+	}
+
+	meta.Address = crypto.PubkeyToAddress(sk.PrivateKey.PublicKey)
+	meta.ChainID = sk.ChainID
+	meta.ValidAfter = sk.ValidAfter
+	meta.ValidUntil = sk.ValidUntil
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	aad, err := keystoreAAD(meta)
+	if err != nil {
+		return nil, err
+	}
+	cipherText := gcm.Seal(nil, nonce, crypto.FromECDSA(sk.PrivateKey), aad)
+
+	id, err := newKeystoreID()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(encryptedKeyJSON{
+		Version: keystoreVersion,
+		ID:      id,
+		Address: hex.EncodeToString(meta.Address.Bytes()),
+		Crypto: cryptoJSON{
+			Cipher:       keystoreCipher,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{Nonce: hex.EncodeToString(nonce)},
+			KDF:          keystoreKDF,
+			KDFParams: scryptParamsJSON{
+				N:     params.N,
+				R:     params.R,
+				P:     params.P,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+		Meta: meta,
+	})
+}
+
+func DecryptSessionKey(keyjson []byte, password string) (*SessionKey, *KeystoreMetadata, error) {
+	k, err := parseKeystore(keyjson)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	salt, err := hex.DecodeString(k.Crypto.KDFParams.Salt)
+	if err != nil {
+		return nil, nil, errors.New("invalid keystore")
+	}
+	nonce, err := hex.DecodeString(k.Crypto.CipherParams.Nonce)
+	if err != nil {
+		return nil, nil, errors.New("invalid keystore")
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, nil, errors.New("invalid keystore")
+	}
+
+	p := k.Crypto.KDFParams
+	if p.DKLen != scryptDKLen {
+		return nil, nil, errors.New("invalid scrypt params")
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, p.N, p.R, p.P, p.DKLen)
+	if err != nil {
+		return nil, nil, errors.New("invalid scrypt params")
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, nil, errors.New("invalid keystore")
+	}
+
+	aad, err := keystoreAAD(k.Meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, cipherText, aad)
+	if err != nil {
+		return nil, nil, errors.New("could not decrypt key with given password")
+	}
+
+	privateKey, err := crypto.ToECDSA(plain)
+	if err != nil {
+		return nil, nil, errors.New("invalid keystore")
+	}
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != k.Meta.Address {
+		return nil, nil, errors.New("keystore address mismatch")
+	}
+
+	meta := k.Meta
+	return &SessionKey{
+		PrivateKey: privateKey,
+		PublicKey:  &privateKey.PublicKey,
+		Address:    meta.Address,
+		ValidAfter: meta.ValidAfter,
+		ValidUntil: meta.ValidUntil,
+		ChainID:    meta.ChainID,
+	}, &meta, nil
+}
+
+func ReadKeystoreMetadata(keyjson []byte) (*KeystoreMetadata, error) {
+	k, err := parseKeystore(keyjson)
+	if err != nil {
+		return nil, err
+	}
+	return &k.Meta, nil
+}
+
+func parseKeystore(keyjson []byte) (*encryptedKeyJSON, error) {
+	var k encryptedKeyJSON
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return nil, errors.New("invalid keystore")
+	}
+	if k.Version != keystoreVersion {
+		return nil, errors.New("unsupported keystore version")
+	}
+	if k.Crypto.Cipher != keystoreCipher {
+		return nil, errors.New("unsupported keystore cipher")
+	}
+	if k.Crypto.KDF != keystoreKDF {
+		return nil, errors.New("unsupported keystore kdf")
+	}
+	addr, err := hex.DecodeString(k.Address)
+	if err != nil || len(addr) != common.AddressLength {
+		return nil, errors.New("invalid keystore")
+	}
+	k.Meta.Address = common.BytesToAddress(addr)
+	return &k, nil
+}
+
+func keystoreAAD(meta KeystoreMetadata) ([]byte, error) {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	return append(meta.Address.Bytes(), metaJSON...), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newKeystoreID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+type KeyStore struct {
+	dir    string
+	params ScryptParams
+	mu     sync.Mutex
+}
+
+func NewKeyStore(dir string, params ScryptParams) (*KeyStore, error) {
+	if dir == "" {
+		return nil, errors.New("empty keystore directory")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &KeyStore{dir: dir, params: params}, nil
+}
+
+func (ks *KeyStore) Dir() string {
+	return ks.dir
+}
+
+func (ks *KeyStore) Store(sk *SessionKey, meta KeystoreMetadata, password string) error {
+	keyjson, err := EncryptSessionKey(sk, meta, password, ks.params)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.write(crypto.PubkeyToAddress(sk.PrivateKey.PublicKey), keyjson)
+}
+
+func (ks *KeyStore) Load(address common.Address, password string) (*SessionKey, *KeystoreMetadata, error) {
+	keyjson, err := ks.read(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DecryptSessionKey(keyjson, password)
+}
+
+func (ks *KeyStore) List() ([]*KeystoreMetadata, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*KeystoreMetadata
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		keyjson, err := os.ReadFile(filepath.Join(ks.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		meta, err := ReadKeystoreMetadata(keyjson)
+		if err != nil {
+			continue
+		}
+		result = append(result, meta)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Compare(result[i].Address.Hex(), result[j].Address.Hex()) < 0
+	})
+	return result, nil
+}
+
+func (ks *KeyStore) Delete(address common.Address) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	err := os.Remove(ks.path(address))
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("key not found")
+	}
+	return err
+}
+
+func (ks *KeyStore) Export(address common.Address, password, newPassword string) ([]byte, error) {
+	sk, meta, err := ks.Load(address, password)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptSessionKey(sk, *meta, newPassword, ks.params)
+}
+
+func (ks *KeyStore) Import(keyjson []byte, password, newPassword string) (*KeystoreMetadata, error) {
+	sk, meta, err := DecryptSessionKey(keyjson, password)
+	if err != nil {
+		return nil, err
+	}
+
+	reencrypted, err := EncryptSessionKey(sk, *meta, newPassword, ks.params)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, err := os.Stat(ks.path(sk.Address)); err == nil {
+		return nil, errors.New("key already exists")
+	}
+	if err := ks.write(sk.Address, reencrypted); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func (ks *KeyStore) read(address common.Address) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	keyjson, err := os.ReadFile(ks.path(address))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("key not found")
+	}
+	return keyjson, err
+}
+
+func (ks *KeyStore) write(address common.Address, keyjson []byte) error {
+	return storage.WriteFileAtomic(ks.path(address), keyjson)
+}
+
+func (ks *KeyStore) path(address common.Address) string {
+	return filepath.Join(ks.dir, hex.EncodeToString(address.Bytes())+".json")
+}
+
+func (ks *KeyStore) Signer(address common.Address, password string) (*signer.PrivateKeySigner, error) {
+	sk, _, err := ks.Load(address, password)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewPrivateKeySigner(sk.PrivateKey)
+}
+
+func NewKeystoreSigner(path, password string) (*signer.PrivateKeySigner, error) {
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sk, _, err := DecryptSessionKey(keyjson, password)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewPrivateKeySigner(sk.PrivateKey)
+}
@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeystoreMetadata() KeystoreMetadata {
+	return KeystoreMetadata{
+		AgentID:       "agent-1",
+		WalletAddress: common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+	}
+}
+
+func TestEncryptSessionKey(t *testing.T) {
+	sk, err := GenerateSessionKey(big.NewInt(8453), time.Hour)
+	require.NoError(t, err)
+
+	keyjson, err := EncryptSessionKey(sk, testKeystoreMetadata(), "hunter2", LightScryptParams)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		got, meta, err := DecryptSessionKey(keyjson, "hunter2")
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, got.Address)
+		assert.Equal(t, crypto.FromECDSA(sk.PrivateKey), crypto.FromECDSA(got.PrivateKey))
+		assert.Equal(t, sk.ChainID, got.ChainID)
+		assert.Equal(t, sk.ValidAfter, got.ValidAfter)
+		assert.Equal(t, sk.ValidUntil, got.ValidUntil)
+		assert.NoError(t, ValidateSessionKey(got))
+
+		assert.Equal(t, sk.Address, meta.Address)
+		assert.Equal(t, "agent-1", meta.AgentID)
+		assert.Equal(t, testKeystoreMetadata().WalletAddress, meta.WalletAddress)
+	})
+
+	t.Run("format", func(t *testing.T) {
+		var k map[string]interface{}
+		require.NoError(t, json.Unmarshal(keyjson, &k))
+		assert.Equal(t, float64(3), k["version"])
+		assert.Len(t, k["id"], 36)
+		assert.Equal(t, common.Bytes2Hex(sk.Address.Bytes()), k["address"])
+
+		c := k["crypto"].(map[string]interface{})
+		assert.Equal(t, "aes-256-gcm", c["cipher"])
+		assert.Equal(t, "scrypt", c["kdf"])
+		kdf := c["kdfparams"].(map[string]interface{})
+		assert.Equal(t, float64(LightScryptParams.N), kdf["n"])
+		assert.Equal(t, float64(32), kdf["dklen"])
+
+		assert.NotContains(t, string(keyjson), common.Bytes2Hex(crypto.FromECDSA(sk.PrivateKey)))
+	})
+
+	t.Run("metadata readable without password", func(t *testing.T) {
+		meta, err := ReadKeystoreMetadata(keyjson)
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, meta.Address)
+		assert.Equal(t, sk.ValidUntil, meta.ValidUntil)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, _, err := DecryptSessionKey(keyjson, "wrong")
+		assert.EqualError(t, err, "could not decrypt key with given password")
+	})
+
+	t.Run("tampered metadata", func(t *testing.T) {
+		var k encryptedKeyJSON
+		require.NoError(t, json.Unmarshal(keyjson, &k))
+		k.Meta.ValidUntil = new(big.Int).Add(sk.ValidUntil, big.NewInt(86400))
+		tampered, err := json.Marshal(k)
+		require.NoError(t, err)
+
+		_, _, err = DecryptSessionKey(tampered, "hunter2")
+		assert.EqualError(t, err, "could not decrypt key with given password")
+	})
+
+	t.Run("fresh salt and nonce", func(t *testing.T) {
+		again, err := EncryptSessionKey(sk, testKeystoreMetadata(), "hunter2", LightScryptParams)
+		require.NoError(t, err)
+		assert.NotEqual(t, keyjson, again)
+	})
+
+	errTests := []struct {
+		name     string
+		sk       *SessionKey
+		password string
+		params   ScryptParams
+		wantErr  string
+	}{
+		{name: "nil session key", sk: nil, password: "pw", params: LightScryptParams, wantErr: "nil session key"},
+		{name: "empty password", sk: sk, password: "", params: LightScryptParams, wantErr: "empty password"},
+		{name: "n not power of two", sk: sk, password: "pw", params: ScryptParams{N: 1000, R: 8, P: 1}, wantErr: "invalid scrypt params"},
+		{name: "zero params", sk: sk, password: "pw", params: ScryptParams{}, wantErr: "invalid scrypt params"},
+	}
+	for _, tc := range errTests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := EncryptSessionKey(tc.sk, testKeystoreMetadata(), tc.password, tc.params)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestDecryptSessionKeyErrors(t *testing.T) {
+	sk, err := GenerateSessionKey(big.NewInt(1), time.Hour)
+	require.NoError(t, err)
+	keyjson, err := EncryptSessionKey(sk, testKeystoreMetadata(), "pw", LightScryptParams)
+	require.NoError(t, err)
+
+	mutate := func(f func(k *encryptedKeyJSON)) []byte {
+		var k encryptedKeyJSON
+		require.NoError(t, json.Unmarshal(keyjson, &k))
+		f(&k)
+		out, err := json.Marshal(k)
+		require.NoError(t, err)
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		keyjson []byte
+		wantErr string
+	}{
+		{name: "not json", keyjson: []byte("nope"), wantErr: "invalid keystore"},
+		{name: "wrong version", keyjson: mutate(func(k *encryptedKeyJSON) { k.Version = 1 }), wantErr: "unsupported keystore version"},
+		{name: "wrong cipher", keyjson: mutate(func(k *encryptedKeyJSON) { k.Crypto.Cipher = "aes-128-ctr" }), wantErr: "unsupported keystore cipher"},
+		{name: "wrong kdf", keyjson: mutate(func(k *encryptedKeyJSON) { k.Crypto.KDF = "pbkdf2" }), wantErr: "unsupported keystore kdf"},
+		{name: "bad address", keyjson: mutate(func(k *encryptedKeyJSON) { k.Address = "zz" }), wantErr: "invalid keystore"},
+		{name: "bad nonce", keyjson: mutate(func(k *encryptedKeyJSON) { k.Crypto.CipherParams.Nonce = "00" }), wantErr: "invalid keystore"},
+		{name: "bad dklen", keyjson: mutate(func(k *encryptedKeyJSON) { k.Crypto.KDFParams.DKLen = 16 }), wantErr: "invalid scrypt params"},
+		{
+			name:    "swapped address",
+			keyjson: mutate(func(k *encryptedKeyJSON) { k.Address = common.Bytes2Hex(common.HexToAddress("0x01").Bytes()) }),
+			wantErr: "could not decrypt key with given password",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := DecryptSessionKey(tc.keyjson, "pw")
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	ks, err := NewKeyStore(dir, LightScryptParams)
+	require.NoError(t, err)
+	assert.Equal(t, dir, ks.Dir())
+
+	sk, err := GenerateSessionKey(big.NewInt(8453), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, ks.Store(sk, testKeystoreMetadata(), "pw"))
+
+	t.Run("file permissions", func(t *testing.T) {
+		info, err := os.Stat(filepath.Join(dir, common.Bytes2Hex(sk.Address.Bytes())+".json"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("load", func(t *testing.T) {
+		got, meta, err := ks.Load(sk.Address, "pw")
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, got.Address)
+		assert.Equal(t, "agent-1", meta.AgentID)
+	})
+
+	t.Run("load missing", func(t *testing.T) {
+		_, _, err := ks.Load(common.HexToAddress("0x01"), "pw")
+		assert.EqualError(t, err, "key not found")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o600))
+		metas, err := ks.List()
+		require.NoError(t, err)
+		require.Len(t, metas, 1)
+		assert.Equal(t, sk.Address, metas[0].Address)
+		assert.Equal(t, sk.ChainID, metas[0].ChainID)
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		exported, err := ks.Export(sk.Address, "pw", "transfer")
+		require.NoError(t, err)
+
+		_, _, err = DecryptSessionKey(exported, "pw")
+		assert.EqualError(t, err, "could not decrypt key with given password")
+
+		other, err := NewKeyStore(filepath.Join(t.TempDir(), "other"), LightScryptParams)
+		require.NoError(t, err)
+		meta, err := other.Import(exported, "transfer", "agent-pw")
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, meta.Address)
+		assert.Equal(t, "agent-1", meta.AgentID)
+
+		got, _, err := other.Load(sk.Address, "agent-pw")
+		require.NoError(t, err)
+		assert.Equal(t, crypto.FromECDSA(sk.PrivateKey), crypto.FromECDSA(got.PrivateKey))
+
+		_, err = other.Import(exported, "transfer", "agent-pw")
+		assert.EqualError(t, err, "key already exists")
+	})
+
+	t.Run("import wrong password", func(t *testing.T) {
+		exported, err := ks.Export(sk.Address, "pw", "transfer")
+		require.NoError(t, err)
+		_, err = ks.Import(exported, "nope", "pw")
+		assert.EqualError(t, err, "could not decrypt key with given password")
+	})
+
+	t.Run("signer", func(t *testing.T) {
+		s, err := ks.Signer(sk.Address, "pw")
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, s.Address())
+
+		_, err = ks.Signer(sk.Address, "wrong")
+		assert.EqualError(t, err, "could not decrypt key with given password")
+	})
+
+	t.Run("keystore file signer", func(t *testing.T) {
+		s, err := NewKeystoreSigner(filepath.Join(dir, common.Bytes2Hex(sk.Address.Bytes())+".json"), "pw")
+		require.NoError(t, err)
+
+		hash := crypto.Keccak256Hash([]byte("file signer"))
+		sig, err := s.SignHash(context.Background(), hash)
+		require.NoError(t, err)
+		assert.True(t, VerifySessionKeySignature(sk.PublicKey, hash.Bytes(), sig))
+
+		_, err = NewKeystoreSigner(filepath.Join(dir, "missing.json"), "pw")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, ks.Delete(sk.Address))
+		assert.EqualError(t, ks.Delete(sk.Address), "key not found")
+
+		metas, err := ks.List()
+		require.NoError(t, err)
+		assert.Empty(t, metas)
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		_, err := NewKeyStore("", LightScryptParams)
+		assert.EqualError(t, err, "empty keystore directory")
+	})
+}
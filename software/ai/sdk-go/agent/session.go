@@ -1,13 +1,16 @@
 package agent
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
 )
 
 func GenerateSessionKey(chainID *big.Int, duration time.Duration) (*SessionKey, error) {
@@ -84,7 +87,7 @@ func ValidateSessionKey(sk *SessionKey) error {
 		return errors.New("nil session key")
 	}
 
-	if sk.PrivateKey == nil {
+	if sk.PrivateKey == nil && sk.Signer == nil {
 		return errors.New("missing private key")
 	}
 
@@ -102,7 +105,12 @@ func ValidateSessionKey(sk *SessionKey) error {
 		return errors.New("session key expired")
 	}
 
-	expectedAddr := crypto.PubkeyToAddress(sk.PrivateKey.PublicKey)
+	var expectedAddr common.Address
+	if sk.Signer != nil {
+		expectedAddr = sk.Signer.Address()
+	} else {
+		expectedAddr = crypto.PubkeyToAddress(sk.PrivateKey.PublicKey)
+	}
 	if sk.Address != expectedAddr {
 		return errors.New("address mismatch")
 	}
@@ -110,11 +118,45 @@ func ValidateSessionKey(sk *SessionKey) error {
 	return nil
 }
 
+func SessionKeySigner(sk *SessionKey) (signer.Signer, error) {
+	if sk == nil {
+		return nil, errors.New("nil session key")
+	}
+	if sk.Signer != nil {
+		return sk.Signer, nil
+	}
+	return signer.NewPrivateKeySigner(sk.PrivateKey)
+}
+
+func NewRemoteSessionKey(s signer.Signer, chainID, validAfter, validUntil *big.Int) (*SessionKey, error) {
+	if s == nil {
+		return nil, errors.New("nil signer")
+	}
+	return &SessionKey{
+		Address:    s.Address(),
+		ValidAfter: validAfter,
+		ValidUntil: validUntil,
+		ChainID:    chainID,
+		Signer:     s,
+	}, nil
+}
+
 func SignWithSessionKey(sk *SessionKey, hash []byte) ([]byte, error) {
+	return SignWithSessionKeyContext(context.Background(), sk, hash)
+}
+
+func SignWithSessionKeyContext(ctx context.Context, sk *SessionKey, hash []byte) ([]byte, error) {
 	if err := ValidateSessionKey(sk); err != nil {
 		return nil, err
 	}
-	return crypto.Sign(hash, sk.PrivateKey)
+	if len(hash) != common.HashLength {
+		return nil, errors.New("invalid hash length")
+	}
+	s, err := SessionKeySigner(sk)
+	if err != nil {
+		return nil, err
+	}
+	return s.SignHash(ctx, common.BytesToHash(hash))
 }
 
 func VerifySessionKeySignature(pubKey *ecdsa.PublicKey, hash []byte, sig []byte) bool {
@@ -1,11 +1,15 @@
 package agent
 
 import (
+	"context"
 	"math/big"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -213,6 +217,63 @@ func TestSignWithSessionKey(t *testing.T) {
 	})
 }
 
+func TestNewRemoteSessionKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keySigner, err := signer.NewPrivateKeySigner(key)
+	require.NoError(t, err)
+	server := httptest.NewServer(signer.NewServer(keySigner))
+	defer server.Close()
+
+	remote, err := signer.NewRemoteSigner(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+
+	now := time.Now().Unix()
+	sk, err := NewRemoteSessionKey(remote, big.NewInt(8453), big.NewInt(now-60), big.NewInt(now+3600))
+	require.NoError(t, err)
+	assert.Nil(t, sk.PrivateKey)
+	assert.Equal(t, keySigner.Address(), sk.Address)
+
+	t.Run("signs through remote signer", func(t *testing.T) {
+		hash := crypto.Keccak256([]byte("remote"))
+		sig, err := SignWithSessionKey(sk, hash)
+		require.NoError(t, err)
+
+		want, err := crypto.Sign(hash, key)
+		require.NoError(t, err)
+		assert.Equal(t, want, sig)
+	})
+
+	t.Run("session key signer", func(t *testing.T) {
+		s, err := SessionKeySigner(sk)
+		require.NoError(t, err)
+		assert.Same(t, remote, s)
+	})
+
+	t.Run("address mismatch", func(t *testing.T) {
+		bad := *sk
+		bad.Address = common.HexToAddress("0x01")
+		assert.EqualError(t, ValidateSessionKey(&bad), "address mismatch")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired, err := NewRemoteSessionKey(remote, big.NewInt(8453), nil, big.NewInt(now-1))
+		require.NoError(t, err)
+		_, err = SignWithSessionKey(expired, crypto.Keccak256([]byte("x")))
+		assert.EqualError(t, err, "session key expired")
+	})
+
+	t.Run("invalid hash length", func(t *testing.T) {
+		_, err := SignWithSessionKey(sk, []byte{0x01})
+		assert.EqualError(t, err, "invalid hash length")
+	})
+
+	t.Run("nil signer", func(t *testing.T) {
+		_, err := NewRemoteSessionKey(nil, big.NewInt(1), nil, nil)
+		assert.EqualError(t, err, "nil signer")
+	})
+}
+
 func TestVerifySessionKeySignature(t *testing.T) {
 	sk, err := GenerateSessionKey(big.NewInt(8453), time.Hour)
 	require.NoError(t, err)
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/storage"
+)
+
+type Store interface {
+	Get(id string) (*Agent, bool, error)
+	Put(a *Agent) error
+	List() ([]*Agent, error)
+}
+
+type MemoryStore struct {
+	agents map[string]*Agent
+	mu     sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		agents: make(map[string]*Agent),
+	}
+}
+
+func (s *MemoryStore) Get(id string) (*Agent, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.agents[id]
+	return a, ok, nil
+}
+
+func (s *MemoryStore) Put(a *Agent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[a.ID] = a
+	return nil
+}
+
+func (s *MemoryStore) List() ([]*Agent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Agent, 0, len(s.agents))
+	for _, a := range s.agents {
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+type FileStore struct {
+	file      *storage.FileStore
+	password  string
+	params    ScryptParams
+	encrypted map[common.Address]json.RawMessage
+	keys      map[common.Address]*ecdsa.PrivateKey
+	mu        sync.Mutex
+}
+
+type agentRecord struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	WalletAddress common.Address  `json:"walletAddress"`
+	SessionKey    json.RawMessage `json:"sessionKey,omitempty"`
+	Status        AgentStatus     `json:"status"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	ExpiresAt     time.Time       `json:"expiresAt"`
+	Permissions   []string        `json:"permissions,omitempty"`
+}
+
+func NewFileStore(path, password string, params ScryptParams) (*FileStore, error) {
+	if password == "" {
+		return nil, errors.New("empty password")
+	}
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{
+		file:      file,
+		password:  password,
+		params:    params,
+		encrypted: make(map[common.Address]json.RawMessage),
+		keys:      make(map[common.Address]*ecdsa.PrivateKey),
+	}, nil
+}
+
+func (s *FileStore) Get(id string) (*Agent, bool, error) {
+	var rec agentRecord
+	ok, err := s.file.Get(id, &rec)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	a, err := s.toAgent(&rec)
+	if err != nil {
+		return nil, false, err
+	}
+	return a, true, nil
+}
+
+func (s *FileStore) Put(a *Agent) error {
+	rec, err := s.newAgentRecord(a)
+	if err != nil {
+		return err
+	}
+	return s.file.Put(a.ID, rec)
+}
+
+func (s *FileStore) List() ([]*Agent, error) {
+	keys := s.file.Keys()
+	result := make([]*Agent, 0, len(keys))
+	for _, key := range keys {
+		a, ok, err := s.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (s *FileStore) newAgentRecord(a *Agent) (*agentRecord, error) {
+	rec := &agentRecord{
+		ID:            a.ID,
+		Name:          a.Name,
+		WalletAddress: a.WalletAddress,
+		Status:        a.Status,
+		CreatedAt:     a.CreatedAt,
+		ExpiresAt:     a.ExpiresAt,
+		Permissions:   a.Permissions,
+	}
+	if a.SessionKey == nil || a.SessionKey.PrivateKey == nil {
+		return rec, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addr := crypto.PubkeyToAddress(a.SessionKey.PrivateKey.PublicKey)
+	keyjson, ok := s.encrypted[addr]
+	if !ok || !keystoreMatches(keyjson, a) {
+		var err error
+		keyjson, err = EncryptSessionKey(a.SessionKey, KeystoreMetadata{
+			AgentID:       a.ID,
+			WalletAddress: a.WalletAddress,
+		}, s.password, s.params)
+		if err != nil {
+			return nil, err
+		}
+		s.encrypted[addr] = keyjson
+		s.keys[addr] = a.SessionKey.PrivateKey
+	}
+	rec.SessionKey = keyjson
+	return rec, nil
+}
+
+func keystoreMatches(keyjson []byte, a *Agent) bool {
+	meta, err := ReadKeystoreMetadata(keyjson)
+	if err != nil {
+		return false
+	}
+	return meta.AgentID == a.ID &&
+		meta.WalletAddress == a.WalletAddress &&
+		bigEqual(meta.ChainID, a.SessionKey.ChainID) &&
+		bigEqual(meta.ValidAfter, a.SessionKey.ValidAfter) &&
+		bigEqual(meta.ValidUntil, a.SessionKey.ValidUntil)
+}
+
+func bigEqual(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+func (s *FileStore) toAgent(rec *agentRecord) (*Agent, error) {
+	a := &Agent{
+		ID:            rec.ID,
+		Name:          rec.Name,
+		WalletAddress: rec.WalletAddress,
+		Status:        rec.Status,
+		CreatedAt:     rec.CreatedAt,
+		ExpiresAt:     rec.ExpiresAt,
+		Permissions:   rec.Permissions,
+	}
+	if len(rec.SessionKey) == 0 {
+		return a, nil
+	}
+
+	meta, err := ReadKeystoreMetadata(rec.SessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[meta.Address]
+	if !ok {
+		sk, _, err := DecryptSessionKey(rec.SessionKey, s.password)
+		if err != nil {
+			return nil, err
+		}
+		key = sk.PrivateKey
+		s.keys[meta.Address] = key
+		s.encrypted[meta.Address] = rec.SessionKey
+	}
+
+	a.SessionKey = &SessionKey{
+		PrivateKey: key,
+		PublicKey:  &key.PublicKey,
+		Address:    meta.Address,
+		ValidAfter: meta.ValidAfter,
+		ValidUntil: meta.ValidUntil,
+		ChainID:    meta.ChainID,
+	}
+	return a, nil
+}
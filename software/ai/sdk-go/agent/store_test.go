@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+	walletAddr := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	store, err := NewFileStore(path, "hunter2", LightScryptParams)
+	require.NoError(t, err)
+	svc := NewAgentService(WithStore(store))
+
+	a, err := svc.CreateAgent(testAgentParams(walletAddr))
+	require.NoError(t, err)
+	revoked, err := svc.CreateAgent(testAgentParams(walletAddr))
+	require.NoError(t, err)
+	require.NoError(t, svc.RevokeAgent(revoked.ID))
+
+	reopened, err := NewFileStore(path, "hunter2", LightScryptParams)
+	require.NoError(t, err)
+	restarted := NewAgentService(WithStore(reopened))
+
+	t.Run("agent restored", func(t *testing.T) {
+		got, err := restarted.GetAgent(a.ID)
+		require.NoError(t, err)
+		assert.Equal(t, a.Name, got.Name)
+		assert.Equal(t, a.WalletAddress, got.WalletAddress)
+		assert.Equal(t, a.Permissions, got.Permissions)
+		assert.Equal(t, AgentStatusActive, got.Status)
+		assert.True(t, a.ExpiresAt.Equal(got.ExpiresAt))
+	})
+
+	t.Run("session key can still sign", func(t *testing.T) {
+		got, err := restarted.GetAgent(a.ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.SessionKey)
+		assert.Equal(t, a.SessionKey.Address, got.SessionKey.Address)
+		assert.Equal(t, a.SessionKey.ValidUntil, got.SessionKey.ValidUntil)
+		assert.Equal(t, a.SessionKey.ChainID, got.SessionKey.ChainID)
+
+		hash := crypto.Keccak256([]byte("payload"))
+		sig, err := SignWithSessionKey(got.SessionKey, hash)
+		require.NoError(t, err)
+		assert.True(t, VerifySessionKeySignature(a.SessionKey.PublicKey, hash, sig))
+	})
+
+	t.Run("revocation persisted", func(t *testing.T) {
+		got, err := restarted.GetAgent(revoked.ID)
+		require.NoError(t, err)
+		assert.Equal(t, AgentStatusRevoked, got.Status)
+		agents, err := restarted.ListAgents(walletAddr)
+		require.NoError(t, err)
+		assert.Len(t, agents, 2)
+	})
+
+	t.Run("expiry persisted", func(t *testing.T) {
+		expired := &Agent{
+			ID:            "expired",
+			WalletAddress: walletAddr,
+			Status:        AgentStatusActive,
+			ExpiresAt:     time.Now().Add(-time.Minute),
+		}
+		require.NoError(t, reopened.Put(expired))
+
+		got, err := restarted.GetAgent("expired")
+		require.NoError(t, err)
+		assert.Equal(t, AgentStatusExpired, got.Status)
+
+		again, err := NewFileStore(path, "hunter2", LightScryptParams)
+		require.NoError(t, err)
+		stored, ok, err := again.Get("expired")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, AgentStatusExpired, stored.Status)
+	})
+
+	t.Run("session key not stored in plaintext", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), hex.EncodeToString(crypto.FromECDSA(a.SessionKey.PrivateKey)))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		wrong, err := NewFileStore(path, "wrong", LightScryptParams)
+		require.NoError(t, err)
+		_, _, err = wrong.Get(a.ID)
+		assert.EqualError(t, err, "could not decrypt key with given password")
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := NewFileStore(path, "", LightScryptParams)
+		assert.EqualError(t, err, "empty password")
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/signer"
 )
 
 type AgentStatus int
@@ -41,6 +42,7 @@ type SessionKey struct {
 	ValidAfter    *big.Int
 	ValidUntil    *big.Int
 	ChainID       *big.Int
+	Signer        signer.Signer
 }
 
 type CreateAgentParams struct {
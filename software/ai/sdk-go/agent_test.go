@@ -0,0 +1,256 @@
+package sigloop
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/agent"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/policy"
+	"github.com/sigloop/sdk-go/signer"
+	"github.com/sigloop/sdk-go/wallet"
+	"github.com/sigloop/sdk-go/x402"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentClient(t *testing.T) {
+	ctx := context.Background()
+	walletAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	router := common.HexToAddress("0x2626664c2603336E57B271c5C0b26F421741e481")
+	other := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	usdc := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	weth := common.HexToAddress("0x4200000000000000000000000000000000000006")
+
+	client := NewClient(wallet.WalletConfig{ChainID: big.NewInt(8453)}, x402.X402Policy{}, 3600)
+	a, err := client.AgentService.CreateAgent(agent.CreateAgentParams{
+		Config:  agent.AgentConfig{Name: "trader", WalletAddress: walletAddr, Duration: time.Hour},
+		ChainID: big.NewInt(8453),
+	})
+	require.NoError(t, err)
+
+	walletPolicy, err := client.PolicyService.CreatePolicy(&policy.Policy{
+		ContractAllowlist: policy.NewContractAllowlist([]common.Address{router}),
+		SpendingLimits:    []policy.SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(1000), Period: time.Hour}},
+	})
+	require.NoError(t, err)
+	_, err = client.PolicyService.AttachToWallet(walletPolicy.ID, walletAddr)
+	require.NoError(t, err)
+
+	ac, err := client.Agent(a.ID)
+	require.NoError(t, err)
+
+	swap := func(router common.Address, amount int64) defi.SwapParams {
+		return defi.SwapParams{TokenIn: usdc, TokenOut: weth, AmountIn: big.NewInt(amount), Router: router}
+	}
+
+	t.Run("released swap does not spend", func(t *testing.T) {
+		result, r, err := ac.ExecuteSwap(ctx, swap(router, 600))
+		require.NoError(t, err)
+		assert.Equal(t, router, result.To)
+		require.NotNil(t, r)
+
+		_, _, err = ac.ExecuteSwap(ctx, swap(router, 600))
+		assert.ErrorContains(t, err, "spending_limit")
+		require.NoError(t, ac.Release(r))
+	})
+
+	t.Run("committed swap spends", func(t *testing.T) {
+		_, r, err := ac.ExecuteSwap(ctx, swap(router, 600))
+		require.NoError(t, err)
+		require.NoError(t, ac.Commit(r))
+
+		_, _, err = ac.ExecuteSwap(ctx, swap(router, 600))
+		assert.ErrorContains(t, err, "spending_limit")
+	})
+
+	t.Run("agent policy overrides wallet policy", func(t *testing.T) {
+		agentPolicy, err := client.PolicyService.CreatePolicy(&policy.Policy{
+			ContractAllowlist: policy.NewContractAllowlist([]common.Address{other}),
+		})
+		require.NoError(t, err)
+		_, err = client.PolicyService.AttachToAgent(agentPolicy.ID, a.ID)
+		require.NoError(t, err)
+		defer client.PolicyService.Detach(policy.AgentKey(a.ID))
+
+		p, err := ac.EffectivePolicy()
+		require.NoError(t, err)
+		assert.Equal(t, agentPolicy.ID, p.ID)
+
+		_, _, err = ac.ExecuteSwap(ctx, swap(router, 1))
+		assert.EqualError(t, err, "policy denied: contract_allowlist: contract 0x2626664c2603336E57B271c5C0b26F421741e481 is not allowed")
+		_, r, err := ac.Supply(ctx, defi.LendingParams{Token: usdc, Amount: big.NewInt(1), Pool: other})
+		assert.NoError(t, err)
+		assert.NoError(t, ac.Commit(r))
+	})
+
+	t.Run("x402 payments are authorized", func(t *testing.T) {
+		paidStatus := http.StatusInternalServerError
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-PAYMENT") != "" {
+				w.WriteHeader(paidStatus)
+				return
+			}
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"scheme":"exact","network":"base","maxAmountRequired":"10","payTo":"0x3333333333333333333333333333333333333333","requiredDeadline":"9999999999"}`))
+		}))
+		defer server.Close()
+
+		transport, err := ac.X402Transport(nil, x402.X402Config{AutoPay: true})
+		require.NoError(t, err)
+		_, err = (&http.Client{Transport: transport}).Get(server.URL)
+		assert.ErrorContains(t, err, "policy denied: contract_allowlist")
+
+		paymentPolicy, err := client.PolicyService.CreatePolicy(&policy.Policy{
+			ContractAllowlist: policy.NewContractAllowlist([]common.Address{x402.PaymentToken}),
+			SpendingLimits:    []policy.SpendingLimit{{Token: x402.PaymentToken, MaxAmount: big.NewInt(10), Period: time.Hour}},
+		})
+		require.NoError(t, err)
+		_, err = client.PolicyService.AttachToAgent(paymentPolicy.ID, a.ID)
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		paidStatus = http.StatusOK
+		resp, err = (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = (&http.Client{Transport: transport}).Get(server.URL)
+		assert.ErrorContains(t, err, "spending_limit")
+	})
+
+	t.Run("borrow counts towards spending limits", func(t *testing.T) {
+		lendingPolicy, err := client.PolicyService.CreatePolicy(&policy.Policy{
+			SpendingLimits: []policy.SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: time.Hour}},
+		})
+		require.NoError(t, err)
+		_, err = client.PolicyService.AttachToAgent(lendingPolicy.ID, a.ID)
+		require.NoError(t, err)
+		defer client.PolicyService.Detach(policy.AgentKey(a.ID))
+
+		_, _, err = ac.Borrow(ctx, defi.LendingParams{Token: usdc, Amount: big.NewInt(101), Pool: other})
+		assert.ErrorContains(t, err, "spending_limit")
+		_, r, err := ac.Borrow(ctx, defi.LendingParams{Token: usdc, Amount: big.NewInt(100), Pool: other})
+		require.NoError(t, err)
+		assert.NoError(t, ac.Release(r))
+	})
+
+	t.Run("revoked agent cannot act", func(t *testing.T) {
+		require.NoError(t, client.AgentService.RevokeAgent(a.ID))
+		_, _, err := ac.Supply(ctx, defi.LendingParams{Token: usdc, Amount: big.NewInt(1), Pool: router})
+		assert.EqualError(t, err, "agent is not active")
+		_, err = client.Agent(a.ID)
+		assert.EqualError(t, err, "agent is not active")
+	})
+
+	t.Run("unknown agent", func(t *testing.T) {
+		_, err := client.Agent("missing")
+		assert.EqualError(t, err, "agent not found")
+	})
+}
+
+func TestAgentClientUnbound(t *testing.T) {
+	usdc := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	pool := common.HexToAddress("0xA238Dd80C259a72e81d7e4664a9801593F98d1c5")
+
+	client := NewClient(wallet.WalletConfig{ChainID: big.NewInt(8453)}, x402.X402Policy{}, 3600)
+	a, err := client.AgentService.CreateAgent(agent.CreateAgentParams{
+		Config:  agent.AgentConfig{Name: "idle", WalletAddress: common.HexToAddress("0x1111111111111111111111111111111111111111"), Duration: time.Hour},
+		ChainID: big.NewInt(8453),
+	})
+	require.NoError(t, err)
+	ac, err := client.Agent(a.ID)
+	require.NoError(t, err)
+
+	_, _, err = ac.Supply(context.Background(), defi.LendingParams{Token: usdc, Amount: big.NewInt(1), Pool: pool})
+	assert.ErrorIs(t, err, policy.ErrNoPolicy)
+}
+
+func TestAgentClientApproval(t *testing.T) {
+	ctx := context.Background()
+	router := common.HexToAddress("0x2626664c2603336E57B271c5C0b26F421741e481")
+	usdc := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	weth := common.HexToAddress("0x4200000000000000000000000000000000000006")
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner, err := signer.NewPrivateKeySigner(key)
+	require.NoError(t, err)
+
+	client := NewClient(wallet.WalletConfig{ChainID: big.NewInt(8453)}, x402.X402Policy{}, 3600)
+	a, err := client.AgentService.CreateAgent(agent.CreateAgentParams{
+		Config:  agent.AgentConfig{Name: "trader", WalletAddress: common.HexToAddress("0x1111111111111111111111111111111111111111"), Duration: time.Hour},
+		ChainID: big.NewInt(8453),
+	})
+	require.NoError(t, err)
+	p, err := client.PolicyService.CreatePolicy(&policy.Policy{
+		ContractAllowlist: policy.NewContractAllowlist([]common.Address{x402.PaymentToken}),
+		Escalation: &policy.Escalation{
+			Thresholds:   []policy.ApprovalThreshold{{Token: x402.PaymentToken, Amount: big.NewInt(5)}},
+			NewContracts: true,
+			Approvers:    []common.Address{owner.Address()},
+		},
+	})
+	require.NoError(t, err)
+	_, err = client.PolicyService.AttachToAgent(p.ID, a.ID)
+	require.NoError(t, err)
+	ac, err := client.Agent(a.ID)
+	require.NoError(t, err)
+
+	approvePending := func(t *testing.T) {
+		pending, err := ac.Approvals(policy.ApprovalPending)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		_, err = client.PolicyService.Approvals().ApproveWithSigner(ctx, pending[0].ID, owner)
+		require.NoError(t, err)
+	}
+
+	t.Run("swap on a new contract", func(t *testing.T) {
+		swap := defi.SwapParams{TokenIn: usdc, TokenOut: weth, AmountIn: big.NewInt(1), Router: router}
+		_, _, err := ac.ExecuteSwap(ctx, swap)
+		assert.ErrorIs(t, err, policy.ErrApprovalRequired)
+
+		approvePending(t)
+		result, r, err := ac.ExecuteSwap(ctx, swap)
+		require.NoError(t, err)
+		assert.Equal(t, router, result.To)
+		require.NoError(t, ac.Commit(r))
+	})
+
+	t.Run("x402 payment above threshold", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-PAYMENT") != "" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"scheme":"exact","network":"base","maxAmountRequired":"10","payTo":"0x3333333333333333333333333333333333333333","requiredDeadline":"9999999999"}`))
+		}))
+		defer server.Close()
+
+		transport, err := ac.X402Transport(nil, x402.X402Config{AutoPay: true})
+		require.NoError(t, err)
+		_, err = (&http.Client{Transport: transport}).Get(server.URL)
+		assert.ErrorIs(t, err, policy.ErrApprovalRequired)
+
+		approvePending(t)
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		executed, err := ac.Approvals(policy.ApprovalExecuted)
+		require.NoError(t, err)
+		assert.Len(t, executed, 2)
+	})
+}
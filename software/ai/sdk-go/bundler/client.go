@@ -0,0 +1,232 @@
+package bundler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+const DefaultPollInterval = 2 * time.Second
+
+type Client struct {
+	url        string
+	entryPoint common.Address
+	version    chain.EntryPointVersion
+	httpClient *http.Client
+	nextID     atomic.Uint64
+}
+
+func NewClient(url string, entryPoint common.Address, version chain.EntryPointVersion, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if version == "" {
+		version = chain.EntryPointV06
+	}
+	return &Client{
+		url:        url,
+		entryPoint: entryPoint,
+		version:    version,
+		httpClient: httpClient,
+	}
+}
+
+func NewClientForChain(cfg *chain.ChainConfig, httpClient *http.Client) (*Client, error) {
+	if cfg == nil {
+		return nil, errors.New("nil chain config")
+	}
+	if cfg.BundlerURL == "" {
+		return nil, errors.New("bundler url not configured")
+	}
+	return NewClient(cfg.BundlerURL, cfg.EntryPoint, cfg.Version(), httpClient), nil
+}
+
+func (c *Client) EntryPoint() common.Address {
+	return c.entryPoint
+}
+
+func (c *Client) EntryPointVersion() chain.EntryPointVersion {
+	return c.version
+}
+
+func (c *Client) SendUserOperation(ctx context.Context, op *encoding.UserOperation) (common.Hash, error) {
+	if op == nil {
+		return common.Hash{}, errors.New("nil user operation")
+	}
+
+	rpcOp, err := encodeUserOperation(op, c.version)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	var hash common.Hash
+	if err := c.call(ctx, &hash, "eth_sendUserOperation", rpcOp, c.entryPoint); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+func (c *Client) EstimateUserOperationGas(ctx context.Context, op *encoding.UserOperation) (*GasEstimate, error) {
+	if op == nil {
+		return nil, errors.New("nil user operation")
+	}
+
+	rpcOp, err := encodeUserOperation(op, c.version)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw rpcGasEstimate
+	if err := c.call(ctx, &raw, "eth_estimateUserOperationGas", rpcOp, c.entryPoint); err != nil {
+		return nil, err
+	}
+
+	return &GasEstimate{
+		PreVerificationGas:            fromHexBig(raw.PreVerificationGas),
+		VerificationGasLimit:          fromHexBig(raw.VerificationGasLimit),
+		CallGasLimit:                  fromHexBig(raw.CallGasLimit),
+		PaymasterVerificationGasLimit: fromHexBig(raw.PaymasterVerificationGasLimit),
+	}, nil
+}
+
+func (c *Client) GetUserOperationByHash(ctx context.Context, hash common.Hash) (*UserOperationByHash, error) {
+	var raw *rpcUserOperationByHash
+	if err := c.call(ctx, &raw, "eth_getUserOperationByHash", hash); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrUserOperationNotFound
+	}
+
+	op, err := decodeUserOperation(raw.UserOperation, c.version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserOperationByHash{
+		UserOperation:   op,
+		EntryPoint:      raw.EntryPoint,
+		BlockNumber:     fromHexBig(raw.BlockNumber),
+		BlockHash:       raw.BlockHash,
+		TransactionHash: raw.TransactionHash,
+	}, nil
+}
+
+func (c *Client) GetUserOperationReceipt(ctx context.Context, hash common.Hash) (*UserOperationReceipt, error) {
+	var raw *rpcUserOperationReceipt
+	if err := c.call(ctx, &raw, "eth_getUserOperationReceipt", hash); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrReceiptNotFound
+	}
+
+	return &UserOperationReceipt{
+		UserOpHash:      raw.UserOpHash,
+		EntryPoint:      raw.EntryPoint,
+		Sender:          raw.Sender,
+		Nonce:           fromHexBig(raw.Nonce),
+		Paymaster:       raw.Paymaster,
+		ActualGasCost:   fromHexBig(raw.ActualGasCost),
+		ActualGasUsed:   fromHexBig(raw.ActualGasUsed),
+		Success:         raw.Success,
+		Reason:          raw.Reason,
+		TransactionHash: raw.Receipt.TransactionHash,
+		BlockHash:       raw.Receipt.BlockHash,
+		BlockNumber:     fromHexBig(raw.Receipt.BlockNumber),
+	}, nil
+}
+
+func (c *Client) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	var entryPoints []common.Address
+	if err := c.call(ctx, &entryPoints, "eth_supportedEntryPoints"); err != nil {
+		return nil, err
+	}
+	return entryPoints, nil
+}
+
+func (c *Client) WaitForReceipt(ctx context.Context, hash common.Hash, interval time.Duration) (*UserOperationReceipt, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := c.GetUserOperationReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ErrReceiptNotFound) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("bundler returned http status %d", resp.StatusCode)
+		}
+		return errors.New("invalid bundler response")
+	}
+
+	if rpcResp.Error != nil {
+		return parseRPCError(rpcResp.Error)
+	}
+
+	if len(rpcResp.Result) == 0 {
+		return errors.New("empty bundler response")
+	}
+
+	return json.Unmarshal(rpcResp.Result, result)
+}
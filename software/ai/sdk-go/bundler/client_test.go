@@ -0,0 +1,429 @@
+package bundler
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEntryPoint = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+
+type stubBundler struct {
+	handlers map[string]func(params []json.RawMessage) (interface{}, *RPCError)
+	calls    map[string]int
+	mu       sync.Mutex
+}
+
+func newStubBundler(t *testing.T) (*stubBundler, *httptest.Server) {
+	t.Helper()
+	stub := &stubBundler{
+		handlers: make(map[string]func(params []json.RawMessage) (interface{}, *RPCError)),
+		calls:    make(map[string]int),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		stub.calls[req.Method]++
+		handler, ok := stub.handlers[req.Method]
+		stub.mu.Unlock()
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if !ok {
+			resp["error"] = &RPCError{Code: -32601, Message: "method not found"}
+		} else if result, rpcErr := handler(req.Params); rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *stubBundler) handle(method string, fn func(params []json.RawMessage) (interface{}, *RPCError)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = fn
+}
+
+func (s *stubBundler) callCount(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func testUserOp() *encoding.UserOperation {
+	return &encoding.UserOperation{
+		Sender:               common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Nonce:                big.NewInt(7),
+		InitCode:             []byte{},
+		CallData:             []byte{0x01, 0x02, 0x03},
+		CallGasLimit:         big.NewInt(100000),
+		VerificationGasLimit: big.NewInt(200000),
+		PreVerificationGas:   big.NewInt(50000),
+		MaxFeePerGas:         big.NewInt(1000000000),
+		MaxPriorityFeePerGas: big.NewInt(100000000),
+		PaymasterAndData:     []byte{},
+		Signature:            []byte{0xde, 0xad},
+	}
+}
+
+func TestNewClientForChain(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cfg := chain.Chains[chain.Base]
+		c, err := NewClientForChain(&cfg, nil)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.BundlerURL, c.url)
+		assert.Equal(t, cfg.EntryPoint, c.EntryPoint())
+		assert.Equal(t, chain.EntryPointV06, c.EntryPointVersion())
+	})
+
+	t.Run("v0.7 chain", func(t *testing.T) {
+		cfg := chain.Chains[chain.Base]
+		cfg.EntryPoint = chain.EntryPointV07Address
+		cfg.EntryPointVersion = chain.EntryPointV07
+		c, err := NewClientForChain(&cfg, nil)
+		require.NoError(t, err)
+		assert.Equal(t, chain.EntryPointV07, c.EntryPointVersion())
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		_, err := NewClientForChain(nil, nil)
+		require.Error(t, err)
+		assert.Equal(t, "nil chain config", err.Error())
+	})
+
+	t.Run("missing bundler url", func(t *testing.T) {
+		_, err := NewClientForChain(&chain.ChainConfig{}, nil)
+		require.Error(t, err)
+		assert.Equal(t, "bundler url not configured", err.Error())
+	})
+}
+
+func TestSendUserOperation(t *testing.T) {
+	opHash := common.HexToHash("0x1234")
+
+	t.Run("success", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		var gotOp map[string]string
+		var gotEntryPoint common.Address
+		stub.handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, *RPCError) {
+			if assert.Len(t, params, 2) {
+				assert.NoError(t, json.Unmarshal(params[0], &gotOp))
+				assert.NoError(t, json.Unmarshal(params[1], &gotEntryPoint))
+			}
+			return opHash, nil
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		hash, err := c.SendUserOperation(context.Background(), testUserOp())
+		require.NoError(t, err)
+		assert.Equal(t, opHash, hash)
+		assert.Equal(t, testEntryPoint, gotEntryPoint)
+		assert.Equal(t, "0x7", gotOp["nonce"])
+		assert.Equal(t, "0x010203", gotOp["callData"])
+		assert.Equal(t, "0x", gotOp["initCode"])
+		assert.Equal(t, "0xdead", gotOp["signature"])
+	})
+
+	t.Run("v0.7 format", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		var gotOp map[string]string
+		stub.handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, *RPCError) {
+			if assert.Len(t, params, 2) {
+				assert.NoError(t, json.Unmarshal(params[0], &gotOp))
+			}
+			return opHash, nil
+		})
+
+		op := testUserOp()
+		factory := common.HexToAddress("0x1111111111111111111111111111111111111111")
+		paymaster := common.HexToAddress("0x2222222222222222222222222222222222222222")
+		op.InitCode = append(factory.Bytes(), 0xab, 0xcd)
+		pmd, err := encoding.PackPaymasterAndData(paymaster, big.NewInt(60000), big.NewInt(30000), []byte{0xef})
+		require.NoError(t, err)
+		op.PaymasterAndData = pmd
+
+		c := NewClient(server.URL, chain.EntryPointV07Address, chain.EntryPointV07, nil)
+		_, err = c.SendUserOperation(context.Background(), op)
+		require.NoError(t, err)
+
+		assert.Equal(t, factory.Hex(), common.HexToAddress(gotOp["factory"]).Hex())
+		assert.Equal(t, "0xabcd", gotOp["factoryData"])
+		assert.Equal(t, paymaster.Hex(), common.HexToAddress(gotOp["paymaster"]).Hex())
+		assert.Equal(t, "0xea60", gotOp["paymasterVerificationGasLimit"])
+		assert.Equal(t, "0x7530", gotOp["paymasterPostOpGasLimit"])
+		assert.Equal(t, "0xef", gotOp["paymasterData"])
+		assert.NotContains(t, gotOp, "initCode")
+		assert.NotContains(t, gotOp, "paymasterAndData")
+	})
+
+	t.Run("v0.7 omits empty factory and paymaster", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		var gotOp map[string]string
+		stub.handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, *RPCError) {
+			if assert.Len(t, params, 2) {
+				assert.NoError(t, json.Unmarshal(params[0], &gotOp))
+			}
+			return opHash, nil
+		})
+
+		c := NewClient(server.URL, chain.EntryPointV07Address, chain.EntryPointV07, nil)
+		_, err := c.SendUserOperation(context.Background(), testUserOp())
+		require.NoError(t, err)
+		assert.NotContains(t, gotOp, "factory")
+		assert.NotContains(t, gotOp, "paymaster")
+		assert.Equal(t, "0x7", gotOp["nonce"])
+	})
+
+	t.Run("v0.7 invalid paymaster and data", func(t *testing.T) {
+		op := testUserOp()
+		op.PaymasterAndData = []byte{0x01, 0x02}
+
+		c := NewClient("http://unused", chain.EntryPointV07Address, chain.EntryPointV07, nil)
+		_, err := c.SendUserOperation(context.Background(), op)
+		require.Error(t, err)
+		assert.Equal(t, "invalid paymaster and data", err.Error())
+	})
+
+	t.Run("nil op", func(t *testing.T) {
+		c := NewClient("http://unused", testEntryPoint, chain.EntryPointV06, nil)
+		_, err := c.SendUserOperation(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, "nil user operation", err.Error())
+	})
+
+	t.Run("aa error", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return nil, &RPCError{Code: -32500, Message: "FailedOp(0, AA21 didn't pay prefund)"}
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		_, err := c.SendUserOperation(context.Background(), testUserOp())
+		require.Error(t, err)
+
+		var aaErr *AAError
+		require.ErrorAs(t, err, &aaErr)
+		assert.Equal(t, "AA21", aaErr.Code)
+		assert.Equal(t, "account", aaErr.Category())
+		assert.True(t, IsAAError(err, "AA21"))
+
+		var rpcErr *RPCError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, -32500, rpcErr.Code)
+	})
+
+	t.Run("plain rpc error", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_sendUserOperation", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return nil, &RPCError{Code: -32602, Message: "invalid params"}
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		_, err := c.SendUserOperation(context.Background(), testUserOp())
+		require.Error(t, err)
+		assert.False(t, IsAAError(err, "AA21"))
+		assert.Equal(t, "bundler rpc error -32602: invalid params", err.Error())
+	})
+}
+
+func TestEstimateUserOperationGas(t *testing.T) {
+	stub, server := newStubBundler(t)
+	stub.handle("eth_estimateUserOperationGas", func(params []json.RawMessage) (interface{}, *RPCError) {
+		return map[string]string{
+			"preVerificationGas":   "0xc350",
+			"verificationGasLimit": "0x30d40",
+			"callGasLimit":         "0x186a0",
+		}, nil
+	})
+
+	c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+	est, err := c.EstimateUserOperationGas(context.Background(), testUserOp())
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(50000), est.PreVerificationGas)
+	assert.Equal(t, big.NewInt(200000), est.VerificationGasLimit)
+	assert.Equal(t, big.NewInt(100000), est.CallGasLimit)
+	assert.Equal(t, int64(0), est.PaymasterVerificationGasLimit.Int64())
+}
+
+func TestGetUserOperationByHash(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_getUserOperationByHash", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return map[string]interface{}{
+				"userOperation":   toRPCUserOperation(testUserOp()),
+				"entryPoint":      testEntryPoint,
+				"blockNumber":     "0x10",
+				"blockHash":       common.HexToHash("0xbb"),
+				"transactionHash": common.HexToHash("0xcc"),
+			}, nil
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		res, err := c.GetUserOperationByHash(context.Background(), common.HexToHash("0x01"))
+		require.NoError(t, err)
+		assert.Equal(t, testUserOp().Sender, res.UserOperation.Sender)
+		assert.Equal(t, big.NewInt(7), res.UserOperation.Nonce)
+		assert.Equal(t, big.NewInt(16), res.BlockNumber)
+		assert.Equal(t, common.HexToHash("0xcc"), res.TransactionHash)
+	})
+
+	t.Run("found v0.7", func(t *testing.T) {
+		op := testUserOp()
+		op.InitCode = append(common.HexToAddress("0x1111111111111111111111111111111111111111").Bytes(), 0xab)
+		pmd, err := encoding.PackPaymasterAndData(common.HexToAddress("0x2222222222222222222222222222222222222222"), big.NewInt(60000), big.NewInt(30000), nil)
+		require.NoError(t, err)
+		op.PaymasterAndData = pmd
+
+		rpcOp, err := toRPCUserOperationV07(op)
+		require.NoError(t, err)
+
+		stub, server := newStubBundler(t)
+		stub.handle("eth_getUserOperationByHash", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return map[string]interface{}{
+				"userOperation":   rpcOp,
+				"entryPoint":      chain.EntryPointV07Address,
+				"blockNumber":     "0x10",
+				"blockHash":       common.HexToHash("0xbb"),
+				"transactionHash": common.HexToHash("0xcc"),
+			}, nil
+		})
+
+		c := NewClient(server.URL, chain.EntryPointV07Address, chain.EntryPointV07, nil)
+		res, err := c.GetUserOperationByHash(context.Background(), common.HexToHash("0x01"))
+		require.NoError(t, err)
+		assert.Equal(t, op.InitCode, res.UserOperation.InitCode)
+		assert.Equal(t, op.PaymasterAndData, res.UserOperation.PaymasterAndData)
+		assert.Equal(t, big.NewInt(7), res.UserOperation.Nonce)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_getUserOperationByHash", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return nil, nil
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		_, err := c.GetUserOperationByHash(context.Background(), common.HexToHash("0x01"))
+		require.ErrorIs(t, err, ErrUserOperationNotFound)
+	})
+}
+
+func TestSupportedEntryPoints(t *testing.T) {
+	stub, server := newStubBundler(t)
+	stub.handle("eth_supportedEntryPoints", func(params []json.RawMessage) (interface{}, *RPCError) {
+		assert.Empty(t, params)
+		return []common.Address{testEntryPoint}, nil
+	})
+
+	c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+	eps, err := c.SupportedEntryPoints(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{testEntryPoint}, eps)
+}
+
+func TestWaitForReceipt(t *testing.T) {
+	opHash := common.HexToHash("0xabcd")
+	receipt := map[string]interface{}{
+		"userOpHash":    opHash,
+		"entryPoint":    testEntryPoint,
+		"sender":        common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		"nonce":         "0x7",
+		"actualGasCost": "0x64",
+		"actualGasUsed": "0x32",
+		"success":       true,
+		"receipt": map[string]interface{}{
+			"transactionHash": common.HexToHash("0xee"),
+			"blockHash":       common.HexToHash("0xff"),
+			"blockNumber":     "0x2a",
+		},
+	}
+
+	t.Run("polls until mined", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		var mu sync.Mutex
+		attempts := 0
+		stub.handle("eth_getUserOperationReceipt", func(params []json.RawMessage) (interface{}, *RPCError) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts < 3 {
+				return nil, nil
+			}
+			return receipt, nil
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		got, err := c.WaitForReceipt(context.Background(), opHash, time.Millisecond)
+		require.NoError(t, err)
+		assert.True(t, got.Success)
+		assert.Equal(t, opHash, got.UserOpHash)
+		assert.Equal(t, big.NewInt(100), got.ActualGasCost)
+		assert.Equal(t, big.NewInt(42), got.BlockNumber)
+		assert.Equal(t, common.HexToHash("0xee"), got.TransactionHash)
+		assert.Equal(t, 3, stub.callCount("eth_getUserOperationReceipt"))
+	})
+
+	t.Run("context timeout", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_getUserOperationReceipt", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return nil, nil
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := c.WaitForReceipt(ctx, opHash, time.Millisecond)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("rpc error stops polling", func(t *testing.T) {
+		stub, server := newStubBundler(t)
+		stub.handle("eth_getUserOperationReceipt", func(params []json.RawMessage) (interface{}, *RPCError) {
+			return nil, &RPCError{Code: -32000, Message: "internal error"}
+		})
+
+		c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+		_, err := c.WaitForReceipt(context.Background(), opHash, time.Millisecond)
+		require.Error(t, err)
+		assert.Equal(t, 1, stub.callCount("eth_getUserOperationReceipt"))
+	})
+}
+
+func TestCallInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("bad gateway"))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, testEntryPoint, chain.EntryPointV06, nil)
+	_, err := c.SupportedEntryPoints(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "bundler returned http status 502", err.Error())
+}
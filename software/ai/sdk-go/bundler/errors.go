@@ -0,0 +1,125 @@
+package bundler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var ErrReceiptNotFound = errors.New("user operation receipt not found")
+
+var ErrUserOperationNotFound = errors.New("user operation not found")
+
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("bundler rpc error %d: %s", e.Code, e.Message)
+}
+
+type AAError struct {
+	Code        string
+	Description string
+	Message     string
+	RPC         *RPCError
+}
+
+func (e *AAError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s %s: %s", e.Code, e.Description, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *AAError) Unwrap() error {
+	if e.RPC == nil {
+		return nil
+	}
+	return e.RPC
+}
+
+func (e *AAError) Category() string {
+	if len(e.Code) < 3 {
+		return "unknown"
+	}
+	switch e.Code[2] {
+	case '1':
+		return "factory"
+	case '2':
+		return "account"
+	case '3':
+		return "paymaster"
+	case '4':
+		return "verification"
+	case '5':
+		return "postOp"
+	case '9':
+		return "entryPoint"
+	}
+	return "unknown"
+}
+
+var aaDescriptions = map[string]string{
+	"AA10": "sender already constructed",
+	"AA13": "initCode failed or OOG",
+	"AA14": "initCode must return sender",
+	"AA15": "initCode must create sender",
+	"AA20": "account not deployed",
+	"AA21": "didn't pay prefund",
+	"AA22": "expired or not due",
+	"AA23": "reverted",
+	"AA24": "signature error",
+	"AA25": "invalid account nonce",
+	"AA26": "over verificationGasLimit",
+	"AA30": "paymaster not deployed",
+	"AA31": "paymaster deposit too low",
+	"AA32": "paymaster expired or not due",
+	"AA33": "paymaster reverted",
+	"AA34": "paymaster signature error",
+	"AA40": "over verificationGasLimit",
+	"AA41": "too little verificationGas",
+	"AA50": "postOp reverted",
+	"AA51": "prefund below actualGasCost",
+	"AA90": "invalid beneficiary",
+	"AA91": "failed send to beneficiary",
+	"AA92": "internal call only",
+	"AA93": "invalid paymasterAndData",
+	"AA94": "gas values overflow",
+	"AA95": "out of gas",
+	"AA96": "invalid aggregator",
+}
+
+var aaCodePattern = regexp.MustCompile(`\bAA\d\d\b`)
+
+func IsAAError(err error, code string) bool {
+	var aaErr *AAError
+	if !errors.As(err, &aaErr) {
+		return false
+	}
+	return aaErr.Code == code
+}
+
+func parseRPCError(rpcErr *RPCError) error {
+	if rpcErr == nil {
+		return nil
+	}
+
+	code := aaCodePattern.FindString(rpcErr.Message)
+	if code == "" && len(rpcErr.Data) > 0 {
+		code = aaCodePattern.FindString(string(rpcErr.Data))
+	}
+	if code == "" {
+		return rpcErr
+	}
+
+	return &AAError{
+		Code:        code,
+		Description: aaDescriptions[code],
+		Message:     rpcErr.Message,
+		RPC:         rpcErr,
+	}
+}
@@ -0,0 +1,81 @@
+package bundler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRPCError(t *testing.T) {
+	tests := []struct {
+		name         string
+		rpcErr       *RPCError
+		wantCode     string
+		wantCategory string
+	}{
+		{
+			name:         "code in message",
+			rpcErr:       &RPCError{Code: -32500, Message: "AA25 invalid account nonce"},
+			wantCode:     "AA25",
+			wantCategory: "account",
+		},
+		{
+			name:         "code in data",
+			rpcErr:       &RPCError{Code: -32500, Message: "validation failed", Data: []byte(`"FailedOp(0,\"AA31 paymaster deposit too low\")"`)},
+			wantCode:     "AA31",
+			wantCategory: "paymaster",
+		},
+		{
+			name:         "factory error",
+			rpcErr:       &RPCError{Code: -32500, Message: "AA13 initCode failed or OOG"},
+			wantCode:     "AA13",
+			wantCategory: "factory",
+		},
+		{
+			name:   "no code",
+			rpcErr: &RPCError{Code: -32602, Message: "invalid params"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := parseRPCError(tc.rpcErr)
+			require.Error(t, err)
+
+			var aaErr *AAError
+			if tc.wantCode == "" {
+				assert.False(t, errors.As(err, &aaErr))
+				assert.Equal(t, tc.rpcErr, err)
+				return
+			}
+
+			require.True(t, errors.As(err, &aaErr))
+			assert.Equal(t, tc.wantCode, aaErr.Code)
+			assert.Equal(t, tc.wantCategory, aaErr.Category())
+			assert.Equal(t, aaDescriptions[tc.wantCode], aaErr.Description)
+			assert.Same(t, tc.rpcErr, aaErr.RPC)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		assert.NoError(t, parseRPCError(nil))
+	})
+}
+
+func TestAAErrorMessage(t *testing.T) {
+	err := &AAError{Code: "AA24", Description: "signature error", Message: "FailedOp(0, AA24 signature error)"}
+	assert.Equal(t, "AA24 signature error: FailedOp(0, AA24 signature error)", err.Error())
+
+	unknown := &AAError{Code: "AA99", Message: "something"}
+	assert.Equal(t, "AA99: something", unknown.Error())
+	assert.Equal(t, "entryPoint", unknown.Category())
+}
+
+func TestIsAAError(t *testing.T) {
+	assert.True(t, IsAAError(&AAError{Code: "AA21"}, "AA21"))
+	assert.False(t, IsAAError(&AAError{Code: "AA21"}, "AA22"))
+	assert.False(t, IsAAError(errors.New("AA21"), "AA21"))
+	assert.False(t, IsAAError(nil, "AA21"))
+}
@@ -0,0 +1,53 @@
+package bundler
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+type GasEstimate struct {
+	PreVerificationGas            *big.Int
+	VerificationGasLimit          *big.Int
+	CallGasLimit                  *big.Int
+	PaymasterVerificationGasLimit *big.Int
+}
+
+type UserOperationByHash struct {
+	UserOperation   *encoding.UserOperation
+	EntryPoint      common.Address
+	BlockNumber     *big.Int
+	BlockHash       common.Hash
+	TransactionHash common.Hash
+}
+
+type UserOperationReceipt struct {
+	UserOpHash      common.Hash
+	EntryPoint      common.Address
+	Sender          common.Address
+	Nonce           *big.Int
+	Paymaster       common.Address
+	ActualGasCost   *big.Int
+	ActualGasUsed   *big.Int
+	Success         bool
+	Reason          string
+	TransactionHash common.Hash
+	BlockHash       common.Hash
+	BlockNumber     *big.Int
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+}
@@ -0,0 +1,241 @@
+package bundler
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+type rpcUserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+type rpcUserOperationV07 struct {
+	Sender                        common.Address  `json:"sender"`
+	Nonce                         *hexutil.Big    `json:"nonce"`
+	Factory                       *common.Address `json:"factory,omitempty"`
+	FactoryData                   *hexutil.Bytes  `json:"factoryData,omitempty"`
+	CallData                      hexutil.Bytes   `json:"callData"`
+	CallGasLimit                  *hexutil.Big    `json:"callGasLimit"`
+	VerificationGasLimit          *hexutil.Big    `json:"verificationGasLimit"`
+	PreVerificationGas            *hexutil.Big    `json:"preVerificationGas"`
+	MaxFeePerGas                  *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas          *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Paymaster                     *common.Address `json:"paymaster,omitempty"`
+	PaymasterVerificationGasLimit *hexutil.Big    `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       *hexutil.Big    `json:"paymasterPostOpGasLimit,omitempty"`
+	PaymasterData                 *hexutil.Bytes  `json:"paymasterData,omitempty"`
+	Signature                     hexutil.Bytes   `json:"signature"`
+}
+
+type rpcGasEstimate struct {
+	PreVerificationGas            *hexutil.Big `json:"preVerificationGas"`
+	VerificationGasLimit          *hexutil.Big `json:"verificationGasLimit"`
+	CallGasLimit                  *hexutil.Big `json:"callGasLimit"`
+	PaymasterVerificationGasLimit *hexutil.Big `json:"paymasterVerificationGasLimit"`
+}
+
+type rpcUserOperationByHash struct {
+	UserOperation   json.RawMessage `json:"userOperation"`
+	EntryPoint      common.Address  `json:"entryPoint"`
+	BlockNumber     *hexutil.Big    `json:"blockNumber"`
+	BlockHash       common.Hash     `json:"blockHash"`
+	TransactionHash common.Hash     `json:"transactionHash"`
+}
+
+type rpcReceipt struct {
+	TransactionHash common.Hash  `json:"transactionHash"`
+	BlockHash       common.Hash  `json:"blockHash"`
+	BlockNumber     *hexutil.Big `json:"blockNumber"`
+}
+
+type rpcUserOperationReceipt struct {
+	UserOpHash    common.Hash    `json:"userOpHash"`
+	EntryPoint    common.Address `json:"entryPoint"`
+	Sender        common.Address `json:"sender"`
+	Nonce         *hexutil.Big   `json:"nonce"`
+	Paymaster     common.Address `json:"paymaster"`
+	ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+	ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+	Success       bool           `json:"success"`
+	Reason        string         `json:"reason"`
+	Receipt       rpcReceipt     `json:"receipt"`
+}
+
+func toRPCUserOperation(op *encoding.UserOperation) *rpcUserOperation {
+	return &rpcUserOperation{
+		Sender:               op.Sender,
+		Nonce:                toHexBig(op.Nonce),
+		InitCode:             nonNilBytes(op.InitCode),
+		CallData:             nonNilBytes(op.CallData),
+		CallGasLimit:         toHexBig(op.CallGasLimit),
+		VerificationGasLimit: toHexBig(op.VerificationGasLimit),
+		PreVerificationGas:   toHexBig(op.PreVerificationGas),
+		MaxFeePerGas:         toHexBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: toHexBig(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     nonNilBytes(op.PaymasterAndData),
+		Signature:            nonNilBytes(op.Signature),
+	}
+}
+
+func fromRPCUserOperation(op *rpcUserOperation) *encoding.UserOperation {
+	if op == nil {
+		return nil
+	}
+	return &encoding.UserOperation{
+		Sender:               op.Sender,
+		Nonce:                fromHexBig(op.Nonce),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         fromHexBig(op.CallGasLimit),
+		VerificationGasLimit: fromHexBig(op.VerificationGasLimit),
+		PreVerificationGas:   fromHexBig(op.PreVerificationGas),
+		MaxFeePerGas:         fromHexBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: fromHexBig(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+func encodeUserOperation(op *encoding.UserOperation, version chain.EntryPointVersion) (interface{}, error) {
+	if version == chain.EntryPointV07 {
+		return toRPCUserOperationV07(op)
+	}
+	return toRPCUserOperation(op), nil
+}
+
+func decodeUserOperation(raw json.RawMessage, version chain.EntryPointVersion) (*encoding.UserOperation, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	if version == chain.EntryPointV07 {
+		var op rpcUserOperationV07
+		if err := json.Unmarshal(raw, &op); err != nil {
+			return nil, err
+		}
+		return fromRPCUserOperationV07(&op)
+	}
+
+	var op rpcUserOperation
+	if err := json.Unmarshal(raw, &op); err != nil {
+		return nil, err
+	}
+	return fromRPCUserOperation(&op), nil
+}
+
+func toRPCUserOperationV07(op *encoding.UserOperation) (*rpcUserOperationV07, error) {
+	rpcOp := &rpcUserOperationV07{
+		Sender:               op.Sender,
+		Nonce:                toHexBig(op.Nonce),
+		CallData:             nonNilBytes(op.CallData),
+		CallGasLimit:         toHexBig(op.CallGasLimit),
+		VerificationGasLimit: toHexBig(op.VerificationGasLimit),
+		PreVerificationGas:   toHexBig(op.PreVerificationGas),
+		MaxFeePerGas:         toHexBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: toHexBig(op.MaxPriorityFeePerGas),
+		Signature:            nonNilBytes(op.Signature),
+	}
+
+	if len(op.InitCode) > 0 {
+		if len(op.InitCode) < 20 {
+			return nil, errors.New("invalid init code")
+		}
+		factory := common.BytesToAddress(op.InitCode[:20])
+		factoryData := hexutil.Bytes(op.InitCode[20:])
+		rpcOp.Factory = &factory
+		rpcOp.FactoryData = &factoryData
+	}
+
+	if len(op.PaymasterAndData) > 0 {
+		paymaster, verificationGasLimit, postOpGasLimit, data, err := encoding.UnpackPaymasterAndData(op.PaymasterAndData)
+		if err != nil {
+			return nil, err
+		}
+		paymasterData := nonNilBytes(data)
+		rpcOp.Paymaster = &paymaster
+		rpcOp.PaymasterVerificationGasLimit = toHexBig(verificationGasLimit)
+		rpcOp.PaymasterPostOpGasLimit = toHexBig(postOpGasLimit)
+		rpcOp.PaymasterData = &paymasterData
+	}
+
+	return rpcOp, nil
+}
+
+func fromRPCUserOperationV07(op *rpcUserOperationV07) (*encoding.UserOperation, error) {
+	initCode := []byte{}
+	if op.Factory != nil {
+		initCode = append(initCode, op.Factory.Bytes()...)
+		if op.FactoryData != nil {
+			initCode = append(initCode, *op.FactoryData...)
+		}
+	}
+
+	paymasterAndData := []byte{}
+	if op.Paymaster != nil {
+		var data []byte
+		if op.PaymasterData != nil {
+			data = *op.PaymasterData
+		}
+		packed, err := encoding.PackPaymasterAndData(
+			*op.Paymaster,
+			fromHexBig(op.PaymasterVerificationGasLimit),
+			fromHexBig(op.PaymasterPostOpGasLimit),
+			data,
+		)
+		if err != nil {
+			return nil, err
+		}
+		paymasterAndData = packed
+	}
+
+	return &encoding.UserOperation{
+		Sender:               op.Sender,
+		Nonce:                fromHexBig(op.Nonce),
+		InitCode:             initCode,
+		CallData:             op.CallData,
+		CallGasLimit:         fromHexBig(op.CallGasLimit),
+		VerificationGasLimit: fromHexBig(op.VerificationGasLimit),
+		PreVerificationGas:   fromHexBig(op.PreVerificationGas),
+		MaxFeePerGas:         fromHexBig(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: fromHexBig(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     paymasterAndData,
+		Signature:            op.Signature,
+	}, nil
+}
+
+func toHexBig(v *big.Int) *hexutil.Big {
+	if v == nil {
+		return (*hexutil.Big)(big.NewInt(0))
+	}
+	return (*hexutil.Big)(v)
+}
+
+func fromHexBig(v *hexutil.Big) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(v.ToInt())
+}
+
+func nonNilBytes(b []byte) hexutil.Bytes {
+	if b == nil {
+		return hexutil.Bytes{}
+	}
+	return b
+}
@@ -56,6 +56,7 @@ func TestGetChainValues(t *testing.T) {
 		assert.Equal(t, big.NewInt(8453), cfg.ChainID)
 		assert.Equal(t, "https://mainnet.base.org", cfg.RPCURL)
 		assert.Equal(t, DefaultEntryPoint, cfg.EntryPoint)
+		assert.Equal(t, EntryPointV06, cfg.Version())
 		assert.False(t, cfg.IsTestnet)
 	})
 
@@ -140,3 +141,15 @@ func TestRegisterChainOverwrite(t *testing.T) {
 
 	assert.Len(t, svc.ListChains(), 4)
 }
+
+func TestEntryPointVersion(t *testing.T) {
+	t.Run("defaults to v0.6", func(t *testing.T) {
+		cfg := ChainConfig{}
+		assert.Equal(t, EntryPointV06, cfg.Version())
+	})
+
+	t.Run("explicit v0.7", func(t *testing.T) {
+		cfg := ChainConfig{EntryPoint: EntryPointV07Address, EntryPointVersion: EntryPointV07}
+		assert.Equal(t, EntryPointV07, cfg.Version())
+	})
+}
@@ -15,15 +15,32 @@ const (
 	ArbitrumSepolia SupportedChain = "arbitrum-sepolia"
 )
 
+type EntryPointVersion string
+
+const (
+	EntryPointV06 EntryPointVersion = "v0.6"
+	EntryPointV07 EntryPointVersion = "v0.7"
+)
+
 type ChainConfig struct {
-	Name        string
-	Chain       SupportedChain
-	ChainID     *big.Int
-	RPCURL      string
-	BundlerURL  string
-	EntryPoint  common.Address
-	USDC        common.Address
-	IsTestnet   bool
-	BlockTime   uint64
-	GasMultiple float64
+	Name              string
+	Chain             SupportedChain
+	ChainID           *big.Int
+	RPCURL            string
+	BundlerURL        string
+	EntryPoint        common.Address
+	EntryPointVersion EntryPointVersion
+	USDC              common.Address
+	UniswapV2Router   common.Address
+	AavePool          common.Address
+	IsTestnet         bool
+	BlockTime         uint64
+	GasMultiple       float64
+}
+
+func (c ChainConfig) Version() EntryPointVersion {
+	if c.EntryPointVersion == "" {
+		return EntryPointV06
+	}
+	return c.EntryPointVersion
 }
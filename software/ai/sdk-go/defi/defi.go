@@ -4,6 +4,20 @@ import (
 	"github.com/sigloop/sdk-go/chain"
 )
 
+const (
+	SwapExactTokensForTokensSignature = "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)"
+	SupplySignature                   = "supply(address,uint256,address,uint16)"
+	BorrowSignature                   = "borrow(address,uint256,uint256,uint16,address)"
+	RepaySignature                    = "repay(address,uint256,uint256,address)"
+)
+
+var (
+	SwapExactTokensForTokensSelector = [4]byte{0x38, 0xed, 0x17, 0x39}
+	SupplySelector                   = [4]byte{0x61, 0x7b, 0xa0, 0x37}
+	BorrowSelector                   = [4]byte{0xa4, 0x15, 0xbc, 0xad}
+	RepaySelector                    = [4]byte{0x57, 0x3a, 0xde, 0x81}
+)
+
 type DeFiService struct {
 	chainService *chain.ChainService
 }
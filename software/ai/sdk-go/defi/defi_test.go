@@ -3,6 +3,7 @@ package defi
 import (
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sigloop/sdk-go/chain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,3 +21,21 @@ func TestNewDeFiServiceNilChain(t *testing.T) {
 	require.NotNil(t, svc)
 	assert.Nil(t, svc.chainService)
 }
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		signature string
+		selector  [4]byte
+	}{
+		{SwapExactTokensForTokensSignature, SwapExactTokensForTokensSelector},
+		{SupplySignature, SupplySelector},
+		{BorrowSignature, BorrowSelector},
+		{RepaySignature, RepaySelector},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.signature, func(t *testing.T) {
+			assert.Equal(t, crypto.Keccak256([]byte(tt.signature))[:4], tt.selector[:])
+		})
+	}
+}
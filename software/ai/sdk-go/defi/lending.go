@@ -54,8 +54,7 @@ func (s *DeFiService) Supply(params LendingParams) (*DeFiResult, error) {
 		return nil, err
 	}
 
-	selector := []byte{0x61, 0x7b, 0xa0, 0x37}
-	calldata := append(selector, packed...)
+	calldata := append(SupplySelector[:], packed...)
 
 	return &DeFiResult{
 		To:       params.Pool,
@@ -90,8 +89,7 @@ func (s *DeFiService) Borrow(params LendingParams) (*DeFiResult, error) {
 		return nil, err
 	}
 
-	selector := []byte{0xa4, 0x15, 0xbc, 0xad}
-	calldata := append(selector, packed...)
+	calldata := append(BorrowSelector[:], packed...)
 
 	return &DeFiResult{
 		To:       params.Pool,
@@ -125,8 +123,7 @@ func (s *DeFiService) Repay(params LendingParams) (*DeFiResult, error) {
 		return nil, err
 	}
 
-	selector := []byte{0x57, 0x3e, 0xba, 0x17}
-	calldata := append(selector, packed...)
+	calldata := append(RepaySelector[:], packed...)
 
 	return &DeFiResult{
 		To:       params.Pool,
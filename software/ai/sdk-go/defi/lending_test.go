@@ -154,7 +154,7 @@ func TestRepay(t *testing.T) {
 		assert.Equal(t, pool, result.To)
 		assert.Equal(t, big.NewInt(0), result.Value)
 		assert.Equal(t, uint64(250000), result.GasLimit)
-		assert.Equal(t, []byte{0x57, 0x3e, 0xba, 0x17}, result.Data[:4])
+		assert.Equal(t, []byte{0x57, 0x3a, 0xde, 0x81}, result.Data[:4])
 	})
 
 	t.Run("without on behalf", func(t *testing.T) {
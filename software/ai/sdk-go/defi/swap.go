@@ -46,8 +46,7 @@ func (s *DeFiService) ExecuteSwap(params SwapParams) (*DeFiResult, error) {
 		return nil, err
 	}
 
-	selector := []byte{0x38, 0xed, 0x17, 0x39}
-	calldata := append(selector, packed...)
+	calldata := append(SwapExactTokensForTokensSelector[:], packed...)
 
 	return &DeFiResult{
 		To:       params.Router,
@@ -1,6 +1,7 @@
 package encoding
 
 import (
+	"bytes"
 	"errors"
 	"math/big"
 
@@ -18,6 +19,12 @@ type PolicyEncoding struct {
 	ValidUntil      *big.Int
 	RateMaxCalls    *big.Int
 	RatePeriod      *big.Int
+	Permissions     []PermissionEncoding
+}
+
+type PermissionEncoding struct {
+	Contract common.Address
+	Selector [4]byte
 }
 
 var policyABIArgs = abi.Arguments{
@@ -31,10 +38,20 @@ var policyABIArgs = abi.Arguments{
 	{Type: mustNewType("uint256")},
 }
 
+var permissionsABIType = mustNewType("tuple[]", []abi.ArgumentMarshaling{
+	{Name: "contract", Type: "address"},
+	{Name: "selector", Type: "bytes4"},
+}...)
+
+var policyWithPermissionsABIArgs = append(append(abi.Arguments{}, policyABIArgs...), abi.Argument{Type: permissionsABIType})
+
 func EncodePolicy(p *PolicyEncoding) ([]byte, error) {
 	if p == nil {
 		return nil, errors.New("nil policy encoding")
 	}
+	if len(p.Permissions) > 0 {
+		return nil, errors.New("permissions need EncodePolicyV2")
+	}
 
 	sigs := make([][4]byte, len(p.FunctionSigs))
 	copy(sigs, p.FunctionSigs)
@@ -51,13 +68,47 @@ func EncodePolicy(p *PolicyEncoding) ([]byte, error) {
 	)
 }
 
+func EncodePolicyV2(p *PolicyEncoding) ([]byte, error) {
+	if p == nil {
+		return nil, errors.New("nil policy encoding")
+	}
+
+	sigs := make([][4]byte, len(p.FunctionSigs))
+	copy(sigs, p.FunctionSigs)
+
+	perms := p.Permissions
+	if perms == nil {
+		perms = []PermissionEncoding{}
+	}
+
+	return policyWithPermissionsABIArgs.Pack(
+		p.SpendingTokens,
+		p.SpendingAmounts,
+		p.Contracts,
+		sigs,
+		p.ValidAfter,
+		p.ValidUntil,
+		p.RateMaxCalls,
+		p.RatePeriod,
+		perms,
+	)
+}
+
 func DecodePolicy(data []byte) (*PolicyEncoding, error) {
-	values, err := policyABIArgs.Unpack(data)
+	return decodePolicy(policyABIArgs, data)
+}
+
+func DecodePolicyV2(data []byte) (*PolicyEncoding, error) {
+	return decodePolicy(policyWithPermissionsABIArgs, data)
+}
+
+func decodePolicy(args abi.Arguments, data []byte) (*PolicyEncoding, error) {
+	values, err := args.Unpack(data)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(values) != 8 {
+	if len(values) != len(args) {
 		return nil, errors.New("invalid policy data")
 	}
 
@@ -101,6 +152,15 @@ func DecodePolicy(data []byte) (*PolicyEncoding, error) {
 		return nil, errors.New("invalid rate period")
 	}
 
+	var perms []PermissionEncoding
+	if len(values) == 9 {
+		converted, ok := abi.ConvertType(values[8], new([]PermissionEncoding)).(*[]PermissionEncoding)
+		if !ok {
+			return nil, errors.New("invalid permissions")
+		}
+		perms = *converted
+	}
+
 	return &PolicyEncoding{
 		SpendingTokens:  tokens,
 		SpendingAmounts: amounts,
@@ -110,9 +170,133 @@ func DecodePolicy(data []byte) (*PolicyEncoding, error) {
 		ValidUntil:      validUntil,
 		RateMaxCalls:    rateMax,
 		RatePeriod:      ratePeriod,
+		Permissions:     perms,
 	}, nil
 }
 
+type AgentPolicy struct {
+	AllowedTargets   []common.Address
+	AllowedSelectors [][4]byte
+	MaxAmountPerTx   *big.Int
+	DailyLimit       *big.Int
+	WeeklyLimit      *big.Int
+	ValidAfter       *big.Int
+	ValidUntil       *big.Int
+	Active           bool
+}
+
+const AddAgentSignature = "addAgent(address,(address[],bytes4[],uint256,uint256,uint256,uint48,uint48,bool))"
+
+var maxUint48 = new(big.Int).SetUint64(1<<48 - 1)
+
+var agentPolicyABIType = mustNewType("tuple", []abi.ArgumentMarshaling{
+	{Name: "allowedTargets", Type: "address[]"},
+	{Name: "allowedSelectors", Type: "bytes4[]"},
+	{Name: "maxAmountPerTx", Type: "uint256"},
+	{Name: "dailyLimit", Type: "uint256"},
+	{Name: "weeklyLimit", Type: "uint256"},
+	{Name: "validAfter", Type: "uint48"},
+	{Name: "validUntil", Type: "uint48"},
+	{Name: "active", Type: "bool"},
+}...)
+
+var agentPolicyABIArgs = abi.Arguments{{Type: agentPolicyABIType}}
+
+var addAgentABIArgs = abi.Arguments{{Type: mustNewType("address")}, {Type: agentPolicyABIType}}
+
+func EncodeAgentPolicy(p *AgentPolicy) ([]byte, error) {
+	packed, err := packAgentPolicy(p)
+	if err != nil {
+		return nil, err
+	}
+	return agentPolicyABIArgs.Pack(packed)
+}
+
+func DecodeAgentPolicy(data []byte) (*AgentPolicy, error) {
+	values, err := agentPolicyABIArgs.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, errors.New("invalid agent policy data")
+	}
+	return unpackAgentPolicy(values[0])
+}
+
+func EncodeAddAgent(agent common.Address, p *AgentPolicy) ([]byte, error) {
+	packed, err := packAgentPolicy(p)
+	if err != nil {
+		return nil, err
+	}
+	args, err := addAgentABIArgs.Pack(agent, packed)
+	if err != nil {
+		return nil, err
+	}
+	return append(crypto.Keccak256([]byte(AddAgentSignature))[:4], args...), nil
+}
+
+func DecodeAddAgent(data []byte) (common.Address, *AgentPolicy, error) {
+	if len(data) < 4 {
+		return common.Address{}, nil, errors.New("calldata too short")
+	}
+	if !bytes.Equal(data[:4], crypto.Keccak256([]byte(AddAgentSignature))[:4]) {
+		return common.Address{}, nil, errors.New("selector mismatch")
+	}
+	values, err := addAgentABIArgs.Unpack(data[4:])
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if len(values) != 2 {
+		return common.Address{}, nil, errors.New("invalid add agent data")
+	}
+	agent, ok := values[0].(common.Address)
+	if !ok {
+		return common.Address{}, nil, errors.New("invalid agent")
+	}
+	p, err := unpackAgentPolicy(values[1])
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return agent, p, nil
+}
+
+func packAgentPolicy(p *AgentPolicy) (*AgentPolicy, error) {
+	if p == nil {
+		return nil, errors.New("nil agent policy")
+	}
+
+	packed := *p
+	if packed.AllowedTargets == nil {
+		packed.AllowedTargets = []common.Address{}
+	}
+	if packed.AllowedSelectors == nil {
+		packed.AllowedSelectors = [][4]byte{}
+	}
+	for _, v := range []**big.Int{&packed.MaxAmountPerTx, &packed.DailyLimit, &packed.WeeklyLimit, &packed.ValidAfter, &packed.ValidUntil} {
+		if *v == nil {
+			*v = new(big.Int)
+		}
+		if (*v).Sign() < 0 {
+			return nil, errors.New("negative agent policy value")
+		}
+	}
+	if packed.ValidAfter.Cmp(maxUint48) > 0 {
+		return nil, errors.New("valid after exceeds uint48")
+	}
+	if packed.ValidUntil.Cmp(maxUint48) > 0 {
+		return nil, errors.New("valid until exceeds uint48")
+	}
+	return &packed, nil
+}
+
+func unpackAgentPolicy(value interface{}) (*AgentPolicy, error) {
+	p, ok := abi.ConvertType(value, new(AgentPolicy)).(*AgentPolicy)
+	if !ok {
+		return nil, errors.New("invalid agent policy")
+	}
+	return p, nil
+}
+
 func EncodeFunctionCall(signature string, args ...interface{}) ([]byte, error) {
 	selector := crypto.Keccak256([]byte(signature))[:4]
 
@@ -133,6 +317,26 @@ func EncodeFunctionCall(signature string, args ...interface{}) ([]byte, error) {
 	return append(selector, packed...), nil
 }
 
+func DecodeFunctionCall(signature string, data []byte) ([]interface{}, error) {
+	if len(data) < 4 {
+		return nil, errors.New("calldata too short")
+	}
+	selector := crypto.Keccak256([]byte(signature))[:4]
+	if !bytes.Equal(data[:4], selector) {
+		return nil, errors.New("selector mismatch")
+	}
+
+	abiArgs, err := parseSignatureArgs(signature)
+	if err != nil {
+		return nil, err
+	}
+	return abiArgs.Unpack(data[4:])
+}
+
+func ParseFunctionArgs(signature string) (abi.Arguments, error) {
+	return parseSignatureArgs(signature)
+}
+
 func parseSignatureArgs(sig string) (abi.Arguments, error) {
 	start := -1
 	end := -1
@@ -190,7 +394,10 @@ func splitParams(s string) []string {
 	return result
 }
 
-func mustNewType(t string) abi.Type {
-	typ, _ := abi.NewType(t, "", nil)
+func mustNewType(t string, components ...abi.ArgumentMarshaling) abi.Type {
+	typ, err := abi.NewType(t, "", components)
+	if err != nil {
+		panic(err)
+	}
 	return typ
 }
@@ -2,9 +2,11 @@ package encoding
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -31,6 +33,13 @@ func TestEncodePolicy(t *testing.T) {
 		_, err := EncodePolicy(nil)
 		require.Error(t, err)
 		assert.Equal(t, "nil policy encoding", err.Error())
+		_, err = EncodePolicyV2(nil)
+		assert.EqualError(t, err, "nil policy encoding")
+	})
+
+	t.Run("permissions need v2", func(t *testing.T) {
+		_, err := EncodePolicy(&PolicyEncoding{Permissions: []PermissionEncoding{{}}})
+		assert.EqualError(t, err, "permissions need EncodePolicyV2")
 	})
 
 	t.Run("empty arrays", func(t *testing.T) {
@@ -89,12 +98,169 @@ func TestDecodePolicy(t *testing.T) {
 		assert.Equal(t, 0, original.RatePeriod.Cmp(decoded.RatePeriod))
 	})
 
+	t.Run("permissions roundtrip", func(t *testing.T) {
+		router := common.HexToAddress("0x1111111111111111111111111111111111111111")
+		original := &PolicyEncoding{
+			SpendingTokens:  []common.Address{},
+			SpendingAmounts: []*big.Int{},
+			Contracts:       []common.Address{},
+			FunctionSigs:    [][4]byte{},
+			ValidAfter:      big.NewInt(0),
+			ValidUntil:      big.NewInt(0),
+			RateMaxCalls:    big.NewInt(0),
+			RatePeriod:      big.NewInt(0),
+			Permissions: []PermissionEncoding{
+				{Contract: router, Selector: [4]byte{0x09, 0x5e, 0xa7, 0xb3}},
+				{Contract: router},
+				{Selector: [4]byte{0xa9, 0x05, 0x9c, 0xbb}},
+			},
+		}
+
+		data, err := EncodePolicyV2(original)
+		require.NoError(t, err)
+
+		decoded, err := DecodePolicyV2(data)
+		require.NoError(t, err)
+		assert.Equal(t, original.Permissions, decoded.Permissions)
+		assert.Equal(t, original.FunctionSigs, decoded.FunctionSigs)
+	})
+
+	t.Run("v2 without permissions", func(t *testing.T) {
+		original := &PolicyEncoding{
+			SpendingTokens:  []common.Address{common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")},
+			SpendingAmounts: []*big.Int{big.NewInt(1000000)},
+			Contracts:       []common.Address{},
+			FunctionSigs:    [][4]byte{},
+			ValidAfter:      big.NewInt(1),
+			ValidUntil:      big.NewInt(2),
+			RateMaxCalls:    big.NewInt(3),
+			RatePeriod:      big.NewInt(4),
+		}
+
+		data, err := EncodePolicyV2(original)
+		require.NoError(t, err)
+
+		decoded, err := DecodePolicyV2(data)
+		require.NoError(t, err)
+		assert.Len(t, decoded.SpendingTokens, 1)
+		assert.Equal(t, big.NewInt(4), decoded.RatePeriod)
+		assert.Empty(t, decoded.Permissions)
+	})
+
+	t.Run("legacy data is not v2", func(t *testing.T) {
+		data, err := EncodePolicy(&PolicyEncoding{
+			SpendingTokens:  []common.Address{},
+			SpendingAmounts: []*big.Int{},
+			Contracts:       []common.Address{},
+			FunctionSigs:    [][4]byte{},
+			ValidAfter:      big.NewInt(0),
+			ValidUntil:      big.NewInt(0),
+			RateMaxCalls:    big.NewInt(0),
+			RatePeriod:      big.NewInt(0),
+		})
+		require.NoError(t, err)
+
+		_, err = DecodePolicyV2(data)
+		assert.Error(t, err)
+	})
+
 	t.Run("invalid data", func(t *testing.T) {
 		_, err := DecodePolicy([]byte{0x01, 0x02, 0x03})
 		require.Error(t, err)
 	})
 }
 
+func testAgentPolicy() *AgentPolicy {
+	return &AgentPolicy{
+		AllowedTargets:   []common.Address{common.HexToAddress("0xBEEF")},
+		AllowedSelectors: [][4]byte{{0xa9, 0x05, 0x9c, 0xbb}},
+		MaxAmountPerTx:   big.NewInt(1),
+		DailyLimit:       big.NewInt(2),
+		WeeklyLimit:      big.NewInt(3),
+		ValidAfter:       big.NewInt(4),
+		ValidUntil:       big.NewInt(5),
+		Active:           true,
+	}
+}
+
+func TestEncodeAgentPolicy(t *testing.T) {
+	t.Run("matches abi.encode layout", func(t *testing.T) {
+		word := func(hex string) string {
+			return strings.Repeat("0", 64-len(hex)) + hex
+		}
+		want := word("20") +
+			word("100") + word("140") + word("1") + word("2") + word("3") + word("4") + word("5") + word("1") +
+			word("1") + word("beef") +
+			word("1") + "a9059cbb" + strings.Repeat("0", 56)
+
+		data, err := EncodeAgentPolicy(testAgentPolicy())
+		require.NoError(t, err)
+		assert.Equal(t, want, common.Bytes2Hex(data))
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		data, err := EncodeAgentPolicy(testAgentPolicy())
+		require.NoError(t, err)
+		decoded, err := DecodeAgentPolicy(data)
+		require.NoError(t, err)
+		assert.Equal(t, testAgentPolicy(), decoded)
+	})
+
+	t.Run("nil fields encode as empty", func(t *testing.T) {
+		data, err := EncodeAgentPolicy(&AgentPolicy{})
+		require.NoError(t, err)
+		decoded, err := DecodeAgentPolicy(data)
+		require.NoError(t, err)
+		assert.Empty(t, decoded.AllowedTargets)
+		assert.Zero(t, decoded.ValidUntil.Sign())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			policy  *AgentPolicy
+			wantErr string
+		}{
+			{"nil policy", nil, "nil agent policy"},
+			{"negative value", &AgentPolicy{DailyLimit: big.NewInt(-1)}, "negative agent policy value"},
+			{"valid after overflow", &AgentPolicy{ValidAfter: new(big.Int).Lsh(big.NewInt(1), 48)}, "valid after exceeds uint48"},
+			{"valid until overflow", &AgentPolicy{ValidUntil: new(big.Int).Lsh(big.NewInt(1), 48)}, "valid until exceeds uint48"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := EncodeAgentPolicy(tt.policy)
+				assert.EqualError(t, err, tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := DecodeAgentPolicy([]byte{0x01})
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeAddAgent(t *testing.T) {
+	agent := common.HexToAddress("0xA11CE")
+
+	data, err := EncodeAddAgent(agent, testAgentPolicy())
+	require.NoError(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte(AddAgentSignature))[:4], data[:4])
+	assert.Equal(t, common.LeftPadBytes(agent.Bytes(), 32), data[4:36])
+	assert.Equal(t, common.LeftPadBytes([]byte{0x40}, 32), data[36:68])
+
+	decodedAgent, decoded, err := DecodeAddAgent(data)
+	require.NoError(t, err)
+	assert.Equal(t, agent, decodedAgent)
+	assert.Equal(t, testAgentPolicy(), decoded)
+
+	_, _, err = DecodeAddAgent(data[:3])
+	assert.EqualError(t, err, "calldata too short")
+
+	_, _, err = DecodeAddAgent(append([]byte{0, 0, 0, 0}, data[4:]...))
+	assert.EqualError(t, err, "selector mismatch")
+}
+
 func TestEncodeFunctionCall(t *testing.T) {
 	t.Run("no args", func(t *testing.T) {
 		data, err := EncodeFunctionCall("totalSupply()")
@@ -133,6 +299,40 @@ func TestEncodeFunctionCall(t *testing.T) {
 	})
 }
 
+func TestDecodeFunctionCall(t *testing.T) {
+	to := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := EncodeFunctionCall("transfer(address,uint256)", to, big.NewInt(1000000))
+		require.NoError(t, err)
+
+		args, err := DecodeFunctionCall("transfer(address,uint256)", data)
+		require.NoError(t, err)
+		require.Len(t, args, 2)
+		assert.Equal(t, to, args[0])
+		assert.Equal(t, big.NewInt(1000000), args[1])
+	})
+
+	t.Run("selector mismatch", func(t *testing.T) {
+		data, err := EncodeFunctionCall("approve(address,uint256)", to, big.NewInt(1))
+		require.NoError(t, err)
+		_, err = DecodeFunctionCall("transfer(address,uint256)", data)
+		assert.EqualError(t, err, "selector mismatch")
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		_, err := DecodeFunctionCall("transfer(address,uint256)", []byte{0xa9, 0x05})
+		assert.EqualError(t, err, "calldata too short")
+	})
+
+	t.Run("truncated arguments", func(t *testing.T) {
+		data, err := EncodeFunctionCall("transfer(address,uint256)", to, big.NewInt(1))
+		require.NoError(t, err)
+		_, err = DecodeFunctionCall("transfer(address,uint256)", data[:40])
+		assert.Error(t, err)
+	})
+}
+
 func TestParseSignatureArgs(t *testing.T) {
 	t.Run("no params", func(t *testing.T) {
 		args, err := parseSignatureArgs("totalSupply()")
@@ -0,0 +1,189 @@
+package encoding
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type PackedUserOperation struct {
+	Sender             common.Address
+	Nonce              *big.Int
+	InitCode           []byte
+	CallData           []byte
+	AccountGasLimits   [32]byte
+	PreVerificationGas *big.Int
+	GasFees            [32]byte
+	PaymasterAndData   []byte
+	Signature          []byte
+}
+
+var packedUserOpABI = abi.Arguments{
+	{Type: mustNewType("address")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("uint256")},
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("bytes32")},
+}
+
+var maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+func PackUint128Pair(high *big.Int, low *big.Int) ([32]byte, error) {
+	var out [32]byte
+	if err := checkUint128(high); err != nil {
+		return out, err
+	}
+	if err := checkUint128(low); err != nil {
+		return out, err
+	}
+	if high != nil {
+		high.FillBytes(out[:16])
+	}
+	if low != nil {
+		low.FillBytes(out[16:])
+	}
+	return out, nil
+}
+
+func UnpackUint128Pair(packed [32]byte) (*big.Int, *big.Int) {
+	return new(big.Int).SetBytes(packed[:16]), new(big.Int).SetBytes(packed[16:])
+}
+
+func PackPaymasterAndData(paymaster common.Address, verificationGasLimit *big.Int, postOpGasLimit *big.Int, data []byte) ([]byte, error) {
+	limits, err := PackUint128Pair(verificationGasLimit, postOpGasLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 52+len(data))
+	out = append(out, paymaster.Bytes()...)
+	out = append(out, limits[:]...)
+	return append(out, data...), nil
+}
+
+func UnpackPaymasterAndData(paymasterAndData []byte) (common.Address, *big.Int, *big.Int, []byte, error) {
+	if len(paymasterAndData) == 0 {
+		return common.Address{}, big.NewInt(0), big.NewInt(0), nil, nil
+	}
+	if len(paymasterAndData) < 52 {
+		return common.Address{}, nil, nil, nil, errors.New("invalid paymaster and data")
+	}
+
+	var limits [32]byte
+	copy(limits[:], paymasterAndData[20:52])
+	verificationGasLimit, postOpGasLimit := UnpackUint128Pair(limits)
+
+	return common.BytesToAddress(paymasterAndData[:20]), verificationGasLimit, postOpGasLimit, paymasterAndData[52:], nil
+}
+
+func ToPackedUserOp(op *UserOperation) (*PackedUserOperation, error) {
+	if op == nil {
+		return nil, errors.New("nil user operation")
+	}
+
+	accountGasLimits, err := PackUint128Pair(op.VerificationGasLimit, op.CallGasLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	gasFees, err := PackUint128Pair(op.MaxPriorityFeePerGas, op.MaxFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PackedUserOperation{
+		Sender:             op.Sender,
+		Nonce:              op.Nonce,
+		InitCode:           op.InitCode,
+		CallData:           op.CallData,
+		AccountGasLimits:   accountGasLimits,
+		PreVerificationGas: op.PreVerificationGas,
+		GasFees:            gasFees,
+		PaymasterAndData:   op.PaymasterAndData,
+		Signature:          op.Signature,
+	}, nil
+}
+
+func FromPackedUserOp(packed *PackedUserOperation) (*UserOperation, error) {
+	if packed == nil {
+		return nil, errors.New("nil packed user operation")
+	}
+
+	verificationGasLimit, callGasLimit := UnpackUint128Pair(packed.AccountGasLimits)
+	maxPriorityFeePerGas, maxFeePerGas := UnpackUint128Pair(packed.GasFees)
+
+	return &UserOperation{
+		Sender:               packed.Sender,
+		Nonce:                packed.Nonce,
+		InitCode:             packed.InitCode,
+		CallData:             packed.CallData,
+		CallGasLimit:         callGasLimit,
+		VerificationGasLimit: verificationGasLimit,
+		PreVerificationGas:   packed.PreVerificationGas,
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+		PaymasterAndData:     packed.PaymasterAndData,
+		Signature:            packed.Signature,
+	}, nil
+}
+
+func PackPackedUserOp(op *PackedUserOperation) ([]byte, error) {
+	if op == nil {
+		return nil, errors.New("nil packed user operation")
+	}
+
+	return packedUserOpABI.Pack(
+		op.Sender,
+		op.Nonce,
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		op.AccountGasLimits,
+		op.PreVerificationGas,
+		op.GasFees,
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+}
+
+func HashPackedUserOp(op *PackedUserOperation, entryPoint common.Address, chainID *big.Int) (common.Hash, error) {
+	packed, err := PackPackedUserOp(op)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	chainArgs := abi.Arguments{
+		{Type: mustNewType("bytes32")},
+		{Type: mustNewType("address")},
+		{Type: mustNewType("uint256")},
+	}
+
+	final, err := chainArgs.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(final), nil
+}
+
+func HashUserOpV07(op *UserOperation, entryPoint common.Address, chainID *big.Int) (common.Hash, error) {
+	packed, err := ToPackedUserOp(op)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return HashPackedUserOp(packed, entryPoint, chainID)
+}
+
+func checkUint128(v *big.Int) error {
+	if v == nil {
+		return nil
+	}
+	if v.Sign() < 0 || v.Cmp(maxUint128) > 0 {
+		return errors.New("value does not fit in uint128")
+	}
+	return nil
+}
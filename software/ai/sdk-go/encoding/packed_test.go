@@ -0,0 +1,198 @@
+package encoding
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackUint128Pair(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		packed, err := PackUint128Pair(big.NewInt(200000), big.NewInt(100000))
+		require.NoError(t, err)
+
+		high, low := UnpackUint128Pair(packed)
+		assert.Equal(t, big.NewInt(200000), high)
+		assert.Equal(t, big.NewInt(100000), low)
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		packed, err := PackUint128Pair(big.NewInt(1), big.NewInt(2))
+		require.NoError(t, err)
+		assert.Equal(t, byte(1), packed[15])
+		assert.Equal(t, byte(2), packed[31])
+	})
+
+	t.Run("nil values are zero", func(t *testing.T) {
+		packed, err := PackUint128Pair(nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, [32]byte{}, packed)
+	})
+
+	t.Run("max uint128", func(t *testing.T) {
+		packed, err := PackUint128Pair(maxUint128, maxUint128)
+		require.NoError(t, err)
+		high, low := UnpackUint128Pair(packed)
+		assert.Equal(t, maxUint128, high)
+		assert.Equal(t, maxUint128, low)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		tooBig := new(big.Int).Lsh(big.NewInt(1), 128)
+		_, err := PackUint128Pair(tooBig, big.NewInt(0))
+		assert.EqualError(t, err, "value does not fit in uint128")
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := PackUint128Pair(big.NewInt(0), big.NewInt(-1))
+		assert.EqualError(t, err, "value does not fit in uint128")
+	})
+}
+
+func TestPackPaymasterAndData(t *testing.T) {
+	paymaster := common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+
+	t.Run("round trip", func(t *testing.T) {
+		packed, err := PackPaymasterAndData(paymaster, big.NewInt(60000), big.NewInt(30000), []byte{0xde, 0xad})
+		require.NoError(t, err)
+		assert.Len(t, packed, 54)
+
+		addr, verificationGasLimit, postOpGasLimit, data, err := UnpackPaymasterAndData(packed)
+		require.NoError(t, err)
+		assert.Equal(t, paymaster, addr)
+		assert.Equal(t, big.NewInt(60000), verificationGasLimit)
+		assert.Equal(t, big.NewInt(30000), postOpGasLimit)
+		assert.Equal(t, []byte{0xde, 0xad}, data)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		addr, verificationGasLimit, postOpGasLimit, data, err := UnpackPaymasterAndData(nil)
+		require.NoError(t, err)
+		assert.Equal(t, common.Address{}, addr)
+		assert.Equal(t, int64(0), verificationGasLimit.Int64())
+		assert.Equal(t, int64(0), postOpGasLimit.Int64())
+		assert.Empty(t, data)
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		_, _, _, _, err := UnpackPaymasterAndData(paymaster.Bytes())
+		assert.EqualError(t, err, "invalid paymaster and data")
+	})
+}
+
+func TestToPackedUserOp(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		op := testUserOp()
+		packed, err := ToPackedUserOp(op)
+		require.NoError(t, err)
+
+		verificationGasLimit, callGasLimit := UnpackUint128Pair(packed.AccountGasLimits)
+		assert.Equal(t, op.VerificationGasLimit, verificationGasLimit)
+		assert.Equal(t, op.CallGasLimit, callGasLimit)
+
+		maxPriorityFeePerGas, maxFeePerGas := UnpackUint128Pair(packed.GasFees)
+		assert.Equal(t, op.MaxPriorityFeePerGas, maxPriorityFeePerGas)
+		assert.Equal(t, op.MaxFeePerGas, maxFeePerGas)
+
+		back, err := FromPackedUserOp(packed)
+		require.NoError(t, err)
+		assert.Equal(t, op, back)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		_, err := ToPackedUserOp(nil)
+		assert.EqualError(t, err, "nil user operation")
+
+		_, err = FromPackedUserOp(nil)
+		assert.EqualError(t, err, "nil packed user operation")
+	})
+
+	t.Run("gas limit overflow", func(t *testing.T) {
+		op := testUserOp()
+		op.CallGasLimit = new(big.Int).Lsh(big.NewInt(1), 130)
+		_, err := ToPackedUserOp(op)
+		assert.EqualError(t, err, "value does not fit in uint128")
+	})
+}
+
+func TestHashUserOpV07(t *testing.T) {
+	entryPoint := common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
+	chainID := big.NewInt(8453)
+
+	t.Run("matches manual encoding", func(t *testing.T) {
+		op := testUserOp()
+		hash, err := HashUserOpV07(op, entryPoint, chainID)
+		require.NoError(t, err)
+
+		word := func(b []byte) []byte {
+			return common.LeftPadBytes(b, 32)
+		}
+		accountGasLimits, err := PackUint128Pair(op.VerificationGasLimit, op.CallGasLimit)
+		require.NoError(t, err)
+		gasFees, err := PackUint128Pair(op.MaxPriorityFeePerGas, op.MaxFeePerGas)
+		require.NoError(t, err)
+
+		var inner []byte
+		inner = append(inner, word(op.Sender.Bytes())...)
+		inner = append(inner, word(op.Nonce.Bytes())...)
+		inner = append(inner, crypto.Keccak256(op.InitCode)...)
+		inner = append(inner, crypto.Keccak256(op.CallData)...)
+		inner = append(inner, accountGasLimits[:]...)
+		inner = append(inner, word(op.PreVerificationGas.Bytes())...)
+		inner = append(inner, gasFees[:]...)
+		inner = append(inner, crypto.Keccak256(op.PaymasterAndData)...)
+
+		var outer []byte
+		outer = append(outer, crypto.Keccak256(inner)...)
+		outer = append(outer, word(entryPoint.Bytes())...)
+		outer = append(outer, word(chainID.Bytes())...)
+
+		assert.Equal(t, crypto.Keccak256Hash(outer), hash)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		hash1, err := HashUserOpV07(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		hash2, err := HashUserOpV07(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		assert.Equal(t, hash1, hash2)
+	})
+
+	t.Run("differs from v0.6 hash", func(t *testing.T) {
+		v06, err := HashUserOp(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		v07, err := HashUserOpV07(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		assert.NotEqual(t, v06, v07)
+	})
+
+	t.Run("different chain", func(t *testing.T) {
+		hash1, err := HashUserOpV07(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		hash2, err := HashUserOpV07(testUserOp(), entryPoint, big.NewInt(1))
+		require.NoError(t, err)
+		assert.NotEqual(t, hash1, hash2)
+	})
+
+	t.Run("different entry point", func(t *testing.T) {
+		hash1, err := HashUserOpV07(testUserOp(), entryPoint, chainID)
+		require.NoError(t, err)
+		hash2, err := HashUserOpV07(testUserOp(), common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"), chainID)
+		require.NoError(t, err)
+		assert.NotEqual(t, hash1, hash2)
+	})
+
+	t.Run("signature is not hashed", func(t *testing.T) {
+		op := testUserOp()
+		hash1, err := HashUserOpV07(op, entryPoint, chainID)
+		require.NoError(t, err)
+		op.Signature = []byte{0x01}
+		hash2, err := HashUserOpV07(op, entryPoint, chainID)
+		require.NoError(t, err)
+		assert.Equal(t, hash1, hash2)
+	})
+}
@@ -1,6 +1,7 @@
 package encoding
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -76,6 +77,17 @@ func HashUserOp(op *UserOperation, entryPoint common.Address, chainID *big.Int)
 	return crypto.Keccak256Hash(final), nil
 }
 
+const (
+	ExecuteSignature        = "execute(address,uint256,bytes)"
+	ExecuteBatchSignature   = "executeBatch(address[],uint256[],bytes[])"
+	ERC7579ExecuteSignature = "execute(bytes32,bytes)"
+)
+
+var (
+	ERC7579SingleMode = [32]byte{}
+	ERC7579BatchMode  = [32]byte{0x01}
+)
+
 func EncodeCallData(target common.Address, value *big.Int, data []byte) ([]byte, error) {
 	executeArgs := abi.Arguments{
 		{Type: mustNewType("address")},
@@ -88,6 +100,68 @@ func EncodeCallData(target common.Address, value *big.Int, data []byte) ([]byte,
 		return nil, err
 	}
 
-	selector := crypto.Keccak256([]byte("execute(address,uint256,bytes)"))[:4]
+	selector := crypto.Keccak256([]byte(ExecuteSignature))[:4]
+	return append(selector, packed...), nil
+}
+
+func EncodeBatchCallData(targets []common.Address, values []*big.Int, data [][]byte) ([]byte, error) {
+	if len(targets) != len(values) || len(targets) != len(data) {
+		return nil, errors.New("batch length mismatch")
+	}
+
+	batchArgs := abi.Arguments{
+		{Type: mustNewType("address[]")},
+		{Type: mustNewType("uint256[]")},
+		{Type: mustNewType("bytes[]")},
+	}
+
+	packed, err := batchArgs.Pack(targets, values, data)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := crypto.Keccak256([]byte(ExecuteBatchSignature))[:4]
 	return append(selector, packed...), nil
 }
+
+func EncodeERC7579CallData(target common.Address, value *big.Int, data []byte) ([]byte, error) {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	execution := make([]byte, 0, 52+len(data))
+	execution = append(execution, target.Bytes()...)
+	execution = append(execution, common.LeftPadBytes(value.Bytes(), 32)...)
+	execution = append(execution, data...)
+	return EncodeFunctionCall(ERC7579ExecuteSignature, ERC7579SingleMode, execution)
+}
+
+func EncodeERC7579BatchCallData(targets []common.Address, values []*big.Int, data [][]byte) ([]byte, error) {
+	if len(targets) != len(values) || len(targets) != len(data) {
+		return nil, errors.New("batch length mismatch")
+	}
+
+	executionsType, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "target", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "callData", Type: "bytes"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type execution struct {
+		Target   common.Address
+		Value    *big.Int
+		CallData []byte
+	}
+	executions := make([]execution, len(targets))
+	for i := range targets {
+		executions[i] = execution{Target: targets[i], Value: values[i], CallData: data[i]}
+	}
+
+	packed, err := abi.Arguments{{Type: executionsType}}.Pack(executions)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeFunctionCall(ERC7579ExecuteSignature, ERC7579BatchMode, packed)
+}
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -164,3 +165,81 @@ func TestEncodeCallData(t *testing.T) {
 		assert.NotEqual(t, result1, result2)
 	})
 }
+
+func TestEncodeBatchCallData(t *testing.T) {
+	targets := []common.Address{
+		common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"),
+		common.HexToAddress("0x1111111111111111111111111111111111111111"),
+	}
+	values := []*big.Int{big.NewInt(0), big.NewInt(5)}
+	data := [][]byte{{0x01, 0x02}, {}}
+
+	t.Run("success", func(t *testing.T) {
+		result, err := EncodeBatchCallData(targets, values, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x47, 0xe1, 0xda, 0x2a}, result[:4])
+	})
+
+	t.Run("single call differs from execute", func(t *testing.T) {
+		batch, err := EncodeBatchCallData(targets[:1], values[:1], data[:1])
+		require.NoError(t, err)
+		single, err := EncodeCallData(targets[0], values[0], data[0])
+		require.NoError(t, err)
+		assert.NotEqual(t, batch[:4], single[:4])
+	})
+
+	t.Run("length mismatch", func(t *testing.T) {
+		_, err := EncodeBatchCallData(targets, values[:1], data)
+		require.Error(t, err)
+		assert.Equal(t, "batch length mismatch", err.Error())
+	})
+}
+
+func TestEncodeERC7579CallData(t *testing.T) {
+	target := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+
+	t.Run("single", func(t *testing.T) {
+		result, err := EncodeERC7579CallData(target, big.NewInt(5), []byte{0x01, 0x02})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xe9, 0xae, 0x5c, 0x53}, result[:4])
+
+		args, err := DecodeFunctionCall(ERC7579ExecuteSignature, result)
+		require.NoError(t, err)
+		assert.Equal(t, ERC7579SingleMode, args[0])
+		expected := append(append(target.Bytes(), common.LeftPadBytes([]byte{5}, 32)...), 0x01, 0x02)
+		assert.Equal(t, expected, args[1])
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		targets := []common.Address{target, common.HexToAddress("0x1111111111111111111111111111111111111111")}
+		result, err := EncodeERC7579BatchCallData(targets, []*big.Int{big.NewInt(0), big.NewInt(5)}, [][]byte{{0x01}, {}})
+		require.NoError(t, err)
+
+		args, err := DecodeFunctionCall(ERC7579ExecuteSignature, result)
+		require.NoError(t, err)
+		assert.Equal(t, ERC7579BatchMode, args[0])
+
+		executionsType, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+			{Name: "target", Type: "address"},
+			{Name: "value", Type: "uint256"},
+			{Name: "callData", Type: "bytes"},
+		})
+		require.NoError(t, err)
+		unpacked, err := abi.Arguments{{Type: executionsType}}.Unpack(args[1].([]byte))
+		require.NoError(t, err)
+		executions := unpacked[0].([]struct {
+			Target   common.Address `json:"target"`
+			Value    *big.Int       `json:"value"`
+			CallData []byte         `json:"callData"`
+		})
+		require.Len(t, executions, 2)
+		assert.Equal(t, targets[1], executions[1].Target)
+		assert.Equal(t, big.NewInt(5), executions[1].Value)
+		assert.Equal(t, []byte{0x01}, executions[0].CallData)
+	})
+
+	t.Run("length mismatch", func(t *testing.T) {
+		_, err := EncodeERC7579BatchCallData([]common.Address{target}, nil, nil)
+		assert.EqualError(t, err, "batch length mismatch")
+	})
+}
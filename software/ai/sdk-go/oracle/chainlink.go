@@ -0,0 +1,127 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	decimalsSelector        = crypto.Keccak256([]byte("decimals()"))[:4]
+	latestRoundDataSelector = crypto.Keccak256([]byte("latestRoundData()"))[:4]
+)
+
+type ContractCaller interface {
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type Feed struct {
+	Token         common.Address
+	Quote         string
+	Aggregator    common.Address
+	TokenDecimals uint8
+	MaxAge        time.Duration
+}
+
+type ChainlinkOracle struct {
+	caller   ContractCaller
+	feeds    map[feedKey]Feed
+	decimals map[common.Address]uint8
+	config   config
+	mu       sync.Mutex
+}
+
+func NewChainlinkOracle(caller ContractCaller, feeds []Feed, opts ...Option) *ChainlinkOracle {
+	o := &ChainlinkOracle{
+		caller:   caller,
+		feeds:    make(map[feedKey]Feed, len(feeds)),
+		decimals: make(map[common.Address]uint8),
+		config:   newConfig(opts),
+	}
+	for _, f := range feeds {
+		o.feeds[feedKey{f.Token, f.Quote}] = f
+	}
+	return o
+}
+
+func (o *ChainlinkOracle) Price(ctx context.Context, token common.Address, quote string) (*Price, error) {
+	feed, ok := o.feeds[feedKey{token, quote}]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoFeed, pair(token, quote))
+	}
+	if o.caller == nil {
+		return nil, errors.New("contract caller not configured")
+	}
+
+	decimals, err := o.aggregatorDecimals(ctx, feed.Aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := o.caller.CallContract(ctx, ethereum.CallMsg{To: &feed.Aggregator, Data: latestRoundDataSelector}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != 5*32 {
+		return nil, errors.New("invalid latestRoundData response")
+	}
+	answer := new(big.Int).SetBytes(out[32:64])
+	if out[32]&0x80 != 0 {
+		answer.Sub(answer, math.BigPow(2, 256))
+	}
+	updatedAt := new(big.Int).SetBytes(out[96:128])
+	if answer.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid price %s from aggregator %s", answer, feed.Aggregator.Hex())
+	}
+	if updatedAt.Sign() == 0 || !updatedAt.IsInt64() {
+		return nil, fmt.Errorf("incomplete round from aggregator %s", feed.Aggregator.Hex())
+	}
+
+	p := &Price{
+		Token:         token,
+		Quote:         quote,
+		Answer:        answer,
+		Decimals:      decimals,
+		TokenDecimals: feed.TokenDecimals,
+		UpdatedAt:     time.Unix(updatedAt.Int64(), 0),
+	}
+	if err := o.config.checkAge(p, feed.MaxAge); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *ChainlinkOracle) aggregatorDecimals(ctx context.Context, aggregator common.Address) (uint8, error) {
+	o.mu.Lock()
+	d, ok := o.decimals[aggregator]
+	o.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	out, err := o.caller.CallContract(ctx, ethereum.CallMsg{To: &aggregator, Data: decimalsSelector}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(out) != 32 {
+		return 0, errors.New("invalid decimals response")
+	}
+	n := new(big.Int).SetBytes(out)
+	if n.Cmp(big.NewInt(77)) > 0 {
+		return 0, errors.New("invalid decimals response")
+	}
+	d = uint8(n.Uint64())
+
+	o.mu.Lock()
+	o.decimals[aggregator] = d
+	o.mu.Unlock()
+	return d, nil
+}
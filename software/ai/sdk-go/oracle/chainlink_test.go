@@ -0,0 +1,108 @@
+package oracle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAggregator struct {
+	decimals  int64
+	answer    *big.Int
+	updatedAt int64
+	calls     int
+	err       error
+}
+
+func (a *fakeAggregator) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	a.calls++
+	if a.err != nil {
+		return nil, a.err
+	}
+	switch {
+	case bytes.Equal(call.Data, decimalsSelector):
+		return common.LeftPadBytes(big.NewInt(a.decimals).Bytes(), 32), nil
+	case bytes.Equal(call.Data, latestRoundDataSelector):
+		answer := new(big.Int).Set(a.answer)
+		if answer.Sign() < 0 {
+			answer.Add(answer, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		var out []byte
+		for _, word := range []*big.Int{big.NewInt(7), answer, big.NewInt(a.updatedAt), big.NewInt(a.updatedAt), big.NewInt(7)} {
+			out = append(out, common.LeftPadBytes(word.Bytes(), 32)...)
+		}
+		return out, nil
+	}
+	return nil, errors.New("execution reverted")
+}
+
+func TestChainlinkOracle(t *testing.T) {
+	ctx := context.Background()
+	aggregator := common.HexToAddress("0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: now}
+
+	newOracle := func(agg *fakeAggregator, feedMaxAge time.Duration) *ChainlinkOracle {
+		return NewChainlinkOracle(agg, []Feed{
+			{Token: weth, Quote: "USD", Aggregator: aggregator, TokenDecimals: 18, MaxAge: feedMaxAge},
+		}, WithClock(clock.Now), WithMaxAge(time.Hour))
+	}
+
+	t.Run("latest round", func(t *testing.T) {
+		agg := &fakeAggregator{decimals: 8, answer: big.NewInt(315025000000), updatedAt: now.Add(-time.Minute).Unix()}
+		o := newOracle(agg, 0)
+		p, err := o.Price(ctx, weth, "USD")
+		require.NoError(t, err)
+		assert.Equal(t, &Price{
+			Token:         weth,
+			Quote:         "USD",
+			Answer:        big.NewInt(315025000000),
+			Decimals:      8,
+			TokenDecimals: 18,
+			UpdatedAt:     time.Unix(now.Add(-time.Minute).Unix(), 0),
+		}, p)
+
+		_, err = o.Price(ctx, weth, "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 3, agg.calls)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		agg := &fakeAggregator{decimals: 8, answer: big.NewInt(1), updatedAt: now.Add(-2 * time.Hour).Unix()}
+		_, err := newOracle(agg, 0).Price(ctx, weth, "USD")
+		assert.ErrorIs(t, err, ErrStalePrice)
+		assert.EqualError(t, err, "stale price for 0x4200000000000000000000000000000000000006/USD: updated 2026-10-16T10:00:00Z")
+
+		_, err = newOracle(agg, 24*time.Hour).Price(ctx, weth, "USD")
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			agg     *fakeAggregator
+			token   common.Address
+			wantErr string
+		}{
+			{"no feed", &fakeAggregator{}, usdc, "no price feed for 0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913/USD"},
+			{"rpc error", &fakeAggregator{err: errors.New("rpc down")}, weth, "rpc down"},
+			{"negative answer", &fakeAggregator{decimals: 8, answer: big.NewInt(-5), updatedAt: now.Unix()}, weth, "invalid price -5 from aggregator 0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70"},
+			{"incomplete round", &fakeAggregator{decimals: 8, answer: big.NewInt(5)}, weth, "incomplete round from aggregator 0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := newOracle(tt.agg, 0).Price(ctx, tt.token, "USD")
+				assert.EqualError(t, err, tt.wantErr)
+			})
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const QuoteDecimals = 8
+
+var (
+	ErrNoFeed     = errors.New("no price feed")
+	ErrStalePrice = errors.New("stale price")
+)
+
+type Price struct {
+	Token         common.Address
+	Quote         string
+	Answer        *big.Int
+	Decimals      uint8
+	TokenDecimals uint8
+	UpdatedAt     time.Time
+}
+
+func (p *Price) Value(amount *big.Int) *big.Int {
+	num := new(big.Int).Mul(amount, p.Answer)
+	num.Mul(num, pow10(QuoteDecimals))
+	den := pow10(int(p.TokenDecimals) + int(p.Decimals))
+	value, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() > 0 {
+		value.Add(value, big.NewInt(1))
+	}
+	return value
+}
+
+type PriceOracle interface {
+	Price(ctx context.Context, token common.Address, quote string) (*Price, error)
+}
+
+type Option func(*config)
+
+type config struct {
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(c *config) {
+		c.maxAge = maxAge
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c *config) checkAge(p *Price, maxAge time.Duration) error {
+	if maxAge <= 0 {
+		maxAge = c.maxAge
+	}
+	if maxAge > 0 && c.now().Sub(p.UpdatedAt) > maxAge {
+		return fmt.Errorf("%w for %s: updated %s", ErrStalePrice, pair(p.Token, p.Quote), p.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+type fallback []PriceOracle
+
+func Fallback(oracles ...PriceOracle) PriceOracle {
+	return fallback(oracles)
+}
+
+func (f fallback) Price(ctx context.Context, token common.Address, quote string) (*Price, error) {
+	var first error
+	for _, o := range f {
+		p, err := o.Price(ctx, token, quote)
+		if err == nil {
+			return p, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("%w for %s", ErrNoFeed, pair(token, quote))
+	}
+	return nil, first
+}
+
+func ParsePrice(s string) (*big.Int, uint8, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" || len(frac) > 77 {
+		return nil, 0, fmt.Errorf("invalid price %q", s)
+	}
+	answer, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok || answer.Sign() <= 0 || strings.ContainsAny(whole+frac, "+-") {
+		return nil, 0, fmt.Errorf("invalid price %q", s)
+	}
+	return answer, uint8(len(frac)), nil
+}
+
+type feedKey struct {
+	token common.Address
+	quote string
+}
+
+func pair(token common.Address, quote string) string {
+	return token.Hex() + "/" + quote
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,130 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	usdc = common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	weth = common.HexToAddress("0x4200000000000000000000000000000000000006")
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestPriceValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  Price
+		amount *big.Int
+		want   *big.Int
+	}{
+		{
+			"one ETH at 3150.25",
+			Price{Answer: big.NewInt(315025000000), Decimals: 8, TokenDecimals: 18},
+			new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+			big.NewInt(315025000000),
+		},
+		{
+			"USDC at par",
+			Price{Answer: big.NewInt(1), Decimals: 0, TokenDecimals: 6},
+			big.NewInt(2_500_000),
+			big.NewInt(250_000_000),
+		},
+		{
+			"fractions round up",
+			Price{Answer: big.NewInt(315025000000), Decimals: 8, TokenDecimals: 18},
+			big.NewInt(1),
+			big.NewInt(1),
+		},
+		{
+			"zero",
+			Price{Answer: big.NewInt(100), Decimals: 2, TokenDecimals: 6},
+			big.NewInt(0),
+			big.NewInt(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want.String(), tt.price.Value(tt.amount).String())
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantAnswer   int64
+		wantDecimals uint8
+		wantErr      string
+	}{
+		{"3150.25", 315025, 2, ""},
+		{"1", 1, 0, ""},
+		{"0.00012", 12, 5, ""},
+		{"0", 0, 0, `invalid price "0"`},
+		{"-1", 0, 0, `invalid price "-1"`},
+		{".5", 0, 0, `invalid price ".5"`},
+		{"1.2.3", 0, 0, `invalid price "1.2.3"`},
+		{"$1", 0, 0, `invalid price "$1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			answer, decimals, err := ParsePrice(tt.in)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, big.NewInt(tt.wantAnswer), answer)
+			assert.Equal(t, tt.wantDecimals, decimals)
+		})
+	}
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+
+	primary := NewStaticOracle(WithClock(clock.Now), WithMaxAge(time.Hour))
+	require.NoError(t, primary.Set(weth, "USD", 18, "3150"))
+	backup := NewStaticOracle(WithClock(clock.Now))
+	require.NoError(t, backup.Set(usdc, "USD", 6, "1"))
+	require.NoError(t, backup.Set(weth, "USD", 18, "3000"))
+
+	o := Fallback(primary, backup)
+
+	p, err := o.Price(ctx, weth, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(3150), p.Answer)
+
+	p, err = o.Price(ctx, usdc, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), p.Answer)
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	p, err = o.Price(ctx, weth, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(3000), p.Answer)
+
+	_, err = o.Price(ctx, weth, "EUR")
+	assert.True(t, errors.Is(err, ErrNoFeed))
+	assert.EqualError(t, err, "no price feed for 0x4200000000000000000000000000000000000006/EUR")
+
+	_, err = Fallback().Price(ctx, weth, "USD")
+	assert.ErrorIs(t, err, ErrNoFeed)
+}
@@ -0,0 +1,114 @@
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type StaticOracle struct {
+	prices map[feedKey]Price
+	config config
+	mu     sync.RWMutex
+}
+
+type priceFile struct {
+	Prices []priceFileEntry `json:"prices"`
+}
+
+type priceFileEntry struct {
+	Token         common.Address `json:"token"`
+	Quote         string         `json:"quote"`
+	Price         string         `json:"price"`
+	TokenDecimals uint8          `json:"tokenDecimals"`
+	UpdatedAt     *time.Time     `json:"updatedAt,omitempty"`
+}
+
+func NewStaticOracle(opts ...Option) *StaticOracle {
+	return &StaticOracle{
+		prices: make(map[feedKey]Price),
+		config: newConfig(opts),
+	}
+}
+
+func LoadStaticOracle(path string, opts ...Option) (*StaticOracle, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file priceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid price file: %w", err)
+	}
+
+	o := NewStaticOracle(opts...)
+	for i, e := range file.Prices {
+		if e.Quote == "" {
+			return nil, fmt.Errorf("prices[%d]: quote required", i)
+		}
+		answer, decimals, err := ParsePrice(e.Price)
+		if err != nil {
+			return nil, fmt.Errorf("prices[%d]: %w", i, err)
+		}
+		updatedAt := info.ModTime()
+		if e.UpdatedAt != nil {
+			updatedAt = *e.UpdatedAt
+		}
+		o.set(Price{
+			Token:         e.Token,
+			Quote:         e.Quote,
+			Answer:        answer,
+			Decimals:      decimals,
+			TokenDecimals: e.TokenDecimals,
+			UpdatedAt:     updatedAt,
+		})
+	}
+	return o, nil
+}
+
+func (o *StaticOracle) Set(token common.Address, quote string, tokenDecimals uint8, price string) error {
+	answer, decimals, err := ParsePrice(price)
+	if err != nil {
+		return err
+	}
+	o.set(Price{
+		Token:         token,
+		Quote:         quote,
+		Answer:        answer,
+		Decimals:      decimals,
+		TokenDecimals: tokenDecimals,
+		UpdatedAt:     o.config.now(),
+	})
+	return nil
+}
+
+func (o *StaticOracle) set(p Price) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prices[feedKey{p.Token, p.Quote}] = p
+}
+
+func (o *StaticOracle) Price(ctx context.Context, token common.Address, quote string) (*Price, error) {
+	o.mu.RLock()
+	p, ok := o.prices[feedKey{token, quote}]
+	o.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoFeed, pair(token, quote))
+	}
+	if err := o.config.checkAge(&p, 0); err != nil {
+		return nil, err
+	}
+	p.Answer = new(big.Int).Set(p.Answer)
+	return &p, nil
+}
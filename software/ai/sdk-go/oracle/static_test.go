@@ -0,0 +1,79 @@
+package oracle
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticOracle(t *testing.T) {
+	ctx := context.Background()
+	o := NewStaticOracle()
+	require.NoError(t, o.Set(usdc, "USD", 6, "0.9998"))
+
+	p, err := o.Price(ctx, usdc, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(9998), p.Answer)
+	assert.Equal(t, uint8(4), p.Decimals)
+	assert.Equal(t, uint8(6), p.TokenDecimals)
+
+	p.Answer.SetInt64(1)
+	again, err := o.Price(ctx, usdc, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(9998), again.Answer)
+
+	_, err = o.Price(ctx, weth, "USD")
+	assert.ErrorIs(t, err, ErrNoFeed)
+	assert.EqualError(t, o.Set(weth, "USD", 18, "free"), `invalid price "free"`)
+}
+
+func TestLoadStaticOracle(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "prices.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "prices": [
+    {"token": "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", "quote": "USD", "price": "1", "tokenDecimals": 6},
+    {"token": "0x4200000000000000000000000000000000000006", "quote": "USD", "price": "3150.25", "tokenDecimals": 18, "updatedAt": "2026-10-16T00:00:00Z"}
+  ]
+}`), 0o600))
+	modified := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	o, err := LoadStaticOracle(path, WithClock(clock.Now), WithMaxAge(8*time.Hour))
+	require.NoError(t, err)
+
+	p, err := o.Price(ctx, usdc, "USD")
+	require.NoError(t, err)
+	assert.True(t, modified.Equal(p.UpdatedAt))
+
+	_, err = o.Price(ctx, weth, "USD")
+	assert.ErrorIs(t, err, ErrStalePrice)
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			data    string
+			wantErr string
+		}{
+			{"not json", "prices", "invalid price file: invalid character 'p' looking for beginning of value"},
+			{"missing quote", `{"prices": [{"token": "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", "price": "1"}]}`, "prices[0]: quote required"},
+			{"bad price", `{"prices": [{"quote": "USD", "price": "1,00"}]}`, `prices[0]: invalid price "1,00"`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "prices.json")
+				require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o600))
+				_, err := LoadStaticOracle(path)
+				assert.EqualError(t, err, tt.wantErr)
+			})
+		}
+	})
+}
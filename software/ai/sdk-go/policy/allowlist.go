@@ -2,6 +2,8 @@ package policy
 
 import (
 	"encoding/hex"
+	"errors"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -28,11 +30,88 @@ func NewFunctionAllowlist(signatures []string) *FunctionAllowlist {
 	return al
 }
 
+const AnySelector = "*"
+
+var AnyContract common.Address
+
+func Selector(signature string) string {
+	if signature == AnySelector {
+		return AnySelector
+	}
+	return hex.EncodeToString(crypto.Keccak256([]byte(signature))[:4])
+}
+
+func Permit(contract common.Address, signature string) Permission {
+	return Permission{Contract: contract, Selector: Selector(signature)}
+}
+
+func NewPermissionList(perms ...Permission) *PermissionList {
+	l := &PermissionList{Permissions: make([]Permission, 0, len(perms))}
+	for _, perm := range perms {
+		l.add(perm)
+	}
+	return l
+}
+
+func (l *PermissionList) Allows(contract common.Address, selector string) bool {
+	return l.level(contract, normalizeSelector(selector)) >= 0
+}
+
+func (l *PermissionList) level(contract common.Address, selector string) int {
+	level := -1
+	for _, perm := range l.Permissions {
+		if perm.Contract != AnyContract && perm.Contract != contract {
+			continue
+		}
+		if perm.Selector != AnySelector && normalizeSelector(perm.Selector) != selector {
+			continue
+		}
+		level = max(level, specificity(perm))
+	}
+	return level
+}
+
+func (l *PermissionList) add(perm Permission) {
+	perm.Selector = normalizeSelector(perm.Selector)
+	for _, existing := range l.Permissions {
+		if existing == perm {
+			return
+		}
+	}
+	l.Permissions = append(l.Permissions, perm)
+}
+
+func normalizeSelector(selector string) string {
+	return strings.TrimPrefix(strings.ToLower(selector), "0x")
+}
+
+func validatePermissions(l *PermissionList) error {
+	for _, perm := range l.Permissions {
+		if perm.Selector != AnySelector && !isSelector(perm.Selector) {
+			return errors.New("invalid permission selector")
+		}
+	}
+	return nil
+}
+
+func isSelector(s string) bool {
+	b, err := hex.DecodeString(normalizeSelector(s))
+	return err == nil && len(b) == 4
+}
+
 func IsAllowed(p *Policy, contract common.Address, functionSig string) bool {
 	if p == nil {
 		return false
 	}
 
+	selectorHex := hex.EncodeToString(crypto.Keccak256([]byte(functionSig))[:4])
+
+	if p.Deny != nil {
+		if allowed, _ := checkDeniedCall(p, contract, selectorHex); !allowed {
+			return false
+		}
+	}
+
 	if p.ContractAllowlist != nil {
 		if !p.ContractAllowlist.Contracts[contract] {
 			return false
@@ -40,12 +119,16 @@ func IsAllowed(p *Policy, contract common.Address, functionSig string) bool {
 	}
 
 	if p.FunctionAllowlist != nil {
-		selector := crypto.Keccak256([]byte(functionSig))[:4]
-		selectorHex := hex.EncodeToString(selector)
 		if !p.FunctionAllowlist.Functions[selectorHex] {
 			return false
 		}
 	}
 
+	if p.Permissions != nil {
+		if !p.Permissions.Allows(contract, selectorHex) {
+			return false
+		}
+	}
+
 	return true
 }
@@ -150,6 +150,61 @@ func TestIsAllowed(t *testing.T) {
 			functionSig: unknownSig,
 			want:        false,
 		},
+		{
+			name: "permission pair allowed",
+			policy: &Policy{
+				Permissions: NewPermissionList(Permit(contract1, approveSig), Permit(contract2, transferSig)),
+			},
+			contract:    contract1,
+			functionSig: approveSig,
+			want:        true,
+		},
+		{
+			name: "permission pair not crossed",
+			policy: &Policy{
+				Permissions: NewPermissionList(Permit(contract1, approveSig), Permit(contract2, transferSig)),
+			},
+			contract:    contract2,
+			functionSig: approveSig,
+			want:        false,
+		},
+		{
+			name: "permission any selector on contract",
+			policy: &Policy{
+				Permissions: NewPermissionList(Permit(contract1, AnySelector)),
+			},
+			contract:    contract1,
+			functionSig: unknownSig,
+			want:        true,
+		},
+		{
+			name: "permission selector on any contract",
+			policy: &Policy{
+				Permissions: NewPermissionList(Permit(AnyContract, transferSig)),
+			},
+			contract:    unknownContract,
+			functionSig: transferSig,
+			want:        true,
+		},
+		{
+			name: "empty permission list denies",
+			policy: &Policy{
+				Permissions: NewPermissionList(),
+			},
+			contract:    contract1,
+			functionSig: transferSig,
+			want:        false,
+		},
+		{
+			name: "permissions and allowlists both apply",
+			policy: &Policy{
+				ContractAllowlist: NewContractAllowlist([]common.Address{contract1}),
+				Permissions:       NewPermissionList(Permit(AnyContract, AnySelector)),
+			},
+			contract:    contract2,
+			functionSig: transferSig,
+			want:        false,
+		},
 	}
 
 	for _, tc := range tests {
@@ -159,3 +214,33 @@ func TestIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestPermit(t *testing.T) {
+	contract := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	assert.Equal(t, Permission{Contract: contract, Selector: "a9059cbb"}, Permit(contract, "transfer(address,uint256)"))
+	assert.Equal(t, Permission{Selector: AnySelector}, Permit(AnyContract, AnySelector))
+}
+
+func TestPermissionList(t *testing.T) {
+	contract := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	t.Run("deduplicates and normalizes", func(t *testing.T) {
+		l := NewPermissionList(
+			Permission{Contract: contract, Selector: "0xA9059CBB"},
+			Permit(contract, "transfer(address,uint256)"),
+		)
+		assert.Equal(t, []Permission{{Contract: contract, Selector: "a9059cbb"}}, l.Permissions)
+	})
+
+	t.Run("allows prefixed selector", func(t *testing.T) {
+		l := NewPermissionList(Permit(contract, "transfer(address,uint256)"))
+		assert.True(t, l.Allows(contract, "0xa9059cbb"))
+		assert.False(t, l.Allows(contract, ""))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, validatePermissions(NewPermissionList(Permit(contract, AnySelector))))
+		assert.EqualError(t, validatePermissions(&PermissionList{Permissions: []Permission{{Contract: contract}}}), "invalid permission selector")
+		assert.EqualError(t, validatePermissions(&PermissionList{Permissions: []Permission{{Selector: "a9059c"}}}), "invalid permission selector")
+	})
+}
@@ -0,0 +1,532 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
+	"github.com/sigloop/sdk-go/storage"
+)
+
+const DefaultApprovalTTL = 24 * time.Hour
+
+var ErrApprovalRequired = errors.New("approval required")
+
+type ApprovalError struct {
+	Request *ApprovalRequest
+	Reasons []string
+}
+
+func (e *ApprovalError) Error() string {
+	msg := ErrApprovalRequired.Error()
+	if e.Request != nil {
+		msg += " (request " + e.Request.ID + ")"
+	}
+	return msg + ": " + strings.Join(e.Reasons, "; ")
+}
+
+func (e *ApprovalError) Unwrap() error {
+	return ErrApprovalRequired
+}
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalInFlight ApprovalStatus = "in_flight"
+	ApprovalDenied   ApprovalStatus = "denied"
+	ApprovalExpired  ApprovalStatus = "expired"
+	ApprovalExecuted ApprovalStatus = "executed"
+)
+
+type ApprovalRequest struct {
+	ID        string
+	PolicyID  string
+	AgentID   string
+	Wallet    common.Address
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+	Token     common.Address
+	Amount    *big.Int
+	Payee     common.Address
+	Action    common.Hash
+	Reasons   []string
+	Approvers []common.Address
+	Status    ApprovalStatus
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	DecidedBy common.Address
+	DecidedAt time.Time
+	Signature []byte
+}
+
+type ApprovalStore interface {
+	Get(id string) (*ApprovalRequest, bool, error)
+	Put(r *ApprovalRequest) error
+	List() ([]*ApprovalRequest, error)
+}
+
+type ApprovalQueue struct {
+	store  ApprovalStore
+	now    func() time.Time
+	notify chan struct{}
+	mu     sync.Mutex
+}
+
+type ApprovalQueueOption func(*ApprovalQueue)
+
+func WithApprovalStore(store ApprovalStore) ApprovalQueueOption {
+	return func(q *ApprovalQueue) {
+		q.store = store
+	}
+}
+
+func WithApprovalClock(now func() time.Time) ApprovalQueueOption {
+	return func(q *ApprovalQueue) {
+		q.now = now
+	}
+}
+
+func NewApprovalQueue(opts ...ApprovalQueueOption) *ApprovalQueue {
+	q := &ApprovalQueue{
+		now:    time.Now,
+		notify: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if q.store == nil {
+		q.store = NewMemoryApprovalStore()
+	}
+	return q
+}
+
+func WithApprovalQueue(q *ApprovalQueue) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.approvals = q
+	}
+}
+
+func (s *PolicyService) Approvals() *ApprovalQueue {
+	return s.approvals
+}
+
+func ActionHash(policyID, agentID string, wallet common.Address, tx *Transaction) common.Hash {
+	return crypto.Keccak256Hash(
+		crypto.Keccak256([]byte(policyID)),
+		crypto.Keccak256([]byte(agentID)),
+		wallet.Bytes(),
+		tx.To.Bytes(),
+		word(tx.Value),
+		crypto.Keccak256(tx.Data),
+		tx.Token.Bytes(),
+		word(tx.Amount),
+		tx.Payee.Bytes(),
+	)
+}
+
+func ApprovalHash(r *ApprovalRequest, status ApprovalStatus) common.Hash {
+	return crypto.Keccak256Hash([]byte(r.ID), r.Action.Bytes(), []byte(status))
+}
+
+func SignApproval(ctx context.Context, s signer.Signer, r *ApprovalRequest, status ApprovalStatus) ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("nil signer")
+	}
+	return s.SignHash(ctx, ApprovalHash(r, status))
+}
+
+func (q *ApprovalQueue) Get(id string) (*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, err := q.get(id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *r
+	return &copied, nil
+}
+
+func (q *ApprovalQueue) List(status ApprovalStatus) ([]*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	all, err := q.list()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ApprovalRequest, 0, len(all))
+	for _, r := range all {
+		if status == "" || r.Status == status {
+			copied := *r
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (q *ApprovalQueue) Approve(id string, approver common.Address, signature []byte) (*ApprovalRequest, error) {
+	return q.decide(id, ApprovalApproved, approver, signature)
+}
+
+func (q *ApprovalQueue) Deny(id string, approver common.Address, signature []byte) (*ApprovalRequest, error) {
+	return q.decide(id, ApprovalDenied, approver, signature)
+}
+
+func (q *ApprovalQueue) ApproveWithSigner(ctx context.Context, id string, s signer.Signer) (*ApprovalRequest, error) {
+	return q.decideWithSigner(ctx, id, ApprovalApproved, s)
+}
+
+func (q *ApprovalQueue) DenyWithSigner(ctx context.Context, id string, s signer.Signer) (*ApprovalRequest, error) {
+	return q.decideWithSigner(ctx, id, ApprovalDenied, s)
+}
+
+func (q *ApprovalQueue) Wait(ctx context.Context, id string) (*ApprovalRequest, error) {
+	for {
+		q.mu.Lock()
+		r, err := q.get(id)
+		notify := q.notify
+		q.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		copied := *r
+		if r.Status != ApprovalPending {
+			return &copied, nil
+		}
+
+		timer := time.NewTimer(r.ExpiresAt.Sub(q.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &copied, ctx.Err()
+		case <-notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (q *ApprovalQueue) decideWithSigner(ctx context.Context, id string, status ApprovalStatus, s signer.Signer) (*ApprovalRequest, error) {
+	if s == nil {
+		return nil, errors.New("nil signer")
+	}
+	r, err := q.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := SignApproval(ctx, s, r, status)
+	if err != nil {
+		return nil, err
+	}
+	return q.decide(id, status, s.Address(), sig)
+}
+
+func (q *ApprovalQueue) decide(id string, status ApprovalStatus, approver common.Address, signature []byte) (*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	r, err := q.get(id)
+	if err != nil {
+		return nil, err
+	}
+	switch r.Status {
+	case ApprovalPending:
+	case ApprovalExpired:
+		return nil, errors.New("approval request expired")
+	default:
+		return nil, errors.New("approval request already decided")
+	}
+	if !containsAddress(r.Approvers, approver) {
+		return nil, errors.New("not an approver")
+	}
+	if err := signer.Verify(approver, ApprovalHash(r, status), signature); err != nil {
+		return nil, err
+	}
+
+	r.Status = status
+	r.DecidedBy = approver
+	r.DecidedAt = q.now()
+	r.Signature = signature
+	if err := q.put(r); err != nil {
+		return nil, err
+	}
+	copied := *r
+	return &copied, nil
+}
+
+func (q *ApprovalQueue) submit(p *Policy, agentID string, wallet common.Address, tx *Transaction, d *Decision) (*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	action := ActionHash(p.ID, agentID, wallet, tx)
+	all, err := q.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range all {
+		if r.Action == action && r.Status == ApprovalPending {
+			copied := *r
+			return &copied, nil
+		}
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	ttl := DefaultApprovalTTL
+	var approvers []common.Address
+	if esc := p.Escalation; esc != nil {
+		if esc.TTL > 0 {
+			ttl = esc.TTL
+		}
+		approvers = append(approvers, esc.Approvers...)
+	}
+	var reasons []string
+	for _, f := range d.Failures() {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", f.Rule, f.Reason))
+	}
+	now := q.now()
+	r := &ApprovalRequest{
+		ID:        id,
+		PolicyID:  p.ID,
+		AgentID:   agentID,
+		Wallet:    wallet,
+		To:        tx.To,
+		Value:     tx.Value,
+		Data:      tx.Data,
+		Token:     tx.Token,
+		Amount:    tx.Amount,
+		Payee:     tx.Payee,
+		Action:    action,
+		Reasons:   reasons,
+		Approvers: approvers,
+		Status:    ApprovalPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := q.put(r); err != nil {
+		return nil, err
+	}
+	copied := *r
+	return &copied, nil
+}
+
+func (q *ApprovalQueue) approved(action common.Hash) (*ApprovalRequest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	all, err := q.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range all {
+		if r.Action == action && r.Status == ApprovalApproved {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *ApprovalQueue) execute(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, err := q.get(id)
+	if err != nil {
+		return err
+	}
+	r.Status = ApprovalExecuted
+	return q.put(r)
+}
+
+func (q *ApprovalQueue) hold(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, err := q.get(id)
+	if err != nil {
+		return err
+	}
+	if r.Status != ApprovalApproved {
+		return errors.New("approval request not approved")
+	}
+	r.Status = ApprovalInFlight
+	return q.put(r)
+}
+
+func (q *ApprovalQueue) unhold(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, err := q.get(id)
+	if err != nil {
+		return err
+	}
+	if r.Status != ApprovalInFlight {
+		return nil
+	}
+	r.Status = ApprovalApproved
+	if err := q.put(r); err != nil {
+		return err
+	}
+	return q.expire(r)
+}
+
+func (q *ApprovalQueue) get(id string) (*ApprovalRequest, error) {
+	r, ok, err := q.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("approval request not found")
+	}
+	return r, q.expire(r)
+}
+
+func (q *ApprovalQueue) list() ([]*ApprovalRequest, error) {
+	all, err := q.store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range all {
+		if err := q.expire(r); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].CreatedAt.Before(all[j].CreatedAt) })
+	return all, nil
+}
+
+func (q *ApprovalQueue) expire(r *ApprovalRequest) error {
+	if (r.Status == ApprovalPending || r.Status == ApprovalApproved) && !q.now().Before(r.ExpiresAt) {
+		r.Status = ApprovalExpired
+		return q.put(r)
+	}
+	return nil
+}
+
+func (q *ApprovalQueue) put(r *ApprovalRequest) error {
+	if err := q.store.Put(r); err != nil {
+		return err
+	}
+	close(q.notify)
+	q.notify = make(chan struct{})
+	return nil
+}
+
+func (s *PolicyService) escalate(p *Policy, agentID string, wallet common.Address, tx *Transaction, d *Decision) error {
+	if !d.NeedsApproval() {
+		return nil
+	}
+
+	r, err := s.approvals.approved(ActionHash(p.ID, agentID, wallet, tx))
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		d.Approval, err = s.approvals.submit(p, agentID, wallet, tx, d)
+		return err
+	}
+
+	for i := range d.Results {
+		if res := &d.Results[i]; !res.Passed && res.Escalate {
+			res.Passed = true
+			res.Reason += "; approved by " + r.DecidedBy.Hex()
+		}
+	}
+	d.Allowed = true
+	d.Approval = r
+	return nil
+}
+
+func word(n *big.Int) []byte {
+	if n == nil {
+		return make([]byte, 32)
+	}
+	return common.LeftPadBytes(n.Bytes(), 32)
+}
+
+type MemoryApprovalStore struct {
+	requests map[string]*ApprovalRequest
+	mu       sync.RWMutex
+}
+
+func NewMemoryApprovalStore() *MemoryApprovalStore {
+	return &MemoryApprovalStore{
+		requests: make(map[string]*ApprovalRequest),
+	}
+}
+
+func (s *MemoryApprovalStore) Get(id string) (*ApprovalRequest, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.requests[id]
+	return r, ok, nil
+}
+
+func (s *MemoryApprovalStore) Put(r *ApprovalRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.ID] = r
+	return nil
+}
+
+func (s *MemoryApprovalStore) List() ([]*ApprovalRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*ApprovalRequest, 0, len(s.requests))
+	for _, r := range s.requests {
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+type FileApprovalStore struct {
+	file *storage.FileStore
+}
+
+func NewFileApprovalStore(path string) (*FileApprovalStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileApprovalStore{file: file}, nil
+}
+
+func (s *FileApprovalStore) Get(id string) (*ApprovalRequest, bool, error) {
+	var r ApprovalRequest
+	ok, err := s.file.Get(id, &r)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &r, true, nil
+}
+
+func (s *FileApprovalStore) Put(r *ApprovalRequest) error {
+	return s.file.Put(r.ID, r)
+}
+
+func (s *FileApprovalStore) List() ([]*ApprovalRequest, error) {
+	keys := s.file.Keys()
+	result := make([]*ApprovalRequest, 0, len(keys))
+	for _, key := range keys {
+		r, ok, err := s.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,352 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newApprover(t *testing.T) signer.Signer {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestEvaluateEscalation(t *testing.T) {
+	approver := common.HexToAddress("0xA11CE00000000000000000000000000000000000")
+	escalation := &Escalation{
+		Thresholds:        []ApprovalThreshold{{Token: evalToken, Amount: big.NewInt(500)}},
+		NewContracts:      true,
+		OutsideTimeWindow: true,
+		Approvers:         []common.Address{approver},
+	}
+
+	tests := []struct {
+		name          string
+		tx            *Transaction
+		allowed       bool
+		needsApproval bool
+		wantReasons   []string
+	}{
+		{
+			"within threshold",
+			&Transaction{To: evalContract, Data: transferCalldata(), Token: evalToken, Amount: big.NewInt(400), Time: evalTime},
+			true,
+			false,
+			nil,
+		},
+		{
+			"above threshold",
+			&Transaction{To: evalContract, Data: transferCalldata(), Token: evalToken, Amount: big.NewInt(600), Time: evalTime},
+			false,
+			true,
+			[]string{"0x2222222222222222222222222222222222222222 amount 600 is above approval threshold 500"},
+		},
+		{
+			"new contract outside hours",
+			&Transaction{To: drainer, Data: transferCalldata(), Time: evalTime.Add(12 * time.Hour)},
+			false,
+			true,
+			[]string{"contract " + drainer.Hex() + " is not allowed", "outside allowed schedule at Wed 2026-03-04 22:30 UTC"},
+		},
+		{
+			"hard failure is not escalated",
+			&Transaction{To: drainer, Data: []byte{0xde, 0xad, 0xbe, 0xef}, Time: evalTime},
+			false,
+			false,
+			[]string{"contract " + drainer.Hex() + " is not allowed", "selector 0xdeadbeef is not allowed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := evalPolicy()
+			p.Escalation = escalation
+			d, err := EvaluateWithLimiter(context.Background(), p, tt.tx, NewRateLimiter())
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, d.Allowed)
+			assert.Equal(t, tt.needsApproval, d.NeedsApproval())
+
+			var reasons []string
+			for _, f := range d.Failures() {
+				reasons = append(reasons, f.Reason)
+			}
+			assert.Equal(t, tt.wantReasons, reasons)
+
+			err = d.Err()
+			assert.Equal(t, tt.needsApproval, errors.Is(err, ErrApprovalRequired))
+		})
+	}
+}
+
+func TestApprovalQueue(t *testing.T) {
+	ctx := context.Background()
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	approver := newApprover(t)
+	stranger := newApprover(t)
+	clock := &fakeClock{now: evalTime}
+
+	newQueue := func(t *testing.T) (*ApprovalQueue, *ApprovalRequest) {
+		q := NewApprovalQueue(WithApprovalClock(clock.Now))
+		p := &Policy{ID: "p1", Escalation: &Escalation{NewContracts: true, Approvers: []common.Address{approver.Address()}, TTL: time.Hour}}
+		d := &Decision{Results: []RuleResult{{Rule: RuleContractAllowlist, Reason: "contract is not allowed", Escalate: true}}}
+		r, err := q.submit(p, "agent-1", wallet, &Transaction{To: drainer, Value: ether(1)}, d)
+		require.NoError(t, err)
+		return q, r
+	}
+
+	t.Run("submit", func(t *testing.T) {
+		q, r := newQueue(t)
+		assert.Equal(t, ApprovalPending, r.Status)
+		assert.Equal(t, []string{"contract_allowlist: contract is not allowed"}, r.Reasons)
+		assert.Equal(t, evalTime.Add(time.Hour), r.ExpiresAt)
+		assert.Equal(t, ActionHash("p1", "agent-1", wallet, &Transaction{To: drainer, Value: ether(1)}), r.Action)
+
+		again, err := q.submit(&Policy{ID: "p1", Escalation: &Escalation{}}, "agent-1", wallet, &Transaction{To: drainer, Value: ether(1)}, &Decision{})
+		require.NoError(t, err)
+		assert.Equal(t, r.ID, again.ID)
+
+		pending, err := q.List(ApprovalPending)
+		require.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		q, r := newQueue(t)
+		decided, err := q.ApproveWithSigner(ctx, r.ID, approver)
+		require.NoError(t, err)
+		assert.Equal(t, ApprovalApproved, decided.Status)
+		assert.Equal(t, approver.Address(), decided.DecidedBy)
+		assert.Equal(t, evalTime, decided.DecidedAt)
+
+		_, err = q.DenyWithSigner(ctx, r.ID, approver)
+		assert.EqualError(t, err, "approval request already decided")
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		q, r := newQueue(t)
+		sig, err := SignApproval(ctx, approver, r, ApprovalDenied)
+		require.NoError(t, err)
+		decided, err := q.Deny(r.ID, approver.Address(), sig)
+		require.NoError(t, err)
+		assert.Equal(t, ApprovalDenied, decided.Status)
+	})
+
+	t.Run("rejected decisions", func(t *testing.T) {
+		q, r := newQueue(t)
+		_, err := q.ApproveWithSigner(ctx, r.ID, stranger)
+		assert.EqualError(t, err, "not an approver")
+
+		sig, err := SignApproval(ctx, approver, r, ApprovalDenied)
+		require.NoError(t, err)
+		_, err = q.Approve(r.ID, approver.Address(), sig)
+		assert.EqualError(t, err, "signature does not match signer")
+
+		_, err = q.Approve("missing", approver.Address(), sig)
+		assert.EqualError(t, err, "approval request not found")
+		_, err = q.ApproveWithSigner(ctx, r.ID, nil)
+		assert.EqualError(t, err, "nil signer")
+
+		got, err := q.Get(r.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ApprovalPending, got.Status)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		q, r := newQueue(t)
+		clock.Advance(time.Hour)
+		defer func() { clock.now = evalTime }()
+
+		got, err := q.Get(r.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ApprovalExpired, got.Status)
+		_, err = q.ApproveWithSigner(ctx, r.ID, approver)
+		assert.EqualError(t, err, "approval request expired")
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		q, r := newQueue(t)
+		done := make(chan *ApprovalRequest)
+		go func() {
+			got, err := q.Wait(ctx, r.ID)
+			assert.NoError(t, err)
+			done <- got
+		}()
+
+		_, err := q.ApproveWithSigner(ctx, r.ID, approver)
+		require.NoError(t, err)
+		select {
+		case got := <-done:
+			assert.Equal(t, ApprovalApproved, got.Status)
+		case <-time.After(time.Second):
+			t.Fatal("Wait did not return")
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		q, r = newQueue(t)
+		got, err := q.Wait(cancelled, r.ID)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, ApprovalPending, got.Status)
+	})
+}
+
+func TestEnforceApproval(t *testing.T) {
+	ctx := context.Background()
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	approver := newApprover(t)
+
+	svc := NewPolicyService()
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits:    []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(1000), Period: time.Hour}},
+		ContractAllowlist: NewContractAllowlist([]common.Address{router}),
+		Escalation: &Escalation{
+			Thresholds: []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(100)}},
+			Approvers:  []common.Address{approver.Address()},
+		},
+	})
+	require.NoError(t, err)
+	_, err = svc.AttachToWallet(created.ID, wallet)
+	require.NoError(t, err)
+	swap := func() *Transaction {
+		return &Transaction{To: router, Token: usdc, Amount: big.NewInt(300)}
+	}
+
+	d, err := svc.Enforce(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	require.NotNil(t, d.Approval)
+	var approvalErr *ApprovalError
+	require.ErrorAs(t, d.Err(), &approvalErr)
+	assert.Equal(t, "approval required (request "+d.Approval.ID+"): approval_threshold: 0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913 amount 300 is above approval threshold 100", d.Err().Error())
+
+	p, err := svc.GetPolicy(created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, p.SpendingLimits[0].Spent)
+
+	_, err = svc.Approvals().ApproveWithSigner(ctx, d.Approval.ID, approver)
+	require.NoError(t, err)
+
+	d, err = svc.Enforce(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.NoError(t, d.Err())
+	assert.Equal(t, ApprovalExecuted, d.Approval.Status)
+	assert.Contains(t, d.Results[len(d.Results)-1].Reason, "; approved by "+approver.Address().Hex())
+
+	p, err = svc.GetPolicy(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(300), p.SpendingLimits[0].Spent)
+
+	d, err = svc.Enforce(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, ApprovalPending, d.Approval.Status)
+
+	executed, err := svc.Approvals().List(ApprovalExecuted)
+	require.NoError(t, err)
+	assert.Len(t, executed, 1)
+}
+
+func TestEnforceReserveApproval(t *testing.T) {
+	ctx := context.Background()
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	approver := newApprover(t)
+
+	tracker, _ := newTestTracker()
+	svc := NewPolicyService(WithSpendingTracker(tracker))
+	escalated := func() *Policy {
+		return &Policy{
+			ContractAllowlist: NewContractAllowlist([]common.Address{router}),
+			Escalation: &Escalation{
+				Thresholds: []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(100)}},
+				Approvers:  []common.Address{approver.Address()},
+			},
+		}
+	}
+	created, err := svc.CreatePolicy(escalated())
+	require.NoError(t, err)
+	_, err = svc.AttachToWallet(created.ID, wallet)
+	require.NoError(t, err)
+	swap := func() *Transaction {
+		return &Transaction{To: router, Token: usdc, Amount: big.NewInt(300)}
+	}
+
+	d, _, err := svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	require.NotNil(t, d.Approval)
+	approved, err := svc.Approvals().ApproveWithSigner(ctx, d.Approval.ID, approver)
+	require.NoError(t, err)
+
+	d, first, err := svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, ApprovalInFlight, d.Approval.Status)
+
+	d, _, err = svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.False(t, d.Allowed, "one approval lets the action run once")
+	assert.NotEqual(t, approved.ID, d.Approval.ID)
+
+	require.NoError(t, svc.Release(first))
+	got, err := svc.Approvals().Get(approved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalApproved, got.Status)
+
+	d, again, err := svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	require.NoError(t, svc.Commit(again))
+	got, err = svc.Approvals().Get(approved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalExecuted, got.Status)
+
+	t.Run("approval is tied to the policy", func(t *testing.T) {
+		d, _, err := svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+		require.NoError(t, err)
+		_, err = svc.Approvals().ApproveWithSigner(ctx, d.Approval.ID, approver)
+		require.NoError(t, err)
+
+		replacement, err := svc.CreatePolicy(escalated())
+		require.NoError(t, err)
+		_, err = svc.AttachToWallet(replacement.ID, wallet)
+		require.NoError(t, err)
+
+		d, _, err = svc.EnforceReserve(ctx, "agent-1", wallet, swap())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, replacement.ID, d.Approval.PolicyID)
+	})
+}
+
+func TestFileApprovalStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approvals.json")
+	store, err := NewFileApprovalStore(path)
+	require.NoError(t, err)
+
+	q := NewApprovalQueue(WithApprovalStore(store))
+	d := &Decision{Results: []RuleResult{{Rule: RuleApprovalThreshold, Reason: "above threshold", Escalate: true}}}
+	r, err := q.submit(&Policy{ID: "p1", Escalation: &Escalation{Approvers: []common.Address{drainer}}}, "agent-1", common.Address{}, &Transaction{To: router, Value: big.NewInt(5)}, d)
+	require.NoError(t, err)
+
+	reopened, err := NewFileApprovalStore(path)
+	require.NoError(t, err)
+	got, ok, err := reopened.Get(r.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, r.Action, got.Action)
+	assert.Equal(t, big.NewInt(5), got.Value)
+	assert.Equal(t, []common.Address{drainer}, got.Approvers)
+	assert.Equal(t, ApprovalPending, got.Status)
+}
@@ -0,0 +1,338 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/storage"
+)
+
+var ErrNoPolicy = errors.New("no policy attached")
+
+type BindingKey struct {
+	Wallet  common.Address
+	AgentID string
+}
+
+func WalletKey(wallet common.Address) BindingKey {
+	return BindingKey{Wallet: wallet}
+}
+
+func AgentKey(agentID string) BindingKey {
+	return BindingKey{AgentID: agentID}
+}
+
+func (k BindingKey) String() string {
+	if k.AgentID != "" {
+		return "agent/" + k.AgentID
+	}
+	return "wallet/" + k.Wallet.Hex()
+}
+
+type Binding struct {
+	Key       BindingKey
+	PolicyID  string
+	CreatedAt time.Time
+}
+
+type BindingStore interface {
+	Get(key BindingKey) (*Binding, bool, error)
+	Put(b *Binding) error
+	Delete(key BindingKey) error
+	List() ([]*Binding, error)
+}
+
+func WithBindingStore(store BindingStore) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.bindings = store
+	}
+}
+
+func WithAllowUnbound() PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.allowUnbound = true
+	}
+}
+
+func (s *PolicyService) AttachToWallet(policyID string, wallet common.Address) (*Binding, error) {
+	if wallet == (common.Address{}) {
+		return nil, errors.New("wallet address required")
+	}
+	return s.attach(policyID, WalletKey(wallet))
+}
+
+func (s *PolicyService) AttachToAgent(policyID, agentID string) (*Binding, error) {
+	if agentID == "" {
+		return nil, errors.New("agent ID required")
+	}
+	return s.attach(policyID, AgentKey(agentID))
+}
+
+func (s *PolicyService) attach(policyID string, key BindingKey) (*Binding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok, err := s.store.Get(policyID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("policy not found")
+	}
+
+	b := &Binding{Key: key, PolicyID: policyID, CreatedAt: time.Now()}
+	if err := s.bindings.Put(b); err != nil {
+		return nil, err
+	}
+	copied := *b
+	return &copied, nil
+}
+
+func (s *PolicyService) Detach(key BindingKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok, err := s.bindings.Get(key); err != nil {
+		return err
+	} else if !ok {
+		return errors.New("binding not found")
+	}
+	return s.bindings.Delete(key)
+}
+
+func (s *PolicyService) Bindings(policyID string) ([]*Binding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listBindings(policyID)
+}
+
+func (s *PolicyService) listBindings(policyID string) ([]*Binding, error) {
+	all, err := s.bindings.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Binding
+	for _, b := range all {
+		if policyID == "" || b.PolicyID == policyID {
+			copied := *b
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key.String() < result[j].Key.String()
+	})
+	return result, nil
+}
+
+func (s *PolicyService) EffectivePolicy(agentID string, wallet common.Address) (*Policy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, err := s.effectivePolicy(agentID, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return clonePolicy(p), nil
+}
+
+func (s *PolicyService) effectivePolicy(agentID string, wallet common.Address) (*Policy, error) {
+	keys := []BindingKey{WalletKey(wallet)}
+	if agentID != "" {
+		keys = []BindingKey{AgentKey(agentID), WalletKey(wallet)}
+	}
+
+	for _, key := range keys {
+		b, ok, err := s.bindings.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		p, ok, err := s.store.Get(b.PolicyID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("attached policy not found")
+		}
+		return p, nil
+	}
+	return nil, ErrNoPolicy
+}
+
+func (s *PolicyService) Enforce(ctx context.Context, agentID string, wallet common.Address, tx *Transaction) (*Decision, error) {
+	if tx == nil {
+		return nil, errors.New("nil transaction")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.effectivePolicy(agentID, wallet)
+	if errors.Is(err, ErrNoPolicy) && s.allowUnbound {
+		return &Decision{Allowed: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tx.AgentID = agentID
+	d, err := s.evaluate(ctx, p, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.escalate(p, agentID, wallet, tx, d); err != nil {
+		return nil, err
+	}
+	if !d.Allowed {
+		return d, nil
+	}
+	if err := s.record(p, tx); err != nil {
+		return nil, err
+	}
+	if d.Approval != nil {
+		if err := s.approvals.execute(d.Approval.ID); err != nil {
+			return nil, err
+		}
+		d.Approval.Status = ApprovalExecuted
+	}
+	return d, nil
+}
+
+func (s *PolicyService) EnforceReserve(ctx context.Context, agentID string, wallet common.Address, tx *Transaction) (*Decision, *Reservation, error) {
+	if s.spending == nil {
+		return nil, nil, errors.New("spending tracker not configured")
+	}
+	if tx == nil {
+		return nil, nil, errors.New("nil transaction")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.effectivePolicy(agentID, wallet)
+	if errors.Is(err, ErrNoPolicy) && s.allowUnbound {
+		return &Decision{Allowed: true}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx.AgentID = agentID
+	d, err := s.evaluate(ctx, p, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.escalate(p, agentID, wallet, tx, d); err != nil {
+		return nil, nil, err
+	}
+	if !d.Allowed {
+		return d, nil, nil
+	}
+	r, err := s.reserve(p, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.Approval != nil {
+		if err := s.approvals.hold(d.Approval.ID); err != nil {
+			if err := s.release(r); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, err
+		}
+		r.approval = d.Approval.ID
+		d.Approval.Status = ApprovalInFlight
+	}
+	return d, r, nil
+}
+
+type MemoryBindingStore struct {
+	bindings map[BindingKey]*Binding
+	mu       sync.RWMutex
+}
+
+func NewMemoryBindingStore() *MemoryBindingStore {
+	return &MemoryBindingStore{
+		bindings: make(map[BindingKey]*Binding),
+	}
+}
+
+func (s *MemoryBindingStore) Get(key BindingKey) (*Binding, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.bindings[key]
+	return b, ok, nil
+}
+
+func (s *MemoryBindingStore) Put(b *Binding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bindings[b.Key] = b
+	return nil
+}
+
+func (s *MemoryBindingStore) Delete(key BindingKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bindings, key)
+	return nil
+}
+
+func (s *MemoryBindingStore) List() ([]*Binding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Binding, 0, len(s.bindings))
+	for _, b := range s.bindings {
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+type FileBindingStore struct {
+	file *storage.FileStore
+}
+
+func NewFileBindingStore(path string) (*FileBindingStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBindingStore{file: file}, nil
+}
+
+func (s *FileBindingStore) Get(key BindingKey) (*Binding, bool, error) {
+	var b Binding
+	ok, err := s.file.Get(key.String(), &b)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &b, true, nil
+}
+
+func (s *FileBindingStore) Put(b *Binding) error {
+	return s.file.Put(b.Key.String(), b)
+}
+
+func (s *FileBindingStore) Delete(key BindingKey) error {
+	return s.file.Delete(key.String())
+}
+
+func (s *FileBindingStore) List() ([]*Binding, error) {
+	keys := s.file.Keys()
+	result := make([]*Binding, 0, len(keys))
+	for _, key := range keys {
+		var b Binding
+		ok, err := s.file.Get(key, &b)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, &b)
+		}
+	}
+	return result, nil
+}
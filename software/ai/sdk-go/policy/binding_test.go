@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectivePolicy(t *testing.T) {
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	svc := NewPolicyService()
+	walletPolicy, err := svc.CreatePolicy(&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{usdc})})
+	require.NoError(t, err)
+	agentPolicy, err := svc.CreatePolicy(&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{router})})
+	require.NoError(t, err)
+
+	_, err = svc.EffectivePolicy("agent-1", wallet)
+	assert.ErrorIs(t, err, ErrNoPolicy)
+
+	_, err = svc.AttachToWallet(walletPolicy.ID, wallet)
+	require.NoError(t, err)
+	_, err = svc.AttachToAgent(agentPolicy.ID, "agent-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		agentID string
+		wallet  common.Address
+		want    string
+		wantErr error
+	}{
+		{"agent override", "agent-1", wallet, agentPolicy.ID, nil},
+		{"wallet default", "agent-2", wallet, walletPolicy.ID, nil},
+		{"wallet only", "", wallet, walletPolicy.ID, nil},
+		{"agent binding ignores wallet", "agent-1", other, agentPolicy.ID, nil},
+		{"nothing attached", "agent-2", other, "", ErrNoPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := svc.EffectivePolicy(tt.agentID, tt.wallet)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p.ID)
+		})
+	}
+
+	t.Run("detach falls back to wallet", func(t *testing.T) {
+		require.NoError(t, svc.Detach(AgentKey("agent-1")))
+		p, err := svc.EffectivePolicy("agent-1", wallet)
+		require.NoError(t, err)
+		assert.Equal(t, walletPolicy.ID, p.ID)
+		assert.EqualError(t, svc.Detach(AgentKey("agent-1")), "binding not found")
+	})
+}
+
+func TestAttach(t *testing.T) {
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	svc := NewPolicyService()
+	created, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := svc.AttachToWallet("missing", wallet)
+		assert.EqualError(t, err, "policy not found")
+		_, err = svc.AttachToWallet(created.ID, common.Address{})
+		assert.EqualError(t, err, "wallet address required")
+		_, err = svc.AttachToAgent(created.ID, "")
+		assert.EqualError(t, err, "agent ID required")
+	})
+
+	t.Run("rebinding replaces", func(t *testing.T) {
+		replacement, err := svc.CreatePolicy(&Policy{Priority: 1})
+		require.NoError(t, err)
+		_, err = svc.AttachToWallet(created.ID, wallet)
+		require.NoError(t, err)
+		_, err = svc.AttachToWallet(replacement.ID, wallet)
+		require.NoError(t, err)
+
+		p, err := svc.EffectivePolicy("", wallet)
+		require.NoError(t, err)
+		assert.Equal(t, replacement.ID, p.ID)
+
+		bindings, err := svc.Bindings(created.ID)
+		require.NoError(t, err)
+		assert.Empty(t, bindings)
+	})
+
+	t.Run("attached policy cannot be deleted", func(t *testing.T) {
+		bound, err := svc.CreatePolicy(&Policy{})
+		require.NoError(t, err)
+		_, err = svc.AttachToAgent(bound.ID, "agent-1")
+		require.NoError(t, err)
+
+		assert.EqualError(t, svc.DeletePolicy(bound.ID), "policy is attached to a wallet or agent")
+		require.NoError(t, svc.Detach(AgentKey("agent-1")))
+		assert.NoError(t, svc.DeletePolicy(bound.ID))
+	})
+}
+
+func TestEnforce(t *testing.T) {
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ctx := context.Background()
+
+	svc := NewPolicyService()
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits:    []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: time.Hour}},
+		ContractAllowlist: NewContractAllowlist([]common.Address{router}),
+	})
+	require.NoError(t, err)
+
+	t.Run("nothing attached is denied", func(t *testing.T) {
+		_, err := svc.Enforce(ctx, "agent-1", wallet, &Transaction{To: drainer})
+		assert.ErrorIs(t, err, ErrNoPolicy)
+	})
+
+	t.Run("nothing attached is allowed when opted in", func(t *testing.T) {
+		d, err := NewPolicyService(WithAllowUnbound()).Enforce(ctx, "agent-1", wallet, &Transaction{To: drainer})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Empty(t, d.PolicyID)
+	})
+
+	_, err = svc.AttachToWallet(created.ID, wallet)
+	require.NoError(t, err)
+
+	t.Run("allowed actions are recorded", func(t *testing.T) {
+		tx := &Transaction{To: router, Token: usdc, Amount: big.NewInt(60)}
+		d, err := svc.Enforce(ctx, "agent-1", wallet, tx)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, created.ID, d.PolicyID)
+		assert.Equal(t, "agent-1", tx.AgentID)
+
+		d, err = svc.Enforce(ctx, "agent-1", wallet, &Transaction{To: router, Token: usdc, Amount: big.NewInt(60)})
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, RuleSpendingLimit, d.Failures()[0].Rule)
+	})
+
+	t.Run("denied actions are not recorded", func(t *testing.T) {
+		d, err := svc.Enforce(ctx, "agent-1", wallet, &Transaction{To: drainer, Token: usdc, Amount: big.NewInt(10)})
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+
+		p, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(60), p.SpendingLimits[0].Spent)
+	})
+
+	t.Run("nil transaction", func(t *testing.T) {
+		_, err := svc.Enforce(ctx, "agent-1", wallet, nil)
+		assert.EqualError(t, err, "nil transaction")
+	})
+}
+
+func TestEnforceReserve(t *testing.T) {
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ctx := context.Background()
+
+	tracker, _ := newTestTracker()
+	svc := NewPolicyService(WithSpendingTracker(tracker))
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: time.Hour}},
+		RateLimit:      &RateLimit{MaxCalls: 1, Period: time.Hour},
+	})
+	require.NoError(t, err)
+	spend := func(amount int64) *Transaction {
+		return &Transaction{To: router, Token: usdc, Amount: big.NewInt(amount)}
+	}
+
+	_, _, err = svc.EnforceReserve(ctx, "agent-1", wallet, spend(60))
+	assert.ErrorIs(t, err, ErrNoPolicy)
+
+	d, r, err := NewPolicyService(WithSpendingTracker(tracker), WithAllowUnbound()).EnforceReserve(ctx, "agent-1", wallet, spend(60))
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Nil(t, r)
+
+	_, err = svc.AttachToWallet(created.ID, wallet)
+	require.NoError(t, err)
+
+	t.Run("release returns the spend and the call", func(t *testing.T) {
+		d, r, err := svc.EnforceReserve(ctx, "agent-1", wallet, spend(60))
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		require.NotNil(t, r)
+
+		d, _, err = svc.EnforceReserve(ctx, "agent-1", wallet, spend(10))
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, RuleRateLimit, d.Failures()[0].Rule)
+
+		require.NoError(t, svc.Release(r))
+		d, err = svc.Evaluate(ctx, created.ID, &Transaction{To: router, Token: usdc, Amount: big.NewInt(100), AgentID: "agent-1"})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	})
+
+	t.Run("commit records the spend", func(t *testing.T) {
+		_, r, err := svc.EnforceReserve(ctx, "agent-1", wallet, spend(60))
+		require.NoError(t, err)
+		require.NoError(t, svc.Commit(r))
+
+		status, err := tracker.Status(created.ID, &created.SpendingLimits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(60), status.Spent)
+	})
+
+	t.Run("requires a tracker", func(t *testing.T) {
+		_, _, err := NewPolicyService().EnforceReserve(ctx, "agent-1", wallet, spend(1))
+		assert.EqualError(t, err, "spending tracker not configured")
+	})
+}
+
+func TestFileBindingStore(t *testing.T) {
+	wallet := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	path := filepath.Join(t.TempDir(), "bindings.json")
+	store, err := NewFileBindingStore(path)
+	require.NoError(t, err)
+
+	svc := NewPolicyService(WithBindingStore(store))
+	created, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+	_, err = svc.AttachToWallet(created.ID, wallet)
+	require.NoError(t, err)
+	_, err = svc.AttachToAgent(created.ID, "agent-1")
+	require.NoError(t, err)
+
+	reopened, err := NewFileBindingStore(path)
+	require.NoError(t, err)
+	bindings, err := reopened.List()
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+
+	b, ok, err := reopened.Get(WalletKey(wallet))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, created.ID, b.PolicyID)
+	assert.Equal(t, wallet, b.Key.Wallet)
+
+	require.NoError(t, reopened.Delete(AgentKey("agent-1")))
+	_, ok, err = reopened.Get(AgentKey("agent-1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
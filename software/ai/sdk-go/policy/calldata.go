@@ -0,0 +1,253 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+type ArgOp string
+
+const (
+	ArgEqual    ArgOp = "eq"
+	ArgNotEqual ArgOp = "ne"
+	ArgMin      ArgOp = "gte"
+	ArgMax      ArgOp = "lte"
+	ArgBetween  ArgOp = "between"
+	ArgIn       ArgOp = "in"
+	ArgNotIn    ArgOp = "not_in"
+)
+
+func CheckCalldata(c *CalldataConstraint, data []byte) error {
+	if c == nil {
+		return errors.New("nil calldata constraint")
+	}
+	if err := validateCalldataConstraint(c); err != nil {
+		return err
+	}
+
+	args, err := encoding.ParseFunctionArgs(c.Signature)
+	if err != nil {
+		return errors.New("invalid function signature")
+	}
+	values, err := encoding.DecodeFunctionCall(c.Signature, data)
+	if err != nil || len(values) != len(args) {
+		return errors.New("could not decode calldata")
+	}
+
+	for _, ac := range c.Args {
+		if err := checkArg(args[ac.Index].Type, values[ac.Index], ac); err != nil {
+			return fmt.Errorf("argument %d: %s", ac.Index, err)
+		}
+	}
+	return nil
+}
+
+func (c *CalldataConstraint) Matches(to common.Address, data []byte) bool {
+	if c.Contract != (common.Address{}) && c.Contract != to {
+		return false
+	}
+	return len(data) >= 4 && bytes.Equal(data[:4], crypto.Keccak256([]byte(c.Signature))[:4])
+}
+
+func validateCalldataConstraint(c *CalldataConstraint) error {
+	open := strings.IndexByte(c.Signature, '(')
+	if open <= 0 || !strings.HasSuffix(c.Signature, ")") {
+		return errors.New("invalid function signature")
+	}
+	args, err := encoding.ParseFunctionArgs(c.Signature)
+	if err != nil {
+		return errors.New("invalid function signature")
+	}
+
+	for _, ac := range c.Args {
+		if ac.Index < 0 || ac.Index >= len(args) {
+			return errors.New("argument index out of range")
+		}
+
+		typ := args[ac.Index].Type
+		for typ.T == abi.SliceTy || typ.T == abi.ArrayTy {
+			typ = *typ.Elem
+		}
+
+		switch ac.Op {
+		case ArgEqual, ArgNotEqual, ArgMin, ArgMax:
+			if len(ac.Values) != 1 {
+				return errors.New("invalid constraint values")
+			}
+		case ArgBetween:
+			if len(ac.Values) != 2 {
+				return errors.New("invalid constraint values")
+			}
+		case ArgIn, ArgNotIn:
+			if len(ac.Values) == 0 {
+				return errors.New("invalid constraint values")
+			}
+		default:
+			return errors.New("unsupported constraint operator")
+		}
+
+		switch typ.T {
+		case abi.IntTy, abi.UintTy:
+		case abi.AddressTy, abi.BoolTy, abi.StringTy, abi.BytesTy, abi.FixedBytesTy:
+			if ac.Op == ArgMin || ac.Op == ArgMax || ac.Op == ArgBetween {
+				return errors.New("operator not supported for argument type")
+			}
+		default:
+			return errors.New("unsupported argument type")
+		}
+
+		for _, v := range ac.Values {
+			if _, err := parseArgValue(typ, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkArg(typ abi.Type, value interface{}, ac ArgConstraint) error {
+	if typ.T == abi.SliceTy || typ.T == abi.ArrayTy {
+		v := reflect.ValueOf(value)
+		for i := 0; i < v.Len(); i++ {
+			if err := checkArg(*typ.Elem, v.Index(i).Interface(), ac); err != nil {
+				return fmt.Errorf("element %d: %s", i, err)
+			}
+		}
+		return nil
+	}
+
+	actual, err := argValue(typ, value)
+	if err != nil {
+		return err
+	}
+	expected := make([]interface{}, len(ac.Values))
+	for i, s := range ac.Values {
+		if expected[i], err = parseArgValue(typ, s); err != nil {
+			return err
+		}
+	}
+
+	if !argMatches(ac.Op, actual, expected) {
+		return fmt.Errorf("%v does not satisfy %s %s", actual, ac.Op, strings.Join(ac.Values, ", "))
+	}
+	return nil
+}
+
+func argMatches(op ArgOp, actual interface{}, expected []interface{}) bool {
+	switch op {
+	case ArgEqual:
+		return argEqual(actual, expected[0])
+	case ArgNotEqual:
+		return !argEqual(actual, expected[0])
+	case ArgMin:
+		return argCompare(actual, expected[0]) >= 0
+	case ArgMax:
+		return argCompare(actual, expected[0]) <= 0
+	case ArgBetween:
+		return argCompare(actual, expected[0]) >= 0 && argCompare(actual, expected[1]) <= 0
+	case ArgIn, ArgNotIn:
+		found := false
+		for _, e := range expected {
+			if argEqual(actual, e) {
+				found = true
+				break
+			}
+		}
+		return found == (op == ArgIn)
+	}
+	return false
+}
+
+func argEqual(a, b interface{}) bool {
+	if x, ok := a.(*big.Int); ok {
+		return x.Cmp(b.(*big.Int)) == 0
+	}
+	return a == b
+}
+
+func argCompare(a, b interface{}) int {
+	return a.(*big.Int).Cmp(b.(*big.Int))
+}
+
+func argValue(typ abi.Type, value interface{}) (interface{}, error) {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if n, ok := value.(*big.Int); ok {
+			return n, nil
+		}
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return big.NewInt(v.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return new(big.Int).SetUint64(v.Uint()), nil
+		}
+	case abi.AddressTy:
+		if addr, ok := value.(common.Address); ok {
+			return addr.Hex(), nil
+		}
+	case abi.BoolTy:
+		if b, ok := value.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+	case abi.StringTy:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case abi.BytesTy:
+		if b, ok := value.([]byte); ok {
+			return hexutil.Encode(b), nil
+		}
+	case abi.FixedBytesTy:
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Array {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b), nil
+		}
+	}
+	return nil, errors.New("unsupported argument type")
+}
+
+func parseArgValue(typ abi.Type, s string) (interface{}, error) {
+	invalid := errors.New("invalid constraint values")
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok || (typ.T == abi.UintTy && n.Sign() < 0) {
+			return nil, invalid
+		}
+		return n, nil
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, invalid
+		}
+		return common.HexToAddress(s).Hex(), nil
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, invalid
+		}
+		return strconv.FormatBool(b), nil
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy, abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil || (typ.T == abi.FixedBytesTy && len(b) != typ.Size) {
+			return nil, invalid
+		}
+		return hexutil.Encode(b), nil
+	}
+	return nil, errors.New("unsupported argument type")
+}
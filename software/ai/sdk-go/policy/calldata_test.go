@@ -0,0 +1,248 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	transferSig = "transfer(address,uint256)"
+	swapSig     = "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)"
+)
+
+var (
+	payee   = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	weth    = common.HexToAddress("0x4200000000000000000000000000000000000006")
+	usdc    = common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	degen   = common.HexToAddress("0x4ed4E862860beD51a9570b96d89aF5E1B0Efefed")
+	agentSA = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+)
+
+func mustCalldata(t *testing.T, sig string, args ...interface{}) []byte {
+	t.Helper()
+	data, err := encoding.EncodeFunctionCall(sig, args...)
+	require.NoError(t, err)
+	return data
+}
+
+func TestCheckCalldata(t *testing.T) {
+	transfer := func(to common.Address, amount int64) []byte {
+		return mustCalldata(t, transferSig, to, big.NewInt(amount))
+	}
+	swap := func(path []common.Address, recipient common.Address) []byte {
+		return mustCalldata(t, swapSig, big.NewInt(1000), big.NewInt(990), path, recipient, big.NewInt(1700000000))
+	}
+
+	tests := []struct {
+		name    string
+		c       CalldataConstraint
+		data    []byte
+		wantErr string
+	}{
+		{
+			name: "address allowlist",
+			c:    CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgIn, Values: []string{payee.Hex()}}}},
+			data: transfer(payee, 1),
+		},
+		{
+			name:    "address not in allowlist",
+			c:       CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgIn, Values: []string{payee.Hex()}}}},
+			data:    transfer(agentSA, 1),
+			wantErr: "argument 0: " + agentSA.Hex() + " does not satisfy in " + payee.Hex(),
+		},
+		{
+			name: "address case insensitive",
+			c:    CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgEqual, Values: []string{"0xAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}}}},
+			data: transfer(payee, 1),
+		},
+		{
+			name: "amount maximum",
+			c:    CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"1000000"}}}},
+			data: transfer(payee, 1000000),
+		},
+		{
+			name:    "amount above maximum",
+			c:       CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"1000000"}}}},
+			data:    transfer(payee, 1000001),
+			wantErr: "argument 1: 1000001 does not satisfy lte 1000000",
+		},
+		{
+			name: "amount range hex bounds",
+			c:    CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgBetween, Values: []string{"0x10", "0x20"}}}},
+			data: transfer(payee, 16),
+		},
+		{
+			name:    "amount below range",
+			c:       CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgBetween, Values: []string{"0x10", "0x20"}}}},
+			data:    transfer(payee, 15),
+			wantErr: "argument 1: 15 does not satisfy between 0x10, 0x20",
+		},
+		{
+			name:    "not equal",
+			c:       CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgNotEqual, Values: []string{"0"}}}},
+			data:    transfer(payee, 0),
+			wantErr: "argument 1: 0 does not satisfy ne 0",
+		},
+		{
+			name: "swap path elements",
+			c: CalldataConstraint{Signature: swapSig, Args: []ArgConstraint{
+				{Index: 2, Op: ArgIn, Values: []string{weth.Hex(), usdc.Hex()}},
+				{Index: 3, Op: ArgEqual, Values: []string{agentSA.Hex()}},
+			}},
+			data: swap([]common.Address{weth, usdc}, agentSA),
+		},
+		{
+			name: "swap path element rejected",
+			c: CalldataConstraint{Signature: swapSig, Args: []ArgConstraint{
+				{Index: 2, Op: ArgIn, Values: []string{weth.Hex(), usdc.Hex()}},
+			}},
+			data:    swap([]common.Address{weth, degen}, agentSA),
+			wantErr: "argument 2: element 1: " + degen.Hex() + " does not satisfy in " + weth.Hex() + ", " + usdc.Hex(),
+		},
+		{
+			name: "swap recipient not in deny list",
+			c: CalldataConstraint{Signature: swapSig, Args: []ArgConstraint{
+				{Index: 3, Op: ArgNotIn, Values: []string{payee.Hex()}},
+			}},
+			data:    swap([]common.Address{weth, usdc}, payee),
+			wantErr: "argument 3: " + payee.Hex() + " does not satisfy not_in " + payee.Hex(),
+		},
+		{
+			name: "bool and bytes",
+			c: CalldataConstraint{Signature: "setFlag(bool,bytes4,string)", Args: []ArgConstraint{
+				{Index: 0, Op: ArgEqual, Values: []string{"true"}},
+				{Index: 1, Op: ArgEqual, Values: []string{"0xA9059CBB"}},
+				{Index: 2, Op: ArgIn, Values: []string{"a", "b"}},
+			}},
+			data: mustCalldata(t, "setFlag(bool,bytes4,string)", true, [4]byte{0xa9, 0x05, 0x9c, 0xbb}, "b"),
+		},
+		{
+			name: "small int types",
+			c:    CalldataConstraint{Signature: "setFee(uint24,int8)", Args: []ArgConstraint{{Index: 0, Op: ArgMax, Values: []string{"3000"}}, {Index: 1, Op: ArgMin, Values: []string{"-5"}}}},
+			data: mustCalldata(t, "setFee(uint24,int8)", big.NewInt(3000), int8(-5)),
+		},
+		{
+			name:    "selector mismatch",
+			c:       CalldataConstraint{Signature: "approve(address,uint256)"},
+			data:    transfer(payee, 1),
+			wantErr: "could not decode calldata",
+		},
+		{
+			name:    "truncated calldata",
+			c:       CalldataConstraint{Signature: transferSig},
+			data:    transfer(payee, 1)[:20],
+			wantErr: "could not decode calldata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			err := CheckCalldata(&c, tt.data)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("nil constraint", func(t *testing.T) {
+		assert.EqualError(t, CheckCalldata(nil, nil), "nil calldata constraint")
+	})
+}
+
+func TestValidateCalldataConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       CalldataConstraint
+		wantErr string
+	}{
+		{"valid", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"1"}}}}, ""},
+		{"missing parens", CalldataConstraint{Signature: "transfer"}, "invalid function signature"},
+		{"missing name", CalldataConstraint{Signature: "(address)"}, "invalid function signature"},
+		{"bad type", CalldataConstraint{Signature: "transfer(adress,uint256)"}, "invalid function signature"},
+		{"index out of range", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 2, Op: ArgEqual, Values: []string{"1"}}}}, "argument index out of range"},
+		{"negative index", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: -1, Op: ArgEqual, Values: []string{"1"}}}}, "argument index out of range"},
+		{"unknown operator", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: "regex", Values: []string{"1"}}}}, "unsupported constraint operator"},
+		{"between needs two values", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgBetween, Values: []string{"1"}}}}, "invalid constraint values"},
+		{"in needs values", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgIn}}}, "invalid constraint values"},
+		{"ordering on address", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgMax, Values: []string{payee.Hex()}}}}, "operator not supported for argument type"},
+		{"bad address", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 0, Op: ArgEqual, Values: []string{"0x1234"}}}}, "invalid constraint values"},
+		{"bad number", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgEqual, Values: []string{"ten"}}}}, "invalid constraint values"},
+		{"negative uint", CalldataConstraint{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMin, Values: []string{"-1"}}}}, "invalid constraint values"},
+		{"wrong fixed bytes size", CalldataConstraint{Signature: "f(bytes4)", Args: []ArgConstraint{{Index: 0, Op: ArgEqual, Values: []string{"0x01"}}}}, "invalid constraint values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			err := validateCalldataConstraint(&c)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEvaluateCalldataConstraints(t *testing.T) {
+	p := &Policy{
+		FunctionAllowlist: NewFunctionAllowlist([]string{transferSig, "approve(address,uint256)"}),
+		CalldataConstraints: []CalldataConstraint{
+			{Contract: usdc, Signature: transferSig, Args: []ArgConstraint{
+				{Index: 0, Op: ArgIn, Values: []string{payee.Hex()}},
+				{Index: 1, Op: ArgMax, Values: []string{"5000000"}},
+			}},
+		},
+	}
+
+	t.Run("passes", func(t *testing.T) {
+		d, err := Evaluate(context.Background(), p, &Transaction{To: usdc, Data: mustCalldata(t, transferSig, payee, big.NewInt(5000000))})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		require.Len(t, d.Results, 2)
+		assert.Equal(t, RuleCalldata, d.Results[1].Rule)
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		d, err := Evaluate(context.Background(), p, &Transaction{To: usdc, Data: mustCalldata(t, transferSig, payee, big.NewInt(5000001))})
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, "transfer(address,uint256): argument 1: 5000001 does not satisfy lte 5000000", d.Failures()[0].Reason)
+	})
+
+	t.Run("other contract not constrained", func(t *testing.T) {
+		d, err := Evaluate(context.Background(), p, &Transaction{To: weth, Data: mustCalldata(t, transferSig, agentSA, big.NewInt(1))})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Len(t, d.Results, 1)
+	})
+
+	t.Run("other function not constrained", func(t *testing.T) {
+		d, err := Evaluate(context.Background(), p, &Transaction{To: usdc, Data: mustCalldata(t, "approve(address,uint256)", agentSA, big.NewInt(1))})
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Len(t, d.Results, 1)
+	})
+
+	t.Run("composed constraints are additive", func(t *testing.T) {
+		extra := &Policy{CalldataConstraints: []CalldataConstraint{
+			{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"100"}}}},
+		}}
+		composed := ComposePolicy(p, extra)
+		require.Len(t, composed.CalldataConstraints, 2)
+
+		d, err := Evaluate(context.Background(), composed, &Transaction{To: usdc, Data: mustCalldata(t, transferSig, payee, big.NewInt(101))})
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Len(t, d.Failures(), 1)
+	})
+}
@@ -0,0 +1,496 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/oracle"
+	"gopkg.in/yaml.v3"
+)
+
+const PolicyFormatVersion = 1
+
+type Token struct {
+	Symbol   string
+	Address  common.Address
+	Decimals uint8
+}
+
+type Codec struct {
+	tokens []Token
+}
+
+type CodecOption func(*Codec)
+
+func WithTokens(tokens ...Token) CodecOption {
+	return func(c *Codec) {
+		c.tokens = append(c.tokens, tokens...)
+	}
+}
+
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type policyDocument struct {
+	Version             int                     `json:"version" yaml:"version"`
+	ID                  string                  `json:"id,omitempty" yaml:"id,omitempty"`
+	Priority            int                     `json:"priority,omitempty" yaml:"priority,omitempty"`
+	CreatedAt           string                  `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	SpendingLimits      []spendingLimitDocument `json:"spendingLimits,omitempty" yaml:"spendingLimits,omitempty"`
+	MaxValuePerTx       string                  `json:"maxValuePerTx,omitempty" yaml:"maxValuePerTx,omitempty"`
+	Contracts           *[]string               `json:"contracts,omitempty" yaml:"contracts,omitempty"`
+	Functions           *[]string               `json:"functions,omitempty" yaml:"functions,omitempty"`
+	Permissions         *[]permissionDocument   `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Deny                *denyDocument           `json:"deny,omitempty" yaml:"deny,omitempty"`
+	CalldataConstraints []calldataDocument      `json:"calldataConstraints,omitempty" yaml:"calldataConstraints,omitempty"`
+	TimeWindow          *timeWindowDocument     `json:"timeWindow,omitempty" yaml:"timeWindow,omitempty"`
+	RateLimit           *rateLimitDocument      `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	Escalation          *escalationDocument     `json:"escalation,omitempty" yaml:"escalation,omitempty"`
+}
+
+type spendingLimitDocument struct {
+	Token  string `json:"token,omitempty" yaml:"token,omitempty"`
+	Quote  string `json:"quote,omitempty" yaml:"quote,omitempty"`
+	Max    string `json:"max" yaml:"max"`
+	Period string `json:"period" yaml:"period"`
+	Window string `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+type permissionDocument struct {
+	Contract string `json:"contract" yaml:"contract"`
+	Function string `json:"function" yaml:"function"`
+}
+
+type denyDocument struct {
+	Contracts []string             `json:"contracts,omitempty" yaml:"contracts,omitempty"`
+	Functions []string             `json:"functions,omitempty" yaml:"functions,omitempty"`
+	Calls     []permissionDocument `json:"calls,omitempty" yaml:"calls,omitempty"`
+	Payees    []string             `json:"payees,omitempty" yaml:"payees,omitempty"`
+	Tokens    []string             `json:"tokens,omitempty" yaml:"tokens,omitempty"`
+}
+
+type calldataDocument struct {
+	Contract  string        `json:"contract,omitempty" yaml:"contract,omitempty"`
+	Signature string        `json:"signature" yaml:"signature"`
+	Args      []argDocument `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+type argDocument struct {
+	Index  int      `json:"index" yaml:"index"`
+	Op     string   `json:"op" yaml:"op"`
+	Values []string `json:"values" yaml:"values,flow"`
+}
+
+type timeWindowDocument struct {
+	Start     string                 `json:"start,omitempty" yaml:"start,omitempty"`
+	End       string                 `json:"end,omitempty" yaml:"end,omitempty"`
+	Days      []string               `json:"days,omitempty" yaml:"days,omitempty,flow"`
+	Hours     *[2]int                `json:"hours,omitempty" yaml:"hours,omitempty,flow"`
+	Location  string                 `json:"location,omitempty" yaml:"location,omitempty"`
+	Windows   []weeklyWindowDocument `json:"windows,omitempty" yaml:"windows,omitempty"`
+	Blackouts []blackoutDocument     `json:"blackouts,omitempty" yaml:"blackouts,omitempty"`
+	Intersect []timeWindowDocument   `json:"intersect,omitempty" yaml:"intersect,omitempty"`
+}
+
+type weeklyWindowDocument struct {
+	Days  []string `json:"days,omitempty" yaml:"days,omitempty,flow"`
+	Start string   `json:"start" yaml:"start"`
+	End   string   `json:"end" yaml:"end"`
+}
+
+type blackoutDocument struct {
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
+type rateLimitDocument struct {
+	MaxCalls uint64 `json:"maxCalls" yaml:"maxCalls"`
+	Period   string `json:"period" yaml:"period"`
+	Mode     string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Calls    uint64 `json:"calls,omitempty" yaml:"calls,omitempty"`
+	ResetAt  string `json:"resetAt,omitempty" yaml:"resetAt,omitempty"`
+}
+
+type escalationDocument struct {
+	Thresholds        []thresholdDocument `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+	NewContracts      bool                `json:"newContracts,omitempty" yaml:"newContracts,omitempty"`
+	OutsideTimeWindow bool                `json:"outsideTimeWindow,omitempty" yaml:"outsideTimeWindow,omitempty"`
+	Approvers         []string            `json:"approvers" yaml:"approvers"`
+	TTL               string              `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+type thresholdDocument struct {
+	Token  string `json:"token,omitempty" yaml:"token,omitempty"`
+	Quote  string `json:"quote,omitempty" yaml:"quote,omitempty"`
+	Amount string `json:"amount" yaml:"amount"`
+}
+
+func (c *Codec) EncodeJSON(p *Policy) ([]byte, error) {
+	doc, err := c.document(p)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (c *Codec) EncodeYAML(p *Policy) ([]byte, error) {
+	doc, err := c.document(p)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Codec) document(p *Policy) (*policyDocument, error) {
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+
+	doc := &policyDocument{Version: PolicyFormatVersion, ID: p.ID, Priority: p.Priority}
+	if !p.CreatedAt.IsZero() {
+		doc.CreatedAt = p.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	for _, sl := range p.SpendingLimits {
+		if sl.Quote != "" {
+			doc.SpendingLimits = append(doc.SpendingLimits, spendingLimitDocument{
+				Quote:  sl.Quote,
+				Max:    formatUnits(quoteUnit(sl.Quote), sl.MaxAmount),
+				Period: formatDuration(sl.Period),
+				Window: string(sl.Window),
+			})
+			continue
+		}
+		doc.SpendingLimits = append(doc.SpendingLimits, spendingLimitDocument{
+			Token:  c.formatToken(sl.Token),
+			Max:    c.formatAmount(sl.Token, sl.MaxAmount),
+			Period: formatDuration(sl.Period),
+			Window: string(sl.Window),
+		})
+	}
+
+	if p.MaxValuePerTx != nil {
+		doc.MaxValuePerTx = c.formatAmount(common.Address{}, p.MaxValuePerTx)
+	}
+
+	if p.ContractAllowlist != nil {
+		contracts := make([]string, 0, len(p.ContractAllowlist.Contracts))
+		for addr, allowed := range p.ContractAllowlist.Contracts {
+			if allowed {
+				contracts = append(contracts, addr.Hex())
+			}
+		}
+		sort.Strings(contracts)
+		doc.Contracts = &contracts
+	}
+
+	if p.FunctionAllowlist != nil {
+		functions := make([]string, 0, len(p.FunctionAllowlist.Functions))
+		for selector, allowed := range p.FunctionAllowlist.Functions {
+			if allowed {
+				functions = append(functions, "0x"+normalizeSelector(selector))
+			}
+		}
+		sort.Strings(functions)
+		doc.Functions = &functions
+	}
+
+	if p.Permissions != nil {
+		perms := make([]permissionDocument, 0, len(p.Permissions.Permissions))
+		for _, perm := range p.Permissions.Permissions {
+			perms = append(perms, formatPermission(perm))
+		}
+		doc.Permissions = &perms
+	}
+
+	if d := p.Deny; d != nil {
+		deny := &denyDocument{}
+		for _, addr := range d.Contracts {
+			deny.Contracts = append(deny.Contracts, addr.Hex())
+		}
+		for _, s := range d.Selectors {
+			deny.Functions = append(deny.Functions, "0x"+normalizeSelector(s))
+		}
+		for _, perm := range d.Calls {
+			deny.Calls = append(deny.Calls, formatPermission(perm))
+		}
+		for _, addr := range d.Payees {
+			deny.Payees = append(deny.Payees, addr.Hex())
+		}
+		for _, addr := range d.Tokens {
+			deny.Tokens = append(deny.Tokens, c.formatToken(addr))
+		}
+		doc.Deny = deny
+	}
+
+	for _, cc := range p.CalldataConstraints {
+		cd := calldataDocument{Signature: cc.Signature}
+		if cc.Contract != AnyContract {
+			cd.Contract = cc.Contract.Hex()
+		}
+		for _, ac := range cc.Args {
+			cd.Args = append(cd.Args, argDocument{Index: ac.Index, Op: string(ac.Op), Values: ac.Values})
+		}
+		doc.CalldataConstraints = append(doc.CalldataConstraints, cd)
+	}
+
+	if p.TimeWindow != nil {
+		tw, err := formatTimeWindow(p.TimeWindow)
+		if err != nil {
+			return nil, err
+		}
+		doc.TimeWindow = tw
+	}
+
+	if rl := p.RateLimit; rl != nil {
+		doc.RateLimit = &rateLimitDocument{MaxCalls: rl.MaxCalls, Period: formatDuration(rl.Period), Mode: string(rl.Mode)}
+	}
+
+	if e := p.Escalation; e != nil {
+		ed := &escalationDocument{NewContracts: e.NewContracts, OutsideTimeWindow: e.OutsideTimeWindow, Approvers: []string{}}
+		for _, th := range e.Thresholds {
+			if th.Quote != "" {
+				ed.Thresholds = append(ed.Thresholds, thresholdDocument{Quote: th.Quote, Amount: formatUnits(quoteUnit(th.Quote), th.Amount)})
+				continue
+			}
+			ed.Thresholds = append(ed.Thresholds, thresholdDocument{Token: c.formatToken(th.Token), Amount: c.formatAmount(th.Token, th.Amount)})
+		}
+		for _, a := range e.Approvers {
+			ed.Approvers = append(ed.Approvers, a.Hex())
+		}
+		if e.TTL > 0 {
+			ed.TTL = formatDuration(e.TTL)
+		}
+		doc.Escalation = ed
+	}
+
+	return doc, nil
+}
+
+func formatPermission(perm Permission) permissionDocument {
+	doc := permissionDocument{Contract: AnySelector, Function: AnySelector}
+	if perm.Contract != AnyContract {
+		doc.Contract = perm.Contract.Hex()
+	}
+	if s := normalizeSelector(perm.Selector); s != AnySelector {
+		doc.Function = "0x" + s
+	}
+	return doc
+}
+
+func formatTimeWindow(tw *TimeWindow) (*timeWindowDocument, error) {
+	doc := &timeWindowDocument{Location: tw.Location, Days: formatWeekdays(tw.Days)}
+	if !tw.Start.IsZero() {
+		doc.Start = tw.Start.Format(time.RFC3339Nano)
+	}
+	if !tw.End.IsZero() {
+		doc.End = tw.End.Format(time.RFC3339Nano)
+	}
+	if tw.Hours != [2]int{} {
+		hours := tw.Hours
+		doc.Hours = &hours
+	}
+
+	for _, w := range tw.Windows {
+		start, err := formatClock(w.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := formatClock(w.End)
+		if err != nil {
+			return nil, err
+		}
+		doc.Windows = append(doc.Windows, weeklyWindowDocument{Days: formatWeekdays(w.Days), Start: start, End: end})
+	}
+
+	for _, b := range tw.Blackouts {
+		doc.Blackouts = append(doc.Blackouts, blackoutDocument{
+			Start: b.Start.Format(time.RFC3339Nano),
+			End:   b.End.Format(time.RFC3339Nano),
+		})
+	}
+
+	for i := range tw.Intersect {
+		inner, err := formatTimeWindow(&tw.Intersect[i])
+		if err != nil {
+			return nil, err
+		}
+		doc.Intersect = append(doc.Intersect, *inner)
+	}
+	return doc, nil
+}
+
+func formatWeekdays(days []time.Weekday) []string {
+	var names []string
+	for _, d := range days {
+		names = append(names, strings.ToLower(d.String()))
+	}
+	return names
+}
+
+func formatClock(d time.Duration) (string, error) {
+	if d%time.Second != 0 {
+		return "", errors.New("window time has sub-second precision")
+	}
+	h, m, s := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+	if s != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s), nil
+	}
+	return fmt.Sprintf("%02d:%02d", h, m), nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d > oneDay && d%oneDay == 0 {
+		return fmt.Sprintf("%dd", d/oneDay)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+var durationDays = regexp.MustCompile(`^(\d+)d(.*)$`)
+
+func parseDuration(s string) (time.Duration, error) {
+	var days time.Duration
+	if m := durationDays.FindStringSubmatch(s); m != nil {
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		days, s = time.Duration(n)*oneDay, m[2]
+		if s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return days + d, nil
+}
+
+func (c *Codec) tokenByAddress(addr common.Address) (Token, bool) {
+	for _, t := range c.tokens {
+		if t.Address == addr {
+			return t, true
+		}
+	}
+	if addr == (common.Address{}) {
+		return Token{Symbol: "ETH", Decimals: 18}, true
+	}
+	return Token{}, false
+}
+
+func (c *Codec) tokenBySymbol(symbol string) (Token, bool) {
+	for _, t := range c.tokens {
+		if strings.EqualFold(t.Symbol, symbol) {
+			return t, true
+		}
+	}
+	if native, _ := c.tokenByAddress(common.Address{}); strings.EqualFold(native.Symbol, symbol) {
+		return native, true
+	}
+	return Token{}, false
+}
+
+func (c *Codec) formatToken(addr common.Address) string {
+	if addr == (common.Address{}) {
+		return "native"
+	}
+	if t, ok := c.tokenByAddress(addr); ok {
+		return t.Symbol
+	}
+	return addr.Hex()
+}
+
+func (c *Codec) formatAmount(token common.Address, amount *big.Int) string {
+	t, ok := c.tokenByAddress(token)
+	if !ok {
+		return amount.String()
+	}
+	return formatUnits(t, amount)
+}
+
+func quoteUnit(quote string) Token {
+	return Token{Symbol: quote, Decimals: oracle.QuoteDecimals}
+}
+
+func formatUnits(t Token, amount *big.Int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(amount, unit, new(big.Int))
+	s := whole.String()
+	if frac.Sign() != 0 {
+		digits := fmt.Sprintf("%0*s", t.Decimals, frac.String())
+		s += "." + strings.TrimRight(digits, "0")
+	}
+	return s + " " + t.Symbol
+}
+
+func (c *Codec) parseAmount(s string, token common.Address) (*big.Int, error) {
+	t, ok := c.tokenByAddress(token)
+	if !ok && len(strings.Fields(s)) == 2 {
+		return nil, fmt.Errorf("amount %q has a unit but token %s is not registered", s, token.Hex())
+	}
+	return parseUnits(s, t)
+}
+
+func parseUnits(s string, t Token) (*big.Int, error) {
+	parts := strings.Fields(s)
+	switch len(parts) {
+	case 1:
+		n, ok := new(big.Int).SetString(parts[0], 10)
+		if !ok || n.Sign() < 0 {
+			return nil, fmt.Errorf("invalid amount %q: expected base units or \"<amount> <symbol>\"", s)
+		}
+		return n, nil
+	case 2:
+	default:
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if !strings.EqualFold(parts[1], t.Symbol) {
+		return nil, fmt.Errorf("amount unit %s does not match token %s", parts[1], t.Symbol)
+	}
+
+	whole, frac, _ := strings.Cut(parts[0], ".")
+	if len(frac) > int(t.Decimals) {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", s, t.Decimals)
+	}
+	n, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", int(t.Decimals)-len(frac)), 10)
+	if !ok || whole == "" || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,440 @@
+package policy
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCodec() *Codec {
+	return NewCodec(WithTokens(Token{Symbol: "USDC", Address: usdc, Decimals: 6}))
+}
+
+func fullPolicy() *Policy {
+	christmas := time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)
+	return &Policy{
+		ID:        "treasury-agent",
+		Priority:  5,
+		CreatedAt: time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC),
+		SpendingLimits: []SpendingLimit{
+			{Token: usdc, MaxAmount: big.NewInt(100_500_000), Period: 24 * time.Hour, Window: SpendingCalendarWindow},
+			{Token: common.Address{}, MaxAmount: big.NewInt(5e17), Period: 7 * 24 * time.Hour},
+			{Token: weth, MaxAmount: big.NewInt(42), Period: 90 * time.Minute, Window: SpendingSlidingWindow},
+			{Quote: "USD", MaxAmount: big.NewInt(250_050_000_000), Period: 24 * time.Hour},
+		},
+		MaxValuePerTx:     big.NewInt(25e16),
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc, router}),
+		FunctionAllowlist: NewFunctionAllowlist([]string{transferSig, swapSig}),
+		Permissions:       NewPermissionList(Permit(usdc, transferSig), Permit(router, AnySelector), Permit(AnyContract, "balanceOf(address)")),
+		Deny: &DenyList{
+			Contracts: []common.Address{drainer},
+			Selectors: []string{Selector("approve(address,uint256)")},
+			Calls:     []Permission{Permit(router, transferSig)},
+			Payees:    []common.Address{payee},
+			Tokens:    []common.Address{usdc, degen},
+		},
+		CalldataConstraints: []CalldataConstraint{
+			{Signature: transferSig, Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"1000000"}}}},
+			{Contract: router, Signature: swapSig, Args: []ArgConstraint{{Index: 2, Op: ArgIn, Values: []string{weth.Hex(), usdc.Hex()}}}},
+		},
+		TimeWindow: &TimeWindow{
+			Start:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:       time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+			Days:      []time.Weekday{time.Monday, time.Friday},
+			Hours:     [2]int{8, 20},
+			Location:  "America/New_York",
+			Windows:   []WeeklyWindow{{Days: []time.Weekday{time.Monday}, Start: 9*time.Hour + 30*time.Minute, End: 17 * time.Hour}},
+			Blackouts: []Blackout{{Start: christmas, End: christmas.AddDate(0, 0, 1)}},
+			Intersect: []TimeWindow{{Location: "Asia/Tokyo", Windows: []WeeklyWindow{{Start: 22 * time.Hour, End: 6*time.Hour + 15*time.Second}}}},
+		},
+		RateLimit: &RateLimit{MaxCalls: 60, Period: time.Hour, Mode: RateLimitSlidingWindow},
+		Escalation: &Escalation{
+			Thresholds: []ApprovalThreshold{
+				{Token: usdc, Amount: big.NewInt(50_000_000)},
+				{Quote: "USD", Amount: big.NewInt(100_000_000_000)},
+			},
+			NewContracts:      true,
+			OutsideTimeWindow: true,
+			Approvers:         []common.Address{payee},
+			TTL:               12 * time.Hour,
+		},
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	c := testCodec()
+	p := fullPolicy()
+
+	t.Run("json", func(t *testing.T) {
+		data, err := c.EncodeJSON(p)
+		require.NoError(t, err)
+		decoded, err := c.DecodeJSON(data)
+		require.NoError(t, err)
+		assert.Equal(t, p, decoded)
+
+		again, err := c.EncodeJSON(decoded)
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(again))
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		data, err := c.EncodeYAML(p)
+		require.NoError(t, err)
+		decoded, err := c.DecodeYAML(data)
+		require.NoError(t, err)
+		assert.Equal(t, p, decoded)
+
+		again, err := c.EncodeYAML(decoded)
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(again))
+	})
+
+	t.Run("empty allowlist is kept", func(t *testing.T) {
+		p := &Policy{ContractAllowlist: NewContractAllowlist(nil)}
+		data, err := c.EncodeJSON(p)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"contracts": []`)
+		decoded, err := c.DecodeJSON(data)
+		require.NoError(t, err)
+		require.NotNil(t, decoded.ContractAllowlist)
+		assert.Empty(t, decoded.ContractAllowlist.Contracts)
+	})
+
+	t.Run("runtime state is not serialized", func(t *testing.T) {
+		p := &Policy{
+			SpendingLimits: []SpendingLimit{*NewSpendingLimit(usdc, big.NewInt(1_000_000), time.Hour)},
+			RateLimit:      &RateLimit{MaxCalls: 1, Period: time.Hour},
+		}
+		data, err := c.EncodeYAML(p)
+		require.NoError(t, err)
+		decoded, err := c.DecodeYAML(data)
+		require.NoError(t, err)
+		assert.Nil(t, decoded.SpendingLimits[0].Spent)
+		assert.True(t, decoded.SpendingLimits[0].ResetAt.IsZero())
+	})
+}
+
+func TestCodecDeprecatedRateLimitFields(t *testing.T) {
+	p, err := testCodec().DecodeYAML([]byte("version: 1\nrateLimit:\n  maxCalls: 5\n  period: 1h\n  calls: 3\n  resetAt: \"2026-03-04T10:00:00Z\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &RateLimit{MaxCalls: 5, Period: time.Hour}, p.RateLimit)
+
+	out, err := testCodec().EncodeYAML(&Policy{RateLimit: &RateLimit{MaxCalls: 5, Period: time.Hour, Calls: 3, ResetAt: evalTime}})
+	require.NoError(t, err)
+	assert.Equal(t, "version: 1\nrateLimit:\n  maxCalls: 5\n  period: 1h\n", string(out))
+}
+
+func TestCodecEncodeYAML(t *testing.T) {
+	p := &Policy{
+		ID: "ops",
+		SpendingLimits: []SpendingLimit{
+			{Token: usdc, MaxAmount: big.NewInt(100_000_000), Period: 24 * time.Hour},
+			{Quote: "USD", MaxAmount: usd(500), Period: 7 * 24 * time.Hour},
+		},
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc}),
+		FunctionAllowlist: NewFunctionAllowlist([]string{transferSig}),
+		TimeWindow: &TimeWindow{
+			Location: "Europe/Berlin",
+			Windows:  []WeeklyWindow{{Days: []time.Weekday{time.Monday, time.Friday}, Start: 9 * time.Hour, End: 17 * time.Hour}},
+		},
+		RateLimit: &RateLimit{MaxCalls: 10, Period: time.Minute},
+	}
+
+	data, err := testCodec().EncodeYAML(p)
+	require.NoError(t, err)
+	assert.Equal(t, `version: 1
+id: ops
+spendingLimits:
+  - token: USDC
+    max: 100 USDC
+    period: 24h
+  - quote: USD
+    max: 500 USD
+    period: 7d
+contracts:
+  - 0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913
+functions:
+  - "0xa9059cbb"
+timeWindow:
+  location: Europe/Berlin
+  windows:
+    - days: [monday, friday]
+      start: "09:00"
+      end: "17:00"
+rateLimit:
+  maxCalls: 10
+  period: 1m
+`, string(data))
+}
+
+func TestCodecAmounts(t *testing.T) {
+	c := testCodec()
+	tests := []struct {
+		token   common.Address
+		text    string
+		amount  *big.Int
+		wantErr string
+	}{
+		{usdc, "100 USDC", big.NewInt(100_000_000), ""},
+		{usdc, "0.5 USDC", big.NewInt(500_000), ""},
+		{usdc, "1.000001 usdc", big.NewInt(1_000_001), ""},
+		{usdc, "2500000", big.NewInt(2_500_000), ""},
+		{common.Address{}, "1.5 ETH", big.NewInt(15e17), ""},
+		{usdc, "1.0000001 USDC", nil, `amount "1.0000001 USDC" has more than 6 decimals`},
+		{usdc, "100 DAI", nil, "amount unit DAI does not match token USDC"},
+		{weth, "1 WETH", nil, `amount "1 WETH" has a unit but token 0x4200000000000000000000000000000000000006 is not registered`},
+		{usdc, "1.5", nil, `invalid amount "1.5": expected base units or "<amount> <symbol>"`},
+		{usdc, "0 USDC", big.NewInt(0), ""},
+		{usdc, "-1", nil, `invalid amount "-1": expected base units or "<amount> <symbol>"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			amount, err := c.parseAmount(tt.text, tt.token)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, amount)
+		})
+	}
+
+	assert.Equal(t, "100.5 USDC", c.formatAmount(usdc, big.NewInt(100_500_000)))
+	assert.Equal(t, "0.000001 USDC", c.formatAmount(usdc, big.NewInt(1)))
+	assert.Equal(t, "42", c.formatAmount(weth, big.NewInt(42)))
+}
+
+func TestCodecDurations(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		text string
+	}{
+		{24 * time.Hour, "24h"},
+		{7 * 24 * time.Hour, "7d"},
+		{36 * time.Hour, "36h"},
+		{90 * time.Minute, "1h30m"},
+		{time.Minute, "1m"},
+		{90 * time.Second, "1m30s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.text, formatDuration(tt.d))
+			d, err := parseDuration(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.d, d)
+		})
+	}
+
+	d, err := parseDuration("1d12h")
+	require.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, d)
+}
+
+func TestCodecDecodeErrors(t *testing.T) {
+	c := testCodec()
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"empty", "", "line 1: empty document"},
+		{"not an object", "- 1\n", "line 1: expected an object"},
+		{"missing version", "id: a\n", `line 1: missing field "version"`},
+		{"unsupported version", "version: 2\n", "line 1: version: unsupported version 2"},
+		{"version as string", "version: \"1\"\n", "line 1: version: expected an integer"},
+		{"unknown field", "version: 1\nspendingLimit: []\n", "line 2: spendingLimit: unknown field"},
+		{"duplicate field", "version: 1\nid: a\nid: b\n", "line 3: id: duplicate field"},
+		{
+			"nested unknown field",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    max: 1 USDC\n    period: 1h\n  - token: USDC\n    maximum: 1 USDC\n    period: 1h\n",
+			"line 7: spendingLimits[1].maximum: unknown field",
+		},
+		{
+			"missing nested field",
+			"version: 1\nrateLimit:\n  period: 1h\n",
+			`line 3: rateLimit: missing field "maxCalls"`,
+		},
+		{
+			"bad amount",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    max: 100 DAI\n    period: 1h\n",
+			"line 4: spendingLimits[0].max: amount unit DAI does not match token USDC",
+		},
+		{
+			"zero spending limit",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    max: 0 USDC\n    period: 1h\n",
+			"line 4: spendingLimits[0].max: must be positive",
+		},
+		{
+			"unknown spending window",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    max: 1 USDC\n    period: 1h\n    window: rolling\n",
+			`line 6: spendingLimits[0].window: unsupported spending window "rolling"`,
+		},
+		{
+			"calendar month window period",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    max: 1 USDC\n    period: 31d\n    window: calendar_month\n",
+			`line 5: spendingLimits[0].period: must be 30d with window "calendar_month"`,
+		},
+		{
+			"token and quote",
+			"version: 1\nspendingLimits:\n  - token: USDC\n    quote: USD\n    max: 1 USD\n    period: 1h\n",
+			"line 4: spendingLimits[0].quote: cannot be combined with token",
+		},
+		{
+			"missing token",
+			"version: 1\nspendingLimits:\n  - max: 1 USDC\n    period: 1h\n",
+			`line 3: spendingLimits[0]: missing field "token"`,
+		},
+		{
+			"quote amount",
+			"version: 1\nspendingLimits:\n  - quote: USD\n    max: 0.000000001 USD\n    period: 1h\n",
+			`line 4: spendingLimits[0].max: amount "0.000000001 USD" has more than 8 decimals`,
+		},
+		{
+			"bad duration",
+			"version: 1\nrateLimit:\n  maxCalls: 1\n  period: 1 hour\n",
+			`line 4: rateLimit.period: invalid duration "1 hour"`,
+		},
+		{
+			"escalation without approvers",
+			"version: 1\nescalation:\n  newContracts: true\n  approvers: []\n",
+			"line 4: escalation.approvers: at least one approver required",
+		},
+		{
+			"escalation flag",
+			"version: 1\nescalation:\n  newContracts: yes please\n  approvers: [\"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913\"]\n",
+			"line 3: escalation.newContracts: expected a boolean",
+		},
+		{
+			"approval threshold amount",
+			"version: 1\nescalation:\n  thresholds:\n    - token: USDC\n      amount: 0 USDC\n  approvers: [\"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913\"]\n",
+			"line 5: escalation.thresholds[0].amount: must be positive",
+		},
+		{"unknown token", "version: 1\ndeny:\n  tokens: [DAI]\n", `line 3: deny.tokens[0]: unknown token "DAI"`},
+		{
+			"bad checksum",
+			"version: 1\ncontracts:\n  - 0x833589fcd6edb6e08f4c7c32d4f71b54bda02913\n  - 0x833589FCD6eDb6E08f4c7C32D4f71b54bdA02913\n",
+			`line 4: contracts[1]: invalid address checksum "0x833589FCD6eDb6E08f4c7C32D4f71b54bdA02913"`,
+		},
+		{"bad function", "version: 1\nfunctions: [transfer]\n", `line 2: functions[0]: invalid function "transfer": expected a signature or 4-byte selector`},
+		{"wildcard deny function", "version: 1\ndeny:\n  functions: [\"*\"]\n", `line 3: deny.functions[0]: invalid function "*": expected a signature or 4-byte selector`},
+		{"bad weekday", "version: 1\ntimeWindow:\n  days: [funday]\n", `line 3: timeWindow.days[0]: invalid weekday "funday"`},
+		{"bad clock", "version: 1\ntimeWindow:\n  windows:\n    - start: \"9am\"\n      end: \"17:00\"\n", `line 4: timeWindow.windows[0].start: invalid time of day "9am": expected HH:MM`},
+		{"unknown zone", "version: 1\ntimeWindow:\n  location: Mars/Base\n", `line 3: timeWindow.location: unknown time zone "Mars/Base"`},
+		{
+			"invalid calldata constraint",
+			"version: 1\ncalldataConstraints:\n  - signature: transfer(address,uint256)\n    args:\n      - {index: 5, op: eq, values: [\"1\"]}\n",
+			"line 3: calldataConstraints[0]: argument index out of range",
+		},
+		{"syntax error", "version: 1\nid: a\n  b: c\n", "line 3: mapping values are not allowed in this context"},
+		{"multiple documents", "version: 1\n---\nversion: 1\n", "line 2: multiple documents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.DecodeYAML([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.EqualError(t, err, tt.wantErr)
+			var de *DecodeError
+			assert.ErrorAs(t, err, &de)
+		})
+	}
+}
+
+func TestCodecDecodeJSONErrors(t *testing.T) {
+	c := testCodec()
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"syntax error", "{\n  \"version\": 1,\n  \"id\": \"a\",\n}\n", "line 4: invalid character '}' looking for beginning of object key string"},
+		{"unknown field", "{\n  \"version\": 1,\n  \"rateLimit\": {\n    \"maxCalls\": 1,\n    \"period\": \"1h\",\n    \"burst\": 2\n  }\n}\n", "line 6: rateLimit.burst: unknown field"},
+		{"yaml is not json", "version: 1\n", "line 1: invalid character 'v' looking for beginning of value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.DecodeJSON([]byte(tt.json))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCodecEncodeValidates(t *testing.T) {
+	_, err := testCodec().EncodeJSON(&Policy{RateLimit: &RateLimit{Period: time.Hour}})
+	assert.EqualError(t, err, "rate limit max calls must be positive")
+
+	_, err = testCodec().EncodeJSON(nil)
+	assert.EqualError(t, err, "nil policy")
+}
+
+func TestPolicySchema(t *testing.T) {
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(PolicySchema(), &schema))
+
+	documents := map[string]interface{}{
+		"spendingLimit":      spendingLimitDocument{},
+		"permission":         permissionDocument{},
+		"deny":               denyDocument{},
+		"calldataConstraint": calldataDocument{},
+		"arg":                argDocument{},
+		"timeWindow":         timeWindowDocument{},
+		"weeklyWindow":       weeklyWindowDocument{},
+		"blackout":           blackoutDocument{},
+		"rateLimit":          rateLimitDocument{},
+		"escalation":         escalationDocument{},
+		"threshold":          thresholdDocument{},
+	}
+
+	fields, required := documentFields(policyDocument{})
+	assert.ElementsMatch(t, fields, keys(schema.Properties))
+	assert.ElementsMatch(t, required, schema.Required)
+
+	for name, doc := range documents {
+		def, ok := schema.Defs[name]
+		require.True(t, ok, name)
+		fields, required := documentFields(doc)
+		assert.ElementsMatch(t, fields, keys(def.Properties), name)
+		assert.ElementsMatch(t, required, def.Required, name)
+	}
+}
+
+func documentFields(doc interface{}) ([]string, []string) {
+	var fields, required []string
+	typ := reflect.TypeOf(doc)
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		fields = append(fields, name)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return fields, required
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
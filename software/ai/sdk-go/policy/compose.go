@@ -1,9 +1,14 @@
 package policy
 
 import (
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// Deprecated: ComposePolicy unions allowlists, pairs the smallest MaxCalls
+// with the longest Period and drops Escalation, so the result can be looser
+// than any of its inputs. Use Compose with ComposeIntersect.
 func ComposePolicy(policies ...*Policy) *Policy {
 	composed := &Policy{
 		SpendingLimits: make([]SpendingLimit, 0),
@@ -18,6 +23,7 @@ func ComposePolicy(policies ...*Policy) *Policy {
 		}
 
 		composed.SpendingLimits = append(composed.SpendingLimits, p.SpendingLimits...)
+		composed.CalldataConstraints = append(composed.CalldataConstraints, p.CalldataConstraints...)
 
 		if p.ContractAllowlist != nil {
 			for addr, allowed := range p.ContractAllowlist.Contracts {
@@ -35,17 +41,25 @@ func ComposePolicy(policies ...*Policy) *Policy {
 			}
 		}
 
+		if p.MaxValuePerTx != nil && (composed.MaxValuePerTx == nil || p.MaxValuePerTx.Cmp(composed.MaxValuePerTx) < 0) {
+			composed.MaxValuePerTx = new(big.Int).Set(p.MaxValuePerTx)
+		}
+
+		if p.Permissions != nil {
+			if composed.Permissions == nil {
+				composed.Permissions = NewPermissionList()
+			}
+			for _, perm := range p.Permissions.Permissions {
+				composed.Permissions.add(perm)
+			}
+		}
+
 		if p.TimeWindow != nil {
 			if composed.TimeWindow == nil {
 				tw := *p.TimeWindow
 				composed.TimeWindow = &tw
 			} else {
-				if p.TimeWindow.Start.After(composed.TimeWindow.Start) {
-					composed.TimeWindow.Start = p.TimeWindow.Start
-				}
-				if !p.TimeWindow.End.IsZero() && (composed.TimeWindow.End.IsZero() || p.TimeWindow.End.Before(composed.TimeWindow.End)) {
-					composed.TimeWindow.End = p.TimeWindow.End
-				}
+				composed.TimeWindow = mergeTimeWindows(composed.TimeWindow, p.TimeWindow)
 			}
 		}
 
@@ -72,5 +86,7 @@ func ComposePolicy(policies ...*Policy) *Policy {
 		composed.FunctionAllowlist = &FunctionAllowlist{Functions: functions}
 	}
 
+	composed.Deny = composeDenyLists(policies)
+
 	return composed
 }
@@ -106,6 +106,23 @@ func TestComposePolicy(t *testing.T) {
 		assert.Len(t, result.FunctionAllowlist.Functions, 2)
 	})
 
+	t.Run("permissions - union of pairs", func(t *testing.T) {
+		router := common.HexToAddress("0x1111111111111111111111111111111111111111")
+		token := common.HexToAddress("0x2222222222222222222222222222222222222222")
+		p1 := &Policy{Permissions: NewPermissionList(Permit(router, "approve(address,uint256)"))}
+		p2 := &Policy{Permissions: NewPermissionList(
+			Permit(router, "approve(address,uint256)"),
+			Permit(token, "transfer(address,uint256)"),
+		)}
+
+		result := ComposePolicy(p1, p2, &Policy{})
+		require.NotNil(t, result.Permissions)
+		assert.Len(t, result.Permissions.Permissions, 2)
+		assert.True(t, IsAllowed(result, router, "approve(address,uint256)"))
+		assert.False(t, IsAllowed(result, token, "approve(address,uint256)"))
+		assert.Len(t, p1.Permissions.Permissions, 1)
+	})
+
 	t.Run("time windows - later start wins", func(t *testing.T) {
 		now := time.Now()
 		p1 := &Policy{
@@ -168,6 +185,81 @@ func TestComposePolicy(t *testing.T) {
 		assert.Equal(t, endTime, result.TimeWindow.End)
 	})
 
+	t.Run("time windows - schedules intersect", func(t *testing.T) {
+		weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		p1 := &Policy{
+			TimeWindow: &TimeWindow{
+				Location: "Europe/Berlin",
+				Windows:  []WeeklyWindow{{Days: weekdays, Start: 8 * time.Hour, End: 18 * time.Hour}},
+			},
+		}
+		p2 := &Policy{
+			TimeWindow: &TimeWindow{
+				Location: "Europe/Berlin",
+				Hours:    [2]int{12, 20},
+			},
+		}
+
+		result := ComposePolicy(p1, p2)
+		require.NotNil(t, result.TimeWindow)
+		assert.Equal(t, "Europe/Berlin", result.TimeWindow.Location)
+		assert.Equal(t, []WeeklyWindow{{Days: weekdays, Start: 12 * time.Hour, End: 18 * time.Hour}}, result.TimeWindow.Windows)
+		assert.Empty(t, result.TimeWindow.Intersect)
+	})
+
+	t.Run("time windows - overnight schedules intersect", func(t *testing.T) {
+		p1 := &Policy{TimeWindow: &TimeWindow{Windows: []WeeklyWindow{{Start: 22 * time.Hour, End: 6 * time.Hour}}}}
+		p2 := &Policy{TimeWindow: &TimeWindow{Windows: []WeeklyWindow{{Start: 0, End: 4 * time.Hour}}}}
+
+		result := ComposePolicy(p1, p2)
+		require.Len(t, result.TimeWindow.Windows, 1)
+		assert.Equal(t, time.Duration(0), result.TimeWindow.Windows[0].Start)
+		assert.Equal(t, 4*time.Hour, result.TimeWindow.Windows[0].End)
+		assert.Len(t, result.TimeWindow.Windows[0].Days, 7)
+	})
+
+	t.Run("time windows - different zones kept as intersect", func(t *testing.T) {
+		p1 := &Policy{TimeWindow: &TimeWindow{Location: "America/New_York", Hours: [2]int{9, 16}}}
+		p2 := &Policy{TimeWindow: &TimeWindow{Location: "Asia/Tokyo", Hours: [2]int{9, 16}}}
+
+		result := ComposePolicy(p1, p2)
+		assert.Equal(t, "America/New_York", result.TimeWindow.Location)
+		require.Len(t, result.TimeWindow.Intersect, 1)
+		assert.Equal(t, "Asia/Tokyo", result.TimeWindow.Intersect[0].Location)
+		assert.Error(t, CheckTimeWindow(result.TimeWindow, time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("time windows - disjoint schedules never match", func(t *testing.T) {
+		p1 := &Policy{TimeWindow: &TimeWindow{Hours: [2]int{1, 2}}}
+		p2 := &Policy{TimeWindow: &TimeWindow{Hours: [2]int{5, 6}}}
+
+		result := ComposePolicy(p1, p2)
+		for h := 0; h < 24; h++ {
+			assert.Error(t, CheckTimeWindow(result.TimeWindow, time.Date(2026, 3, 4, h, 30, 0, 0, time.UTC)))
+		}
+	})
+
+	t.Run("time windows - schedule adopted and blackouts combined", func(t *testing.T) {
+		now := time.Now()
+		p1 := &Policy{TimeWindow: &TimeWindow{Blackouts: []Blackout{{Start: now, End: now.Add(time.Hour)}}}}
+		p2 := &Policy{TimeWindow: &TimeWindow{
+			Location:  "Asia/Tokyo",
+			Hours:     [2]int{9, 17},
+			Blackouts: []Blackout{{Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour)}},
+		}}
+
+		result := ComposePolicy(p1, p2)
+		assert.Equal(t, "Asia/Tokyo", result.TimeWindow.Location)
+		assert.Equal(t, [2]int{9, 17}, result.TimeWindow.Hours)
+		assert.Len(t, result.TimeWindow.Blackouts, 2)
+		assert.Len(t, p1.TimeWindow.Blackouts, 1)
+	})
+
+	t.Run("max value - lower maximum wins", func(t *testing.T) {
+		result := ComposePolicy(&Policy{MaxValuePerTx: big.NewInt(100)}, &Policy{}, &Policy{MaxValuePerTx: big.NewInt(10)})
+		assert.Equal(t, big.NewInt(10), result.MaxValuePerTx)
+	})
+
 	t.Run("rate limits - lower max calls wins", func(t *testing.T) {
 		p1 := &Policy{
 			RateLimit: &RateLimit{
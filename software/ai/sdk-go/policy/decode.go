@@ -0,0 +1,930 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+	"gopkg.in/yaml.v3"
+)
+
+type DecodeError struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+type decoder struct {
+	codec *Codec
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func (c *Codec) DecodeJSON(data []byte) (*Policy, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			line, col := position(data, se.Offset)
+			return nil, &DecodeError{Line: line, Column: col, Message: se.Error()}
+		}
+		return nil, err
+	}
+	return c.DecodeYAML(data)
+}
+
+func (c *Codec) DecodeYAML(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var root yaml.Node
+	if err := dec.Decode(&root); err != nil {
+		if err == io.EOF {
+			return nil, &DecodeError{Line: 1, Message: "empty document"}
+		}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &DecodeError{Line: line, Message: m[2]}
+		}
+		return nil, err
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); err != io.EOF {
+		return nil, &DecodeError{Line: extra.Line, Message: "multiple documents"}
+	}
+
+	d := &decoder{codec: c}
+	p, err := d.policy(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func position(data []byte, offset int64) (int, int) {
+	line, col := 1, 1
+	for _, b := range data[:min(int(offset), len(data))] {
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+func fail(n *yaml.Node, path, format string, args ...interface{}) error {
+	return &DecodeError{Line: n.Line, Column: n.Column, Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func isNull(n *yaml.Node) bool {
+	n = resolve(n)
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func (d *decoder) fields(n *yaml.Node, path string, required []string, optional ...string) (map[string]*yaml.Node, error) {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return nil, fail(n, path, "expected an object")
+	}
+
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		known := false
+		for _, name := range append(required, optional...) {
+			if key.Value == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fail(key, fieldPath(path, key.Value), "unknown field")
+		}
+		if _, dup := fields[key.Value]; dup {
+			return nil, fail(key, fieldPath(path, key.Value), "duplicate field")
+		}
+		if !isNull(value) {
+			fields[key.Value] = value
+		}
+	}
+
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			return nil, fail(n, path, "missing field %q", name)
+		}
+	}
+	return fields, nil
+}
+
+func fieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func itemPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func (d *decoder) list(n *yaml.Node, path string) ([]*yaml.Node, error) {
+	n = resolve(n)
+	if n.Kind != yaml.SequenceNode {
+		return nil, fail(n, path, "expected a list")
+	}
+	return n.Content, nil
+}
+
+func (d *decoder) str(n *yaml.Node, path string) (string, error) {
+	n = resolve(n)
+	if n.Kind != yaml.ScalarNode {
+		return "", fail(n, path, "expected a string")
+	}
+	return n.Value, nil
+}
+
+func (d *decoder) integer(n *yaml.Node, path string) (int64, error) {
+	n = resolve(n)
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!int" {
+		return 0, fail(n, path, "expected an integer")
+	}
+	v, err := strconv.ParseInt(n.Value, 0, 64)
+	if err != nil {
+		return 0, fail(n, path, "expected an integer")
+	}
+	return v, nil
+}
+
+func (d *decoder) boolean(n *yaml.Node, path string) (bool, error) {
+	n = resolve(n)
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" {
+		return false, fail(n, path, "expected a boolean")
+	}
+	v, err := strconv.ParseBool(n.Value)
+	if err != nil {
+		return false, fail(n, path, "expected a boolean")
+	}
+	return v, nil
+}
+
+func (d *decoder) stringList(n *yaml.Node, path string) ([]string, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(items))
+	for i, item := range items {
+		s, err := d.str(item, itemPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func (d *decoder) address(n *yaml.Node, path string) (common.Address, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !common.IsHexAddress(s) || !strings.HasPrefix(s, "0x") {
+		return common.Address{}, fail(n, path, "invalid address %q", s)
+	}
+	addr := common.HexToAddress(s)
+	if hex := s[2:]; hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && addr.Hex() != s {
+		return common.Address{}, fail(n, path, "invalid address checksum %q", s)
+	}
+	return addr, nil
+}
+
+func (d *decoder) addresses(n *yaml.Node, path string) ([]common.Address, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, 0, len(items))
+	for i, item := range items {
+		addr, err := d.address(item, itemPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func (d *decoder) token(n *yaml.Node, path string, native bool) (common.Address, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if native && s == "native" {
+		return common.Address{}, nil
+	}
+	if strings.HasPrefix(s, "0x") {
+		return d.address(n, path)
+	}
+	t, ok := d.codec.tokenBySymbol(s)
+	if !ok || (!native && t.Address == (common.Address{})) {
+		return common.Address{}, fail(n, path, "unknown token %q", s)
+	}
+	return t.Address, nil
+}
+
+func (d *decoder) tokens(n *yaml.Node, path string) ([]common.Address, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, 0, len(items))
+	for i, item := range items {
+		addr, err := d.token(item, itemPath(path, i), false)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func (d *decoder) contract(n *yaml.Node, path string) (common.Address, error) {
+	if s, err := d.str(n, path); err == nil && s == "*" {
+		return AnyContract, nil
+	}
+	return d.address(n, path)
+}
+
+func (d *decoder) selector(n *yaml.Node, path string, wildcard bool) (string, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case s == AnySelector && wildcard:
+		return AnySelector, nil
+	case strings.Contains(s, "("):
+		if _, err := encoding.ParseFunctionArgs(s); err != nil {
+			return "", fail(n, path, "invalid function signature %q", s)
+		}
+		return Selector(s), nil
+	case strings.HasPrefix(s, "0x") && isSelector(s):
+		return normalizeSelector(s), nil
+	}
+	return "", fail(n, path, "invalid function %q: expected a signature or 4-byte selector", s)
+}
+
+func (d *decoder) selectors(n *yaml.Node, path string) ([]string, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	selectors := make([]string, 0, len(items))
+	for i, item := range items {
+		s, err := d.selector(item, itemPath(path, i), false)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, s)
+	}
+	return selectors, nil
+}
+
+func (d *decoder) amount(n *yaml.Node, path string, token common.Address) (*big.Int, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := d.codec.parseAmount(s, token)
+	if err != nil {
+		return nil, fail(n, path, "%s", err)
+	}
+	return amount, nil
+}
+
+func (d *decoder) quote(n *yaml.Node, path string) (string, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return "", err
+	}
+	if s == "" || len(strings.Fields(s)) != 1 {
+		return "", fail(n, path, "invalid quote currency %q", s)
+	}
+	return s, nil
+}
+
+func (d *decoder) quoteAmount(n *yaml.Node, path string, quote string) (*big.Int, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseUnits(s, quoteUnit(quote))
+	if err != nil {
+		return nil, fail(n, path, "%s", err)
+	}
+	return amount, nil
+}
+
+func (d *decoder) duration(n *yaml.Node, path string) (time.Duration, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := parseDuration(s)
+	if err != nil || v <= 0 {
+		return 0, fail(n, path, "invalid duration %q", s)
+	}
+	return v, nil
+}
+
+func (d *decoder) timestamp(n *yaml.Node, path string) (time.Time, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fail(n, path, "invalid time %q: expected RFC 3339", s)
+	}
+	return t, nil
+}
+
+func (d *decoder) clock(n *yaml.Node, path string) (time.Duration, error) {
+	s, err := d.str(n, path)
+	if err != nil {
+		return 0, err
+	}
+	layout := "15:04"
+	if strings.Count(s, ":") == 2 {
+		layout = "15:04:05"
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return 0, fail(n, path, "invalid time of day %q: expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+func (d *decoder) weekdays(n *yaml.Node, path string) ([]time.Weekday, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]time.Weekday, 0, len(items))
+	for i, item := range items {
+		s, err := d.str(item, itemPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		day, ok := parseWeekday(s)
+		if !ok {
+			return nil, fail(item, itemPath(path, i), "invalid weekday %q", s)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func (d *decoder) policy(n *yaml.Node) (*Policy, error) {
+	f, err := d.fields(n, "", []string{"version"},
+		"id", "priority", "createdAt", "spendingLimits", "maxValuePerTx", "contracts", "functions",
+		"permissions", "deny", "calldataConstraints", "timeWindow", "rateLimit", "escalation")
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := d.integer(f["version"], "version")
+	if err != nil {
+		return nil, err
+	}
+	if version != PolicyFormatVersion {
+		return nil, fail(f["version"], "version", "unsupported version %d", version)
+	}
+
+	p := &Policy{}
+	if v, ok := f["id"]; ok {
+		if p.ID, err = d.str(v, "id"); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["priority"]; ok {
+		priority, err := d.integer(v, "priority")
+		if err != nil {
+			return nil, err
+		}
+		p.Priority = int(priority)
+	}
+	if v, ok := f["createdAt"]; ok {
+		if p.CreatedAt, err = d.timestamp(v, "createdAt"); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := f["spendingLimits"]; ok {
+		items, err := d.list(v, "spendingLimits")
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			sl, err := d.spendingLimit(item, itemPath("spendingLimits", i))
+			if err != nil {
+				return nil, err
+			}
+			p.SpendingLimits = append(p.SpendingLimits, sl)
+		}
+	}
+	if v, ok := f["maxValuePerTx"]; ok {
+		if p.MaxValuePerTx, err = d.amount(v, "maxValuePerTx", common.Address{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := f["contracts"]; ok {
+		addrs, err := d.addresses(v, "contracts")
+		if err != nil {
+			return nil, err
+		}
+		p.ContractAllowlist = NewContractAllowlist(addrs)
+	}
+
+	if v, ok := f["functions"]; ok {
+		selectors, err := d.selectors(v, "functions")
+		if err != nil {
+			return nil, err
+		}
+		p.FunctionAllowlist = &FunctionAllowlist{Functions: make(map[string]bool, len(selectors))}
+		for _, s := range selectors {
+			p.FunctionAllowlist.Functions[s] = true
+		}
+	}
+
+	if v, ok := f["permissions"]; ok {
+		perms, err := d.permissions(v, "permissions")
+		if err != nil {
+			return nil, err
+		}
+		p.Permissions = NewPermissionList(perms...)
+	}
+
+	if v, ok := f["deny"]; ok {
+		if p.Deny, err = d.denyList(v, "deny"); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := f["calldataConstraints"]; ok {
+		items, err := d.list(v, "calldataConstraints")
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			cc, err := d.calldataConstraint(item, itemPath("calldataConstraints", i))
+			if err != nil {
+				return nil, err
+			}
+			p.CalldataConstraints = append(p.CalldataConstraints, cc)
+		}
+	}
+
+	if v, ok := f["timeWindow"]; ok {
+		if p.TimeWindow, err = d.timeWindow(v, "timeWindow"); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := f["rateLimit"]; ok {
+		if p.RateLimit, err = d.rateLimit(v, "rateLimit"); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := f["escalation"]; ok {
+		if p.Escalation, err = d.escalation(v, "escalation"); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (d *decoder) spendingLimit(n *yaml.Node, path string) (SpendingLimit, error) {
+	var sl SpendingLimit
+	f, err := d.fields(n, path, []string{"max", "period"}, "token", "quote", "window")
+	if err != nil {
+		return sl, err
+	}
+	if v, ok := f["quote"]; ok {
+		if _, ok := f["token"]; ok {
+			return sl, fail(v, fieldPath(path, "quote"), "cannot be combined with token")
+		}
+		if sl.Quote, err = d.quote(v, fieldPath(path, "quote")); err != nil {
+			return sl, err
+		}
+		if sl.MaxAmount, err = d.quoteAmount(f["max"], fieldPath(path, "max"), sl.Quote); err != nil {
+			return sl, err
+		}
+	} else {
+		if _, ok := f["token"]; !ok {
+			return sl, fail(n, path, "missing field %q", "token")
+		}
+		if sl.Token, err = d.token(f["token"], fieldPath(path, "token"), true); err != nil {
+			return sl, err
+		}
+		if sl.MaxAmount, err = d.amount(f["max"], fieldPath(path, "max"), sl.Token); err != nil {
+			return sl, err
+		}
+	}
+	if sl.MaxAmount.Sign() == 0 {
+		return sl, fail(f["max"], fieldPath(path, "max"), "must be positive")
+	}
+	if sl.Period, err = d.duration(f["period"], fieldPath(path, "period")); err != nil {
+		return sl, err
+	}
+	if v, ok := f["window"]; ok {
+		window, err := d.str(v, fieldPath(path, "window"))
+		if err != nil {
+			return sl, err
+		}
+		switch SpendingWindow(window) {
+		case SpendingFixedWindow, SpendingSlidingWindow, SpendingCalendarWindow:
+			sl.Window = SpendingWindow(window)
+		case SpendingCalendarMonthWindow:
+			if sl.Period != Month {
+				return sl, fail(f["period"], fieldPath(path, "period"), "must be 30d with window %q", window)
+			}
+			sl.Window = SpendingWindow(window)
+		default:
+			return sl, fail(v, fieldPath(path, "window"), "unsupported spending window %q", window)
+		}
+	}
+	return sl, nil
+}
+
+func (d *decoder) permissions(n *yaml.Node, path string) ([]Permission, error) {
+	items, err := d.list(n, path)
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]Permission, 0, len(items))
+	for i, item := range items {
+		itemPath := itemPath(path, i)
+		f, err := d.fields(item, itemPath, []string{"contract", "function"})
+		if err != nil {
+			return nil, err
+		}
+		var perm Permission
+		if perm.Contract, err = d.contract(f["contract"], fieldPath(itemPath, "contract")); err != nil {
+			return nil, err
+		}
+		if perm.Selector, err = d.selector(f["function"], fieldPath(itemPath, "function"), true); err != nil {
+			return nil, err
+		}
+		perms = append(perms, perm)
+	}
+	return perms, nil
+}
+
+func (d *decoder) denyList(n *yaml.Node, path string) (*DenyList, error) {
+	f, err := d.fields(n, path, nil, "contracts", "functions", "calls", "payees", "tokens")
+	if err != nil {
+		return nil, err
+	}
+	deny := &DenyList{}
+	if v, ok := f["contracts"]; ok {
+		if deny.Contracts, err = d.addresses(v, fieldPath(path, "contracts")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["functions"]; ok {
+		if deny.Selectors, err = d.selectors(v, fieldPath(path, "functions")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["calls"]; ok {
+		if deny.Calls, err = d.permissions(v, fieldPath(path, "calls")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["payees"]; ok {
+		if deny.Payees, err = d.addresses(v, fieldPath(path, "payees")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["tokens"]; ok {
+		if deny.Tokens, err = d.tokens(v, fieldPath(path, "tokens")); err != nil {
+			return nil, err
+		}
+	}
+	return deny, nil
+}
+
+func (d *decoder) calldataConstraint(n *yaml.Node, path string) (CalldataConstraint, error) {
+	var cc CalldataConstraint
+	f, err := d.fields(n, path, []string{"signature"}, "contract", "args")
+	if err != nil {
+		return cc, err
+	}
+	if v, ok := f["contract"]; ok {
+		if cc.Contract, err = d.contract(v, fieldPath(path, "contract")); err != nil {
+			return cc, err
+		}
+	}
+	if cc.Signature, err = d.str(f["signature"], fieldPath(path, "signature")); err != nil {
+		return cc, err
+	}
+	if v, ok := f["args"]; ok {
+		items, err := d.list(v, fieldPath(path, "args"))
+		if err != nil {
+			return cc, err
+		}
+		for i, item := range items {
+			argPath := itemPath(fieldPath(path, "args"), i)
+			af, err := d.fields(item, argPath, []string{"index", "op", "values"})
+			if err != nil {
+				return cc, err
+			}
+			idx, err := d.integer(af["index"], fieldPath(argPath, "index"))
+			if err != nil {
+				return cc, err
+			}
+			op, err := d.str(af["op"], fieldPath(argPath, "op"))
+			if err != nil {
+				return cc, err
+			}
+			values, err := d.stringList(af["values"], fieldPath(argPath, "values"))
+			if err != nil {
+				return cc, err
+			}
+			cc.Args = append(cc.Args, ArgConstraint{Index: int(idx), Op: ArgOp(op), Values: values})
+		}
+	}
+	if err := validateCalldataConstraint(&cc); err != nil {
+		return cc, fail(n, path, "%s", err)
+	}
+	return cc, nil
+}
+
+func (d *decoder) timeWindow(n *yaml.Node, path string) (*TimeWindow, error) {
+	f, err := d.fields(n, path, nil, "start", "end", "days", "hours", "location", "windows", "blackouts", "intersect")
+	if err != nil {
+		return nil, err
+	}
+	tw := &TimeWindow{}
+	if v, ok := f["start"]; ok {
+		if tw.Start, err = d.timestamp(v, fieldPath(path, "start")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["end"]; ok {
+		if tw.End, err = d.timestamp(v, fieldPath(path, "end")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["days"]; ok {
+		if tw.Days, err = d.weekdays(v, fieldPath(path, "days")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["hours"]; ok {
+		items, err := d.list(v, fieldPath(path, "hours"))
+		if err != nil {
+			return nil, err
+		}
+		if len(items) != 2 {
+			return nil, fail(v, fieldPath(path, "hours"), "expected [start, end]")
+		}
+		for i, item := range items {
+			h, err := d.integer(item, itemPath(fieldPath(path, "hours"), i))
+			if err != nil {
+				return nil, err
+			}
+			if h < 0 || h > 23 {
+				return nil, fail(item, itemPath(fieldPath(path, "hours"), i), "hour out of range")
+			}
+			tw.Hours[i] = int(h)
+		}
+	}
+	if v, ok := f["location"]; ok {
+		if tw.Location, err = d.str(v, fieldPath(path, "location")); err != nil {
+			return nil, err
+		}
+		if _, err := loadLocation(tw.Location); err != nil {
+			return nil, fail(v, fieldPath(path, "location"), "unknown time zone %q", tw.Location)
+		}
+	}
+
+	if v, ok := f["windows"]; ok {
+		items, err := d.list(v, fieldPath(path, "windows"))
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			wPath := itemPath(fieldPath(path, "windows"), i)
+			wf, err := d.fields(item, wPath, []string{"start", "end"}, "days")
+			if err != nil {
+				return nil, err
+			}
+			var w WeeklyWindow
+			if v, ok := wf["days"]; ok {
+				if w.Days, err = d.weekdays(v, fieldPath(wPath, "days")); err != nil {
+					return nil, err
+				}
+			}
+			if w.Start, err = d.clock(wf["start"], fieldPath(wPath, "start")); err != nil {
+				return nil, err
+			}
+			if w.End, err = d.clock(wf["end"], fieldPath(wPath, "end")); err != nil {
+				return nil, err
+			}
+			tw.Windows = append(tw.Windows, w)
+		}
+	}
+
+	if v, ok := f["blackouts"]; ok {
+		items, err := d.list(v, fieldPath(path, "blackouts"))
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			bPath := itemPath(fieldPath(path, "blackouts"), i)
+			bf, err := d.fields(item, bPath, []string{"start", "end"})
+			if err != nil {
+				return nil, err
+			}
+			var b Blackout
+			if b.Start, err = d.timestamp(bf["start"], fieldPath(bPath, "start")); err != nil {
+				return nil, err
+			}
+			if b.End, err = d.timestamp(bf["end"], fieldPath(bPath, "end")); err != nil {
+				return nil, err
+			}
+			if !b.End.After(b.Start) {
+				return nil, fail(item, bPath, "end must be after start")
+			}
+			tw.Blackouts = append(tw.Blackouts, b)
+		}
+	}
+
+	if v, ok := f["intersect"]; ok {
+		items, err := d.list(v, fieldPath(path, "intersect"))
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			inner, err := d.timeWindow(item, itemPath(fieldPath(path, "intersect"), i))
+			if err != nil {
+				return nil, err
+			}
+			tw.Intersect = append(tw.Intersect, *inner)
+		}
+	}
+
+	if !tw.End.IsZero() && tw.Start.After(tw.End) {
+		return nil, fail(n, path, "start after end")
+	}
+	return tw, nil
+}
+
+func (d *decoder) rateLimit(n *yaml.Node, path string) (*RateLimit, error) {
+	f, err := d.fields(n, path, []string{"maxCalls", "period"}, "mode", "calls", "resetAt")
+	if err != nil {
+		return nil, err
+	}
+	rl := &RateLimit{}
+	maxCalls, err := d.integer(f["maxCalls"], fieldPath(path, "maxCalls"))
+	if err != nil {
+		return nil, err
+	}
+	if maxCalls <= 0 {
+		return nil, fail(f["maxCalls"], fieldPath(path, "maxCalls"), "must be positive")
+	}
+	rl.MaxCalls = uint64(maxCalls)
+	if rl.Period, err = d.duration(f["period"], fieldPath(path, "period")); err != nil {
+		return nil, err
+	}
+	if v, ok := f["mode"]; ok {
+		mode, err := d.str(v, fieldPath(path, "mode"))
+		if err != nil {
+			return nil, err
+		}
+		switch RateLimitMode(mode) {
+		case RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket:
+			rl.Mode = RateLimitMode(mode)
+		default:
+			return nil, fail(v, fieldPath(path, "mode"), "unsupported rate limit mode %q", mode)
+		}
+	}
+	return rl, nil
+}
+
+func (d *decoder) escalation(n *yaml.Node, path string) (*Escalation, error) {
+	f, err := d.fields(n, path, []string{"approvers"}, "thresholds", "newContracts", "outsideTimeWindow", "ttl")
+	if err != nil {
+		return nil, err
+	}
+	e := &Escalation{}
+	if v, ok := f["thresholds"]; ok {
+		items, err := d.list(v, fieldPath(path, "thresholds"))
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			th, err := d.threshold(item, itemPath(fieldPath(path, "thresholds"), i))
+			if err != nil {
+				return nil, err
+			}
+			e.Thresholds = append(e.Thresholds, th)
+		}
+	}
+	if v, ok := f["newContracts"]; ok {
+		if e.NewContracts, err = d.boolean(v, fieldPath(path, "newContracts")); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := f["outsideTimeWindow"]; ok {
+		if e.OutsideTimeWindow, err = d.boolean(v, fieldPath(path, "outsideTimeWindow")); err != nil {
+			return nil, err
+		}
+	}
+	if e.Approvers, err = d.addresses(f["approvers"], fieldPath(path, "approvers")); err != nil {
+		return nil, err
+	}
+	if len(e.Approvers) == 0 {
+		return nil, fail(f["approvers"], fieldPath(path, "approvers"), "at least one approver required")
+	}
+	if v, ok := f["ttl"]; ok {
+		if e.TTL, err = d.duration(v, fieldPath(path, "ttl")); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (d *decoder) threshold(n *yaml.Node, path string) (ApprovalThreshold, error) {
+	var th ApprovalThreshold
+	f, err := d.fields(n, path, []string{"amount"}, "token", "quote")
+	if err != nil {
+		return th, err
+	}
+	if v, ok := f["quote"]; ok {
+		if _, ok := f["token"]; ok {
+			return th, fail(v, fieldPath(path, "quote"), "cannot be combined with token")
+		}
+		if th.Quote, err = d.quote(v, fieldPath(path, "quote")); err != nil {
+			return th, err
+		}
+		if th.Amount, err = d.quoteAmount(f["amount"], fieldPath(path, "amount"), th.Quote); err != nil {
+			return th, err
+		}
+	} else {
+		if _, ok := f["token"]; !ok {
+			return th, fail(n, path, "missing field %q", "token")
+		}
+		if th.Token, err = d.token(f["token"], fieldPath(path, "token"), true); err != nil {
+			return th, err
+		}
+		if th.Amount, err = d.amount(f["amount"], fieldPath(path, "amount"), th.Token); err != nil {
+			return th, err
+		}
+	}
+	if th.Amount.Sign() == 0 {
+		return th, fail(f["amount"], fieldPath(path, "amount"), "must be positive")
+	}
+	return th, nil
+}
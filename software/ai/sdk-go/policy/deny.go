@@ -0,0 +1,219 @@
+package policy
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+var payeeFunctions = []struct {
+	signature string
+	index     int
+}{
+	{"transfer(address,uint256)", 0},
+	{"transferFrom(address,address,uint256)", 1},
+}
+
+func (d *DenyList) match(contract common.Address, selector string) (int, string) {
+	level, rule := -1, ""
+	for _, c := range d.Contracts {
+		if c == contract && level < 1 {
+			level, rule = 1, "contract "+c.Hex()
+		}
+	}
+	for _, s := range d.Selectors {
+		if selector != "" && normalizeSelector(s) == selector && level < 1 {
+			level, rule = 1, "selector 0x"+selector
+		}
+	}
+	for _, perm := range d.Calls {
+		if perm.Contract != AnyContract && perm.Contract != contract {
+			continue
+		}
+		if s := normalizeSelector(perm.Selector); s != AnySelector && s != selector {
+			continue
+		}
+		if l := specificity(perm); l > level {
+			level, rule = l, describeCall(perm.Contract, normalizeSelector(perm.Selector))
+		}
+	}
+	return level, rule
+}
+
+func (d *DenyList) add(other *DenyList) {
+	for _, c := range other.Contracts {
+		d.Contracts = appendAddress(d.Contracts, c)
+	}
+	for _, s := range other.Selectors {
+		s = normalizeSelector(s)
+		found := false
+		for _, existing := range d.Selectors {
+			if existing == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			d.Selectors = append(d.Selectors, s)
+		}
+	}
+	for _, perm := range other.Calls {
+		d.addCall(perm)
+	}
+	for _, payee := range other.Payees {
+		d.Payees = appendAddress(d.Payees, payee)
+	}
+	for _, token := range other.Tokens {
+		d.Tokens = appendAddress(d.Tokens, token)
+	}
+}
+
+func (d *DenyList) addCall(perm Permission) {
+	perm.Selector = normalizeSelector(perm.Selector)
+	for _, existing := range d.Calls {
+		if existing == perm {
+			return
+		}
+	}
+	d.Calls = append(d.Calls, perm)
+}
+
+func appendAddress(addrs []common.Address, addr common.Address) []common.Address {
+	for _, existing := range addrs {
+		if existing == addr {
+			return addrs
+		}
+	}
+	return append(addrs, addr)
+}
+
+func specificity(perm Permission) int {
+	level := 0
+	if perm.Contract != AnyContract {
+		level++
+	}
+	if perm.Selector != AnySelector {
+		level++
+	}
+	return level
+}
+
+func describeCall(contract common.Address, selector string) string {
+	target := contract.Hex()
+	if contract == AnyContract {
+		target = "any contract"
+	}
+	switch selector {
+	case "":
+		return "call without selector on " + target
+	case AnySelector:
+		return "any function on " + target
+	}
+	return fmt.Sprintf("0x%s on %s", selector, target)
+}
+
+func checkDeniedCall(p *Policy, contract common.Address, selector string) (bool, string) {
+	for _, token := range p.Deny.Tokens {
+		if token == contract {
+			return false, fmt.Sprintf("token %s is denied", token.Hex())
+		}
+	}
+
+	level, rule := p.Deny.match(contract, selector)
+	if level < 0 {
+		return true, "no deny rule matches"
+	}
+	if p.Permissions != nil && p.Permissions.level(contract, selector) > level {
+		return true, fmt.Sprintf("%s is permitted over denied %s", describeCall(contract, selector), rule)
+	}
+	return false, rule + " is denied"
+}
+
+func evaluateDenyList(p *Policy, tx *Transaction) (bool, string) {
+	for _, token := range p.Deny.Tokens {
+		if token == tx.Token {
+			return false, fmt.Sprintf("token %s is denied", token.Hex())
+		}
+	}
+	for _, payee := range transactionPayees(tx) {
+		for _, denied := range p.Deny.Payees {
+			if denied == payee {
+				return false, fmt.Sprintf("payee %s is denied", payee.Hex())
+			}
+		}
+	}
+
+	selector := ""
+	if len(tx.Data) >= 4 {
+		selector = hex.EncodeToString(tx.Data[:4])
+	}
+	return checkDeniedCall(p, tx.To, selector)
+}
+
+func transactionPayees(tx *Transaction) []common.Address {
+	var payees []common.Address
+	if tx.Payee != (common.Address{}) {
+		payees = append(payees, tx.Payee)
+	}
+	if tx.Value != nil && tx.Value.Sign() > 0 {
+		payees = append(payees, tx.To)
+	}
+	for _, f := range payeeFunctions {
+		values, err := encoding.DecodeFunctionCall(f.signature, tx.Data)
+		if err != nil {
+			continue
+		}
+		if addr, ok := values[f.index].(common.Address); ok {
+			payees = append(payees, addr)
+		}
+	}
+	return payees
+}
+
+func composeDenyLists(policies []*Policy) *DenyList {
+	var composed *DenyList
+	for i, p := range policies {
+		if p == nil || p.Deny == nil {
+			continue
+		}
+		if composed == nil {
+			composed = &DenyList{}
+		}
+		composed.add(p.Deny)
+
+		for j, other := range policies {
+			if j == i || other == nil || other.Permissions == nil {
+				continue
+			}
+			for _, perm := range other.Permissions.Permissions {
+				selector := normalizeSelector(perm.Selector)
+				level, _ := p.Deny.match(perm.Contract, selector)
+				if level < 0 || level >= specificity(perm) {
+					continue
+				}
+				if p.Permissions != nil && p.Permissions.level(perm.Contract, selector) > level {
+					continue
+				}
+				composed.addCall(perm)
+			}
+		}
+	}
+	return composed
+}
+
+func validateDenyList(d *DenyList) error {
+	for _, s := range d.Selectors {
+		if !isSelector(s) {
+			return errors.New("invalid deny selector")
+		}
+	}
+	for _, perm := range d.Calls {
+		if perm.Selector != AnySelector && !isSelector(perm.Selector) {
+			return errors.New("invalid deny selector")
+		}
+	}
+	return nil
+}
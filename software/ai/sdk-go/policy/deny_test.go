@@ -0,0 +1,188 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	drainer = common.HexToAddress("0xdddddddddddddddddddddddddddddddddddddddd")
+	router  = common.HexToAddress("0x2626664c2603336E57B271c5C0b26F421741e481")
+)
+
+func TestEvaluateDenyList(t *testing.T) {
+	approveSig := "approve(address,uint256)"
+	p := &Policy{
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc, weth, drainer, router}),
+		Permissions: NewPermissionList(
+			Permit(AnyContract, AnySelector),
+			Permit(router, approveSig),
+			Permit(drainer, AnySelector),
+		),
+		Deny: &DenyList{
+			Contracts: []common.Address{drainer},
+			Selectors: []string{Selector(approveSig)},
+			Calls:     []Permission{Permit(router, transferSig)},
+			Payees:    []common.Address{payee},
+			Tokens:    []common.Address{degen},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		tx     *Transaction
+		passed bool
+		reason string
+	}{
+		{
+			"no deny rule matches",
+			&Transaction{To: usdc, Data: mustCalldata(t, transferSig, agentSA, big.NewInt(1))},
+			true,
+			"no deny rule matches",
+		},
+		{
+			"denied contract overrides allowlist",
+			&Transaction{To: drainer, Data: mustCalldata(t, transferSig, agentSA, big.NewInt(1))},
+			false,
+			"contract 0xDDdDddDdDdddDDddDDddDDDDdDdDDdDDdDDDDDDd is denied",
+		},
+		{
+			"denied selector",
+			&Transaction{To: usdc, Data: mustCalldata(t, approveSig, agentSA, big.NewInt(1))},
+			false,
+			"selector 0x095ea7b3 is denied",
+		},
+		{
+			"specific permission overrides denied selector",
+			&Transaction{To: router, Data: mustCalldata(t, approveSig, agentSA, big.NewInt(1))},
+			true,
+			"0x095ea7b3 on 0x2626664c2603336E57B271c5C0b26F421741e481 is permitted over denied selector 0x095ea7b3",
+		},
+		{
+			"denied call",
+			&Transaction{To: router, Data: mustCalldata(t, transferSig, agentSA, big.NewInt(1))},
+			false,
+			"0xa9059cbb on 0x2626664c2603336E57B271c5C0b26F421741e481 is denied",
+		},
+		{
+			"denied erc20 recipient",
+			&Transaction{To: usdc, Data: mustCalldata(t, transferSig, payee, big.NewInt(1))},
+			false,
+			"payee 0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa is denied",
+		},
+		{
+			"denied native recipient",
+			&Transaction{To: payee, Value: big.NewInt(1)},
+			false,
+			"payee 0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa is denied",
+		},
+		{
+			"denied explicit payee",
+			&Transaction{To: usdc, Payee: payee},
+			false,
+			"payee 0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa is denied",
+		},
+		{
+			"denied token",
+			&Transaction{To: weth, Token: degen, Amount: big.NewInt(1)},
+			false,
+			"token 0x4ed4E862860beD51a9570b96d89aF5E1B0Efefed is denied",
+		},
+		{
+			"denied token contract",
+			&Transaction{To: degen, Data: mustCalldata(t, transferSig, agentSA, big.NewInt(1))},
+			false,
+			"token 0x4ed4E862860beD51a9570b96d89aF5E1B0Efefed is denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Evaluate(context.Background(), p, tt.tx)
+			require.NoError(t, err)
+			require.NotEmpty(t, d.Results)
+			assert.Equal(t, RuleDenyList, d.Results[0].Rule)
+			assert.Equal(t, tt.passed, d.Results[0].Passed)
+			assert.Equal(t, tt.reason, d.Results[0].Reason)
+		})
+	}
+}
+
+func TestDenyPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		deny     *DenyList
+		perms    *PermissionList
+		contract common.Address
+		want     bool
+	}{
+		{"equal specificity deny wins", &DenyList{Contracts: []common.Address{drainer}}, NewPermissionList(Permit(drainer, AnySelector)), drainer, false},
+		{"pair permission overrides contract deny", &DenyList{Contracts: []common.Address{drainer}}, NewPermissionList(Permit(drainer, transferSig)), drainer, true},
+		{"pair deny beats pair permission", &DenyList{Calls: []Permission{Permit(drainer, transferSig)}}, NewPermissionList(Permit(drainer, transferSig)), drainer, false},
+		{"contract permission overrides deny all", &DenyList{Calls: []Permission{Permit(AnyContract, AnySelector)}}, NewPermissionList(Permit(usdc, AnySelector)), usdc, true},
+		{"deny all", &DenyList{Calls: []Permission{Permit(AnyContract, AnySelector)}}, NewPermissionList(Permit(usdc, AnySelector)), weth, false},
+		{"wildcard permission never overrides", &DenyList{Selectors: []string{Selector(transferSig)}}, NewPermissionList(Permit(AnyContract, AnySelector)), usdc, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Policy{Deny: tt.deny, Permissions: tt.perms}
+			assert.Equal(t, tt.want, IsAllowed(p, tt.contract, transferSig))
+		})
+	}
+}
+
+func TestComposeDenyLists(t *testing.T) {
+	t.Run("deny survives broad allowlist", func(t *testing.T) {
+		blocked := &Policy{Deny: &DenyList{Contracts: []common.Address{drainer}}}
+		broad := &Policy{ContractAllowlist: NewContractAllowlist([]common.Address{usdc, drainer})}
+
+		composed := ComposePolicy(broad, blocked)
+		require.NotNil(t, composed.Deny)
+		assert.Equal(t, []common.Address{drainer}, composed.Deny.Contracts)
+		assert.False(t, IsAllowed(composed, drainer, transferSig))
+		assert.True(t, IsAllowed(composed, usdc, transferSig))
+	})
+
+	t.Run("permission does not override another policy's deny", func(t *testing.T) {
+		blocked := &Policy{Deny: &DenyList{Contracts: []common.Address{drainer}}}
+		exception := &Policy{Permissions: NewPermissionList(Permit(drainer, transferSig), Permit(usdc, AnySelector))}
+
+		assert.True(t, IsAllowed(&Policy{Deny: blocked.Deny, Permissions: exception.Permissions}, drainer, transferSig))
+
+		composed := ComposePolicy(blocked, exception)
+		assert.Equal(t, []Permission{Permit(drainer, transferSig)}, composed.Deny.Calls)
+		assert.False(t, IsAllowed(composed, drainer, transferSig))
+	})
+
+	t.Run("own exception is kept", func(t *testing.T) {
+		own := &Policy{
+			Deny:        &DenyList{Contracts: []common.Address{drainer}},
+			Permissions: NewPermissionList(Permit(drainer, transferSig)),
+		}
+		other := &Policy{Permissions: NewPermissionList(Permit(drainer, transferSig))}
+
+		composed := ComposePolicy(own, other)
+		assert.Empty(t, composed.Deny.Calls)
+		assert.True(t, IsAllowed(composed, drainer, transferSig))
+	})
+
+	t.Run("lists are merged without duplicates", func(t *testing.T) {
+		a := &Policy{Deny: &DenyList{Payees: []common.Address{payee}, Selectors: []string{"0xA9059CBB"}}}
+		b := &Policy{Deny: &DenyList{Payees: []common.Address{payee}, Tokens: []common.Address{degen}, Selectors: []string{Selector(transferSig)}}}
+
+		composed := ComposePolicy(a, b)
+		assert.Equal(t, []common.Address{payee}, composed.Deny.Payees)
+		assert.Equal(t, []common.Address{degen}, composed.Deny.Tokens)
+		assert.Equal(t, []string{"a9059cbb"}, composed.Deny.Selectors)
+	})
+
+	t.Run("no deny lists", func(t *testing.T) {
+		assert.Nil(t, ComposePolicy(&Policy{}, &Policy{}).Deny)
+	})
+}
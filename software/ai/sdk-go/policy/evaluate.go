@@ -0,0 +1,612 @@
+package policy
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/oracle"
+)
+
+type Rule string
+
+const (
+	RuleContractAllowlist Rule = "contract_allowlist"
+	RuleFunctionAllowlist Rule = "function_allowlist"
+	RuleDenyList          Rule = "deny_list"
+	RulePermission        Rule = "permission"
+	RuleCalldata          Rule = "calldata_constraint"
+	RuleMaxValue          Rule = "max_value"
+	RuleSpendingLimit     Rule = "spending_limit"
+	RuleTimeWindow        Rule = "time_window"
+	RuleRateLimit         Rule = "rate_limit"
+	RuleApprovalThreshold Rule = "approval_threshold"
+)
+
+type Transaction struct {
+	To      common.Address
+	Value   *big.Int
+	Data    []byte
+	Token   common.Address
+	Amount  *big.Int
+	Payee   common.Address
+	AgentID string
+	Time    time.Time
+	Prices  []oracle.Price
+
+	priceErrs map[priceKey]error
+}
+
+type priceKey struct {
+	token common.Address
+	quote string
+}
+
+func (tx *Transaction) price(token common.Address, quote string) *oracle.Price {
+	for i := range tx.Prices {
+		if tx.Prices[i].Token == token && tx.Prices[i].Quote == quote {
+			return &tx.Prices[i]
+		}
+	}
+	return nil
+}
+
+type tokenSpend struct {
+	token  common.Address
+	amount *big.Int
+}
+
+func (tx *Transaction) spends() []tokenSpend {
+	var result []tokenSpend
+	if tx.Value != nil && tx.Value.Sign() != 0 {
+		result = append(result, tokenSpend{common.Address{}, tx.Value})
+	}
+	if tx.Token != (common.Address{}) && tx.Amount != nil && tx.Amount.Sign() != 0 {
+		result = append(result, tokenSpend{tx.Token, tx.Amount})
+	}
+	return result
+}
+
+type RuleResult struct {
+	Rule     Rule
+	Passed   bool
+	Reason   string
+	Escalate bool
+}
+
+type Decision struct {
+	PolicyID string
+	Allowed  bool
+	Results  []RuleResult
+	Approval *ApprovalRequest
+}
+
+func (d *Decision) Failures() []RuleResult {
+	var failures []RuleResult
+	for _, r := range d.Results {
+		if !r.Passed {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
+
+func (d *Decision) NeedsApproval() bool {
+	failures := d.Failures()
+	if d.Allowed || len(failures) == 0 {
+		return false
+	}
+	for _, r := range failures {
+		if !r.Escalate {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	failures := d.Failures()
+	reasons := make([]string, 0, len(failures))
+	for _, r := range failures {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r.Rule, r.Reason))
+	}
+	if d.NeedsApproval() {
+		return &ApprovalError{Request: d.Approval, Reasons: reasons}
+	}
+	return errors.New("policy denied: " + strings.Join(reasons, "; "))
+}
+
+func (d *Decision) add(rule Rule, passed bool, reason string) {
+	d.Results = append(d.Results, RuleResult{Rule: rule, Passed: passed, Reason: reason})
+	if !passed {
+		d.Allowed = false
+	}
+}
+
+func (d *Decision) replaceEach(rule Rule, result func() (bool, string)) {
+	d.Allowed = true
+	for i := range d.Results {
+		if d.Results[i].Rule == rule {
+			d.Results[i].Passed, d.Results[i].Reason = result()
+		}
+		if !d.Results[i].Passed {
+			d.Allowed = false
+		}
+	}
+}
+
+func (d *Decision) replace(rule Rule, passed bool, reason string) {
+	d.replaceEach(rule, func() (bool, string) {
+		return passed, reason
+	})
+}
+
+func Evaluate(ctx context.Context, p *Policy, tx *Transaction) (*Decision, error) {
+	return EvaluateWithLimiter(ctx, p, tx, nil)
+}
+
+func EvaluateWithLimiter(ctx context.Context, p *Policy, tx *Transaction, limiter *RateLimiter) (*Decision, error) {
+	if p == nil {
+		return nil, errors.New("nil policy")
+	}
+	if tx == nil {
+		return nil, errors.New("nil transaction")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := tx.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	d := &Decision{PolicyID: p.ID, Allowed: true}
+	add := d.add
+	esc := p.Escalation
+	if esc == nil {
+		esc = &Escalation{}
+	}
+	escalate := func(rule Rule, reason string) {
+		d.Results = append(d.Results, RuleResult{Rule: rule, Reason: reason, Escalate: true})
+		d.Allowed = false
+	}
+
+	if p.Deny != nil {
+		passed, reason := evaluateDenyList(p, tx)
+		add(RuleDenyList, passed, reason)
+	}
+
+	if p.ContractAllowlist != nil {
+		switch {
+		case p.ContractAllowlist.Contracts[tx.To]:
+			add(RuleContractAllowlist, true, fmt.Sprintf("contract %s is allowed", tx.To.Hex()))
+		case esc.NewContracts:
+			escalate(RuleContractAllowlist, fmt.Sprintf("contract %s is not allowed", tx.To.Hex()))
+		default:
+			add(RuleContractAllowlist, false, fmt.Sprintf("contract %s is not allowed", tx.To.Hex()))
+		}
+	}
+
+	if p.FunctionAllowlist != nil {
+		if len(tx.Data) < 4 {
+			add(RuleFunctionAllowlist, false, "transaction has no function selector")
+		} else {
+			selector := hex.EncodeToString(tx.Data[:4])
+			if p.FunctionAllowlist.Functions[selector] {
+				add(RuleFunctionAllowlist, true, fmt.Sprintf("selector 0x%s is allowed", selector))
+			} else {
+				add(RuleFunctionAllowlist, false, fmt.Sprintf("selector 0x%s is not allowed", selector))
+			}
+		}
+	}
+
+	if p.Permissions != nil {
+		selector := ""
+		if len(tx.Data) >= 4 {
+			selector = hex.EncodeToString(tx.Data[:4])
+		}
+		call := describeCall(tx.To, selector)
+		if p.Permissions.Allows(tx.To, selector) {
+			add(RulePermission, true, call+" is permitted")
+		} else {
+			add(RulePermission, false, call+" is not permitted")
+		}
+	}
+
+	for i := range p.CalldataConstraints {
+		c := &p.CalldataConstraints[i]
+		if !c.Matches(tx.To, tx.Data) {
+			continue
+		}
+		if err := CheckCalldata(c, tx.Data); err != nil {
+			add(RuleCalldata, false, fmt.Sprintf("%s: %s", c.Signature, err))
+		} else {
+			add(RuleCalldata, true, fmt.Sprintf("%s arguments satisfy constraints", c.Signature))
+		}
+	}
+
+	if p.MaxValuePerTx != nil {
+		passed, reason := evaluateMaxValue(p.MaxValuePerTx, tx)
+		add(RuleMaxValue, passed, reason)
+	}
+
+	for i := range p.SpendingLimits {
+		sl := &p.SpendingLimits[i]
+		passed, reason := evaluateSpendingLimit(sl, tx, periodSpent(sl, now))
+		add(RuleSpendingLimit, passed, reason)
+	}
+
+	for i := range esc.Thresholds {
+		if passed, reason := evaluateThreshold(&esc.Thresholds[i], tx); passed {
+			add(RuleApprovalThreshold, true, reason)
+		} else {
+			escalate(RuleApprovalThreshold, reason)
+		}
+	}
+
+	if p.TimeWindow != nil {
+		passed, reason := evaluateTimeWindow(p.TimeWindow, now)
+		if !passed && esc.OutsideTimeWindow {
+			escalate(RuleTimeWindow, reason)
+		} else {
+			add(RuleTimeWindow, passed, reason)
+		}
+	}
+
+	if p.RateLimit != nil {
+		passed, reason, err := evaluateRateLimit(limiter, p, tx)
+		if err != nil {
+			return nil, err
+		}
+		add(RuleRateLimit, passed, reason)
+	}
+
+	return d, nil
+}
+
+func Record(p *Policy, tx *Transaction) error {
+	if p == nil {
+		return errors.New("nil policy")
+	}
+	if tx == nil {
+		return errors.New("nil transaction")
+	}
+
+	now := tx.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for i := range p.SpendingLimits {
+		sl := &p.SpendingLimits[i]
+		amount, err := spendAmount(sl, tx)
+		if err != nil {
+			return err
+		}
+		if amount == nil || amount.Sign() <= 0 {
+			continue
+		}
+		addSpend(sl, amount, now)
+	}
+	return nil
+}
+
+func (s *PolicyService) Evaluate(ctx context.Context, id string, tx *Transaction) (*Decision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("policy not found")
+	}
+	return s.evaluate(ctx, p, tx)
+}
+
+func (s *PolicyService) evaluate(ctx context.Context, p *Policy, tx *Transaction) (*Decision, error) {
+	if err := s.price(ctx, p, tx); err != nil {
+		return nil, err
+	}
+	d, err := EvaluateWithLimiter(ctx, p, tx, s.limiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.spending != nil && len(p.SpendingLimits) > 0 {
+		spent, err := s.spending.spent(p.ID, p.SpendingLimits)
+		if err != nil {
+			return nil, err
+		}
+		i := 0
+		d.replaceEach(RuleSpendingLimit, func() (bool, string) {
+			passed, reason := evaluateSpendingLimit(&p.SpendingLimits[i], tx, spent[i])
+			i++
+			return passed, reason
+		})
+	}
+	return d, nil
+}
+
+func (s *PolicyService) Record(id string, tx *Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("policy not found")
+	}
+	if tx == nil {
+		return errors.New("nil transaction")
+	}
+	if err := s.price(context.Background(), p, tx); err != nil {
+		return err
+	}
+	return s.record(p, tx)
+}
+
+func (s *PolicyService) record(p *Policy, tx *Transaction) error {
+	if err := s.allowRate(p, tx); err != nil {
+		return err
+	}
+	if s.spending != nil {
+		return s.spending.Spend(p.ID, p.SpendingLimits, tx)
+	}
+	return s.recordState(p, tx)
+}
+
+func (s *PolicyService) allowRate(p *Policy, tx *Transaction) error {
+	if p.RateLimit != nil {
+		if _, err := s.limiter.Allow(RateLimitKey{PolicyID: p.ID, AgentID: tx.AgentID}, p.RateLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PolicyService) recordState(p *Policy, tx *Transaction) error {
+	if err := Record(p, tx); err != nil {
+		return err
+	}
+	return s.store.Put(p)
+}
+
+func (s *PolicyService) Reserve(ctx context.Context, id string, tx *Transaction) (*Decision, *Reservation, error) {
+	if s.spending == nil {
+		return nil, nil, errors.New("spending tracker not configured")
+	}
+	if tx == nil {
+		return nil, nil, errors.New("nil transaction")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok, err := s.store.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, errors.New("policy not found")
+	}
+	d, err := s.evaluate(ctx, p, tx)
+	if err != nil || !d.Allowed {
+		return d, nil, err
+	}
+	r, err := s.reserve(p, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, r, nil
+}
+
+func (s *PolicyService) reserve(p *Policy, tx *Transaction) (*Reservation, error) {
+	r, err := s.spending.Reserve(p.ID, p.SpendingLimits, tx)
+	if err != nil {
+		return nil, err
+	}
+	if p.RateLimit != nil {
+		key := RateLimitKey{PolicyID: p.ID, AgentID: tx.AgentID}
+		_, at, err := s.limiter.allow(key, p.RateLimit)
+		if err != nil {
+			if err := s.spending.Release(r); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+		r.rate = &rateHold{key: key, limit: *p.RateLimit, at: at}
+	}
+	return r, nil
+}
+
+func (s *PolicyService) Commit(r *Reservation) error {
+	if s.spending == nil {
+		return errors.New("spending tracker not configured")
+	}
+
+	if r == nil {
+		return errors.New("nil reservation")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok, err := s.store.Get(r.PolicyID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("policy not found")
+	}
+	overrun := s.spending.Commit(r)
+	if overrun != nil && !errors.Is(overrun, ErrSpendingOverrun) {
+		return overrun
+	}
+	if r.approval != "" {
+		if err := s.approvals.execute(r.approval); err != nil {
+			return err
+		}
+	}
+	return overrun
+}
+
+func (s *PolicyService) Release(r *Reservation) error {
+	if s.spending == nil {
+		return errors.New("spending tracker not configured")
+	}
+	return s.release(r)
+}
+
+func (s *PolicyService) release(r *Reservation) error {
+	if err := s.spending.Release(r); err != nil {
+		return err
+	}
+	if h := r.rate; h != nil {
+		if err := s.limiter.refund(h.key, &h.limit, h.at); err != nil {
+			return err
+		}
+	}
+	if r.approval != "" {
+		return s.approvals.unhold(r.approval)
+	}
+	return nil
+}
+
+func spendAmount(sl *SpendingLimit, tx *Transaction) (*big.Int, error) {
+	if sl.Quote != "" {
+		return quoteAmount(sl.Quote, tx)
+	}
+	if sl.Token == (common.Address{}) {
+		return tx.Value, nil
+	}
+	if sl.Token == tx.Token {
+		return tx.Amount, nil
+	}
+	return nil, nil
+}
+
+func quoteAmount(quote string, tx *Transaction) (*big.Int, error) {
+	total := new(big.Int)
+	for _, s := range tx.spends() {
+		if s.amount.Sign() < 0 {
+			return s.amount, nil
+		}
+		p := tx.price(s.token, quote)
+		if p == nil {
+			if err := tx.priceErrs[priceKey{s.token, quote}]; err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("no %s price for %s", quote, tokenName(s.token))
+		}
+		total.Add(total, p.Value(s.amount))
+	}
+	return total, nil
+}
+
+func tokenName(token common.Address) string {
+	if token == (common.Address{}) {
+		return "native"
+	}
+	return token.Hex()
+}
+
+func evaluateSpendingLimit(sl *SpendingLimit, tx *Transaction, spent *big.Int) (bool, string) {
+	token := tokenName(sl.Token)
+	if sl.Quote != "" {
+		token = sl.Quote
+	}
+
+	amount, err := spendAmount(sl, tx)
+	if err != nil {
+		return false, err.Error()
+	}
+	if amount == nil || amount.Sign() == 0 {
+		return true, fmt.Sprintf("no %s spend", token)
+	}
+	if amount.Sign() < 0 {
+		return false, fmt.Sprintf("negative %s amount", token)
+	}
+	if sl.MaxAmount == nil {
+		return false, fmt.Sprintf("%s limit has no maximum", token)
+	}
+
+	total := new(big.Int).Add(spent, amount)
+	if total.Cmp(sl.MaxAmount) > 0 {
+		return false, fmt.Sprintf("%s spend %s would bring period total to %s, above limit %s", token, amount, total, sl.MaxAmount)
+	}
+	return true, fmt.Sprintf("%s spend %s brings period total to %s of %s", token, amount, total, sl.MaxAmount)
+}
+
+func evaluateThreshold(th *ApprovalThreshold, tx *Transaction) (bool, string) {
+	token := tokenName(th.Token)
+	if th.Quote != "" {
+		token = th.Quote
+	}
+
+	amount, err := spendAmount(&SpendingLimit{Token: th.Token, Quote: th.Quote}, tx)
+	if err != nil {
+		return false, err.Error()
+	}
+	if amount == nil || amount.Sign() == 0 {
+		return true, fmt.Sprintf("no %s spend", token)
+	}
+	if amount.Sign() < 0 {
+		return false, fmt.Sprintf("negative %s amount", token)
+	}
+	if amount.Cmp(th.Amount) > 0 {
+		return false, fmt.Sprintf("%s amount %s is above approval threshold %s", token, amount, th.Amount)
+	}
+	return true, fmt.Sprintf("%s amount %s is within approval threshold %s", token, amount, th.Amount)
+}
+
+func evaluateMaxValue(max *big.Int, tx *Transaction) (bool, string) {
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	if value.Cmp(max) > 0 {
+		return false, fmt.Sprintf("value %s is above per-transaction maximum %s", value, max)
+	}
+	return true, fmt.Sprintf("value %s is within per-transaction maximum %s", value, max)
+}
+
+func evaluateTimeWindow(tw *TimeWindow, now time.Time) (bool, string) {
+	if err := CheckTimeWindow(tw, now); err != nil {
+		loc, lerr := loadLocation(tw.Location)
+		if lerr != nil {
+			return false, err.Error()
+		}
+		return false, fmt.Sprintf("%s at %s", err, now.In(loc).Format("Mon 2006-01-02 15:04 MST"))
+	}
+	return true, "within time window"
+}
+
+func evaluateRateLimit(limiter *RateLimiter, p *Policy, tx *Transaction) (bool, string, error) {
+	if limiter == nil {
+		if p.RateLimit.MaxCalls == 0 || p.RateLimit.Period <= 0 {
+			return false, "", errors.New("invalid rate limit")
+		}
+		return true, "not enforced: calls are only counted by a RateLimiter", nil
+	}
+	status, err := limiter.Check(RateLimitKey{PolicyID: p.ID, AgentID: tx.AgentID}, p.RateLimit)
+	if err != nil {
+		return false, "", err
+	}
+	if !status.Allowed {
+		return false, fmt.Sprintf("rate limit exceeded until %s", status.RetryAt.Format(time.RFC3339)), nil
+	}
+	return true, fmt.Sprintf("%d of %d calls remaining", status.Remaining, p.RateLimit.MaxCalls), nil
+}
@@ -0,0 +1,347 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	evalContract = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	evalToken    = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	evalTime     = time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+)
+
+func transferCalldata() []byte {
+	return crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+}
+
+func evalPolicy() *Policy {
+	return &Policy{
+		ID:                "p1",
+		ContractAllowlist: NewContractAllowlist([]common.Address{evalContract}),
+		FunctionAllowlist: NewFunctionAllowlist([]string{"transfer(address,uint256)"}),
+		SpendingLimits: []SpendingLimit{
+			{Token: evalToken, MaxAmount: big.NewInt(1000), Spent: big.NewInt(0), Period: time.Hour, ResetAt: evalTime.Add(time.Hour)},
+		},
+		TimeWindow: &TimeWindow{
+			Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			Hours: [2]int{9, 17},
+		},
+		RateLimit: &RateLimit{MaxCalls: 2, Period: time.Hour},
+	}
+}
+
+func evalTx() *Transaction {
+	return &Transaction{
+		To:     evalContract,
+		Data:   transferCalldata(),
+		Token:  evalToken,
+		Amount: big.NewInt(500),
+		Time:   evalTime,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("all rules pass", func(t *testing.T) {
+		d, err := EvaluateWithLimiter(ctx, evalPolicy(), evalTx(), NewRateLimiter())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, "p1", d.PolicyID)
+		assert.Empty(t, d.Failures())
+		assert.NoError(t, d.Err())
+
+		rules := make([]Rule, 0, len(d.Results))
+		for _, r := range d.Results {
+			rules = append(rules, r.Rule)
+			assert.True(t, r.Passed)
+			assert.NotEmpty(t, r.Reason)
+		}
+		assert.Equal(t, []Rule{
+			RuleContractAllowlist,
+			RuleFunctionAllowlist,
+			RuleSpendingLimit,
+			RuleTimeWindow,
+			RuleRateLimit,
+		}, rules)
+	})
+
+	t.Run("rate limit is not enforced without a limiter", func(t *testing.T) {
+		d, err := Evaluate(ctx, evalPolicy(), evalTx())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		result := d.Results[len(d.Results)-1]
+		assert.Equal(t, RuleRateLimit, result.Rule)
+		assert.True(t, result.Passed)
+		assert.Equal(t, "not enforced: calls are only counted by a RateLimiter", result.Reason)
+
+		p := evalPolicy()
+		p.RateLimit.MaxCalls = 0
+		_, err = Evaluate(ctx, p, evalTx())
+		assert.EqualError(t, err, "invalid rate limit")
+	})
+
+	t.Run("reports every failure", func(t *testing.T) {
+		tx := evalTx()
+		tx.To = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		tx.Data = crypto.Keccak256([]byte("approve(address,uint256)"))[:4]
+		tx.Amount = big.NewInt(1001)
+		tx.Time = time.Date(2026, 3, 7, 20, 0, 0, 0, time.UTC)
+
+		p := evalPolicy()
+		p.SpendingLimits[0].ResetAt = tx.Time.Add(time.Hour)
+		limiter := NewRateLimiter()
+		for i := 0; i < 2; i++ {
+			_, err := limiter.Allow(RateLimitKey{PolicyID: p.ID, AgentID: tx.AgentID}, p.RateLimit)
+			require.NoError(t, err)
+		}
+
+		d, err := EvaluateWithLimiter(ctx, p, tx, limiter)
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		require.Len(t, d.Failures(), 5)
+		for _, r := range d.Results {
+			assert.False(t, r.Passed, r.Rule)
+		}
+		assert.Contains(t, d.Err().Error(), "policy denied: contract_allowlist: contract 0x3333333333333333333333333333333333333333 is not allowed")
+		assert.Contains(t, d.Err().Error(), "time_window: outside allowed schedule at Sat 2026-03-07 20:00 UTC")
+	})
+
+	t.Run("empty policy allows", func(t *testing.T) {
+		d, err := Evaluate(ctx, &Policy{}, evalTx())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Empty(t, d.Results)
+	})
+
+	t.Run("missing selector", func(t *testing.T) {
+		tx := evalTx()
+		tx.Data = nil
+		d, err := Evaluate(ctx, &Policy{FunctionAllowlist: NewFunctionAllowlist([]string{"transfer(address,uint256)"})}, tx)
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, "transaction has no function selector", d.Results[0].Reason)
+	})
+
+	t.Run("does not mutate policy", func(t *testing.T) {
+		p := evalPolicy()
+		p.SpendingLimits[0].Spent = big.NewInt(900)
+		p.SpendingLimits[0].ResetAt = evalTime.Add(-time.Minute)
+
+		d, err := EvaluateWithLimiter(ctx, p, evalTx(), NewRateLimiter())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, big.NewInt(900), p.SpendingLimits[0].Spent)
+	})
+
+	t.Run("nil policy", func(t *testing.T) {
+		_, err := Evaluate(ctx, nil, evalTx())
+		assert.EqualError(t, err, "nil policy")
+	})
+
+	t.Run("nil transaction", func(t *testing.T) {
+		_, err := Evaluate(ctx, evalPolicy(), nil)
+		assert.EqualError(t, err, "nil transaction")
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := Evaluate(cctx, evalPolicy(), evalTx())
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestEvaluateSpendingLimit(t *testing.T) {
+	native := SpendingLimit{MaxAmount: big.NewInt(100), Spent: big.NewInt(40), Period: time.Hour, ResetAt: evalTime.Add(time.Hour)}
+	token := SpendingLimit{Token: evalToken, MaxAmount: big.NewInt(100), Spent: big.NewInt(0), Period: time.Hour, ResetAt: evalTime.Add(time.Hour)}
+
+	tests := []struct {
+		name   string
+		limit  SpendingLimit
+		tx     *Transaction
+		passed bool
+	}{
+		{"native within limit", native, &Transaction{Value: big.NewInt(60), Time: evalTime}, true},
+		{"native over limit", native, &Transaction{Value: big.NewInt(61), Time: evalTime}, false},
+		{"native no value", native, &Transaction{Time: evalTime}, true},
+		{"token uses amount", token, &Transaction{Token: evalToken, Amount: big.NewInt(101), Value: big.NewInt(1), Time: evalTime}, false},
+		{"other token not applicable", token, &Transaction{Token: common.HexToAddress("0x9"), Amount: big.NewInt(101), Time: evalTime}, true},
+		{"negative amount", token, &Transaction{Token: evalToken, Amount: big.NewInt(-1), Time: evalTime}, false},
+		{"period expired", native, &Transaction{Value: big.NewInt(100), Time: evalTime.Add(2 * time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Evaluate(context.Background(), &Policy{SpendingLimits: []SpendingLimit{tt.limit}}, tt.tx)
+			require.NoError(t, err)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, RuleSpendingLimit, d.Results[0].Rule)
+			assert.Equal(t, tt.passed, d.Results[0].Passed, d.Results[0].Reason)
+		})
+	}
+}
+
+func TestEvaluateMaxValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    *big.Int
+		value  *big.Int
+		passed bool
+	}{
+		{"within maximum", big.NewInt(100), big.NewInt(100), true},
+		{"above maximum", big.NewInt(100), big.NewInt(101), false},
+		{"no value", big.NewInt(100), nil, true},
+		{"zero maximum blocks value", big.NewInt(0), big.NewInt(1), false},
+		{"zero maximum allows no value", big.NewInt(0), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Evaluate(context.Background(), &Policy{MaxValuePerTx: tt.max}, &Transaction{Value: tt.value, Time: evalTime})
+			require.NoError(t, err)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, RuleMaxValue, d.Results[0].Rule)
+			assert.Equal(t, tt.passed, d.Results[0].Passed, d.Results[0].Reason)
+		})
+	}
+}
+
+func TestEvaluateTimeWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		window TimeWindow
+		at     time.Time
+		passed bool
+	}{
+		{"no restriction", TimeWindow{}, evalTime, true},
+		{"inside hours", TimeWindow{Hours: [2]int{9, 17}}, evalTime, true},
+		{"end hour inclusive", TimeWindow{Hours: [2]int{9, 10}}, evalTime, true},
+		{"outside hours", TimeWindow{Hours: [2]int{11, 17}}, evalTime, false},
+		{"allowed day", TimeWindow{Days: []time.Weekday{time.Wednesday}}, evalTime, true},
+		{"disallowed day", TimeWindow{Days: []time.Weekday{time.Sunday}}, evalTime, false},
+		{"before start", TimeWindow{Start: evalTime.Add(time.Minute)}, evalTime, false},
+		{"after end", TimeWindow{End: evalTime.Add(-time.Minute)}, evalTime, false},
+		{"non-utc time", TimeWindow{Hours: [2]int{9, 17}}, evalTime.In(time.FixedZone("X", -10*3600)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := tt.window
+			d, err := Evaluate(context.Background(), &Policy{TimeWindow: &window}, &Transaction{Time: tt.at})
+			require.NoError(t, err)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, tt.passed, d.Results[0].Passed, d.Results[0].Reason)
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("updates counters", func(t *testing.T) {
+		p := evalPolicy()
+		require.NoError(t, Record(p, evalTx()))
+		assert.Equal(t, big.NewInt(500), p.SpendingLimits[0].Spent)
+
+		require.NoError(t, Record(p, evalTx()))
+		d, err := EvaluateWithLimiter(context.Background(), p, evalTx(), NewRateLimiter())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Len(t, d.Failures(), 1)
+	})
+
+	t.Run("resets expired periods", func(t *testing.T) {
+		p := evalPolicy()
+		p.SpendingLimits[0].Spent = big.NewInt(900)
+		tx := evalTx()
+		tx.Time = evalTime.Add(2 * time.Hour)
+
+		require.NoError(t, Record(p, tx))
+		assert.Equal(t, big.NewInt(500), p.SpendingLimits[0].Spent)
+		assert.Equal(t, tx.Time.Add(time.Hour), p.SpendingLimits[0].ResetAt)
+	})
+
+	t.Run("nil policy", func(t *testing.T) {
+		assert.EqualError(t, Record(nil, evalTx()), "nil policy")
+	})
+
+	t.Run("nil transaction", func(t *testing.T) {
+		assert.EqualError(t, Record(evalPolicy(), nil), "nil transaction")
+	})
+}
+
+func TestPolicyServiceEvaluate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("persists recorded usage", func(t *testing.T) {
+		store, err := NewFileStore(t.TempDir() + "/policies.json")
+		require.NoError(t, err)
+		svc := NewPolicyService(WithStore(store))
+
+		created, err := svc.CreatePolicy(evalPolicy())
+		require.NoError(t, err)
+
+		d, err := svc.Evaluate(ctx, created.ID, evalTx())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+
+		require.NoError(t, svc.Record(created.ID, evalTx()))
+		require.NoError(t, svc.Record(created.ID, evalTx()))
+
+		d, err = svc.Evaluate(ctx, created.ID, evalTx())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Len(t, d.Failures(), 2)
+
+		got, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1000), got.SpendingLimits[0].Spent)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := NewPolicyService()
+		_, err := svc.Evaluate(ctx, "missing", evalTx())
+		assert.EqualError(t, err, "policy not found")
+		assert.EqualError(t, svc.Record("missing", evalTx()), "policy not found")
+	})
+}
+
+func TestEvaluatePermissions(t *testing.T) {
+	router := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	p := &Policy{Permissions: NewPermissionList(
+		Permit(router, "approve(address,uint256)"),
+		Permit(evalContract, AnySelector),
+	)}
+	approve := crypto.Keccak256([]byte("approve(address,uint256)"))[:4]
+
+	tests := []struct {
+		name   string
+		tx     *Transaction
+		passed bool
+		reason string
+	}{
+		{"pair permitted", &Transaction{To: router, Data: approve}, true, "0x095ea7b3 on 0x4444444444444444444444444444444444444444 is permitted"},
+		{"cross product denied", &Transaction{To: router, Data: transferCalldata()}, false, "0xa9059cbb on 0x4444444444444444444444444444444444444444 is not permitted"},
+		{"wildcard selector", &Transaction{To: evalContract, Data: approve}, true, "0x095ea7b3 on 0x1111111111111111111111111111111111111111 is permitted"},
+		{"plain transfer to wildcard contract", &Transaction{To: evalContract, Value: big.NewInt(1)}, true, "call without selector on 0x1111111111111111111111111111111111111111 is permitted"},
+		{"plain transfer denied", &Transaction{To: router, Value: big.NewInt(1)}, false, "call without selector on 0x4444444444444444444444444444444444444444 is not permitted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Evaluate(context.Background(), p, tt.tx)
+			require.NoError(t, err)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, RulePermission, d.Results[0].Rule)
+			assert.Equal(t, tt.passed, d.Results[0].Passed)
+			assert.Equal(t, tt.reason, d.Results[0].Reason)
+		})
+	}
+}
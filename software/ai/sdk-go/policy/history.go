@@ -0,0 +1,315 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/storage"
+)
+
+const FieldPriority = "Priority"
+
+type PolicyVersion struct {
+	PolicyID  string
+	Revision  int
+	Policy    *Policy
+	Deleted   bool
+	CreatedAt time.Time
+}
+
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+type HistoryStore interface {
+	Append(v *PolicyVersion) error
+	Versions(policyID string) ([]*PolicyVersion, error)
+}
+
+type MemoryHistory struct {
+	versions map[string][]*PolicyVersion
+	mu       sync.RWMutex
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{
+		versions: make(map[string][]*PolicyVersion),
+	}
+}
+
+func (h *MemoryHistory) Append(v *PolicyVersion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.versions[v.PolicyID] = append(h.versions[v.PolicyID], cloneVersion(v))
+	return nil
+}
+
+func (h *MemoryHistory) Versions(policyID string) ([]*PolicyVersion, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stored := h.versions[policyID]
+	result := make([]*PolicyVersion, len(stored))
+	for i, v := range stored {
+		result[i] = cloneVersion(v)
+	}
+	return result, nil
+}
+
+type FileHistory struct {
+	file *storage.FileStore
+	mu   sync.Mutex
+}
+
+func NewFileHistory(path string) (*FileHistory, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileHistory{file: file}, nil
+}
+
+func (h *FileHistory) Append(v *PolicyVersion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var versions []*PolicyVersion
+	if _, err := h.file.Get(v.PolicyID, &versions); err != nil {
+		return err
+	}
+	return h.file.Put(v.PolicyID, append(versions, v))
+}
+
+func (h *FileHistory) Versions(policyID string) ([]*PolicyVersion, error) {
+	var versions []*PolicyVersion
+	if _, err := h.file.Get(policyID, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func DiffPolicies(a, b *Policy) ([]FieldChange, error) {
+	codec := NewCodec()
+	da, err := codec.document(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := codec.document(b)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []struct {
+		name string
+		a, b interface{}
+	}{
+		{FieldPriority, da.Priority, db.Priority},
+		{FieldSpendingLimits, da.SpendingLimits, db.SpendingLimits},
+		{FieldMaxValuePerTx, da.MaxValuePerTx, db.MaxValuePerTx},
+		{FieldContractAllowlist, da.Contracts, db.Contracts},
+		{FieldFunctionAllowlist, da.Functions, db.Functions},
+		{FieldPermissions, da.Permissions, db.Permissions},
+		{FieldDeny, da.Deny, db.Deny},
+		{FieldCalldataConstraints, da.CalldataConstraints, db.CalldataConstraints},
+		{FieldTimeWindow, da.TimeWindow, db.TimeWindow},
+		{FieldRateLimit, da.RateLimit, db.RateLimit},
+		{FieldEscalation, da.Escalation, db.Escalation},
+	}
+
+	var changes []FieldChange
+	for _, f := range fields {
+		from, err := diffValue(f.a)
+		if err != nil {
+			return nil, err
+		}
+		to, err := diffValue(f.b)
+		if err != nil {
+			return nil, err
+		}
+		if from != to {
+			changes = append(changes, FieldChange{Field: f.name, From: from, To: to})
+		}
+	}
+	return changes, nil
+}
+
+func diffValue(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(data, []byte("null")) || bytes.Equal(data, []byte(`""`)) {
+		return "", nil
+	}
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		return s, nil
+	}
+	return string(data), nil
+}
+
+func findVersion(versions []*PolicyVersion, revision int) (*PolicyVersion, error) {
+	for _, v := range versions {
+		if v.Revision == revision {
+			return v, nil
+		}
+	}
+	return nil, errors.New("policy version not found")
+}
+
+func carryState(next, prev *Policy) {
+	used := make([]bool, len(prev.SpendingLimits))
+	for i := range next.SpendingLimits {
+		sl := &next.SpendingLimits[i]
+		sl.Spent, sl.ResetAt, sl.Spends = nil, time.Time{}, nil
+		for j, old := range prev.SpendingLimits {
+			if used[j] || old.Token != sl.Token || old.Quote != sl.Quote || old.Period != sl.Period || spendingWindow(&old) != spendingWindow(sl) {
+				continue
+			}
+			used[j] = true
+			sl.Spent, sl.ResetAt, sl.Spends = cloneInt(old.Spent), old.ResetAt, cloneSpends(old.Spends)
+			break
+		}
+	}
+}
+
+func cloneVersion(v *PolicyVersion) *PolicyVersion {
+	copied := *v
+	copied.Policy = clonePolicy(v.Policy)
+	return &copied
+}
+
+func clonePolicy(p *Policy) *Policy {
+	if p == nil {
+		return nil
+	}
+	c := *p
+
+	if p.SpendingLimits != nil {
+		c.SpendingLimits = make([]SpendingLimit, len(p.SpendingLimits))
+		for i, sl := range p.SpendingLimits {
+			sl.MaxAmount = cloneInt(sl.MaxAmount)
+			sl.Spent = cloneInt(sl.Spent)
+			sl.Spends = cloneSpends(sl.Spends)
+			c.SpendingLimits[i] = sl
+		}
+	}
+	c.MaxValuePerTx = cloneInt(p.MaxValuePerTx)
+
+	if p.ContractAllowlist != nil {
+		contracts := make(map[common.Address]bool, len(p.ContractAllowlist.Contracts))
+		for k, v := range p.ContractAllowlist.Contracts {
+			contracts[k] = v
+		}
+		c.ContractAllowlist = &ContractAllowlist{Contracts: contracts}
+	}
+	if p.FunctionAllowlist != nil {
+		functions := make(map[string]bool, len(p.FunctionAllowlist.Functions))
+		for k, v := range p.FunctionAllowlist.Functions {
+			functions[k] = v
+		}
+		c.FunctionAllowlist = &FunctionAllowlist{Functions: functions}
+	}
+	if p.Permissions != nil {
+		c.Permissions = &PermissionList{Permissions: cloneSlice(p.Permissions.Permissions)}
+	}
+	if p.Deny != nil {
+		c.Deny = &DenyList{
+			Contracts: cloneSlice(p.Deny.Contracts),
+			Selectors: cloneSlice(p.Deny.Selectors),
+			Calls:     cloneSlice(p.Deny.Calls),
+			Payees:    cloneSlice(p.Deny.Payees),
+			Tokens:    cloneSlice(p.Deny.Tokens),
+		}
+	}
+
+	if p.CalldataConstraints != nil {
+		c.CalldataConstraints = make([]CalldataConstraint, len(p.CalldataConstraints))
+		for i, cc := range p.CalldataConstraints {
+			if cc.Args != nil {
+				args := make([]ArgConstraint, len(cc.Args))
+				for j, arg := range cc.Args {
+					arg.Values = cloneSlice(arg.Values)
+					args[j] = arg
+				}
+				cc.Args = args
+			}
+			c.CalldataConstraints[i] = cc
+		}
+	}
+
+	c.TimeWindow = cloneTimeWindow(p.TimeWindow)
+	if p.RateLimit != nil {
+		rl := *p.RateLimit
+		c.RateLimit = &rl
+	}
+	c.Escalation = cloneEscalation(p.Escalation)
+	return &c
+}
+
+func cloneEscalation(e *Escalation) *Escalation {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	if e.Thresholds != nil {
+		c.Thresholds = make([]ApprovalThreshold, len(e.Thresholds))
+		for i, th := range e.Thresholds {
+			th.Amount = cloneInt(th.Amount)
+			c.Thresholds[i] = th
+		}
+	}
+	c.Approvers = cloneSlice(e.Approvers)
+	return &c
+}
+
+func cloneTimeWindow(tw *TimeWindow) *TimeWindow {
+	if tw == nil {
+		return nil
+	}
+	c := *tw
+	c.Days = cloneSlice(tw.Days)
+	if tw.Windows != nil {
+		c.Windows = make([]WeeklyWindow, len(tw.Windows))
+		for i, w := range tw.Windows {
+			w.Days = cloneSlice(w.Days)
+			c.Windows[i] = w
+		}
+	}
+	c.Blackouts = cloneSlice(tw.Blackouts)
+	if tw.Intersect != nil {
+		c.Intersect = make([]TimeWindow, len(tw.Intersect))
+		for i := range tw.Intersect {
+			c.Intersect[i] = *cloneTimeWindow(&tw.Intersect[i])
+		}
+	}
+	return &c
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+func cloneSpends(spends []SpendRecord) []SpendRecord {
+	result := cloneSlice(spends)
+	for i := range result {
+		result[i].Amount = cloneInt(result[i].Amount)
+	}
+	return result
+}
+
+func cloneInt(n *big.Int) *big.Int {
+	if n == nil {
+		return nil
+	}
+	return new(big.Int).Set(n)
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyHistory(t *testing.T) {
+	svc := NewPolicyService()
+	created, err := svc.CreatePolicy(&Policy{RateLimit: &RateLimit{MaxCalls: 5, Period: time.Hour}})
+	require.NoError(t, err)
+
+	created.RateLimit.MaxCalls = 10
+	created.ContractAllowlist = NewContractAllowlist([]common.Address{usdc})
+	_, err = svc.UpdatePolicy(created)
+	require.NoError(t, err)
+
+	versions, err := svc.PolicyHistory(created.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].Revision)
+	assert.Equal(t, uint64(5), versions[0].Policy.RateLimit.MaxCalls)
+	assert.Equal(t, 2, versions[1].Revision)
+	assert.Equal(t, uint64(10), versions[1].Policy.RateLimit.MaxCalls)
+
+	t.Run("versions are immutable", func(t *testing.T) {
+		versions[0].Policy.RateLimit.MaxCalls = 99
+		v, err := svc.GetPolicyVersion(created.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), v.Policy.RateLimit.MaxCalls)
+	})
+
+	t.Run("usage does not create versions", func(t *testing.T) {
+		require.NoError(t, svc.Record(created.ID, &Transaction{To: usdc}))
+		versions, err := svc.PolicyHistory(created.ID)
+		require.NoError(t, err)
+		assert.Len(t, versions, 2)
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		changes, err := svc.DiffPolicyVersions(created.ID, 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []FieldChange{
+			{Field: FieldContractAllowlist, To: `["0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"]`},
+			{Field: FieldRateLimit, From: `{"maxCalls":5,"period":"1h"}`, To: `{"maxCalls":10,"period":"1h"}`},
+		}, changes)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := svc.PolicyHistory("missing")
+		assert.EqualError(t, err, "policy not found")
+
+		_, err = svc.GetPolicyVersion(created.ID, 3)
+		assert.EqualError(t, err, "policy version not found")
+
+		require.NoError(t, svc.DeletePolicy(created.ID))
+		_, err = svc.DiffPolicyVersions(created.ID, 2, 3)
+		assert.EqualError(t, err, "cannot diff a deleted version")
+
+		versions, err := svc.PolicyHistory(created.ID)
+		require.NoError(t, err)
+		assert.Len(t, versions, 3)
+	})
+}
+
+func TestDiffPolicies(t *testing.T) {
+	a := &Policy{
+		Priority:       1,
+		SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(100), Period: 24 * time.Hour, Spent: big.NewInt(40)}},
+	}
+	b := &Policy{
+		Priority:       2,
+		SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(100), Period: 24 * time.Hour}},
+		MaxValuePerTx:  ether(1),
+	}
+
+	changes, err := DiffPolicies(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Field: FieldPriority, From: "1", To: "2"},
+		{Field: FieldMaxValuePerTx, To: "1 ETH"},
+	}, changes)
+
+	changes, err = DiffPolicies(a, a)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	history, err := NewFileHistory(path)
+	require.NoError(t, err)
+
+	svc := NewPolicyService(WithHistory(history))
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(100), Period: time.Hour}},
+	})
+	require.NoError(t, err)
+	created.Priority = 3
+	_, err = svc.UpdatePolicy(created)
+	require.NoError(t, err)
+
+	reopened, err := NewFileHistory(path)
+	require.NoError(t, err)
+	versions, err := reopened.Versions(created.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 0, versions[0].Policy.Priority)
+	assert.Equal(t, 3, versions[1].Policy.Priority)
+	assert.Equal(t, big.NewInt(100), versions[1].Policy.SpendingLimits[0].MaxAmount)
+}
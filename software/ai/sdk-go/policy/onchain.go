@@ -0,0 +1,276 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+const (
+	maxUint48 = 1<<48 - 1
+)
+
+type AgentPolicyReport struct {
+	Unrepresentable []UnrepresentableRule
+}
+
+type UnrepresentableRule struct {
+	Field  string
+	Reason string
+}
+
+func (r *AgentPolicyReport) Lossless() bool {
+	return len(r.Unrepresentable) == 0
+}
+
+func (r *AgentPolicyReport) String() string {
+	if r.Lossless() {
+		return "all rules are represented on-chain\n"
+	}
+	var b strings.Builder
+	for _, u := range r.Unrepresentable {
+		fmt.Fprintf(&b, "%s: %s\n", u.Field, u.Reason)
+	}
+	return b.String()
+}
+
+func (r *AgentPolicyReport) add(field, format string, args ...interface{}) {
+	r.Unrepresentable = append(r.Unrepresentable, UnrepresentableRule{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+func ToAgentPolicy(p *Policy) (*encoding.AgentPolicy, *AgentPolicyReport, error) {
+	if err := validatePolicy(p); err != nil {
+		return nil, nil, err
+	}
+
+	ap := &encoding.AgentPolicy{
+		AllowedTargets:   []common.Address{},
+		AllowedSelectors: [][4]byte{},
+		MaxAmountPerTx:   new(big.Int).Set(math.MaxBig256),
+		DailyLimit:       new(big.Int),
+		WeeklyLimit:      new(big.Int),
+		ValidAfter:       new(big.Int),
+		ValidUntil:       new(big.Int),
+		Active:           true,
+	}
+	report := &AgentPolicyReport{}
+
+	for _, sl := range p.SpendingLimits {
+		var limit *big.Int
+		switch {
+		case sl.Quote != "":
+			report.add(FieldSpendingLimits, "%s limit: on-chain limits apply to native value only", sl.Quote)
+			continue
+		case sl.Token != (common.Address{}):
+			report.add(FieldSpendingLimits, "token %s limit: on-chain limits apply to native value only", sl.Token.Hex())
+			continue
+		case sl.Period == Day:
+			limit = ap.DailyLimit
+		case sl.Period == Week:
+			limit = ap.WeeklyLimit
+		default:
+			report.add(FieldSpendingLimits, "native limit per %s: on-chain limits are daily or weekly", formatDuration(sl.Period))
+			continue
+		}
+		if w := spendingWindow(&sl); w != SpendingCalendarWindow {
+			report.add(FieldSpendingLimits, "%s native limit per %s: on-chain limits reset at calendar boundaries", w, formatDuration(sl.Period))
+		}
+		if limit.Sign() != 0 {
+			report.add(FieldSpendingLimits, "more than one native limit per %s: the lowest is kept", formatDuration(sl.Period))
+			if sl.MaxAmount.Cmp(limit) >= 0 {
+				continue
+			}
+		}
+		limit.Set(sl.MaxAmount)
+	}
+
+	if p.MaxValuePerTx != nil {
+		ap.MaxAmountPerTx.Set(p.MaxValuePerTx)
+	}
+
+	if p.ContractAllowlist != nil {
+		for addr, allowed := range p.ContractAllowlist.Contracts {
+			if allowed {
+				ap.AllowedTargets = append(ap.AllowedTargets, addr)
+			}
+		}
+		sort.Slice(ap.AllowedTargets, func(i, j int) bool {
+			return bytes.Compare(ap.AllowedTargets[i].Bytes(), ap.AllowedTargets[j].Bytes()) < 0
+		})
+		if len(ap.AllowedTargets) == 0 {
+			ap.Active = false
+			report.add(FieldContractAllowlist, "an empty allowlist allows nothing: encoded as an inactive policy")
+		}
+	}
+
+	if p.FunctionAllowlist != nil {
+		n := 0
+		for _, allowed := range p.FunctionAllowlist.Functions {
+			if allowed {
+				n++
+			}
+		}
+		if n == 0 {
+			ap.Active = false
+			report.add(FieldFunctionAllowlist, "an empty allowlist allows nothing: encoded as an inactive policy")
+		} else {
+			report.add(FieldFunctionAllowlist, "%d functions: the on-chain policy checks the account's execute selector, not the called function", n)
+		}
+	}
+
+	if p.Permissions != nil {
+		report.add(FieldPermissions, "%d (contract, function) pairs: the on-chain policy cannot pair targets with selectors", len(p.Permissions.Permissions))
+	}
+
+	if p.Deny != nil {
+		d := p.Deny
+		denyAll := countDenyAll(d.Calls)
+		if denyAll > 0 {
+			ap.Active = false
+		}
+		if len(d.Contracts)+len(d.Selectors)+len(d.Payees)+len(d.Tokens)+len(d.Calls) > denyAll {
+			report.add(FieldDeny, "deny rules have no on-chain equivalent")
+		}
+	}
+
+	if len(p.CalldataConstraints) > 0 {
+		report.add(FieldCalldataConstraints, "%d calldata constraints have no on-chain equivalent", len(p.CalldataConstraints))
+	}
+
+	if tw := p.TimeWindow; tw != nil {
+		if !tw.Start.IsZero() {
+			start := tw.Start.Unix()
+			if tw.Start.Nanosecond() != 0 {
+				start++
+				report.add(FieldTimeWindow, "start is rounded up to a whole second")
+			}
+			if start > maxUint48 {
+				return nil, nil, errors.New("time window start out of range")
+			}
+			ap.ValidAfter.SetInt64(max(start, 0))
+		}
+		if !tw.End.IsZero() {
+			end := tw.End.Unix()
+			if tw.End.Nanosecond() != 0 {
+				report.add(FieldTimeWindow, "end is rounded down to a whole second")
+			}
+			if end < 1 || end > maxUint48 {
+				return nil, nil, errors.New("time window end out of range")
+			}
+			ap.ValidUntil.SetInt64(end)
+		}
+		if len(tw.Days) > 0 || tw.Hours != [2]int{} || len(tw.Windows) > 0 || len(tw.Blackouts) > 0 || len(tw.Intersect) > 0 || tw.Location != "" {
+			report.add(FieldTimeWindow, "weekly schedules, hours, time zones and blackouts have no on-chain equivalent")
+		}
+	}
+
+	if p.RateLimit != nil {
+		report.add(FieldRateLimit, "%d calls per %s: the on-chain policy has no rate limit", p.RateLimit.MaxCalls, formatDuration(p.RateLimit.Period))
+	}
+
+	if e := p.Escalation; e != nil {
+		others := e.NewContracts || e.OutsideTimeWindow
+		for _, th := range e.Thresholds {
+			if th.Token != (common.Address{}) || th.Quote != "" {
+				others = true
+				continue
+			}
+			if th.Amount.Cmp(ap.MaxAmountPerTx) < 0 {
+				ap.MaxAmountPerTx.Set(th.Amount)
+			}
+			report.add(FieldEscalation, "native approval threshold %s: the on-chain policy cannot wait for approval, so larger values are denied", th.Amount)
+		}
+		if others {
+			report.add(FieldEscalation, "token thresholds, new contracts and time window escalations are allowed on-chain without approval")
+		}
+	}
+
+	return ap, report, nil
+}
+
+func FromAgentPolicy(ap *encoding.AgentPolicy) (*Policy, error) {
+	if ap == nil {
+		return nil, errors.New("nil agent policy")
+	}
+
+	p := &Policy{}
+
+	if len(ap.AllowedTargets) > 0 {
+		p.ContractAllowlist = NewContractAllowlist(ap.AllowedTargets)
+	}
+
+	if ap.MaxAmountPerTx != nil && ap.MaxAmountPerTx.Cmp(math.MaxBig256) < 0 {
+		p.MaxValuePerTx = new(big.Int).Set(ap.MaxAmountPerTx)
+	}
+
+	if ap.DailyLimit != nil && ap.DailyLimit.Sign() > 0 {
+		p.SpendingLimits = append(p.SpendingLimits, SpendingLimit{MaxAmount: new(big.Int).Set(ap.DailyLimit), Period: Day, Window: SpendingCalendarWindow})
+	}
+	if ap.WeeklyLimit != nil && ap.WeeklyLimit.Sign() > 0 {
+		p.SpendingLimits = append(p.SpendingLimits, SpendingLimit{MaxAmount: new(big.Int).Set(ap.WeeklyLimit), Period: Week, Window: SpendingCalendarWindow})
+	}
+
+	validAfter, err := uint48Time(ap.ValidAfter)
+	if err != nil {
+		return nil, fmt.Errorf("valid after: %w", err)
+	}
+	validUntil, err := uint48Time(ap.ValidUntil)
+	if err != nil {
+		return nil, fmt.Errorf("valid until: %w", err)
+	}
+	if !validAfter.IsZero() || !validUntil.IsZero() {
+		p.TimeWindow = &TimeWindow{Start: validAfter, End: validUntil}
+	}
+
+	if err := checkAllowedSelectors(ap.AllowedSelectors); err != nil {
+		return nil, err
+	}
+	if !ap.Active {
+		p.Deny = &DenyList{Calls: []Permission{{Contract: AnyContract, Selector: AnySelector}}}
+	}
+
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func uint48Time(v *big.Int) (time.Time, error) {
+	if v == nil || v.Sign() == 0 {
+		return time.Time{}, nil
+	}
+	if v.Sign() < 0 || !v.IsInt64() || v.Int64() > maxUint48 {
+		return time.Time{}, errors.New("out of uint48 range")
+	}
+	return time.Unix(v.Int64(), 0).UTC(), nil
+}
+
+func checkAllowedSelectors(selectors [][4]byte) error {
+	for _, sel := range selectors {
+		switch hex.EncodeToString(sel[:]) {
+		case Selector(encoding.ExecuteSignature), Selector(encoding.ExecuteBatchSignature):
+		default:
+			return fmt.Errorf("allowed selector 0x%x has no policy equivalent: only execute and executeBatch are supported", sel)
+		}
+	}
+	return nil
+}
+
+func countDenyAll(calls []Permission) int {
+	n := 0
+	for _, c := range calls {
+		if c.Contract == AnyContract && c.Selector == AnySelector {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,278 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+func onchainPolicy() *Policy {
+	return &Policy{
+		SpendingLimits: []SpendingLimit{
+			{MaxAmount: ether(10), Period: 24 * time.Hour, Window: SpendingCalendarWindow},
+			{MaxAmount: ether(50), Period: 7 * 24 * time.Hour, Window: SpendingCalendarWindow},
+		},
+		MaxValuePerTx:     ether(1),
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc, router}),
+		TimeWindow: &TimeWindow{
+			Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestToAgentPolicy(t *testing.T) {
+	ap, report, err := ToAgentPolicy(onchainPolicy())
+	require.NoError(t, err)
+	assert.True(t, report.Lossless())
+	assert.Equal(t, "all rules are represented on-chain\n", report.String())
+
+	assert.Equal(t, &encoding.AgentPolicy{
+		AllowedTargets:   []common.Address{router, usdc},
+		AllowedSelectors: [][4]byte{},
+		MaxAmountPerTx:   ether(1),
+		DailyLimit:       ether(10),
+		WeeklyLimit:      ether(50),
+		ValidAfter:       big.NewInt(1767225600),
+		ValidUntil:       big.NewInt(1798761600),
+		Active:           true,
+	}, ap)
+
+	t.Run("unset fields are unrestricted", func(t *testing.T) {
+		ap, report, err := ToAgentPolicy(&Policy{})
+		require.NoError(t, err)
+		assert.True(t, report.Lossless())
+		assert.Empty(t, ap.AllowedTargets)
+		assert.Empty(t, ap.AllowedSelectors)
+		assert.Equal(t, math.MaxBig256, ap.MaxAmountPerTx)
+		assert.Zero(t, ap.DailyLimit.Sign())
+		assert.Zero(t, ap.ValidUntil.Sign())
+		assert.True(t, ap.Active)
+	})
+
+	t.Run("deny everything is inactive", func(t *testing.T) {
+		p := onchainPolicy()
+		p.Deny = &DenyList{Calls: []Permission{Permit(AnyContract, AnySelector)}}
+		ap, report, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.True(t, report.Lossless())
+		assert.False(t, ap.Active)
+	})
+
+	t.Run("empty allowlist is inactive", func(t *testing.T) {
+		ap, report, err := ToAgentPolicy(&Policy{ContractAllowlist: NewContractAllowlist(nil)})
+		require.NoError(t, err)
+		assert.False(t, ap.Active)
+		assert.Equal(t, []UnrepresentableRule{
+			{Field: FieldContractAllowlist, Reason: "an empty allowlist allows nothing: encoded as an inactive policy"},
+		}, report.Unrepresentable)
+	})
+
+	t.Run("function allowlist stays off-chain", func(t *testing.T) {
+		p := onchainPolicy()
+		p.FunctionAllowlist = NewFunctionAllowlist([]string{transferSig})
+		ap, report, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.Empty(t, ap.AllowedSelectors)
+		assert.True(t, ap.Active)
+		assert.Equal(t, []UnrepresentableRule{
+			{Field: FieldFunctionAllowlist, Reason: "1 functions: the on-chain policy checks the account's execute selector, not the called function"},
+		}, report.Unrepresentable)
+
+		ap, report, err = ToAgentPolicy(&Policy{FunctionAllowlist: NewFunctionAllowlist(nil)})
+		require.NoError(t, err)
+		assert.False(t, ap.Active)
+		assert.Len(t, report.Unrepresentable, 1)
+	})
+
+	t.Run("native approval thresholds cap the value", func(t *testing.T) {
+		p := onchainPolicy()
+		p.Escalation = &Escalation{
+			Thresholds: []ApprovalThreshold{{Amount: big.NewInt(1e17)}, {Token: usdc, Amount: big.NewInt(100)}},
+			Approvers:  []common.Address{payee},
+		}
+		ap, report, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1e17), ap.MaxAmountPerTx)
+		assert.Equal(t, []UnrepresentableRule{
+			{Field: FieldEscalation, Reason: "native approval threshold 100000000000000000: the on-chain policy cannot wait for approval, so larger values are denied"},
+			{Field: FieldEscalation, Reason: "token thresholds, new contracts and time window escalations are allowed on-chain without approval"},
+		}, report.Unrepresentable)
+
+		p.Escalation = &Escalation{Thresholds: []ApprovalThreshold{{Amount: ether(2)}}, Approvers: []common.Address{payee}}
+		ap, report, err = ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.Equal(t, ether(1), ap.MaxAmountPerTx)
+		assert.Len(t, report.Unrepresentable, 1)
+	})
+
+	t.Run("sub-second bounds are rounded inwards", func(t *testing.T) {
+		start := time.Unix(100, 500)
+		ap, report, err := ToAgentPolicy(&Policy{TimeWindow: &TimeWindow{Start: start, End: start.Add(time.Minute)}})
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(101), ap.ValidAfter)
+		assert.Equal(t, big.NewInt(160), ap.ValidUntil)
+		assert.Len(t, report.Unrepresentable, 2)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := ToAgentPolicy(nil)
+		assert.EqualError(t, err, "nil policy")
+
+		_, _, err = ToAgentPolicy(&Policy{TimeWindow: &TimeWindow{End: time.Unix(1<<48, 0)}})
+		assert.EqualError(t, err, "time window end out of range")
+	})
+}
+
+func TestToAgentPolicyReport(t *testing.T) {
+	p := &Policy{
+		SpendingLimits: []SpendingLimit{
+			{Token: usdc, MaxAmount: big.NewInt(100), Period: 24 * time.Hour},
+			{MaxAmount: big.NewInt(5), Period: time.Hour},
+			{MaxAmount: big.NewInt(30), Period: 24 * time.Hour, Window: SpendingCalendarWindow},
+			{MaxAmount: big.NewInt(20), Period: 24 * time.Hour, Window: SpendingSlidingWindow},
+			{Quote: "USD", MaxAmount: big.NewInt(1), Period: 24 * time.Hour},
+		},
+		Permissions:         NewPermissionList(Permit(usdc, transferSig)),
+		Deny:                &DenyList{Contracts: []common.Address{drainer}},
+		CalldataConstraints: []CalldataConstraint{{Signature: transferSig}},
+		TimeWindow:          &TimeWindow{Hours: [2]int{9, 17}},
+		RateLimit:           &RateLimit{MaxCalls: 10, Period: time.Minute},
+	}
+
+	ap, report, err := ToAgentPolicy(p)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(20), ap.DailyLimit)
+	assert.True(t, ap.Active)
+	assert.False(t, report.Lossless())
+	assert.Equal(t, `SpendingLimits: token 0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913 limit: on-chain limits apply to native value only
+SpendingLimits: native limit per 1h: on-chain limits are daily or weekly
+SpendingLimits: sliding_window native limit per 24h: on-chain limits reset at calendar boundaries
+SpendingLimits: more than one native limit per 24h: the lowest is kept
+SpendingLimits: USD limit: on-chain limits apply to native value only
+Permissions: 1 (contract, function) pairs: the on-chain policy cannot pair targets with selectors
+Deny: deny rules have no on-chain equivalent
+CalldataConstraints: 1 calldata constraints have no on-chain equivalent
+TimeWindow: weekly schedules, hours, time zones and blackouts have no on-chain equivalent
+RateLimit: 10 calls per 1m: the on-chain policy has no rate limit
+`, report.String())
+}
+
+func TestFromAgentPolicy(t *testing.T) {
+	t.Run("lossless roundtrip", func(t *testing.T) {
+		ap, _, err := ToAgentPolicy(onchainPolicy())
+		require.NoError(t, err)
+		p, err := FromAgentPolicy(ap)
+		require.NoError(t, err)
+		assert.Equal(t, onchainPolicy(), p)
+
+		again, _, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.Equal(t, ap, again)
+	})
+
+	t.Run("inactive policy denies every call", func(t *testing.T) {
+		ap, _, err := ToAgentPolicy(onchainPolicy())
+		require.NoError(t, err)
+		ap.Active = false
+
+		p, err := FromAgentPolicy(ap)
+		require.NoError(t, err)
+		assert.Equal(t, onchainPolicy().ContractAllowlist, p.ContractAllowlist)
+		d, err := Evaluate(context.Background(), p, &Transaction{To: usdc, Data: common.FromHex("a9059cbb"), Time: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+
+		again, report, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.True(t, report.Lossless())
+		assert.Equal(t, ap, again)
+	})
+
+	t.Run("zero values are unrestricted", func(t *testing.T) {
+		p, err := FromAgentPolicy(&encoding.AgentPolicy{MaxAmountPerTx: math.MaxBig256, Active: true})
+		require.NoError(t, err)
+		assert.Equal(t, &Policy{}, p)
+	})
+
+	t.Run("selectors restrict the account call", func(t *testing.T) {
+		execute := [4]byte(common.FromHex(Selector(encoding.ExecuteSignature)))
+		executeBatch := [4]byte(common.FromHex(Selector(encoding.ExecuteBatchSignature)))
+		p, err := FromAgentPolicy(&encoding.AgentPolicy{AllowedSelectors: [][4]byte{execute}, MaxAmountPerTx: math.MaxBig256, Active: true})
+		require.NoError(t, err)
+		assert.Equal(t, &Policy{}, p)
+
+		_, err = FromAgentPolicy(&encoding.AgentPolicy{AllowedSelectors: [][4]byte{{0xa9, 0x05, 0x9c, 0xbb}}, MaxAmountPerTx: math.MaxBig256, Active: true})
+		assert.EqualError(t, err, "allowed selector 0xa9059cbb has no policy equivalent: only execute and executeBatch are supported")
+
+		_, err = FromAgentPolicy(&encoding.AgentPolicy{AllowedSelectors: [][4]byte{execute, {0xa9, 0x05, 0x9c, 0xbb}}, MaxAmountPerTx: math.MaxBig256, Active: true})
+		assert.EqualError(t, err, "allowed selector 0xa9059cbb has no policy equivalent: only execute and executeBatch are supported")
+
+		ap, _, err := ToAgentPolicy(onchainPolicy())
+		require.NoError(t, err)
+		withSelectors := *ap
+		withSelectors.AllowedSelectors = [][4]byte{execute, executeBatch}
+		data, err := encoding.EncodeAgentPolicy(&withSelectors)
+		require.NoError(t, err)
+		decoded, err := encoding.DecodeAgentPolicy(data)
+		require.NoError(t, err)
+		assert.Equal(t, withSelectors.AllowedSelectors, decoded.AllowedSelectors)
+
+		p, err = FromAgentPolicy(decoded)
+		require.NoError(t, err)
+		assert.Equal(t, onchainPolicy(), p)
+		again, _, err := ToAgentPolicy(p)
+		require.NoError(t, err)
+		assert.Equal(t, ap, again)
+	})
+
+	t.Run("zero max amount blocks value", func(t *testing.T) {
+		p, err := FromAgentPolicy(&encoding.AgentPolicy{MaxAmountPerTx: big.NewInt(0), Active: true})
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(0), p.MaxValuePerTx)
+	})
+
+	t.Run("through addAgent calldata", func(t *testing.T) {
+		agent := common.HexToAddress("0xA11CE")
+		ap, _, err := ToAgentPolicy(onchainPolicy())
+		require.NoError(t, err)
+		data, err := encoding.EncodeAddAgent(agent, ap)
+		require.NoError(t, err)
+
+		decodedAgent, decoded, err := encoding.DecodeAddAgent(data)
+		require.NoError(t, err)
+		assert.Equal(t, agent, decodedAgent)
+		p, err := FromAgentPolicy(decoded)
+		require.NoError(t, err)
+		assert.Equal(t, onchainPolicy(), p)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			policy  *encoding.AgentPolicy
+			wantErr string
+		}{
+			{"nil policy", nil, "nil agent policy"},
+			{"valid after overflow", &encoding.AgentPolicy{ValidAfter: new(big.Int).Lsh(big.NewInt(1), 48)}, "valid after: out of uint48 range"},
+			{"valid until before valid after", &encoding.AgentPolicy{ValidAfter: big.NewInt(200), ValidUntil: big.NewInt(100)}, "time window start after end"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := FromAgentPolicy(tt.policy)
+				assert.EqualError(t, err, tt.wantErr)
+			})
+		}
+	})
+}
@@ -1,39 +1,112 @@
 package policy
 
 import (
-	"encoding/hex"
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type PolicyService struct {
-	policies map[string]*Policy
-	mu       sync.RWMutex
+	store     Store
+	history   HistoryStore
+	bindings  BindingStore
+	limiter   *RateLimiter
+	spending  *SpendingTracker
+	prices    *priceFeed
+	approvals *ApprovalQueue
+	mu        sync.RWMutex
+
+	allowUnbound bool
+}
+
+type PolicyFilter struct {
+	Contract      common.Address
+	Tokens        []common.Address
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	Match         func(*Policy) bool
+}
+
+var ErrRevisionConflict = errors.New("policy revision conflict")
+
+type PolicyServiceOption func(*PolicyService)
+
+func WithStore(store Store) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.store = store
+	}
+}
+
+func WithHistory(history HistoryStore) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.history = history
+	}
+}
+
+func WithRateLimiter(limiter *RateLimiter) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.limiter = limiter
+	}
 }
 
-func NewPolicyService() *PolicyService {
-	return &PolicyService{
-		policies: make(map[string]*Policy),
+func WithSpendingTracker(tracker *SpendingTracker) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.spending = tracker
 	}
 }
 
+func NewPolicyService(opts ...PolicyServiceOption) *PolicyService {
+	s := &PolicyService{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	if s.history == nil {
+		s.history = NewMemoryHistory()
+	}
+	if s.bindings == nil {
+		s.bindings = NewMemoryBindingStore()
+	}
+	if s.approvals == nil {
+		s.approvals = NewApprovalQueue()
+	}
+	if s.limiter == nil {
+		s.limiter = NewRateLimiter()
+	}
+	return s
+}
+
 func (s *PolicyService) CreatePolicy(p *Policy) (*Policy, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if p == nil {
-		return nil, errors.New("nil policy")
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+
+	id, err := s.newPolicyID()
+	if err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
-	idBytes := crypto.Keccak256([]byte(now.String()))
-	p.ID = hex.EncodeToString(idBytes[:16])
+	p.ID = id
 	p.CreatedAt = now
+	p.UpdatedAt = now
+	p.Revision = 1
 
-	s.policies[p.ID] = p
+	if err := s.store.Put(clonePolicy(p)); err != nil {
+		return nil, err
+	}
+	if err := s.history.Append(&PolicyVersion{PolicyID: id, Revision: 1, Policy: clonePolicy(p), CreatedAt: now}); err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
@@ -41,14 +114,213 @@ func (s *PolicyService) GetPolicy(id string) (*Policy, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	p, ok := s.policies[id]
+	p, ok, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
 		return nil, errors.New("policy not found")
 	}
-	return p, nil
+	return clonePolicy(p), nil
+}
+
+func (s *PolicyService) UpdatePolicy(p *Policy) (*Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validatePolicy(p); err != nil {
+		return nil, err
+	}
+
+	current, ok, err := s.store.Get(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("policy not found")
+	}
+	if p.Revision != current.Revision {
+		return nil, ErrRevisionConflict
+	}
+
+	now := time.Now()
+	next := clonePolicy(p)
+	next.CreatedAt = current.CreatedAt
+	next.UpdatedAt = now
+	next.Revision = current.Revision + 1
+	carryState(next, current)
+
+	if err := s.store.Put(next); err != nil {
+		return nil, err
+	}
+	if err := s.history.Append(&PolicyVersion{PolicyID: next.ID, Revision: next.Revision, Policy: clonePolicy(next), CreatedAt: now}); err != nil {
+		return nil, err
+	}
+	return clonePolicy(next), nil
+}
+
+func (s *PolicyService) DeletePolicy(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok, err := s.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("policy not found")
+	}
+	bound, err := s.listBindings(id)
+	if err != nil {
+		return err
+	}
+	if len(bound) > 0 {
+		return errors.New("policy is attached to a wallet or agent")
+	}
+
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	if s.spending != nil {
+		for _, sl := range current.SpendingLimits {
+			if err := s.spending.Reset(NewSpendingKey(id, &sl)); err != nil {
+				return err
+			}
+		}
+	}
+	return s.history.Append(&PolicyVersion{PolicyID: id, Revision: current.Revision + 1, Deleted: true, CreatedAt: time.Now()})
+}
+
+func (s *PolicyService) ListPolicies(filter PolicyFilter) ([]*Policy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policies, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Policy, 0, len(policies))
+	for _, p := range policies {
+		if filter.matches(p) {
+			result = append(result, clonePolicy(p))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *PolicyService) PolicyHistory(id string) ([]*PolicyVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions, err := s.history.Versions(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, errors.New("policy not found")
+	}
+	return versions, nil
+}
+
+func (s *PolicyService) GetPolicyVersion(id string, revision int) (*PolicyVersion, error) {
+	versions, err := s.PolicyHistory(id)
+	if err != nil {
+		return nil, err
+	}
+	return findVersion(versions, revision)
+}
+
+func (s *PolicyService) DiffPolicyVersions(id string, from, to int) ([]FieldChange, error) {
+	versions, err := s.PolicyHistory(id)
+	if err != nil {
+		return nil, err
+	}
+	a, err := findVersion(versions, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := findVersion(versions, to)
+	if err != nil {
+		return nil, err
+	}
+	if a.Deleted || b.Deleted {
+		return nil, errors.New("cannot diff a deleted version")
+	}
+	return DiffPolicies(a.Policy, b.Policy)
+}
+
+func (s *PolicyService) newPolicyID() (string, error) {
+	for {
+		id, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		_, exists, err := s.store.Get(id)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return id, nil
+		}
+	}
+}
+
+func (f *PolicyFilter) matches(p *Policy) bool {
+	if f.Contract != (common.Address{}) && !namesContract(p, f.Contract) {
+		return false
+	}
+	if len(f.Tokens) > 0 && !limitsAnyToken(p, f.Tokens) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !p.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !p.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.UpdatedAfter.IsZero() && !p.UpdatedAt.After(f.UpdatedAfter) {
+		return false
+	}
+	return f.Match == nil || f.Match(p)
+}
+
+func namesContract(p *Policy, contract common.Address) bool {
+	if p.ContractAllowlist != nil && p.ContractAllowlist.Contracts[contract] {
+		return true
+	}
+	if p.Permissions != nil {
+		for _, perm := range p.Permissions.Permissions {
+			if perm.Contract == contract {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func limitsAnyToken(p *Policy, tokens []common.Address) bool {
+	for _, sl := range p.SpendingLimits {
+		for _, token := range tokens {
+			if sl.Token == token {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (s *PolicyService) ValidatePolicy(p *Policy) error {
+	return validatePolicy(p)
+}
+
+func validatePolicy(p *Policy) error {
 	if p == nil {
 		return errors.New("nil policy")
 	}
@@ -60,17 +332,45 @@ func (s *PolicyService) ValidatePolicy(p *Policy) error {
 		if sl.Period <= 0 {
 			return errors.New("invalid spending limit period")
 		}
+		if sl.Quote != "" && sl.Token != (common.Address{}) {
+			return errors.New("quote spending limit cannot have a token")
+		}
+		switch sl.Window {
+		case "", SpendingFixedWindow, SpendingSlidingWindow, SpendingCalendarWindow:
+		case SpendingCalendarMonthWindow:
+			if sl.Period != Month {
+				return errors.New("calendar month spending window needs a Month period")
+			}
+		default:
+			return errors.New("invalid spending window")
+		}
 	}
 
-	if p.TimeWindow != nil {
-		if !p.TimeWindow.End.IsZero() && p.TimeWindow.Start.After(p.TimeWindow.End) {
-			return errors.New("time window start after end")
+	if p.MaxValuePerTx != nil && p.MaxValuePerTx.Sign() < 0 {
+		return errors.New("invalid max value per transaction")
+	}
+
+	if p.Permissions != nil {
+		if err := validatePermissions(p.Permissions); err != nil {
+			return err
+		}
+	}
+
+	if p.Deny != nil {
+		if err := validateDenyList(p.Deny); err != nil {
+			return err
 		}
-		if p.TimeWindow.Hours[0] < 0 || p.TimeWindow.Hours[0] > 23 {
-			return errors.New("invalid start hour")
+	}
+
+	for i := range p.CalldataConstraints {
+		if err := validateCalldataConstraint(&p.CalldataConstraints[i]); err != nil {
+			return err
 		}
-		if p.TimeWindow.Hours[1] < 0 || p.TimeWindow.Hours[1] > 23 {
-			return errors.New("invalid end hour")
+	}
+
+	if p.TimeWindow != nil {
+		if err := validateTimeWindow(p.TimeWindow); err != nil {
+			return err
 		}
 	}
 
@@ -81,7 +381,41 @@ func (s *PolicyService) ValidatePolicy(p *Policy) error {
 		if p.RateLimit.Period <= 0 {
 			return errors.New("invalid rate limit period")
 		}
+		switch p.RateLimit.Mode {
+		case "", RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket:
+		default:
+			return errors.New("unsupported rate limit mode")
+		}
 	}
 
+	if p.Escalation != nil {
+		if err := validateEscalation(p.Escalation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateEscalation(e *Escalation) error {
+	for _, th := range e.Thresholds {
+		if th.Amount == nil || th.Amount.Sign() <= 0 {
+			return errors.New("invalid approval threshold amount")
+		}
+		if th.Quote != "" && th.Token != (common.Address{}) {
+			return errors.New("quote approval threshold cannot have a token")
+		}
+	}
+	if len(e.Approvers) == 0 {
+		return errors.New("escalation requires at least one approver")
+	}
+	for _, a := range e.Approvers {
+		if a == (common.Address{}) {
+			return errors.New("invalid approver address")
+		}
+	}
+	if e.TTL < 0 {
+		return errors.New("invalid approval ttl")
+	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -12,8 +13,8 @@ import (
 func TestNewPolicyService(t *testing.T) {
 	svc := NewPolicyService()
 	assert.NotNil(t, svc)
-	assert.NotNil(t, svc.policies)
-	assert.Empty(t, svc.policies)
+	assert.IsType(t, &MemoryStore{}, svc.store)
+	assert.Empty(t, svc.store.(*MemoryStore).policies)
 }
 
 func TestCreatePolicy(t *testing.T) {
@@ -41,6 +42,191 @@ func TestCreatePolicy(t *testing.T) {
 		require.Error(t, err)
 		assert.Equal(t, "nil policy", err.Error())
 	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		svc := NewPolicyService()
+		_, err := svc.CreatePolicy(&Policy{RateLimit: &RateLimit{Period: time.Hour}})
+		assert.EqualError(t, err, "rate limit max calls must be positive")
+
+		policies, err := svc.ListPolicies(PolicyFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, policies)
+	})
+
+	t.Run("unique ids", func(t *testing.T) {
+		svc := NewPolicyService()
+		ids := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			created, err := svc.CreatePolicy(&Policy{})
+			require.NoError(t, err)
+			assert.Len(t, created.ID, 32)
+			ids[created.ID] = true
+		}
+		assert.Len(t, ids, 1000)
+	})
+
+	t.Run("stores a copy", func(t *testing.T) {
+		svc := NewPolicyService()
+		p := &Policy{RateLimit: &RateLimit{MaxCalls: 5, Period: time.Hour}}
+		created, err := svc.CreatePolicy(p)
+		require.NoError(t, err)
+		assert.Equal(t, 1, created.Revision)
+		assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+		p.RateLimit.MaxCalls = 50
+		got, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), got.RateLimit.MaxCalls)
+
+		got.RateLimit.MaxCalls = 500
+		again, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), again.RateLimit.MaxCalls)
+	})
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		svc := NewPolicyService()
+		created, err := svc.CreatePolicy(&Policy{RateLimit: &RateLimit{MaxCalls: 5, Period: time.Hour}})
+		require.NoError(t, err)
+
+		created.RateLimit.MaxCalls = 10
+		updated, err := svc.UpdatePolicy(created)
+		require.NoError(t, err)
+		assert.Equal(t, 2, updated.Revision)
+		assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+		assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+		assert.Equal(t, 1, created.Revision)
+
+		got, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated, got)
+	})
+
+	t.Run("stale revision conflicts", func(t *testing.T) {
+		svc := NewPolicyService()
+		created, err := svc.CreatePolicy(&Policy{})
+		require.NoError(t, err)
+
+		first, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		second, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+
+		first.Priority = 1
+		_, err = svc.UpdatePolicy(first)
+		require.NoError(t, err)
+
+		second.Priority = 2
+		_, err = svc.UpdatePolicy(second)
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+
+		got, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.Priority)
+	})
+
+	t.Run("keeps usage counters", func(t *testing.T) {
+		svc := NewPolicyService()
+		created, err := svc.CreatePolicy(&Policy{
+			SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(1000), Period: time.Hour}},
+			RateLimit:      &RateLimit{MaxCalls: 5, Period: time.Hour},
+		})
+		require.NoError(t, err)
+		require.NoError(t, svc.Record(created.ID, &Transaction{Value: big.NewInt(300)}))
+
+		created.SpendingLimits[0].MaxAmount = big.NewInt(2000)
+		created.SpendingLimits = append(created.SpendingLimits, SpendingLimit{MaxAmount: big.NewInt(9000), Period: 24 * time.Hour})
+		updated, err := svc.UpdatePolicy(created)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(300), updated.SpendingLimits[0].Spent)
+		assert.Nil(t, updated.SpendingLimits[1].Spent)
+
+		status, err := svc.limiter.Check(RateLimitKey{PolicyID: created.ID}, updated.RateLimit)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), status.Remaining)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		svc := NewPolicyService()
+		_, err := svc.UpdatePolicy(&Policy{ID: "missing"})
+		assert.EqualError(t, err, "policy not found")
+
+		_, err = svc.UpdatePolicy(nil)
+		assert.EqualError(t, err, "nil policy")
+
+		created, err := svc.CreatePolicy(&Policy{})
+		require.NoError(t, err)
+		created.MaxValuePerTx = big.NewInt(-1)
+		_, err = svc.UpdatePolicy(created)
+		assert.EqualError(t, err, "invalid max value per transaction")
+	})
+}
+
+func TestDeletePolicy(t *testing.T) {
+	svc := NewPolicyService()
+	created, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeletePolicy(created.ID))
+	_, err = svc.GetPolicy(created.ID)
+	assert.EqualError(t, err, "policy not found")
+	assert.EqualError(t, svc.DeletePolicy(created.ID), "policy not found")
+
+	versions, err := svc.PolicyHistory(created.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.True(t, versions[1].Deleted)
+	assert.Nil(t, versions[1].Policy)
+	assert.Equal(t, 2, versions[1].Revision)
+}
+
+func TestListPolicies(t *testing.T) {
+	svc := NewPolicyService()
+	router := common.HexToAddress("0x2626664c2603336E57B271c5C0b26F421741e481")
+	usdc := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+
+	swap, err := svc.CreatePolicy(&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{router})})
+	require.NoError(t, err)
+	spend, err := svc.CreatePolicy(&Policy{
+		Priority:       5,
+		SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: time.Hour}},
+		Permissions:    NewPermissionList(Permit(router, "exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))")),
+	})
+	require.NoError(t, err)
+	empty, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+
+	ids := func(policies []*Policy) []string {
+		var result []string
+		for _, p := range policies {
+			result = append(result, p.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter PolicyFilter
+		want   []string
+	}{
+		{"all", PolicyFilter{}, []string{swap.ID, spend.ID, empty.ID}},
+		{"contract", PolicyFilter{Contract: router}, []string{swap.ID, spend.ID}},
+		{"token", PolicyFilter{Tokens: []common.Address{usdc}}, []string{spend.ID}},
+		{"native token", PolicyFilter{Tokens: []common.Address{{}}}, nil},
+		{"created after", PolicyFilter{CreatedAfter: swap.CreatedAt}, []string{spend.ID, empty.ID}},
+		{"created before", PolicyFilter{CreatedBefore: spend.CreatedAt}, []string{swap.ID}},
+		{"match", PolicyFilter{Match: func(p *Policy) bool { return p.Priority > 0 }}, []string{spend.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := svc.ListPolicies(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(policies))
+		})
+	}
 }
 
 func TestGetPolicy(t *testing.T) {
@@ -140,6 +326,11 @@ func TestValidatePolicy(t *testing.T) {
 			},
 			wantErr: "invalid spending limit period",
 		},
+		{
+			name:    "negative max value per transaction",
+			policy:  &Policy{MaxValuePerTx: big.NewInt(-1)},
+			wantErr: "invalid max value per transaction",
+		},
 		{
 			name: "time window start after end",
 			policy: &Policy{
@@ -227,6 +418,115 @@ func TestValidatePolicy(t *testing.T) {
 			},
 			wantErr: "invalid rate limit period",
 		},
+		{
+			name: "escalation without approvers",
+			policy: &Policy{
+				Escalation: &Escalation{NewContracts: true},
+			},
+			wantErr: "escalation requires at least one approver",
+		},
+		{
+			name: "approval threshold zero amount",
+			policy: &Policy{
+				Escalation: &Escalation{
+					Thresholds: []ApprovalThreshold{{Amount: big.NewInt(0)}},
+					Approvers:  []common.Address{common.HexToAddress("0x1")},
+				},
+			},
+			wantErr: "invalid approval threshold amount",
+		},
+		{
+			name: "approval threshold with token and quote",
+			policy: &Policy{
+				Escalation: &Escalation{
+					Thresholds: []ApprovalThreshold{{Token: common.HexToAddress("0x2"), Quote: "USD", Amount: big.NewInt(1)}},
+					Approvers:  []common.Address{common.HexToAddress("0x1")},
+				},
+			},
+			wantErr: "quote approval threshold cannot have a token",
+		},
+		{
+			name: "negative approval ttl",
+			policy: &Policy{
+				Escalation: &Escalation{
+					Approvers: []common.Address{common.HexToAddress("0x1")},
+					TTL:       -time.Hour,
+				},
+			},
+			wantErr: "invalid approval ttl",
+		},
+		{
+			name: "invalid permission selector",
+			policy: &Policy{
+				Permissions: &PermissionList{Permissions: []Permission{{Selector: "transfer"}}},
+			},
+			wantErr: "invalid permission selector",
+		},
+		{
+			name: "wildcard deny selector",
+			policy: &Policy{
+				Deny: &DenyList{Selectors: []string{AnySelector}},
+			},
+			wantErr: "invalid deny selector",
+		},
+		{
+			name: "invalid deny call selector",
+			policy: &Policy{
+				Deny: &DenyList{Calls: []Permission{{Selector: "0x1234"}}},
+			},
+			wantErr: "invalid deny selector",
+		},
+		{
+			name: "invalid calldata constraint",
+			policy: &Policy{
+				CalldataConstraints: []CalldataConstraint{
+					{Signature: "transfer(address,uint256)", Args: []ArgConstraint{{Index: 1, Op: ArgMax, Values: []string{"lots"}}}},
+				},
+			},
+			wantErr: "invalid constraint values",
+		},
+		{
+			name: "spending limit unknown window",
+			policy: &Policy{
+				SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(1), Period: Day, Window: "rolling"}},
+			},
+			wantErr: "invalid spending window",
+		},
+		{
+			name: "calendar month window with another period",
+			policy: &Policy{
+				SpendingLimits: []SpendingLimit{{MaxAmount: big.NewInt(1), Period: 31 * Day, Window: SpendingCalendarMonthWindow}},
+			},
+			wantErr: "calendar month spending window needs a Month period",
+		},
+		{
+			name: "quote limit with token",
+			policy: &Policy{
+				SpendingLimits: []SpendingLimit{{Token: usdc, Quote: "USD", MaxAmount: big.NewInt(1), Period: Day}},
+			},
+			wantErr: "quote spending limit cannot have a token",
+		},
+		{
+			name: "rate limit unknown mode",
+			policy: &Policy{
+				RateLimit: &RateLimit{
+					MaxCalls: 10,
+					Period:   time.Minute,
+					Mode:     "leaky_bucket",
+				},
+			},
+			wantErr: "unsupported rate limit mode",
+		},
+		{
+			name: "valid token bucket rate limit",
+			policy: &Policy{
+				RateLimit: &RateLimit{
+					MaxCalls: 10,
+					Period:   time.Minute,
+					Mode:     RateLimitTokenBucket,
+				},
+			},
+		},
 		{
 			name: "valid rate limit",
 			policy: &Policy{
@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/sigloop/sdk-go/oracle"
+)
+
+type PriceAction string
+
+const (
+	PriceDeny      PriceAction = "deny"
+	PriceLastKnown PriceAction = "last_known"
+	PriceIgnore    PriceAction = "ignore"
+)
+
+type PriceOption func(*priceFeed)
+
+type priceFeed struct {
+	oracle    oracle.PriceOracle
+	onStale   PriceAction
+	onMissing PriceAction
+	last      map[priceKey]oracle.Price
+	mu        sync.Mutex
+}
+
+func WithPriceOracle(o oracle.PriceOracle, opts ...PriceOption) PolicyServiceOption {
+	return func(s *PolicyService) {
+		s.prices = newPriceFeed(o, opts...)
+	}
+}
+
+func newPriceFeed(o oracle.PriceOracle, opts ...PriceOption) *priceFeed {
+	f := &priceFeed{
+		oracle:    o,
+		onStale:   PriceDeny,
+		onMissing: PriceDeny,
+		last:      make(map[priceKey]oracle.Price),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func OnStalePrice(action PriceAction) PriceOption {
+	return func(f *priceFeed) {
+		f.onStale = action
+	}
+}
+
+func OnMissingPrice(action PriceAction) PriceOption {
+	return func(f *priceFeed) {
+		f.onMissing = action
+	}
+}
+
+func (s *PolicyService) price(ctx context.Context, p *Policy, tx *Transaction) error {
+	if s.prices == nil || tx == nil {
+		return nil
+	}
+	for _, quote := range quotes(p) {
+		for _, spend := range tx.spends() {
+			key := priceKey{spend.token, quote}
+			if tx.price(key.token, key.quote) != nil || tx.priceErrs[key] != nil {
+				continue
+			}
+			price, err := s.prices.fetch(ctx, key)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				if tx.priceErrs == nil {
+					tx.priceErrs = make(map[priceKey]error)
+				}
+				tx.priceErrs[key] = err
+				continue
+			}
+			tx.Prices = append(tx.Prices, *price)
+		}
+	}
+	return nil
+}
+
+func (f *priceFeed) fetch(ctx context.Context, key priceKey) (*oracle.Price, error) {
+	price, err := f.oracle.Price(ctx, key.token, key.quote)
+	if err == nil {
+		f.mu.Lock()
+		f.last[key] = *price
+		f.mu.Unlock()
+		return price, nil
+	}
+
+	action := PriceDeny
+	switch {
+	case errors.Is(err, oracle.ErrStalePrice):
+		action = f.onStale
+	case errors.Is(err, oracle.ErrNoFeed):
+		action = f.onMissing
+	}
+
+	switch action {
+	case PriceLastKnown:
+		f.mu.Lock()
+		last, ok := f.last[key]
+		f.mu.Unlock()
+		if ok {
+			return &last, nil
+		}
+	case PriceIgnore:
+		return &oracle.Price{Token: key.token, Quote: key.quote, Answer: new(big.Int)}, nil
+	}
+	return nil, err
+}
+
+func quotes(p *Policy) []string {
+	var result []string
+	add := func(quote string) {
+		if quote == "" {
+			return
+		}
+		for _, q := range result {
+			if q == quote {
+				return
+			}
+		}
+		result = append(result, quote)
+	}
+	for _, sl := range p.SpendingLimits {
+		add(sl.Quote)
+	}
+	if p.Escalation != nil {
+		for _, th := range p.Escalation.Thresholds {
+			add(th.Quote)
+		}
+	}
+	return result
+}
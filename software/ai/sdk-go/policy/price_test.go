@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/oracle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(100_000_000))
+}
+
+func quoteLimit() SpendingLimit {
+	return SpendingLimit{Quote: "USD", MaxAmount: usd(5000), Spent: big.NewInt(0), Period: time.Hour, ResetAt: evalTime.Add(time.Hour)}
+}
+
+func TestEvaluateQuoteSpendingLimit(t *testing.T) {
+	prices := []oracle.Price{
+		{Quote: "USD", Answer: big.NewInt(3000), TokenDecimals: 18},
+		{Token: usdc, Quote: "USD", Answer: big.NewInt(1), TokenDecimals: 6},
+	}
+
+	tests := []struct {
+		name       string
+		tx         *Transaction
+		passed     bool
+		wantReason string
+	}{
+		{
+			"native converted",
+			&Transaction{Value: ether(1), Prices: prices, Time: evalTime},
+			true,
+			"USD spend 300000000000 brings period total to 300000000000 of 500000000000",
+		},
+		{
+			"native and token summed",
+			&Transaction{Value: ether(1), Token: usdc, Amount: big.NewInt(2_500_000_000), Prices: prices, Time: evalTime},
+			false,
+			"USD spend 550000000000 would bring period total to 550000000000, above limit 500000000000",
+		},
+		{
+			"missing price",
+			&Transaction{Token: weth, Amount: ether(1), Prices: prices, Time: evalTime},
+			false,
+			"no USD price for 0x4200000000000000000000000000000000000006",
+		},
+		{
+			"no spend",
+			&Transaction{Time: evalTime},
+			true,
+			"no USD spend",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Evaluate(context.Background(), &Policy{SpendingLimits: []SpendingLimit{quoteLimit()}}, tt.tx)
+			require.NoError(t, err)
+			require.Len(t, d.Results, 1)
+			assert.Equal(t, tt.passed, d.Results[0].Passed)
+			assert.Equal(t, tt.wantReason, d.Results[0].Reason)
+		})
+	}
+}
+
+func TestPolicyServicePriceOracle(t *testing.T) {
+	ctx := context.Background()
+	ethTx := func() *Transaction {
+		return &Transaction{Value: ether(1), Time: evalTime}
+	}
+
+	newService := func(opts ...PriceOption) (*PolicyService, *oracle.StaticOracle, *fakeClock, string) {
+		clock := &fakeClock{now: evalTime}
+		o := oracle.NewStaticOracle(oracle.WithClock(clock.Now), oracle.WithMaxAge(time.Hour))
+		require.NoError(t, o.Set(usdc, "USD", 6, "1"))
+		require.NoError(t, o.Set(weth, "USD", 18, "3000"))
+		require.NoError(t, o.Set(common.Address{}, "USD", 18, "3000"))
+		svc := NewPolicyService(WithPriceOracle(o, opts...))
+		p, err := svc.CreatePolicy(&Policy{SpendingLimits: []SpendingLimit{quoteLimit()}})
+		require.NoError(t, err)
+		return svc, o, clock, p.ID
+	}
+
+	t.Run("converts at evaluation time", func(t *testing.T) {
+		svc, o, _, id := newService()
+		d, err := svc.Evaluate(ctx, id, ethTx())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		require.NoError(t, svc.Record(id, ethTx()))
+
+		require.NoError(t, o.Set(common.Address{}, "USD", 18, "2000.01"))
+		d, err = svc.Evaluate(ctx, id, ethTx())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+
+		got, err := svc.GetPolicy(id)
+		require.NoError(t, err)
+		assert.Equal(t, usd(3000), got.SpendingLimits[0].Spent)
+	})
+
+	t.Run("stale price denies", func(t *testing.T) {
+		svc, _, clock, id := newService()
+		clock.Advance(2 * time.Hour)
+		d, err := svc.Evaluate(ctx, id, ethTx())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, "stale price for 0x0000000000000000000000000000000000000000/USD: updated 2026-03-04T10:30:00Z", d.Failures()[0].Reason)
+		assert.EqualError(t, svc.Record(id, ethTx()), d.Failures()[0].Reason)
+	})
+
+	t.Run("stale price uses last known", func(t *testing.T) {
+		svc, _, clock, id := newService(OnStalePrice(PriceLastKnown))
+		d, err := svc.Evaluate(ctx, id, ethTx())
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+
+		clock.Advance(2 * time.Hour)
+		tx := ethTx()
+		d, err = svc.Evaluate(ctx, id, tx)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		require.Len(t, tx.Prices, 1)
+		assert.Equal(t, big.NewInt(3000), tx.Prices[0].Answer)
+	})
+
+	t.Run("missing feed", func(t *testing.T) {
+		svc, _, _, id := newService()
+		tx := &Transaction{Token: evalToken, Amount: big.NewInt(1), Time: evalTime}
+		d, err := svc.Evaluate(ctx, id, tx)
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, "no price feed for 0x2222222222222222222222222222222222222222/USD", d.Failures()[0].Reason)
+
+		svc, _, _, id = newService(OnMissingPrice(PriceIgnore))
+		tx = &Transaction{Token: evalToken, Amount: big.NewInt(1), Time: evalTime}
+		d, err = svc.Evaluate(ctx, id, tx)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	})
+
+	t.Run("reserve", func(t *testing.T) {
+		clock := &fakeClock{now: evalTime}
+		o := oracle.NewStaticOracle(oracle.WithClock(clock.Now))
+		require.NoError(t, o.Set(common.Address{}, "USD", 18, "3000"))
+		tracker, _ := newTestTracker()
+		svc := NewPolicyService(WithPriceOracle(o), WithSpendingTracker(tracker))
+		p, err := svc.CreatePolicy(&Policy{SpendingLimits: []SpendingLimit{{Quote: "USD", MaxAmount: usd(5000), Period: Day}}})
+		require.NoError(t, err)
+
+		_, r, err := svc.Reserve(ctx, p.ID, ethTx())
+		require.NoError(t, err)
+		require.NoError(t, svc.Commit(r))
+
+		status, err := tracker.Status(p.ID, &p.SpendingLimits[0])
+		require.NoError(t, err)
+		assert.Equal(t, usd(3000), status.Spent)
+		assert.Equal(t, "p1/USD/24h0m0s", NewSpendingKey("p1", &p.SpendingLimits[0]).String())
+
+		d, _, err := svc.Reserve(ctx, p.ID, ethTx())
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+	})
+}
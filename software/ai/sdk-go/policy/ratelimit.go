@@ -0,0 +1,296 @@
+package policy
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sigloop/sdk-go/storage"
+)
+
+type RateLimitMode string
+
+const (
+	RateLimitFixedWindow   RateLimitMode = "fixed_window"
+	RateLimitSlidingWindow RateLimitMode = "sliding_window"
+	RateLimitTokenBucket   RateLimitMode = "token_bucket"
+)
+
+type RateLimitKey struct {
+	PolicyID string
+	AgentID  string
+}
+
+func (k RateLimitKey) String() string {
+	return k.PolicyID + "/" + k.AgentID
+}
+
+type RateLimitState struct {
+	WindowStart time.Time   `json:"windowStart"`
+	Calls       uint64      `json:"calls,omitempty"`
+	Timestamps  []time.Time `json:"timestamps,omitempty"`
+	Tokens      float64     `json:"tokens,omitempty"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+type RateLimitStatus struct {
+	Allowed   bool
+	Remaining uint64
+	RetryAt   time.Time
+}
+
+type RateLimitStore interface {
+	Load(key RateLimitKey) (*RateLimitState, bool, error)
+	Save(key RateLimitKey, state *RateLimitState) error
+	Delete(key RateLimitKey) error
+}
+
+type RateLimiter struct {
+	store RateLimitStore
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+type RateLimiterOption func(*RateLimiter)
+
+func WithRateLimitStore(store RateLimitStore) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.store = store
+	}
+}
+
+func WithClock(now func() time.Time) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.now = now
+	}
+}
+
+func NewRateLimiter(opts ...RateLimiterOption) *RateLimiter {
+	l := &RateLimiter{}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.store == nil {
+		l.store = NewMemoryRateLimitStore()
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	return l
+}
+
+func (l *RateLimiter) Check(key RateLimitKey, rl *RateLimit) (*RateLimitStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, status, err := l.advance(key, rl)
+	return status, err
+}
+
+func (l *RateLimiter) Allow(key RateLimitKey, rl *RateLimit) (*RateLimitStatus, error) {
+	status, _, err := l.allow(key, rl)
+	return status, err
+}
+
+func (l *RateLimiter) allow(key RateLimitKey, rl *RateLimit) (*RateLimitStatus, time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, status, err := l.advance(key, rl)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !status.Allowed {
+		return status, time.Time{}, errors.New("rate limit exceeded")
+	}
+
+	now := l.now()
+	switch rateLimitMode(rl) {
+	case RateLimitFixedWindow:
+		state.Calls++
+	case RateLimitSlidingWindow:
+		state.Timestamps = append(state.Timestamps, now)
+	case RateLimitTokenBucket:
+		state.Tokens--
+	}
+	status.Remaining--
+
+	if err := l.store.Save(key, state); err != nil {
+		return nil, time.Time{}, err
+	}
+	return status, now, nil
+}
+
+func (l *RateLimiter) refund(key RateLimitKey, rl *RateLimit, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, _, err := l.advance(key, rl)
+	if err != nil {
+		return err
+	}
+	switch rateLimitMode(rl) {
+	case RateLimitFixedWindow:
+		if state.Calls > 0 && !at.Before(state.WindowStart) {
+			state.Calls--
+		}
+	case RateLimitSlidingWindow:
+		for i, ts := range state.Timestamps {
+			if ts.Equal(at) {
+				state.Timestamps = append(state.Timestamps[:i], state.Timestamps[i+1:]...)
+				break
+			}
+		}
+	case RateLimitTokenBucket:
+		state.Tokens = math.Min(float64(rl.MaxCalls), state.Tokens+1)
+	}
+	return l.store.Save(key, state)
+}
+
+func (l *RateLimiter) Reset(key RateLimitKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.store.Delete(key)
+}
+
+func (l *RateLimiter) advance(key RateLimitKey, rl *RateLimit) (*RateLimitState, *RateLimitStatus, error) {
+	if rl == nil {
+		return nil, nil, errors.New("nil rate limit")
+	}
+	if rl.MaxCalls == 0 || rl.Period <= 0 {
+		return nil, nil, errors.New("invalid rate limit")
+	}
+
+	loaded, ok, err := l.store.Load(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	state := &RateLimitState{}
+	if ok {
+		*state = *loaded
+		state.Timestamps = append([]time.Time(nil), loaded.Timestamps...)
+	}
+
+	now := l.now()
+	status := &RateLimitStatus{}
+
+	switch rateLimitMode(rl) {
+	case RateLimitFixedWindow:
+		if state.WindowStart.IsZero() || !now.Before(state.WindowStart.Add(rl.Period)) {
+			state.WindowStart = now
+			state.Calls = 0
+		}
+		if state.Calls < rl.MaxCalls {
+			status.Remaining = rl.MaxCalls - state.Calls
+		}
+		status.RetryAt = state.WindowStart.Add(rl.Period)
+
+	case RateLimitSlidingWindow:
+		cutoff := now.Add(-rl.Period)
+		kept := state.Timestamps[:0]
+		for _, ts := range state.Timestamps {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		state.Timestamps = kept
+		if n := uint64(len(kept)); n < rl.MaxCalls {
+			status.Remaining = rl.MaxCalls - n
+		}
+		if len(kept) > 0 {
+			status.RetryAt = kept[0].Add(rl.Period)
+		}
+
+	case RateLimitTokenBucket:
+		capacity := float64(rl.MaxCalls)
+		if state.UpdatedAt.IsZero() {
+			state.Tokens = capacity
+		} else if elapsed := now.Sub(state.UpdatedAt); elapsed > 0 {
+			state.Tokens = math.Min(capacity, state.Tokens+capacity*elapsed.Seconds()/rl.Period.Seconds())
+		}
+		state.UpdatedAt = now
+		status.Remaining = uint64(math.Floor(state.Tokens))
+		if state.Tokens < 1 {
+			wait := (1 - state.Tokens) * rl.Period.Seconds() / capacity
+			status.RetryAt = now.Add(time.Duration(math.Ceil(wait * float64(time.Second))))
+		}
+
+	default:
+		return nil, nil, errors.New("unsupported rate limit mode")
+	}
+
+	status.Allowed = status.Remaining > 0
+	if status.Allowed {
+		status.RetryAt = time.Time{}
+	}
+	return state, status, nil
+}
+
+func rateLimitMode(rl *RateLimit) RateLimitMode {
+	if rl.Mode == "" {
+		return RateLimitFixedWindow
+	}
+	return rl.Mode
+}
+
+type MemoryRateLimitStore struct {
+	states map[RateLimitKey]*RateLimitState
+	mu     sync.RWMutex
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		states: make(map[RateLimitKey]*RateLimitState),
+	}
+}
+
+func (s *MemoryRateLimitStore) Load(key RateLimitKey) (*RateLimitState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[key]
+	return state, ok, nil
+}
+
+func (s *MemoryRateLimitStore) Save(key RateLimitKey, state *RateLimitState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state
+	return nil
+}
+
+func (s *MemoryRateLimitStore) Delete(key RateLimitKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+type FileRateLimitStore struct {
+	file *storage.FileStore
+}
+
+func NewFileRateLimitStore(path string) (*FileRateLimitStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRateLimitStore{file: file}, nil
+}
+
+func (s *FileRateLimitStore) Load(key RateLimitKey) (*RateLimitState, bool, error) {
+	var state RateLimitState
+	ok, err := s.file.Get(key.String(), &state)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &state, true, nil
+}
+
+func (s *FileRateLimitStore) Save(key RateLimitKey, state *RateLimitState) error {
+	return s.file.Put(key.String(), state)
+}
+
+func (s *FileRateLimitStore) Delete(key RateLimitKey) error {
+	return s.file.Delete(key.String())
+}
@@ -0,0 +1,276 @@
+package policy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(opts ...RateLimiterOption) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return NewRateLimiter(append([]RateLimiterOption{WithClock(clock.Now)}, opts...)...), clock
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	l := NewRateLimiter()
+	assert.IsType(t, &MemoryRateLimitStore{}, l.store)
+	assert.NotNil(t, l.now)
+}
+
+func TestRateLimiterFixedWindow(t *testing.T) {
+	l, clock := newTestLimiter()
+	key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+	rl := &RateLimit{MaxCalls: 2, Period: time.Minute}
+
+	status, err := l.Allow(key, rl)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), status.Remaining)
+
+	clock.Advance(30 * time.Second)
+	_, err = l.Allow(key, rl)
+	require.NoError(t, err)
+
+	status, err = l.Allow(key, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+	assert.False(t, status.Allowed)
+	assert.Equal(t, clock.Now().Add(30*time.Second), status.RetryAt)
+
+	clock.Advance(30 * time.Second)
+	status, err = l.Allow(key, rl)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), status.Remaining)
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	l, clock := newTestLimiter()
+	key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+	rl := &RateLimit{MaxCalls: 2, Period: time.Minute, Mode: RateLimitSlidingWindow}
+
+	_, err := l.Allow(key, rl)
+	require.NoError(t, err)
+	clock.Advance(40 * time.Second)
+	_, err = l.Allow(key, rl)
+	require.NoError(t, err)
+
+	clock.Advance(10 * time.Second)
+	status, err := l.Allow(key, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+	assert.Equal(t, clock.Now().Add(10*time.Second), status.RetryAt)
+
+	clock.Advance(10 * time.Second)
+	status, err = l.Allow(key, rl)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), status.Remaining)
+
+	_, err = l.Allow(key, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	l, clock := newTestLimiter()
+	key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+	rl := &RateLimit{MaxCalls: 4, Period: time.Minute, Mode: RateLimitTokenBucket}
+
+	for i := 0; i < 4; i++ {
+		_, err := l.Allow(key, rl)
+		require.NoError(t, err)
+	}
+	status, err := l.Allow(key, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+	assert.Equal(t, clock.Now().Add(15*time.Second), status.RetryAt)
+
+	clock.Advance(15 * time.Second)
+	_, err = l.Allow(key, rl)
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	status, err = l.Check(key, rl)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), status.Remaining)
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	l, _ := newTestLimiter()
+	rl := &RateLimit{MaxCalls: 1, Period: time.Minute}
+
+	_, err := l.Allow(RateLimitKey{PolicyID: "p1", AgentID: "a1"}, rl)
+	require.NoError(t, err)
+	_, err = l.Allow(RateLimitKey{PolicyID: "p1", AgentID: "a2"}, rl)
+	require.NoError(t, err)
+	_, err = l.Allow(RateLimitKey{PolicyID: "p2", AgentID: "a1"}, rl)
+	require.NoError(t, err)
+	_, err = l.Allow(RateLimitKey{PolicyID: "p1", AgentID: "a1"}, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+
+	require.NoError(t, l.Reset(RateLimitKey{PolicyID: "p1", AgentID: "a1"}))
+	_, err = l.Allow(RateLimitKey{PolicyID: "p1", AgentID: "a1"}, rl)
+	require.NoError(t, err)
+}
+
+func TestRateLimiterCheckDoesNotConsume(t *testing.T) {
+	l, _ := newTestLimiter()
+	key := RateLimitKey{PolicyID: "p1"}
+	rl := &RateLimit{MaxCalls: 1, Period: time.Minute, Mode: RateLimitSlidingWindow}
+
+	for i := 0; i < 3; i++ {
+		status, err := l.Check(key, rl)
+		require.NoError(t, err)
+		assert.True(t, status.Allowed)
+	}
+	_, err := l.Allow(key, rl)
+	require.NoError(t, err)
+	status, err := l.Check(key, rl)
+	require.NoError(t, err)
+	assert.False(t, status.Allowed)
+}
+
+func TestRateLimiterErrors(t *testing.T) {
+	l, _ := newTestLimiter()
+	key := RateLimitKey{PolicyID: "p1"}
+
+	tests := []struct {
+		name    string
+		rl      *RateLimit
+		wantErr string
+	}{
+		{"nil rate limit", nil, "nil rate limit"},
+		{"zero max calls", &RateLimit{Period: time.Minute}, "invalid rate limit"},
+		{"zero period", &RateLimit{MaxCalls: 1}, "invalid rate limit"},
+		{"unknown mode", &RateLimit{MaxCalls: 1, Period: time.Minute, Mode: "leaky"}, "unsupported rate limit mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := l.Allow(key, tt.rl)
+			assert.EqualError(t, err, tt.wantErr)
+			_, err = l.Check(key, tt.rl)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	for _, mode := range []RateLimitMode{RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket} {
+		t.Run(string(mode), func(t *testing.T) {
+			l, _ := newTestLimiter()
+			key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+			rl := &RateLimit{MaxCalls: 10, Period: time.Minute, Mode: mode}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			allowed := 0
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := l.Allow(key, rl); err == nil {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, 10, allowed)
+		})
+	}
+}
+
+func TestRateLimiterRefund(t *testing.T) {
+	for _, mode := range []RateLimitMode{RateLimitFixedWindow, RateLimitSlidingWindow, RateLimitTokenBucket} {
+		t.Run(string(mode), func(t *testing.T) {
+			l, clock := newTestLimiter()
+			key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+			rl := &RateLimit{MaxCalls: 2, Period: time.Minute, Mode: mode}
+
+			_, err := l.Allow(key, rl)
+			require.NoError(t, err)
+			clock.Advance(time.Second)
+			_, at, err := l.allow(key, rl)
+			require.NoError(t, err)
+			_, err = l.Allow(key, rl)
+			assert.EqualError(t, err, "rate limit exceeded")
+
+			require.NoError(t, l.refund(key, rl, at))
+			status, err := l.Check(key, rl)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), status.Remaining)
+		})
+	}
+}
+
+func TestFileRateLimitStore(t *testing.T) {
+	path := t.TempDir() + "/ratelimits.json"
+	store, err := NewFileRateLimitStore(path)
+	require.NoError(t, err)
+
+	l, clock := newTestLimiter(WithRateLimitStore(store))
+	key := RateLimitKey{PolicyID: "p1", AgentID: "a1"}
+	rl := &RateLimit{MaxCalls: 2, Period: time.Minute, Mode: RateLimitSlidingWindow}
+
+	_, err = l.Allow(key, rl)
+	require.NoError(t, err)
+	_, err = l.Allow(key, rl)
+	require.NoError(t, err)
+
+	reopened, err := NewFileRateLimitStore(path)
+	require.NoError(t, err)
+	l2 := NewRateLimiter(WithRateLimitStore(reopened), WithClock(clock.Now))
+	_, err = l2.Allow(key, rl)
+	assert.EqualError(t, err, "rate limit exceeded")
+
+	require.NoError(t, l2.Reset(key))
+	_, ok, err := reopened.Load(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPolicyServiceRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+	svc := NewPolicyService(WithRateLimiter(l))
+
+	created, err := svc.CreatePolicy(&Policy{
+		RateLimit: &RateLimit{MaxCalls: 1, Period: time.Minute, Mode: RateLimitTokenBucket},
+	})
+	require.NoError(t, err)
+
+	tx := &Transaction{AgentID: "a1"}
+	d, err := svc.Evaluate(ctx, created.ID, tx)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "1 of 1 calls remaining", d.Results[0].Reason)
+
+	require.NoError(t, svc.Record(created.ID, tx))
+	assert.EqualError(t, svc.Record(created.ID, tx), "rate limit exceeded")
+
+	d, err = svc.Evaluate(ctx, created.ID, tx)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Contains(t, d.Results[0].Reason, "rate limit exceeded until")
+
+	d, err = svc.Evaluate(ctx, created.ID, &Transaction{AgentID: "a2"})
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+}
@@ -0,0 +1,12 @@
+package policy
+
+import (
+	_ "embed"
+)
+
+//go:embed policy.schema.json
+var policySchema []byte
+
+func PolicySchema() []byte {
+	return append([]byte(nil), policySchema...)
+}
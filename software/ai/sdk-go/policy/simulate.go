@@ -0,0 +1,388 @@
+package policy
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/oracle"
+	"github.com/sigloop/sdk-go/x402"
+)
+
+const erc20TransferSignature = "transfer(address,uint256)"
+
+var spendSignatures = []string{
+	erc20TransferSignature,
+	defi.SwapExactTokensForTokensSignature,
+	defi.SupplySignature,
+	defi.BorrowSignature,
+	defi.RepaySignature,
+}
+
+type ActionKind string
+
+const (
+	ActionTransaction   ActionKind = "transaction"
+	ActionUserOperation ActionKind = "user_operation"
+	ActionPayment       ActionKind = "x402_payment"
+	ActionDeFi          ActionKind = "defi"
+)
+
+type Action struct {
+	Kind        ActionKind
+	Source      string
+	Transaction *Transaction
+}
+
+type Outcome string
+
+const (
+	OutcomeAllowed  Outcome = "allowed"
+	OutcomeDenied   Outcome = "denied"
+	OutcomeApproval Outcome = "needs_approval"
+)
+
+type SimulatedAction struct {
+	Action   Action
+	Outcome  Outcome
+	Decision *Decision
+	Baseline Outcome
+}
+
+type OutcomeChange struct {
+	Index   int
+	Action  Action
+	Before  Outcome
+	After   Outcome
+	Reasons []string
+}
+
+type SpendingSeries struct {
+	Token     common.Address
+	Quote     string
+	Period    time.Duration
+	MaxAmount *big.Int
+	Points    []SpendingPoint
+}
+
+type SpendingPoint struct {
+	Time  time.Time
+	Spent *big.Int
+}
+
+type SimulationReport struct {
+	Actions    []SimulatedAction
+	Allowed    int
+	Denied     int
+	Escalated  int
+	Rejections map[Rule]int
+	Spending   []SpendingSeries
+	Changes    []OutcomeChange
+}
+
+type SimulationOption func(*simulation)
+
+type simulation struct {
+	baseline *Policy
+	prices   *priceFeed
+}
+
+func WithBaseline(p *Policy) SimulationOption {
+	return func(s *simulation) {
+		s.baseline = p
+	}
+}
+
+func WithSimulationPrices(o oracle.PriceOracle, opts ...PriceOption) SimulationOption {
+	return func(s *simulation) {
+		s.prices = newPriceFeed(o, opts...)
+	}
+}
+
+func Simulate(ctx context.Context, candidate *Policy, actions []Action, opts ...SimulationOption) (*SimulationReport, error) {
+	sim := &simulation{}
+	for _, opt := range opts {
+		opt(sim)
+	}
+
+	if err := validatePolicy(candidate); err != nil {
+		return nil, err
+	}
+	ordered, err := sortActions(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	current := newReplay(candidate, sim.prices)
+	var baseline *replay
+	if sim.baseline != nil {
+		if err := validatePolicy(sim.baseline); err != nil {
+			return nil, fmt.Errorf("baseline: %w", err)
+		}
+		baseline = newReplay(sim.baseline, sim.prices)
+	}
+
+	report := &SimulationReport{Rejections: make(map[Rule]int)}
+	for _, sl := range current.p.SpendingLimits {
+		report.Spending = append(report.Spending, SpendingSeries{
+			Token:     sl.Token,
+			Quote:     sl.Quote,
+			Period:    sl.Period,
+			MaxAmount: cloneInt(sl.MaxAmount),
+		})
+	}
+
+	for i, a := range ordered {
+		d, err := current.step(ctx, a.Transaction)
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %w", i, err)
+		}
+		result := SimulatedAction{Action: a, Outcome: outcomeOf(d), Decision: d}
+		switch result.Outcome {
+		case OutcomeAllowed:
+			report.Allowed++
+		case OutcomeApproval:
+			report.Escalated++
+		default:
+			report.Denied++
+		}
+
+		rejected := make(map[Rule]bool)
+		for _, f := range d.Failures() {
+			if !rejected[f.Rule] {
+				rejected[f.Rule] = true
+				report.Rejections[f.Rule]++
+			}
+		}
+
+		for j := range current.p.SpendingLimits {
+			report.Spending[j].Points = append(report.Spending[j].Points, SpendingPoint{
+				Time:  a.Transaction.Time,
+				Spent: periodSpent(&current.p.SpendingLimits[j], a.Transaction.Time),
+			})
+		}
+
+		if baseline != nil {
+			bd, err := baseline.step(ctx, a.Transaction)
+			if err != nil {
+				return nil, fmt.Errorf("baseline action %d: %w", i, err)
+			}
+			result.Baseline = outcomeOf(bd)
+			if result.Baseline != result.Outcome {
+				reasons := failureReasons(d)
+				if result.Outcome == OutcomeAllowed {
+					reasons = failureReasons(bd)
+				}
+				report.Changes = append(report.Changes, OutcomeChange{
+					Index:   i,
+					Action:  a,
+					Before:  result.Baseline,
+					After:   result.Outcome,
+					Reasons: reasons,
+				})
+			}
+		}
+
+		report.Actions = append(report.Actions, result)
+	}
+	return report, nil
+}
+
+func (s *PolicyService) Simulate(ctx context.Context, id string, candidate *Policy, actions []Action, opts ...SimulationOption) (*SimulationReport, error) {
+	current, err := s.GetPolicy(id)
+	if err != nil {
+		return nil, err
+	}
+	prices := s.prices
+	opts = append([]SimulationOption{WithBaseline(current), func(sim *simulation) { sim.prices = prices }}, opts...)
+	return Simulate(ctx, candidate, actions, opts...)
+}
+
+type replay struct {
+	p   *Policy
+	svc *PolicyService
+	now time.Time
+}
+
+func newReplay(p *Policy, prices *priceFeed) *replay {
+	r := &replay{p: resetState(p), svc: &PolicyService{prices: prices}}
+	r.svc.limiter = NewRateLimiter(WithClock(func() time.Time { return r.now }))
+	return r
+}
+
+func (r *replay) step(ctx context.Context, action *Transaction) (*Decision, error) {
+	tx := *action
+	tx.Prices = cloneSlice(action.Prices)
+	tx.priceErrs = nil
+	r.now = tx.Time
+
+	d, err := r.svc.evaluate(ctx, r.p, &tx)
+	if err != nil || !d.Allowed {
+		return d, err
+	}
+	if err := r.svc.allowRate(r.p, &tx); err != nil {
+		return nil, err
+	}
+	return d, Record(r.p, &tx)
+}
+
+func resetState(p *Policy) *Policy {
+	c := clonePolicy(p)
+	for i := range c.SpendingLimits {
+		sl := &c.SpendingLimits[i]
+		sl.Spent, sl.Spends, sl.ResetAt = nil, nil, time.Time{}
+	}
+	return c
+}
+
+func sortActions(actions []Action) ([]Action, error) {
+	sorted := cloneSlice(actions)
+	for i, a := range sorted {
+		if a.Transaction == nil {
+			return nil, fmt.Errorf("action %d has no transaction", i)
+		}
+		if a.Transaction.Time.IsZero() {
+			return nil, fmt.Errorf("action %d has no time", i)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Transaction.Time.Before(sorted[j].Transaction.Time)
+	})
+	return sorted, nil
+}
+
+func outcomeOf(d *Decision) Outcome {
+	switch {
+	case d.Allowed:
+		return OutcomeAllowed
+	case d.NeedsApproval():
+		return OutcomeApproval
+	default:
+		return OutcomeDenied
+	}
+}
+
+func failureReasons(d *Decision) []string {
+	var reasons []string
+	for _, f := range d.Failures() {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", f.Rule, f.Reason))
+	}
+	return reasons
+}
+
+func TransactionAction(tx *Transaction) Action {
+	return Action{Kind: ActionTransaction, Transaction: tx}
+}
+
+func PaymentAction(agentID string, r x402.PaymentRecord) Action {
+	tx := &Transaction{
+		To:      x402.PaymentToken,
+		Data:    append([]byte(nil), x402.TransferWithAuthorizationSelector[:]...),
+		Token:   x402.PaymentToken,
+		Amount:  cloneInt(r.Amount),
+		Payee:   r.PayTo,
+		AgentID: agentID,
+	}
+	if r.Timestamp != 0 {
+		tx.Time = time.Unix(int64(r.Timestamp), 0).UTC()
+	}
+	return Action{Kind: ActionPayment, Source: r.Resource, Transaction: tx}
+}
+
+func DeFiAction(agentID string, r *defi.DeFiResult, at time.Time) (Action, error) {
+	if r == nil {
+		return Action{}, errors.New("nil defi result")
+	}
+	tx, err := callTransaction(r.To, r.Value, r.Data)
+	if err != nil {
+		return Action{}, err
+	}
+	tx.AgentID, tx.Time = agentID, at
+	return Action{Kind: ActionDeFi, Transaction: tx}, nil
+}
+
+func UserOperationActions(agentID string, op *encoding.UserOperation, at time.Time) ([]Action, error) {
+	if op == nil {
+		return nil, errors.New("nil user operation")
+	}
+	targets, values, data, err := executeCalls(op.CallData)
+	if err != nil {
+		return nil, err
+	}
+
+	source := fmt.Sprintf("%s/%s", op.Sender.Hex(), op.Nonce)
+	actions := make([]Action, 0, len(targets))
+	for i := range targets {
+		tx, err := callTransaction(targets[i], values[i], data[i])
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		tx.AgentID, tx.Time = agentID, at
+		actions = append(actions, Action{Kind: ActionUserOperation, Source: source, Transaction: tx})
+	}
+	return actions, nil
+}
+
+func executeCalls(callData []byte) ([]common.Address, []*big.Int, [][]byte, error) {
+	if len(callData) < 4 {
+		return nil, nil, nil, errors.New("user operation has no call data")
+	}
+	switch hex.EncodeToString(callData[:4]) {
+	case Selector(encoding.ExecuteSignature):
+		args, err := encoding.DecodeFunctionCall(encoding.ExecuteSignature, callData)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return []common.Address{args[0].(common.Address)}, []*big.Int{args[1].(*big.Int)}, [][]byte{args[2].([]byte)}, nil
+	case Selector(encoding.ExecuteBatchSignature):
+		args, err := encoding.DecodeFunctionCall(encoding.ExecuteBatchSignature, callData)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		targets, values, data := args[0].([]common.Address), args[1].([]*big.Int), args[2].([][]byte)
+		if len(targets) != len(values) || len(targets) != len(data) {
+			return nil, nil, nil, errors.New("batch length mismatch")
+		}
+		return targets, values, data, nil
+	default:
+		return nil, nil, nil, errors.New("user operation call data is not execute or executeBatch")
+	}
+}
+
+func callTransaction(to common.Address, value *big.Int, data []byte) (*Transaction, error) {
+	tx := &Transaction{To: to, Value: value, Data: data}
+	if len(data) < 4 {
+		return tx, nil
+	}
+
+	selector := hex.EncodeToString(data[:4])
+	for _, sig := range spendSignatures {
+		if Selector(sig) != selector {
+			continue
+		}
+		args, err := encoding.DecodeFunctionCall(sig, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sig, err)
+		}
+		switch sig {
+		case erc20TransferSignature:
+			tx.Token, tx.Payee, tx.Amount = to, args[0].(common.Address), args[1].(*big.Int)
+		case defi.SwapExactTokensForTokensSignature:
+			if path := args[2].([]common.Address); len(path) > 0 {
+				tx.Token = path[0]
+			}
+			tx.Amount = args[0].(*big.Int)
+		default:
+			tx.Token, tx.Amount = args[0].(common.Address), args[1].(*big.Int)
+		}
+		return tx, nil
+	}
+	return tx, nil
+}
@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/x402"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+	payment := func(amount int64, at time.Duration) Action {
+		return PaymentAction("agent-1", x402.PaymentRecord{
+			Resource:  "https://api.example.com/data",
+			Amount:    big.NewInt(amount),
+			PayTo:     payee,
+			Timestamp: uint64(evalTime.Add(at).Unix()),
+		})
+	}
+	actions := []Action{
+		payment(4_000_000, 2*time.Hour),
+		payment(4_000_000, 0),
+		payment(4_000_000, time.Hour),
+		payment(4_000_000, 25*time.Hour),
+		TransactionAction(&Transaction{To: drainer, Time: evalTime.Add(3 * time.Hour)}),
+	}
+
+	current := &Policy{SpendingLimits: []SpendingLimit{{Token: x402.PaymentToken, MaxAmount: big.NewInt(20_000_000), Period: Day}}}
+	candidate := &Policy{
+		SpendingLimits:    []SpendingLimit{{Token: x402.PaymentToken, MaxAmount: big.NewInt(10_000_000), Period: Day, Window: SpendingSlidingWindow}},
+		ContractAllowlist: NewContractAllowlist([]common.Address{x402.PaymentToken}),
+	}
+
+	report, err := Simulate(ctx, candidate, actions, WithBaseline(current))
+	require.NoError(t, err)
+
+	require.Len(t, report.Actions, 5)
+	assert.Equal(t, evalTime, report.Actions[0].Action.Transaction.Time)
+	assert.Equal(t, ActionPayment, report.Actions[0].Action.Kind)
+	assert.Equal(t, "https://api.example.com/data", report.Actions[0].Action.Source)
+	assert.Equal(t, 3, report.Allowed)
+	assert.Equal(t, 2, report.Denied)
+	assert.Equal(t, map[Rule]int{RuleSpendingLimit: 1, RuleContractAllowlist: 1}, report.Rejections)
+
+	require.Len(t, report.Spending, 1)
+	var curve []int64
+	for _, p := range report.Spending[0].Points {
+		curve = append(curve, p.Spent.Int64())
+	}
+	assert.Equal(t, []int64{4_000_000, 8_000_000, 8_000_000, 8_000_000, 4_000_000}, curve)
+
+	require.Len(t, report.Changes, 2)
+	assert.Equal(t, OutcomeChange{
+		Index:   2,
+		Action:  actions[0],
+		Before:  OutcomeAllowed,
+		After:   OutcomeDenied,
+		Reasons: []string{"spending_limit: " + x402.PaymentToken.Hex() + " spend 4000000 would bring period total to 12000000, above limit 10000000"},
+	}, report.Changes[0])
+	assert.Equal(t, 3, report.Changes[1].Index)
+	assert.Equal(t, []string{"contract_allowlist: contract " + drainer.Hex() + " is not allowed"}, report.Changes[1].Reasons)
+
+	assert.Nil(t, candidate.SpendingLimits[0].Spent)
+}
+
+func TestSimulateRateLimit(t *testing.T) {
+	candidate := &Policy{RateLimit: &RateLimit{MaxCalls: 2, Period: time.Hour, Mode: RateLimitSlidingWindow}}
+	var actions []Action
+	for _, at := range []time.Duration{0, 10 * time.Minute, 20 * time.Minute, 61 * time.Minute} {
+		actions = append(actions, TransactionAction(&Transaction{To: router, Time: evalTime.Add(at)}))
+	}
+
+	report, err := Simulate(context.Background(), candidate, actions)
+	require.NoError(t, err)
+	var outcomes []Outcome
+	for _, a := range report.Actions {
+		outcomes = append(outcomes, a.Outcome)
+		assert.Empty(t, a.Baseline)
+	}
+	assert.Equal(t, []Outcome{OutcomeAllowed, OutcomeAllowed, OutcomeDenied, OutcomeAllowed}, outcomes)
+	assert.Empty(t, report.Changes)
+}
+
+func TestPolicyServiceSimulate(t *testing.T) {
+	svc := NewPolicyService()
+	current, err := svc.CreatePolicy(&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{router})})
+	require.NoError(t, err)
+	candidate := &Policy{
+		ContractAllowlist: NewContractAllowlist([]common.Address{router}),
+		Escalation:        &Escalation{Thresholds: []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(100)}}, Approvers: []common.Address{payee}},
+	}
+
+	swap := &defi.DeFiResult{To: router, Data: mustCalldata(t, swapSig, big.NewInt(500), big.NewInt(490), []common.Address{usdc, weth}, agentSA, big.NewInt(1700000000))}
+	action, err := DeFiAction("agent-1", swap, evalTime)
+	require.NoError(t, err)
+	assert.Equal(t, usdc, action.Transaction.Token)
+	assert.Equal(t, big.NewInt(500), action.Transaction.Amount)
+
+	borrow := &defi.DeFiResult{To: router, Data: mustCalldata(t, defi.BorrowSignature, usdc, big.NewInt(40), big.NewInt(2), uint16(0), agentSA)}
+	borrowAction, err := DeFiAction("agent-1", borrow, evalTime)
+	require.NoError(t, err)
+	assert.Equal(t, usdc, borrowAction.Transaction.Token)
+	assert.Equal(t, big.NewInt(40), borrowAction.Transaction.Amount)
+
+	report, err := svc.Simulate(context.Background(), current.ID, candidate, []Action{action})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Escalated)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, OutcomeAllowed, report.Changes[0].Before)
+	assert.Equal(t, OutcomeApproval, report.Changes[0].After)
+
+	_, err = svc.Simulate(context.Background(), "missing", candidate, nil)
+	assert.EqualError(t, err, "policy not found")
+}
+
+func TestUserOperationActions(t *testing.T) {
+	transfer := mustCalldata(t, transferSig, payee, big.NewInt(7))
+	supply := mustCalldata(t, defi.SupplySignature, usdc, big.NewInt(9), agentSA, uint16(0))
+	batch, err := encoding.EncodeBatchCallData([]common.Address{usdc, router}, []*big.Int{big.NewInt(0), big.NewInt(0)}, [][]byte{transfer, supply})
+	require.NoError(t, err)
+
+	actions, err := UserOperationActions("agent-1", &encoding.UserOperation{Sender: agentSA, Nonce: big.NewInt(3), CallData: batch}, evalTime)
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	tx := actions[0].Transaction
+	assert.Equal(t, []interface{}{usdc, transfer, usdc, big.NewInt(7), payee, "agent-1", evalTime}, []interface{}{tx.To, tx.Data, tx.Token, tx.Amount, tx.Payee, tx.AgentID, tx.Time})
+	assert.Zero(t, tx.Value.Sign())
+	assert.Equal(t, usdc, actions[1].Transaction.Token)
+	assert.Equal(t, big.NewInt(9), actions[1].Transaction.Amount)
+	assert.Equal(t, ActionUserOperation, actions[1].Kind)
+	assert.Equal(t, agentSA.Hex()+"/3", actions[1].Source)
+
+	single, err := encoding.EncodeCallData(router, ether(1), nil)
+	require.NoError(t, err)
+	actions, err = UserOperationActions("agent-1", &encoding.UserOperation{Sender: agentSA, Nonce: big.NewInt(4), CallData: single}, evalTime)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, ether(1), actions[0].Transaction.Value)
+
+	_, err = UserOperationActions("agent-1", &encoding.UserOperation{CallData: transfer}, evalTime)
+	assert.EqualError(t, err, "user operation call data is not execute or executeBatch")
+}
+
+func TestSimulateErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		candidate *Policy
+		actions   []Action
+		opts      []SimulationOption
+		wantErr   string
+	}{
+		{"nil candidate", nil, nil, nil, "nil policy"},
+		{"no transaction", &Policy{}, []Action{{Kind: ActionTransaction}}, nil, "action 0 has no transaction"},
+		{"no time", &Policy{}, []Action{PaymentAction("agent-1", x402.PaymentRecord{Amount: big.NewInt(1)})}, nil, "action 0 has no time"},
+		{"invalid baseline", &Policy{}, nil, []SimulationOption{WithBaseline(&Policy{MaxValuePerTx: big.NewInt(-1)})}, "baseline: invalid max value per transaction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Simulate(ctx, tt.candidate, tt.actions, tt.opts...)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -1,13 +1,39 @@
 package policy
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/storage"
 )
 
+const defaultReservationTTL = 10 * time.Minute
+
+const (
+	Day   = 24 * time.Hour
+	Week  = 7 * Day
+	Month = 30 * Day
+)
+
+type SpendingWindow string
+
+const (
+	SpendingFixedWindow         SpendingWindow = "fixed_window"
+	SpendingSlidingWindow       SpendingWindow = "sliding_window"
+	SpendingCalendarWindow      SpendingWindow = "calendar"
+	SpendingCalendarMonthWindow SpendingWindow = "calendar_month"
+)
+
+var spendingMu sync.Mutex
+
+var ErrSpendingOverrun = errors.New("spending overrun")
+
 func NewSpendingLimit(token common.Address, maxAmount *big.Int, period time.Duration) *SpendingLimit {
 	return &SpendingLimit{
 		Token:     token,
@@ -19,6 +45,12 @@ func NewSpendingLimit(token common.Address, maxAmount *big.Int, period time.Dura
 }
 
 func CheckSpendingLimit(sl *SpendingLimit, amount *big.Int) error {
+	spendingMu.Lock()
+	defer spendingMu.Unlock()
+	return checkSpendingLimit(sl, amount)
+}
+
+func checkSpendingLimit(sl *SpendingLimit, amount *big.Int) error {
 	if sl == nil {
 		return errors.New("nil spending limit")
 	}
@@ -27,11 +59,7 @@ func CheckSpendingLimit(sl *SpendingLimit, amount *big.Int) error {
 		return errors.New("invalid amount")
 	}
 
-	now := time.Now()
-	if now.After(sl.ResetAt) {
-		sl.Spent = big.NewInt(0)
-		sl.ResetAt = now.Add(sl.Period)
-	}
+	advanceSpendingLimit(sl, time.Now())
 
 	newTotal := new(big.Int).Add(sl.Spent, amount)
 	if newTotal.Cmp(sl.MaxAmount) > 0 {
@@ -42,10 +70,594 @@ func CheckSpendingLimit(sl *SpendingLimit, amount *big.Int) error {
 }
 
 func UpdateSpending(sl *SpendingLimit, amount *big.Int) error {
-	if err := CheckSpendingLimit(sl, amount); err != nil {
+	spendingMu.Lock()
+	defer spendingMu.Unlock()
+
+	if err := checkSpendingLimit(sl, amount); err != nil {
 		return err
 	}
 
+	addSpend(sl, amount, time.Now())
+	return nil
+}
+
+func spendingWindow(sl *SpendingLimit) SpendingWindow {
+	if sl.Window == "" {
+		return SpendingFixedWindow
+	}
+	return sl.Window
+}
+
+func calendarWindow(window SpendingWindow, period time.Duration, now time.Time) (time.Time, time.Time) {
+	if window == SpendingCalendarMonthWindow {
+		y, m, _ := now.UTC().Date()
+		start := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	n := now.UnixNano()
+	offset := n % int64(period)
+	if offset < 0 {
+		offset += int64(period)
+	}
+	start := time.Unix(0, n-offset).UTC()
+	return start, start.Add(period)
+}
+
+func periodSpent(sl *SpendingLimit, now time.Time) *big.Int {
+	current := *sl
+	advanceSpendingLimit(&current, now)
+	return current.Spent
+}
+
+func advanceSpendingLimit(sl *SpendingLimit, now time.Time) {
+	switch spendingWindow(sl) {
+	case SpendingSlidingWindow:
+		cutoff := now.Add(-sl.Period)
+		var kept []SpendRecord
+		spent := big.NewInt(0)
+		for _, r := range sl.Spends {
+			if r.Amount != nil && r.Time.After(cutoff) {
+				kept = append(kept, r)
+				spent.Add(spent, r.Amount)
+			}
+		}
+		sl.Spends, sl.Spent, sl.ResetAt = kept, spent, time.Time{}
+		if len(kept) > 0 {
+			sl.ResetAt = kept[0].Time.Add(sl.Period)
+		}
+	case SpendingCalendarWindow, SpendingCalendarMonthWindow:
+		_, end := calendarWindow(sl.Window, sl.Period, now)
+		if sl.Spent == nil || !sl.ResetAt.Equal(end) {
+			sl.Spent = big.NewInt(0)
+			sl.ResetAt = end
+		}
+	default:
+		if sl.Spent == nil || now.After(sl.ResetAt) {
+			sl.Spent = big.NewInt(0)
+			sl.ResetAt = now.Add(sl.Period)
+		}
+	}
+}
+
+func addSpend(sl *SpendingLimit, amount *big.Int, now time.Time) {
+	advanceSpendingLimit(sl, now)
 	sl.Spent = new(big.Int).Add(sl.Spent, amount)
+	if spendingWindow(sl) == SpendingSlidingWindow {
+		sl.Spends = append(sl.Spends, SpendRecord{Amount: new(big.Int).Set(amount), Time: now})
+		if len(sl.Spends) == 1 {
+			sl.ResetAt = now.Add(sl.Period)
+		}
+	}
+}
+
+type SpendingKey struct {
+	PolicyID string
+	Token    common.Address
+	Quote    string
+	Period   time.Duration
+	Window   SpendingWindow
+}
+
+func NewSpendingKey(policyID string, sl *SpendingLimit) SpendingKey {
+	key := SpendingKey{PolicyID: policyID, Token: sl.Token, Quote: sl.Quote, Period: sl.Period}
+	if w := spendingWindow(sl); w != SpendingFixedWindow {
+		key.Window = w
+	}
+	return key
+}
+
+func (k SpendingKey) String() string {
+	unit := k.Token.Hex()
+	if k.Quote != "" {
+		unit = k.Quote
+	}
+	s := fmt.Sprintf("%s/%s/%s", k.PolicyID, unit, k.Period)
+	if k.Window != "" && k.Window != SpendingFixedWindow {
+		s += "/" + string(k.Window)
+	}
+	return s
+}
+
+type SpendingState struct {
+	WindowStart time.Time      `json:"windowStart"`
+	Spent       *big.Int       `json:"spent"`
+	Spends      []SpendRecord  `json:"spends,omitempty"`
+	Holds       []SpendingHold `json:"holds,omitempty"`
+}
+
+func (s *SpendingState) add(key SpendingKey, amount *big.Int, now time.Time) {
+	s.Spent.Add(s.Spent, amount)
+	if key.Window == SpendingSlidingWindow {
+		s.Spends = append(s.Spends, SpendRecord{Amount: new(big.Int).Set(amount), Time: now})
+	}
+}
+
+func (s *SpendingState) resetAt(key SpendingKey) time.Time {
+	switch key.Window {
+	case SpendingSlidingWindow:
+		if len(s.Spends) == 0 {
+			return time.Time{}
+		}
+		return s.Spends[0].Time.Add(key.Period)
+	case SpendingCalendarWindow, SpendingCalendarMonthWindow:
+		_, end := calendarWindow(key.Window, key.Period, s.WindowStart)
+		return end
+	default:
+		return s.WindowStart.Add(key.Period)
+	}
+}
+
+type SpendingHold struct {
+	ID        string    `json:"id"`
+	Amount    *big.Int  `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SpendingStatus struct {
+	Spent     *big.Int
+	Reserved  *big.Int
+	Remaining *big.Int
+	ResetAt   time.Time
+}
+
+type Reservation struct {
+	ID        string
+	PolicyID  string
+	Amounts   []ReservedAmount
+	ExpiresAt time.Time
+
+	rate     *rateHold
+	approval string
+	settled  bool
+}
+
+type rateHold struct {
+	key   RateLimitKey
+	limit RateLimit
+	at    time.Time
+}
+
+type ReservedAmount struct {
+	Key    SpendingKey
+	Amount *big.Int
+
+	max *big.Int
+}
+
+type SpendingStore interface {
+	Load(key SpendingKey) (*SpendingState, bool, error)
+	Save(key SpendingKey, state *SpendingState) error
+	Delete(key SpendingKey) error
+}
+
+type SpendingTracker struct {
+	store SpendingStore
+	now   func() time.Time
+	ttl   time.Duration
+	mu    sync.Mutex
+}
+
+type SpendingTrackerOption func(*SpendingTracker)
+
+func WithSpendingStore(store SpendingStore) SpendingTrackerOption {
+	return func(t *SpendingTracker) {
+		t.store = store
+	}
+}
+
+func WithSpendingClock(now func() time.Time) SpendingTrackerOption {
+	return func(t *SpendingTracker) {
+		t.now = now
+	}
+}
+
+func WithReservationTTL(ttl time.Duration) SpendingTrackerOption {
+	return func(t *SpendingTracker) {
+		t.ttl = ttl
+	}
+}
+
+func NewSpendingTracker(opts ...SpendingTrackerOption) *SpendingTracker {
+	t := &SpendingTracker{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.store == nil {
+		t.store = NewMemorySpendingStore()
+	}
+	if t.now == nil {
+		t.now = time.Now
+	}
+	if t.ttl <= 0 {
+		t.ttl = defaultReservationTTL
+	}
+	return t
+}
+
+func (t *SpendingTracker) Reserve(policyID string, limits []SpendingLimit, tx *Transaction) (*Reservation, error) {
+	if tx == nil {
+		return nil, errors.New("nil transaction")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	amounts, states, err := t.check(policyID, limits, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	now := t.now()
+	r := &Reservation{ID: id, PolicyID: policyID, ExpiresAt: now.Add(t.ttl)}
+	for _, ra := range amounts {
+		state := states[ra.Key]
+		state.Holds = append(state.Holds, SpendingHold{ID: id, Amount: new(big.Int).Set(ra.Amount), ExpiresAt: r.ExpiresAt})
+		if err := t.store.Save(ra.Key, state); err != nil {
+			return nil, err
+		}
+		r.Amounts = append(r.Amounts, ra)
+	}
+	return r, nil
+}
+
+func (t *SpendingTracker) Commit(r *Reservation) error {
+	return t.settle(r, true)
+}
+
+func (t *SpendingTracker) Release(r *Reservation) error {
+	return t.settle(r, false)
+}
+
+func (t *SpendingTracker) settle(r *Reservation, commit bool) error {
+	if r == nil {
+		return errors.New("nil reservation")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r.settled {
+		return errors.New("reservation already settled")
+	}
+
+	var overrun error
+	states := make(map[SpendingKey]*SpendingState, len(r.Amounts))
+	for _, ra := range r.Amounts {
+		state, err := t.load(ra.Key)
+		if err != nil {
+			return err
+		}
+		held := false
+		kept := state.Holds[:0]
+		for _, h := range state.Holds {
+			if h.ID == r.ID {
+				held = true
+				continue
+			}
+			kept = append(kept, h)
+		}
+		state.Holds = kept
+		if commit && overrun == nil {
+			total := new(big.Int).Add(state.Spent, heldAmount(state))
+			switch {
+			case !held:
+				overrun = fmt.Errorf("%w: reservation expired", ErrSpendingOverrun)
+			case total.Add(total, ra.Amount).Cmp(ra.max) > 0:
+				overrun = fmt.Errorf("%w: spending limit exceeded", ErrSpendingOverrun)
+			}
+		}
+		states[ra.Key] = state
+	}
+
+	for _, ra := range r.Amounts {
+		state := states[ra.Key]
+		if commit {
+			state.add(ra.Key, ra.Amount, t.now())
+		}
+		if err := t.store.Save(ra.Key, state); err != nil {
+			return err
+		}
+	}
+	r.settled = true
+	return overrun
+}
+
+func (t *SpendingTracker) Spend(policyID string, limits []SpendingLimit, tx *Transaction) error {
+	if tx == nil {
+		return errors.New("nil transaction")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	amounts, err := spendAmounts(policyID, limits, tx)
+	if err != nil {
+		return err
+	}
+	for _, ra := range amounts {
+		if ra.Amount.Sign() < 0 {
+			return errors.New("invalid amount")
+		}
+		state, err := t.load(ra.Key)
+		if err != nil {
+			return err
+		}
+		state.add(ra.Key, ra.Amount, t.now())
+		if err := t.store.Save(ra.Key, state); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (t *SpendingTracker) Status(policyID string, sl *SpendingLimit) (*SpendingStatus, error) {
+	if sl == nil {
+		return nil, errors.New("nil spending limit")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := NewSpendingKey(policyID, sl)
+	state, err := t.load(key)
+	if err != nil {
+		return nil, err
+	}
+	reserved := heldAmount(state)
+	remaining := new(big.Int)
+	if sl.MaxAmount != nil {
+		remaining.Sub(sl.MaxAmount, state.Spent)
+		remaining.Sub(remaining, reserved)
+		if remaining.Sign() < 0 {
+			remaining.SetInt64(0)
+		}
+	}
+	return &SpendingStatus{
+		Spent:     state.Spent,
+		Reserved:  reserved,
+		Remaining: remaining,
+		ResetAt:   state.resetAt(key),
+	}, nil
+}
+
+func (t *SpendingTracker) Reset(key SpendingKey) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.store.Delete(key)
+}
+
+func (t *SpendingTracker) spent(policyID string, limits []SpendingLimit) ([]*big.Int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]*big.Int, len(limits))
+	for i := range limits {
+		state, err := t.load(NewSpendingKey(policyID, &limits[i]))
+		if err != nil {
+			return nil, err
+		}
+		result[i] = new(big.Int).Add(state.Spent, heldAmount(state))
+	}
+	return result, nil
+}
+
+func (t *SpendingTracker) check(policyID string, limits []SpendingLimit, tx *Transaction) ([]ReservedAmount, map[SpendingKey]*SpendingState, error) {
+	amounts, err := spendAmounts(policyID, limits, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	states := make(map[SpendingKey]*SpendingState, len(amounts))
+	spends := make(map[SpendingKey]*big.Int, len(amounts))
+	for _, ra := range amounts {
+		if ra.Amount.Sign() < 0 {
+			return nil, nil, errors.New("invalid amount")
+		}
+		state, err := t.load(ra.Key)
+		if err != nil {
+			return nil, nil, err
+		}
+		states[ra.Key] = state
+		spends[ra.Key] = ra.Amount
+	}
+
+	for _, sl := range limits {
+		key := NewSpendingKey(policyID, &sl)
+		state, ok := states[key]
+		if !ok {
+			continue
+		}
+		if sl.MaxAmount == nil {
+			return nil, nil, errors.New("spending limit has no maximum")
+		}
+		total := new(big.Int).Add(state.Spent, heldAmount(state))
+		total.Add(total, spends[key])
+		if total.Cmp(sl.MaxAmount) > 0 {
+			return nil, nil, errors.New("spending limit exceeded")
+		}
+		for i := range amounts {
+			if amounts[i].Key == key {
+				amounts[i].max = sl.MaxAmount
+			}
+		}
+	}
+	return amounts, states, nil
+}
+
+func (t *SpendingTracker) load(key SpendingKey) (*SpendingState, error) {
+	if key.Period <= 0 {
+		return nil, errors.New("invalid spending period")
+	}
+
+	loaded, ok, err := t.store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	state := &SpendingState{Spent: new(big.Int)}
+	if ok {
+		state.WindowStart = loaded.WindowStart
+		state.Spent = cloneInt(loaded.Spent)
+		if state.Spent == nil {
+			state.Spent = new(big.Int)
+		}
+		state.Spends = cloneSlice(loaded.Spends)
+		state.Holds = cloneSlice(loaded.Holds)
+	}
+
+	now := t.now()
+	switch key.Window {
+	case "", SpendingFixedWindow:
+		if state.WindowStart.IsZero() || !now.Before(state.WindowStart.Add(key.Period)) {
+			state.WindowStart = now
+			state.Spent = new(big.Int)
+		}
+	case SpendingCalendarWindow, SpendingCalendarMonthWindow:
+		if start, _ := calendarWindow(key.Window, key.Period, now); !state.WindowStart.Equal(start) {
+			state.WindowStart = start
+			state.Spent = new(big.Int)
+		}
+	case SpendingSlidingWindow:
+		state.WindowStart = now.Add(-key.Period)
+		kept := state.Spends[:0]
+		state.Spent = new(big.Int)
+		for _, r := range state.Spends {
+			if r.Amount != nil && r.Time.After(state.WindowStart) {
+				kept = append(kept, r)
+				state.Spent.Add(state.Spent, r.Amount)
+			}
+		}
+		state.Spends = kept
+	default:
+		return nil, errors.New("invalid spending window")
+	}
+	kept := state.Holds[:0]
+	for _, h := range state.Holds {
+		if now.Before(h.ExpiresAt) {
+			kept = append(kept, h)
+		}
+	}
+	state.Holds = kept
+	return state, nil
+}
+
+func spendAmounts(policyID string, limits []SpendingLimit, tx *Transaction) ([]ReservedAmount, error) {
+	var result []ReservedAmount
+	seen := make(map[SpendingKey]bool)
+	for i := range limits {
+		sl := &limits[i]
+		amount, err := spendAmount(sl, tx)
+		if err != nil {
+			return nil, err
+		}
+		if amount == nil || amount.Sign() == 0 {
+			continue
+		}
+		key := NewSpendingKey(policyID, sl)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, ReservedAmount{Key: key, Amount: new(big.Int).Set(amount)})
+	}
+	return result, nil
+}
+
+func heldAmount(state *SpendingState) *big.Int {
+	total := new(big.Int)
+	for _, h := range state.Holds {
+		total.Add(total, h.Amount)
+	}
+	return total
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type MemorySpendingStore struct {
+	states map[SpendingKey]*SpendingState
+	mu     sync.RWMutex
+}
+
+func NewMemorySpendingStore() *MemorySpendingStore {
+	return &MemorySpendingStore{
+		states: make(map[SpendingKey]*SpendingState),
+	}
+}
+
+func (s *MemorySpendingStore) Load(key SpendingKey) (*SpendingState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[key]
+	return state, ok, nil
+}
+
+func (s *MemorySpendingStore) Save(key SpendingKey, state *SpendingState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state
+	return nil
+}
+
+func (s *MemorySpendingStore) Delete(key SpendingKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+type FileSpendingStore struct {
+	file *storage.FileStore
+}
+
+func NewFileSpendingStore(path string) (*FileSpendingStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSpendingStore{file: file}, nil
+}
+
+func (s *FileSpendingStore) Load(key SpendingKey) (*SpendingState, bool, error) {
+	var state SpendingState
+	ok, err := s.file.Get(key.String(), &state)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &state, true, nil
+}
+
+func (s *FileSpendingStore) Save(key SpendingKey, state *SpendingState) error {
+	return s.file.Put(key.String(), state)
+}
+
+func (s *FileSpendingStore) Delete(key SpendingKey) error {
+	return s.file.Delete(key.String())
+}
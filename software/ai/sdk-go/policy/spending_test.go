@@ -1,7 +1,10 @@
 package policy
 
 import (
+	"context"
 	"math/big"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -187,3 +190,472 @@ func TestUpdateSpending(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestCalendarWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    SpendingWindow
+		period    time.Duration
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			"daily starts at midnight UTC",
+			SpendingCalendarWindow,
+			Day,
+			time.Date(2026, 10, 14, 23, 59, 0, 0, time.FixedZone("PDT", -7*3600)),
+			time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly starts on Thursday like block.timestamp / 1 weeks",
+			SpendingCalendarWindow,
+			Week,
+			time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"calendar month",
+			SpendingCalendarMonthWindow,
+			Month,
+			time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC),
+			time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"30 days is not a calendar month",
+			SpendingCalendarWindow,
+			Month,
+			time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC),
+			time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"hourly",
+			SpendingCalendarWindow,
+			time.Hour,
+			time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := calendarWindow(tt.window, tt.period, tt.now)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
+
+func TestSpendingWindows(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 10, 14, 22, 0, 0, 0, time.UTC)
+	spend := func(amount int64, at time.Duration) *Transaction {
+		return &Transaction{Token: usdc, Amount: big.NewInt(amount), Time: start.Add(at)}
+	}
+	allowed := func(t *testing.T, p *Policy, tx *Transaction) bool {
+		d, err := Evaluate(ctx, p, tx)
+		require.NoError(t, err)
+		if d.Allowed {
+			require.NoError(t, Record(p, tx))
+		}
+		return d.Allowed
+	}
+
+	t.Run("fixed window starts at the first spend", func(t *testing.T) {
+		p := &Policy{SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day}}}
+		assert.True(t, allowed(t, p, spend(100, 0)))
+		assert.False(t, allowed(t, p, spend(1, 3*time.Hour)))
+		assert.True(t, allowed(t, p, spend(100, Day+time.Second)))
+	})
+
+	t.Run("calendar window resets at midnight UTC", func(t *testing.T) {
+		p := &Policy{SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day, Window: SpendingCalendarWindow}}}
+		assert.True(t, allowed(t, p, spend(100, 0)))
+		assert.False(t, allowed(t, p, spend(1, time.Hour+59*time.Minute)))
+		assert.True(t, allowed(t, p, spend(100, 2*time.Hour)))
+		assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), p.SpendingLimits[0].ResetAt)
+	})
+
+	t.Run("sliding window counts the last period of spends", func(t *testing.T) {
+		p := &Policy{SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day, Window: SpendingSlidingWindow}}}
+		assert.True(t, allowed(t, p, spend(60, 0)))
+		assert.True(t, allowed(t, p, spend(40, 12*time.Hour)))
+		assert.False(t, allowed(t, p, spend(10, 23*time.Hour)))
+
+		assert.True(t, allowed(t, p, spend(10, Day+time.Second)))
+		assert.False(t, allowed(t, p, spend(60, Day+2*time.Second)))
+
+		sl := p.SpendingLimits[0]
+		assert.Equal(t, big.NewInt(50), sl.Spent)
+		assert.Len(t, sl.Spends, 2)
+		assert.Equal(t, start.Add(36*time.Hour), sl.ResetAt)
+	})
+
+	t.Run("update spending", func(t *testing.T) {
+		sl := &SpendingLimit{MaxAmount: big.NewInt(100), Period: time.Hour, Window: SpendingSlidingWindow}
+		require.NoError(t, UpdateSpending(sl, big.NewInt(70)))
+		assert.EqualError(t, UpdateSpending(sl, big.NewInt(31)), "spending limit exceeded")
+		assert.NoError(t, CheckSpendingLimit(sl, big.NewInt(30)))
+		assert.Len(t, sl.Spends, 1)
+	})
+}
+
+func newTestTracker(opts ...SpendingTrackerOption) (*SpendingTracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return NewSpendingTracker(append([]SpendingTrackerOption{WithSpendingClock(clock.Now)}, opts...)...), clock
+}
+
+func TestSpendingTracker(t *testing.T) {
+	limits := []SpendingLimit{
+		{Token: usdc, MaxAmount: big.NewInt(1000), Period: time.Hour},
+		{Token: usdc, MaxAmount: big.NewInt(1500), Period: 24 * time.Hour},
+	}
+	spend := func(amount int64) *Transaction {
+		return &Transaction{Token: usdc, Amount: big.NewInt(amount)}
+	}
+
+	t.Run("reserve and commit", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		r, err := tracker.Reserve("p1", limits, spend(600))
+		require.NoError(t, err)
+		require.Len(t, r.Amounts, 2)
+
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(0), status.Spent)
+		assert.Equal(t, big.NewInt(600), status.Reserved)
+		assert.Equal(t, big.NewInt(400), status.Remaining)
+
+		_, err = tracker.Reserve("p1", limits, spend(500))
+		assert.EqualError(t, err, "spending limit exceeded")
+
+		require.NoError(t, tracker.Commit(r))
+		status, err = tracker.Status("p1", &limits[1])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(600), status.Spent)
+		assert.Equal(t, big.NewInt(0), status.Reserved)
+
+		assert.EqualError(t, tracker.Commit(r), "reservation already settled")
+		assert.EqualError(t, tracker.Release(r), "reservation already settled")
+	})
+
+	t.Run("release frees the amount", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		r, err := tracker.Reserve("p1", limits, spend(1000))
+		require.NoError(t, err)
+		require.NoError(t, tracker.Release(r))
+
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1000), status.Remaining)
+	})
+
+	t.Run("expired reservations are dropped", func(t *testing.T) {
+		tracker, clock := newTestTracker(WithReservationTTL(time.Minute))
+		_, err := tracker.Reserve("p1", limits, spend(1000))
+		require.NoError(t, err)
+		_, err = tracker.Reserve("p1", limits, spend(1))
+		assert.Error(t, err)
+
+		clock.Advance(time.Minute)
+		_, err = tracker.Reserve("p1", limits, spend(1000))
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired reservations are recorded as an overrun", func(t *testing.T) {
+		tracker, clock := newTestTracker(WithReservationTTL(time.Minute))
+		capped := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: time.Hour}}
+		stale, err := tracker.Reserve("p1", capped, spend(80))
+		require.NoError(t, err)
+		clock.Advance(2 * time.Minute)
+		fresh, err := tracker.Reserve("p1", capped, spend(80))
+		require.NoError(t, err)
+
+		err = tracker.Commit(stale)
+		assert.ErrorIs(t, err, ErrSpendingOverrun)
+		assert.EqualError(t, err, "spending overrun: reservation expired")
+		assert.EqualError(t, tracker.Release(stale), "reservation already settled")
+		assert.EqualError(t, tracker.Commit(fresh), "spending overrun: spending limit exceeded")
+
+		status, err := tracker.Status("p1", &capped[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(160), status.Spent)
+		assert.Zero(t, status.Reserved.Sign())
+		assert.Zero(t, status.Remaining.Sign())
+	})
+
+	t.Run("commit rechecks the limit", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		r, err := tracker.Reserve("p1", limits, spend(700))
+		require.NoError(t, err)
+		require.NoError(t, tracker.Spend("p1", limits, spend(400)))
+
+		err = tracker.Commit(r)
+		assert.ErrorIs(t, err, ErrSpendingOverrun)
+		assert.EqualError(t, err, "spending overrun: spending limit exceeded")
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1100), status.Spent)
+		assert.Zero(t, status.Reserved.Sign())
+	})
+
+	t.Run("window resets", func(t *testing.T) {
+		tracker, clock := newTestTracker()
+		require.NoError(t, tracker.Spend("p1", limits, spend(900)))
+		clock.Advance(time.Hour)
+
+		hourly, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(0), hourly.Spent)
+		assert.Equal(t, clock.Now().Add(time.Hour), hourly.ResetAt)
+
+		daily, err := tracker.Status("p1", &limits[1])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(900), daily.Spent)
+
+		_, err = tracker.Reserve("p1", limits, spend(700))
+		assert.EqualError(t, err, "spending limit exceeded")
+	})
+
+	t.Run("policies are tracked separately", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		require.NoError(t, tracker.Spend("p1", limits, spend(1000)))
+		_, err := tracker.Reserve("p2", limits, spend(1000))
+		assert.NoError(t, err)
+	})
+
+	t.Run("transactions without spend reserve nothing", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		r, err := tracker.Reserve("p1", limits, &Transaction{Value: big.NewInt(5)})
+		require.NoError(t, err)
+		assert.Empty(t, r.Amounts)
+		assert.NoError(t, tracker.Commit(r))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		_, err := tracker.Reserve("p1", limits, nil)
+		assert.EqualError(t, err, "nil transaction")
+		_, err = tracker.Reserve("p1", limits, spend(-1))
+		assert.EqualError(t, err, "invalid amount")
+		assert.EqualError(t, tracker.Commit(nil), "nil reservation")
+		_, err = tracker.Status("p1", nil)
+		assert.EqualError(t, err, "nil spending limit")
+	})
+}
+
+func TestSpendingTrackerWindows(t *testing.T) {
+	spend := func(amount int64) *Transaction {
+		return &Transaction{Token: usdc, Amount: big.NewInt(amount)}
+	}
+
+	t.Run("calendar", func(t *testing.T) {
+		tracker, clock := newTestTracker()
+		clock.Advance(22 * time.Hour)
+		limits := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day, Window: SpendingCalendarWindow}}
+
+		require.NoError(t, tracker.Spend("p1", limits, spend(100)))
+		_, err := tracker.Reserve("p1", limits, spend(1))
+		assert.EqualError(t, err, "spending limit exceeded")
+
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), status.ResetAt)
+
+		clock.Advance(2 * time.Hour)
+		_, err = tracker.Reserve("p1", limits, spend(100))
+		assert.NoError(t, err)
+	})
+
+	t.Run("calendar month", func(t *testing.T) {
+		tracker, clock := newTestTracker()
+		limits := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Month, Window: SpendingCalendarMonthWindow}}
+
+		require.NoError(t, tracker.Spend("p1", limits, spend(100)))
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), status.ResetAt)
+
+		clock.Advance(30 * Day)
+		_, err = tracker.Reserve("p1", limits, spend(1))
+		assert.EqualError(t, err, "spending limit exceeded")
+
+		clock.Advance(Day)
+		_, err = tracker.Reserve("p1", limits, spend(100))
+		assert.NoError(t, err)
+	})
+
+	t.Run("sliding", func(t *testing.T) {
+		tracker, clock := newTestTracker()
+		limits := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day, Window: SpendingSlidingWindow}}
+
+		require.NoError(t, tracker.Spend("p1", limits, spend(60)))
+		clock.Advance(12 * time.Hour)
+		r, err := tracker.Reserve("p1", limits, spend(40))
+		require.NoError(t, err)
+		require.NoError(t, tracker.Commit(r))
+
+		clock.Advance(11 * time.Hour)
+		_, err = tracker.Reserve("p1", limits, spend(1))
+		assert.EqualError(t, err, "spending limit exceeded")
+
+		clock.Advance(time.Hour)
+		status, err := tracker.Status("p1", &limits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(40), status.Spent)
+		assert.Equal(t, big.NewInt(60), status.Remaining)
+		assert.Equal(t, clock.Now().Add(12*time.Hour), status.ResetAt)
+	})
+
+	t.Run("windows are tracked separately", func(t *testing.T) {
+		tracker, _ := newTestTracker()
+		fixed := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day}}
+		sliding := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(100), Period: Day, Window: SpendingSlidingWindow}}
+
+		require.NoError(t, tracker.Spend("p1", fixed, spend(100)))
+		_, err := tracker.Reserve("p1", sliding, spend(100))
+		assert.NoError(t, err)
+		assert.Equal(t, "p1/"+usdc.Hex()+"/24h0m0s/sliding_window", NewSpendingKey("p1", &sliding[0]).String())
+	})
+}
+
+func TestSpendingTrackerConcurrency(t *testing.T) {
+	tracker := NewSpendingTracker()
+	limits := []SpendingLimit{{MaxAmount: big.NewInt(500), Period: time.Hour}}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := tracker.Reserve("p1", limits, &Transaction{Value: big.NewInt(10)})
+			if err != nil {
+				return
+			}
+			if tracker.Commit(r) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, succeeded)
+	status, err := tracker.Status("p1", &limits[0])
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(500), status.Spent)
+}
+
+func TestFileSpendingStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spending.json")
+	limits := []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(1000), Period: time.Hour}}
+
+	store, err := NewFileSpendingStore(path)
+	require.NoError(t, err)
+	tracker, clock := newTestTracker(WithSpendingStore(store))
+	require.NoError(t, tracker.Spend("p1", limits, &Transaction{Token: usdc, Amount: big.NewInt(300)}))
+	_, err = tracker.Reserve("p1", limits, &Transaction{Token: usdc, Amount: big.NewInt(200)})
+	require.NoError(t, err)
+
+	reopened, err := NewFileSpendingStore(path)
+	require.NoError(t, err)
+	restarted := NewSpendingTracker(WithSpendingStore(reopened), WithSpendingClock(clock.Now))
+	status, err := restarted.Status("p1", &limits[0])
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(300), status.Spent)
+	assert.Equal(t, big.NewInt(200), status.Reserved)
+	assert.Equal(t, big.NewInt(500), status.Remaining)
+}
+
+func TestPolicyServiceReserve(t *testing.T) {
+	ctx := context.Background()
+	tracker, _ := newTestTracker()
+	svc := NewPolicyService(WithSpendingTracker(tracker))
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits: []SpendingLimit{{Token: usdc, MaxAmount: big.NewInt(1000), Period: time.Hour}},
+	})
+	require.NoError(t, err)
+	spend := func(amount int64) *Transaction {
+		return &Transaction{Token: usdc, Amount: big.NewInt(amount)}
+	}
+
+	d, r, err := svc.Reserve(ctx, created.ID, spend(800))
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	require.NotNil(t, r)
+
+	t.Run("evaluation counts reservations", func(t *testing.T) {
+		d, err := svc.Evaluate(ctx, created.ID, spend(300))
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913 spend 300 would bring period total to 1100, above limit 1000", d.Failures()[0].Reason)
+
+		d, r2, err := svc.Reserve(ctx, created.ID, spend(300))
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Nil(t, r2)
+	})
+
+	t.Run("commit records the spend", func(t *testing.T) {
+		require.NoError(t, svc.Commit(r))
+		status, err := tracker.Status(created.ID, &created.SpendingLimits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(800), status.Spent)
+
+		p, err := svc.GetPolicy(created.ID)
+		require.NoError(t, err)
+		assert.Nil(t, p.SpendingLimits[0].Spent)
+	})
+
+	t.Run("commit after delete", func(t *testing.T) {
+		deleted, err := svc.CreatePolicy(&Policy{SpendingLimits: created.SpendingLimits})
+		require.NoError(t, err)
+		_, r, err := svc.Reserve(ctx, deleted.ID, spend(100))
+		require.NoError(t, err)
+		require.NoError(t, svc.DeletePolicy(deleted.ID))
+		assert.EqualError(t, svc.Commit(r), "policy not found")
+	})
+
+	t.Run("release", func(t *testing.T) {
+		_, r, err := svc.Reserve(ctx, created.ID, spend(200))
+		require.NoError(t, err)
+		require.NoError(t, svc.Release(r))
+		d, err := svc.Evaluate(ctx, created.ID, spend(200))
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	})
+
+	t.Run("record goes through the tracker", func(t *testing.T) {
+		require.NoError(t, svc.Record(created.ID, spend(200)))
+		status, err := tracker.Status(created.ID, &created.SpendingLimits[0])
+		require.NoError(t, err)
+		assert.Zero(t, status.Remaining.Sign())
+	})
+
+	t.Run("commit past the limit records an overrun", func(t *testing.T) {
+		overrun, err := svc.CreatePolicy(&Policy{SpendingLimits: created.SpendingLimits})
+		require.NoError(t, err)
+		_, r, err := svc.Reserve(ctx, overrun.ID, spend(600))
+		require.NoError(t, err)
+		require.NoError(t, svc.Record(overrun.ID, spend(400)))
+		require.NoError(t, tracker.Spend(overrun.ID, overrun.SpendingLimits, spend(300)))
+
+		assert.ErrorIs(t, svc.Commit(r), ErrSpendingOverrun)
+		status, err := tracker.Status(overrun.ID, &overrun.SpendingLimits[0])
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1300), status.Spent)
+	})
+
+	t.Run("requires a tracker", func(t *testing.T) {
+		_, _, err := NewPolicyService().Reserve(ctx, created.ID, spend(1))
+		assert.EqualError(t, err, "spending tracker not configured")
+	})
+}
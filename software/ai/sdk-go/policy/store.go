@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"sync"
+
+	"github.com/sigloop/sdk-go/storage"
+)
+
+type Store interface {
+	Get(id string) (*Policy, bool, error)
+	Put(p *Policy) error
+	Delete(id string) error
+	List() ([]*Policy, error)
+}
+
+type MemoryStore struct {
+	policies map[string]*Policy
+	mu       sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		policies: make(map[string]*Policy),
+	}
+}
+
+func (s *MemoryStore) Get(id string) (*Policy, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.policies[id]
+	return p, ok, nil
+}
+
+func (s *MemoryStore) Put(p *Policy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[p.ID] = p
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.policies, id)
+	return nil
+}
+
+func (s *MemoryStore) List() ([]*Policy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Policy, 0, len(s.policies))
+	for _, p := range s.policies {
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+type FileStore struct {
+	file *storage.FileStore
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{file: file}, nil
+}
+
+func (s *FileStore) Get(id string) (*Policy, bool, error) {
+	var p Policy
+	ok, err := s.file.Get(id, &p)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &p, true, nil
+}
+
+func (s *FileStore) Put(p *Policy) error {
+	return s.file.Put(p.ID, p)
+}
+
+func (s *FileStore) Delete(id string) error {
+	return s.file.Delete(id)
+}
+
+func (s *FileStore) List() ([]*Policy, error) {
+	keys := s.file.Keys()
+	result := make([]*Policy, 0, len(keys))
+	for _, key := range keys {
+		p, ok, err := s.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
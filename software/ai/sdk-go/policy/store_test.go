@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	target := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	token := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	svc := NewPolicyService(WithStore(store))
+
+	created, err := svc.CreatePolicy(&Policy{
+		SpendingLimits: []SpendingLimit{
+			{Token: token, MaxAmount: big.NewInt(1000), Spent: big.NewInt(250), Period: 24 * time.Hour},
+		},
+		ContractAllowlist: &ContractAllowlist{Contracts: map[common.Address]bool{target: true}},
+		FunctionAllowlist: NewFunctionAllowlist([]string{"transfer(address,uint256)"}),
+		TimeWindow: &TimeWindow{
+			Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Days:  []time.Weekday{time.Monday, time.Friday},
+			Hours: [2]int{9, 17},
+		},
+		RateLimit: &RateLimit{MaxCalls: 10, Period: time.Hour},
+	})
+	require.NoError(t, err)
+
+	reopened, err := NewFileStore(path)
+	require.NoError(t, err)
+	restarted := NewPolicyService(WithStore(reopened))
+
+	got, err := restarted.GetPolicy(created.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, created.ID, got.ID)
+	assert.True(t, created.CreatedAt.Equal(got.CreatedAt))
+	require.Len(t, got.SpendingLimits, 1)
+	assert.Equal(t, token, got.SpendingLimits[0].Token)
+	assert.Equal(t, big.NewInt(1000), got.SpendingLimits[0].MaxAmount)
+	assert.Equal(t, big.NewInt(250), got.SpendingLimits[0].Spent)
+	assert.Equal(t, 24*time.Hour, got.SpendingLimits[0].Period)
+	assert.True(t, got.ContractAllowlist.Contracts[target])
+	assert.True(t, got.FunctionAllowlist.Functions["a9059cbb"])
+	assert.Equal(t, created.TimeWindow.Days, got.TimeWindow.Days)
+	assert.Equal(t, [2]int{9, 17}, got.TimeWindow.Hours)
+	assert.True(t, created.TimeWindow.End.Equal(got.TimeWindow.End))
+	assert.Equal(t, uint64(10), got.RateLimit.MaxCalls)
+
+	assert.True(t, IsAllowed(got, target, "transfer(address,uint256)"))
+	assert.False(t, IsAllowed(got, token, "transfer(address,uint256)"))
+}
+
+func TestFileStoreDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	svc := NewPolicyService(WithStore(store))
+
+	kept, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+	deleted, err := svc.CreatePolicy(&Policy{})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeletePolicy(deleted.ID))
+
+	reopened, err := NewFileStore(path)
+	require.NoError(t, err)
+	_, ok, err := reopened.Get(deleted.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	policies, err := NewPolicyService(WithStore(reopened)).ListPolicies(PolicyFilter{})
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, kept.ID, policies[0].ID)
+}
@@ -0,0 +1,848 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type ComposeStrategy string
+
+const (
+	ComposeIntersect ComposeStrategy = "intersect"
+	ComposeUnion     ComposeStrategy = "union"
+	ComposeOverride  ComposeStrategy = "override"
+)
+
+const (
+	FieldSpendingLimits      = "SpendingLimits"
+	FieldMaxValuePerTx       = "MaxValuePerTx"
+	FieldContractAllowlist   = "ContractAllowlist"
+	FieldFunctionAllowlist   = "FunctionAllowlist"
+	FieldPermissions         = "Permissions"
+	FieldDeny                = "Deny"
+	FieldCalldataConstraints = "CalldataConstraints"
+	FieldTimeWindow          = "TimeWindow"
+	FieldRateLimit           = "RateLimit"
+	FieldEscalation          = "Escalation"
+)
+
+type CompositionReport struct {
+	Strategy ComposeStrategy
+	Fields   []FieldDerivation
+}
+
+type FieldDerivation struct {
+	Field   string
+	Sources []string
+	Detail  string
+}
+
+const notSetByEvery = "unrestricted: not set by every policy"
+
+type layer struct {
+	name   string
+	policy *Policy
+}
+
+type composition struct {
+	layers []layer
+	policy *Policy
+	report *CompositionReport
+}
+
+func (r *CompositionReport) Field(name string) *FieldDerivation {
+	for i := range r.Fields {
+		if r.Fields[i].Field == name {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+func (r *CompositionReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "strategy: %s\n", r.Strategy)
+	for _, f := range r.Fields {
+		fmt.Fprintf(&b, "%s: %s", f.Field, f.Detail)
+		if len(f.Sources) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(f.Sources, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func Compose(strategy ComposeStrategy, policies ...*Policy) (*Policy, *CompositionReport, error) {
+	c := &composition{
+		policy: &Policy{SpendingLimits: make([]SpendingLimit, 0)},
+		report: &CompositionReport{Strategy: strategy},
+	}
+	for i, p := range policies {
+		if p == nil {
+			continue
+		}
+		name := p.ID
+		if name == "" {
+			name = fmt.Sprintf("policy[%d]", i)
+		}
+		c.layers = append(c.layers, layer{name: name, policy: p})
+	}
+
+	var err error
+	switch strategy {
+	case ComposeIntersect:
+		c.intersect()
+	case ComposeUnion:
+		err = c.union()
+	case ComposeOverride:
+		c.override()
+	default:
+		return nil, nil, errors.New("unsupported compose strategy")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.policy, c.report, nil
+}
+
+func (c *composition) derive(field string, sources []string, detail string) {
+	if len(sources) == 0 && detail == "" {
+		detail = "not set by any policy"
+	}
+	c.report.Fields = append(c.report.Fields, FieldDerivation{Field: field, Sources: sources, Detail: detail})
+}
+
+func (c *composition) intersect() {
+	p := c.policy
+
+	var sources []string
+	for _, l := range c.layers {
+		if len(l.policy.SpendingLimits) > 0 {
+			p.SpendingLimits = append(p.SpendingLimits, l.policy.SpendingLimits...)
+			sources = append(sources, l.name)
+		}
+	}
+	c.derive(FieldSpendingLimits, sources, countDetail(sources, "all %d limits apply", len(p.SpendingLimits)))
+
+	var from string
+	for _, l := range c.layers {
+		if v := l.policy.MaxValuePerTx; v != nil && (p.MaxValuePerTx == nil || v.Cmp(p.MaxValuePerTx) < 0) {
+			p.MaxValuePerTx, from = new(big.Int).Set(v), l.name
+		}
+	}
+	c.deriveMaxValue("lowest", from)
+
+	var contracts map[common.Address]bool
+	sources = nil
+	for _, l := range c.layers {
+		if al := l.policy.ContractAllowlist; al != nil {
+			contracts = intersectSets(contracts, al.Contracts)
+			sources = append(sources, l.name)
+		}
+	}
+	if sources != nil {
+		p.ContractAllowlist = &ContractAllowlist{Contracts: contracts}
+	}
+	c.derive(FieldContractAllowlist, sources, countDetail(sources, "intersection: %d contracts", len(contracts)))
+
+	var functions map[string]bool
+	sources = nil
+	for _, l := range c.layers {
+		if al := l.policy.FunctionAllowlist; al != nil {
+			functions = intersectSets(functions, al.Functions)
+			sources = append(sources, l.name)
+		}
+	}
+	if sources != nil {
+		p.FunctionAllowlist = &FunctionAllowlist{Functions: functions}
+	}
+	c.derive(FieldFunctionAllowlist, sources, countDetail(sources, "intersection: %d selectors", len(functions)))
+
+	sources = nil
+	for _, l := range c.layers {
+		if l.policy.Permissions == nil {
+			continue
+		}
+		if p.Permissions == nil {
+			p.Permissions = NewPermissionList(l.policy.Permissions.Permissions...)
+		} else {
+			p.Permissions = intersectPermissions(p.Permissions, l.policy.Permissions)
+		}
+		sources = append(sources, l.name)
+	}
+	if p.Permissions != nil {
+		c.derive(FieldPermissions, sources, countDetail(sources, "intersection: %d pairs", len(p.Permissions.Permissions)))
+	} else {
+		c.derive(FieldPermissions, nil, "")
+	}
+
+	c.composeDeny()
+
+	sources = nil
+	for _, l := range c.layers {
+		if len(l.policy.CalldataConstraints) > 0 {
+			p.CalldataConstraints = append(p.CalldataConstraints, l.policy.CalldataConstraints...)
+			sources = append(sources, l.name)
+		}
+	}
+	c.derive(FieldCalldataConstraints, sources, countDetail(sources, "all %d constraints apply", len(p.CalldataConstraints)))
+
+	sources = nil
+	for _, l := range c.layers {
+		if l.policy.TimeWindow == nil {
+			continue
+		}
+		if p.TimeWindow == nil {
+			tw := *l.policy.TimeWindow
+			p.TimeWindow = &tw
+		} else {
+			p.TimeWindow = mergeTimeWindows(p.TimeWindow, l.policy.TimeWindow)
+		}
+		sources = append(sources, l.name)
+	}
+	c.derive(FieldTimeWindow, sources, countDetail(sources, "intersection of %d windows", len(sources)))
+
+	from = ""
+	for _, l := range c.layers {
+		rl := l.policy.RateLimit
+		if rl == nil {
+			continue
+		}
+		if p.RateLimit == nil || rateLess(new(big.Int).SetUint64(rl.MaxCalls), rl.Period, new(big.Int).SetUint64(p.RateLimit.MaxCalls), p.RateLimit.Period) {
+			copied := *rl
+			p.RateLimit = &copied
+			from = l.name
+		}
+	}
+	c.deriveRateLimit("lowest rate", from)
+
+	c.composeEscalation(true)
+}
+
+func (c *composition) union() error {
+	p := c.policy
+
+	units, ok := c.limitedUnits()
+	var sources []string
+	for _, unit := range units {
+		var best []SpendingLimit
+		var bestRate *SpendingLimit
+		var from string
+		for _, l := range c.layers {
+			limits := limitsFor(l.policy.SpendingLimits, unit)
+			strictest := strictestLimit(limits)
+			if bestRate == nil || rateLess(limitAmount(bestRate), bestRate.Period, limitAmount(strictest), strictest.Period) {
+				best, bestRate, from = limits, strictest, l.name
+			}
+		}
+		p.SpendingLimits = append(p.SpendingLimits, best...)
+		sources = appendSource(sources, from)
+	}
+	switch {
+	case !ok:
+		c.derive(FieldSpendingLimits, nil, "unrestricted: a policy sets no spending limits")
+	case len(units) == 0:
+		c.derive(FieldSpendingLimits, nil, "unrestricted: no token is limited by every policy")
+	default:
+		c.derive(FieldSpendingLimits, sources, fmt.Sprintf("highest rate for %d tokens limited by every policy", len(units)))
+	}
+
+	var from string
+	for _, l := range c.layers {
+		v := l.policy.MaxValuePerTx
+		if v == nil {
+			p.MaxValuePerTx = nil
+			break
+		}
+		if p.MaxValuePerTx == nil || v.Cmp(p.MaxValuePerTx) > 0 {
+			p.MaxValuePerTx, from = new(big.Int).Set(v), l.name
+		}
+	}
+	if p.MaxValuePerTx != nil {
+		c.deriveMaxValue("highest", from)
+	} else {
+		c.derive(FieldMaxValuePerTx, nil, notSetByEvery)
+	}
+
+	contracts, sources, ok := unionAllowlists(c.layers, func(p *Policy) map[common.Address]bool {
+		if p.ContractAllowlist == nil {
+			return nil
+		}
+		return p.ContractAllowlist.Contracts
+	})
+	if ok {
+		p.ContractAllowlist = &ContractAllowlist{Contracts: contracts}
+		c.derive(FieldContractAllowlist, sources, fmt.Sprintf("union: %d contracts", len(contracts)))
+	} else {
+		c.derive(FieldContractAllowlist, nil, notSetByEvery)
+	}
+
+	functions, sources, ok := unionAllowlists(c.layers, func(p *Policy) map[string]bool {
+		if p.FunctionAllowlist == nil {
+			return nil
+		}
+		return p.FunctionAllowlist.Functions
+	})
+	if ok {
+		p.FunctionAllowlist = &FunctionAllowlist{Functions: functions}
+		c.derive(FieldFunctionAllowlist, sources, fmt.Sprintf("union: %d selectors", len(functions)))
+	} else {
+		c.derive(FieldFunctionAllowlist, nil, notSetByEvery)
+	}
+
+	sources = nil
+	for _, l := range c.layers {
+		if l.policy.Permissions == nil {
+			p.Permissions, sources = nil, nil
+			break
+		}
+		if p.Permissions == nil {
+			p.Permissions = NewPermissionList()
+		}
+		for _, perm := range l.policy.Permissions.Permissions {
+			p.Permissions.add(perm)
+		}
+		sources = append(sources, l.name)
+	}
+	if p.Permissions != nil {
+		c.derive(FieldPermissions, sources, fmt.Sprintf("union: %d pairs", len(p.Permissions.Permissions)))
+	} else {
+		c.derive(FieldPermissions, nil, notSetByEvery)
+	}
+
+	sources = nil
+	for _, l := range c.layers {
+		if l.policy.Deny == nil {
+			p.Deny, sources = nil, nil
+			break
+		}
+		if p.Deny == nil {
+			p.Deny = &DenyList{}
+			p.Deny.add(l.policy.Deny)
+		} else {
+			p.Deny = commonDenyList(p.Deny, l.policy.Deny)
+		}
+		sources = append(sources, l.name)
+	}
+	if p.Deny != nil {
+		c.derive(FieldDeny, sources, "entries denied by every policy")
+	} else {
+		c.derive(FieldDeny, nil, notSetByEvery)
+	}
+
+	sources = nil
+	for i, l := range c.layers {
+		if i == 0 {
+			p.CalldataConstraints = append(p.CalldataConstraints, l.policy.CalldataConstraints...)
+		} else {
+			p.CalldataConstraints = commonConstraints(p.CalldataConstraints, l.policy.CalldataConstraints)
+		}
+		sources = append(sources, l.name)
+	}
+	if len(p.CalldataConstraints) > 0 {
+		c.derive(FieldCalldataConstraints, sources, fmt.Sprintf("%d constraints set by every policy", len(p.CalldataConstraints)))
+	} else {
+		c.derive(FieldCalldataConstraints, nil, "unrestricted: no constraint is set by every policy")
+	}
+
+	sources = nil
+	for _, l := range c.layers {
+		if l.policy.TimeWindow == nil {
+			p.TimeWindow, sources = nil, nil
+			break
+		}
+		if p.TimeWindow == nil {
+			tw := *l.policy.TimeWindow
+			p.TimeWindow = &tw
+		} else {
+			tw, err := unionTimeWindows(p.TimeWindow, l.policy.TimeWindow)
+			if err != nil {
+				return err
+			}
+			p.TimeWindow = tw
+		}
+		sources = append(sources, l.name)
+	}
+	if p.TimeWindow != nil {
+		c.derive(FieldTimeWindow, sources, fmt.Sprintf("union of %d windows", len(sources)))
+	} else {
+		c.derive(FieldTimeWindow, nil, notSetByEvery)
+	}
+
+	from = ""
+	for _, l := range c.layers {
+		rl := l.policy.RateLimit
+		if rl == nil {
+			p.RateLimit = nil
+			break
+		}
+		if p.RateLimit == nil || rateLess(new(big.Int).SetUint64(p.RateLimit.MaxCalls), p.RateLimit.Period, new(big.Int).SetUint64(rl.MaxCalls), rl.Period) {
+			copied := *rl
+			p.RateLimit = &copied
+			from = l.name
+		}
+	}
+	if p.RateLimit != nil {
+		c.deriveRateLimit("highest rate", from)
+	} else {
+		c.derive(FieldRateLimit, nil, notSetByEvery)
+	}
+
+	c.composeEscalation(false)
+	return nil
+}
+
+func (c *composition) override() {
+	p := c.policy
+	layers := append([]layer(nil), c.layers...)
+	sort.SliceStable(layers, func(i, j int) bool { return layers[i].policy.Priority > layers[j].policy.Priority })
+
+	pick := func(field string, set func(*Policy) bool, apply func(*Policy)) {
+		for i, l := range layers {
+			if !set(l.policy) {
+				continue
+			}
+			apply(l.policy)
+			detail := fmt.Sprintf("priority %d", l.policy.Priority)
+			var overridden []string
+			for _, lower := range layers[i+1:] {
+				if set(lower.policy) {
+					overridden = append(overridden, lower.name)
+				}
+			}
+			if len(overridden) > 0 {
+				detail += ", overrides " + strings.Join(overridden, ", ")
+			}
+			c.derive(field, []string{l.name}, detail)
+			return
+		}
+		c.derive(field, nil, "")
+	}
+
+	pick(FieldSpendingLimits, func(q *Policy) bool { return len(q.SpendingLimits) > 0 }, func(q *Policy) {
+		p.SpendingLimits = append(p.SpendingLimits, q.SpendingLimits...)
+	})
+	pick(FieldMaxValuePerTx, func(q *Policy) bool { return q.MaxValuePerTx != nil }, func(q *Policy) {
+		p.MaxValuePerTx = new(big.Int).Set(q.MaxValuePerTx)
+	})
+	pick(FieldContractAllowlist, func(q *Policy) bool { return q.ContractAllowlist != nil }, func(q *Policy) {
+		p.ContractAllowlist = q.ContractAllowlist
+	})
+	pick(FieldFunctionAllowlist, func(q *Policy) bool { return q.FunctionAllowlist != nil }, func(q *Policy) {
+		p.FunctionAllowlist = q.FunctionAllowlist
+	})
+	pick(FieldPermissions, func(q *Policy) bool { return q.Permissions != nil }, func(q *Policy) {
+		p.Permissions = q.Permissions
+	})
+	pick(FieldDeny, func(q *Policy) bool { return q.Deny != nil }, func(q *Policy) {
+		p.Deny = q.Deny
+	})
+	pick(FieldCalldataConstraints, func(q *Policy) bool { return len(q.CalldataConstraints) > 0 }, func(q *Policy) {
+		p.CalldataConstraints = append(p.CalldataConstraints, q.CalldataConstraints...)
+	})
+	pick(FieldTimeWindow, func(q *Policy) bool { return q.TimeWindow != nil }, func(q *Policy) {
+		tw := *q.TimeWindow
+		p.TimeWindow = &tw
+	})
+	pick(FieldRateLimit, func(q *Policy) bool { return q.RateLimit != nil }, func(q *Policy) {
+		rl := *q.RateLimit
+		p.RateLimit = &rl
+	})
+	pick(FieldEscalation, func(q *Policy) bool { return q.Escalation != nil }, func(q *Policy) {
+		p.Escalation = cloneEscalation(q.Escalation)
+	})
+}
+
+func (c *composition) composeEscalation(strict bool) {
+	var e Escalation
+	var sources []string
+	every := true
+	newContracts, outsideTimeWindow := strict, strict
+	for _, l := range c.layers {
+		le := l.policy.Escalation
+		if le == nil {
+			every = false
+			if strict {
+				newContracts, outsideTimeWindow = false, false
+			}
+			continue
+		}
+		if strict {
+			newContracts, outsideTimeWindow = newContracts && le.NewContracts, outsideTimeWindow && le.OutsideTimeWindow
+		} else {
+			newContracts, outsideTimeWindow = newContracts || le.NewContracts, outsideTimeWindow || le.OutsideTimeWindow
+		}
+		for _, a := range le.Approvers {
+			if !containsAddress(e.Approvers, a) {
+				e.Approvers = append(e.Approvers, a)
+			}
+		}
+		if e.TTL == 0 || (strict && le.TTL > 0 && le.TTL < e.TTL) || (!strict && le.TTL > e.TTL) {
+			e.TTL = le.TTL
+		}
+		sources = append(sources, l.name)
+	}
+	if strict {
+		for _, l := range c.layers {
+			if l.policy.Escalation != nil {
+				e.Thresholds = append(e.Thresholds, cloneEscalation(l.policy.Escalation).Thresholds...)
+			}
+		}
+	} else if every {
+		e.Thresholds = c.commonThresholds()
+	}
+	e.NewContracts, e.OutsideTimeWindow = newContracts && len(sources) > 0, outsideTimeWindow && len(sources) > 0
+
+	if len(e.Thresholds) == 0 && !e.NewContracts && !e.OutsideTimeWindow {
+		detail := ""
+		if len(sources) > 0 {
+			detail = "no escalation rule survives composition"
+		}
+		c.derive(FieldEscalation, nil, detail)
+		return
+	}
+	c.policy.Escalation = &e
+	var rules []string
+	if n := len(e.Thresholds); n > 0 {
+		rules = append(rules, fmt.Sprintf("%d thresholds", n))
+	}
+	if e.NewContracts {
+		rules = append(rules, "new contracts")
+	}
+	if e.OutsideTimeWindow {
+		rules = append(rules, "outside time window")
+	}
+	c.derive(FieldEscalation, sources, fmt.Sprintf("approval for %s, %d approvers", strings.Join(rules, ", "), len(e.Approvers)))
+}
+
+func (c *composition) commonThresholds() []ApprovalThreshold {
+	var result []ApprovalThreshold
+	for _, th := range c.layers[0].policy.Escalation.Thresholds {
+		unit := spendUnit{token: th.Token, quote: th.Quote}
+		best, ok := th, true
+		for _, l := range c.layers[1:] {
+			found := false
+			for _, other := range l.policy.Escalation.Thresholds {
+				if (spendUnit{token: other.Token, quote: other.Quote}) != unit {
+					continue
+				}
+				found = true
+				if other.Amount.Cmp(best.Amount) > 0 {
+					best = other
+				}
+			}
+			ok = ok && found
+		}
+		if ok && !containsThreshold(result, unit) {
+			best.Amount = cloneInt(best.Amount)
+			result = append(result, best)
+		}
+	}
+	return result
+}
+
+func containsThreshold(thresholds []ApprovalThreshold, unit spendUnit) bool {
+	for _, th := range thresholds {
+		if (spendUnit{token: th.Token, quote: th.Quote}) == unit {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *composition) composeDeny() {
+	policies := make([]*Policy, len(c.layers))
+	var sources []string
+	for i, l := range c.layers {
+		policies[i] = l.policy
+		if l.policy.Deny != nil {
+			sources = append(sources, l.name)
+		}
+	}
+	c.policy.Deny = composeDenyLists(policies)
+	c.derive(FieldDeny, sources, countDetail(sources, "union of %d deny lists", len(sources)))
+}
+
+func (c *composition) deriveMaxValue(rule, from string) {
+	if c.policy.MaxValuePerTx == nil {
+		c.derive(FieldMaxValuePerTx, nil, "")
+		return
+	}
+	c.derive(FieldMaxValuePerTx, []string{from}, fmt.Sprintf("%s maximum: %s", rule, c.policy.MaxValuePerTx))
+}
+
+func (c *composition) deriveRateLimit(rule, from string) {
+	rl := c.policy.RateLimit
+	if rl == nil {
+		c.derive(FieldRateLimit, nil, "")
+		return
+	}
+	c.derive(FieldRateLimit, []string{from}, fmt.Sprintf("%s: %d calls per %s", rule, rl.MaxCalls, rl.Period))
+}
+
+type spendUnit struct {
+	token common.Address
+	quote string
+}
+
+func unitOf(sl *SpendingLimit) spendUnit {
+	return spendUnit{token: sl.Token, quote: sl.Quote}
+}
+
+func (c *composition) limitedUnits() ([]spendUnit, bool) {
+	var units []spendUnit
+	for i, l := range c.layers {
+		if len(l.policy.SpendingLimits) == 0 {
+			return nil, false
+		}
+		if i == 0 {
+			for j := range l.policy.SpendingLimits {
+				if u := unitOf(&l.policy.SpendingLimits[j]); !containsUnit(units, u) {
+					units = append(units, u)
+				}
+			}
+			continue
+		}
+		kept := units[:0]
+		for _, u := range units {
+			if len(limitsFor(l.policy.SpendingLimits, u)) > 0 {
+				kept = append(kept, u)
+			}
+		}
+		units = kept
+	}
+	return units, len(c.layers) > 0
+}
+
+func containsUnit(units []spendUnit, u spendUnit) bool {
+	for _, x := range units {
+		if x == u {
+			return true
+		}
+	}
+	return false
+}
+
+func countDetail(sources []string, format string, n int) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(format, n)
+}
+
+func appendSource(sources []string, name string) []string {
+	for _, s := range sources {
+		if s == name {
+			return sources
+		}
+	}
+	return append(sources, name)
+}
+
+func intersectSets[K comparable](current, next map[K]bool) map[K]bool {
+	result := make(map[K]bool)
+	for k, allowed := range next {
+		if allowed && (current == nil || current[k]) {
+			result[k] = true
+		}
+	}
+	return result
+}
+
+func unionAllowlists[K comparable](layers []layer, get func(*Policy) map[K]bool) (map[K]bool, []string, bool) {
+	if len(layers) == 0 {
+		return nil, nil, false
+	}
+	result := make(map[K]bool)
+	var sources []string
+	for _, l := range layers {
+		set := get(l.policy)
+		if set == nil {
+			return nil, nil, false
+		}
+		for k, allowed := range set {
+			if allowed {
+				result[k] = true
+			}
+		}
+		sources = append(sources, l.name)
+	}
+	return result, sources, true
+}
+
+func intersectPermissions(a, b *PermissionList) *PermissionList {
+	l := NewPermissionList()
+	for _, x := range a.Permissions {
+		for _, y := range b.Permissions {
+			if m, ok := meetPermission(x, y); ok {
+				l.add(m)
+			}
+		}
+	}
+	return l
+}
+
+func meetPermission(a, b Permission) (Permission, bool) {
+	var m Permission
+	switch {
+	case a.Contract == AnyContract:
+		m.Contract = b.Contract
+	case b.Contract == AnyContract || a.Contract == b.Contract:
+		m.Contract = a.Contract
+	default:
+		return Permission{}, false
+	}
+
+	as, bs := normalizeSelector(a.Selector), normalizeSelector(b.Selector)
+	switch {
+	case as == AnySelector:
+		m.Selector = bs
+	case bs == AnySelector || as == bs:
+		m.Selector = as
+	default:
+		return Permission{}, false
+	}
+	return m, true
+}
+
+func commonDenyList(a, b *DenyList) *DenyList {
+	shared := &DenyList{}
+	for _, c := range a.Contracts {
+		if containsAddress(b.Contracts, c) {
+			shared.Contracts = append(shared.Contracts, c)
+		}
+	}
+	for _, s := range a.Selectors {
+		for _, other := range b.Selectors {
+			if normalizeSelector(other) == s {
+				shared.Selectors = append(shared.Selectors, s)
+				break
+			}
+		}
+	}
+	for _, perm := range a.Calls {
+		for _, other := range b.Calls {
+			if other.Contract == perm.Contract && normalizeSelector(other.Selector) == perm.Selector {
+				shared.Calls = append(shared.Calls, perm)
+				break
+			}
+		}
+	}
+	for _, payee := range a.Payees {
+		if containsAddress(b.Payees, payee) {
+			shared.Payees = append(shared.Payees, payee)
+		}
+	}
+	for _, token := range a.Tokens {
+		if containsAddress(b.Tokens, token) {
+			shared.Tokens = append(shared.Tokens, token)
+		}
+	}
+	return shared
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func commonConstraints(a, b []CalldataConstraint) []CalldataConstraint {
+	var result []CalldataConstraint
+	for _, x := range a {
+		for _, y := range b {
+			if reflect.DeepEqual(x, y) {
+				result = append(result, x)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func limitsFor(limits []SpendingLimit, unit spendUnit) []SpendingLimit {
+	var result []SpendingLimit
+	for _, sl := range limits {
+		if unitOf(&sl) == unit {
+			result = append(result, sl)
+		}
+	}
+	return result
+}
+
+func strictestLimit(limits []SpendingLimit) *SpendingLimit {
+	var strictest *SpendingLimit
+	for i := range limits {
+		sl := &limits[i]
+		if strictest == nil || rateLess(limitAmount(sl), sl.Period, limitAmount(strictest), strictest.Period) {
+			strictest = sl
+		}
+	}
+	return strictest
+}
+
+func limitAmount(sl *SpendingLimit) *big.Int {
+	if sl.MaxAmount == nil {
+		return new(big.Int)
+	}
+	return sl.MaxAmount
+}
+
+func rateLess(a *big.Int, aPeriod time.Duration, b *big.Int, bPeriod time.Duration) bool {
+	left := new(big.Int).Mul(a, big.NewInt(int64(bPeriod)))
+	right := new(big.Int).Mul(b, big.NewInt(int64(aPeriod)))
+	return left.Cmp(right) < 0
+}
+
+func unionTimeWindows(a, b *TimeWindow) (*TimeWindow, error) {
+	if len(a.Intersect) > 0 || len(b.Intersect) > 0 {
+		return nil, errors.New("cannot union intersected time windows")
+	}
+
+	merged := &TimeWindow{Location: a.Location}
+	if !a.Start.IsZero() && !b.Start.IsZero() {
+		merged.Start = a.Start
+		if b.Start.Before(a.Start) {
+			merged.Start = b.Start
+		}
+	}
+	if !a.End.IsZero() && !b.End.IsZero() {
+		merged.End = a.End
+		if b.End.After(a.End) {
+			merged.End = b.End
+		}
+	}
+
+	for _, x := range a.Blackouts {
+		for _, y := range b.Blackouts {
+			start, end := x.Start, x.End
+			if y.Start.After(start) {
+				start = y.Start
+			}
+			if y.End.Before(end) {
+				end = y.End
+			}
+			if end.After(start) {
+				merged.Blackouts = append(merged.Blackouts, Blackout{Start: start, End: end})
+			}
+		}
+	}
+
+	if hasSchedule(a) && hasSchedule(b) {
+		if !sameLocation(a.Location, b.Location) {
+			return nil, errors.New("cannot union time windows in different time zones")
+		}
+		merged.Windows = windowsFromSpans(normalizeSpans(append(schedule(a), schedule(b)...)))
+	}
+	return merged, nil
+}
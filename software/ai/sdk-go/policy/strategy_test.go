@@ -0,0 +1,247 @@
+package policy
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orgPolicy() *Policy {
+	return &Policy{
+		ID:                "org",
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc, weth}),
+		SpendingLimits: []SpendingLimit{
+			{Token: usdc, MaxAmount: big.NewInt(10000), Period: 24 * time.Hour},
+		},
+		MaxValuePerTx: big.NewInt(1000),
+		TimeWindow:    &TimeWindow{Windows: []WeeklyWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}}},
+		RateLimit:     &RateLimit{MaxCalls: 100, Period: 24 * time.Hour},
+		Deny:          &DenyList{Contracts: []common.Address{drainer}},
+	}
+}
+
+func agentPolicy() *Policy {
+	return &Policy{
+		ID:                "agent",
+		Priority:          10,
+		ContractAllowlist: NewContractAllowlist([]common.Address{usdc, router}),
+		SpendingLimits: []SpendingLimit{
+			{Token: usdc, MaxAmount: big.NewInt(500), Period: time.Hour},
+		},
+		MaxValuePerTx: big.NewInt(100),
+		TimeWindow:    &TimeWindow{Windows: []WeeklyWindow{{Start: 12 * time.Hour, End: 20 * time.Hour}}},
+		RateLimit:     &RateLimit{MaxCalls: 10, Period: time.Minute},
+	}
+}
+
+func TestComposeIntersect(t *testing.T) {
+	p, report, err := Compose(ComposeIntersect, orgPolicy(), agentPolicy())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[common.Address]bool{usdc: true}, p.ContractAllowlist.Contracts)
+	assert.Len(t, p.SpendingLimits, 2)
+	assert.Equal(t, []WeeklyWindow{{Days: allWeekdays(), Start: 12 * time.Hour, End: 17 * time.Hour}}, p.TimeWindow.Windows)
+	assert.Equal(t, big.NewInt(100), p.MaxValuePerTx)
+	assert.Equal(t, uint64(100), p.RateLimit.MaxCalls)
+	assert.Equal(t, 24*time.Hour, p.RateLimit.Period)
+	assert.Equal(t, []common.Address{drainer}, p.Deny.Contracts)
+	assert.Nil(t, p.FunctionAllowlist)
+
+	assert.Equal(t, ComposeIntersect, report.Strategy)
+	assert.Equal(t, &FieldDerivation{Field: FieldContractAllowlist, Sources: []string{"org", "agent"}, Detail: "intersection: 1 contracts"}, report.Field(FieldContractAllowlist))
+	assert.Equal(t, &FieldDerivation{Field: FieldRateLimit, Sources: []string{"org"}, Detail: "lowest rate: 100 calls per 24h0m0s"}, report.Field(FieldRateLimit))
+	assert.Equal(t, &FieldDerivation{Field: FieldFunctionAllowlist, Detail: "not set by any policy"}, report.Field(FieldFunctionAllowlist))
+	assert.Equal(t, &FieldDerivation{Field: FieldMaxValuePerTx, Sources: []string{"agent"}, Detail: "lowest maximum: 100"}, report.Field(FieldMaxValuePerTx))
+	assert.Len(t, report.Fields, 10)
+
+	t.Run("disjoint allowlists allow nothing", func(t *testing.T) {
+		p, _, err := Compose(ComposeIntersect,
+			&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{usdc})},
+			&Policy{ContractAllowlist: NewContractAllowlist([]common.Address{weth})},
+		)
+		require.NoError(t, err)
+		require.NotNil(t, p.ContractAllowlist)
+		assert.Empty(t, p.ContractAllowlist.Contracts)
+		assert.False(t, IsAllowed(p, usdc, transferSig))
+	})
+
+	t.Run("strict and lax is strict", func(t *testing.T) {
+		strict := &Policy{Permissions: NewPermissionList(Permit(usdc, transferSig))}
+		lax := &Policy{Permissions: NewPermissionList(Permit(AnyContract, AnySelector))}
+		p, _, err := Compose(ComposeIntersect, strict, lax)
+		require.NoError(t, err)
+		assert.Equal(t, []Permission{Permit(usdc, transferSig)}, p.Permissions.Permissions)
+	})
+
+	t.Run("escalation", func(t *testing.T) {
+		org := &Policy{ID: "org", Escalation: &Escalation{
+			Thresholds:   []ApprovalThreshold{{Quote: "USD", Amount: big.NewInt(1000)}},
+			NewContracts: true,
+			Approvers:    []common.Address{payee},
+			TTL:          24 * time.Hour,
+		}}
+		agent := &Policy{ID: "agent", Escalation: &Escalation{
+			Thresholds:        []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(50)}},
+			NewContracts:      true,
+			OutsideTimeWindow: true,
+			Approvers:         []common.Address{drainer, payee},
+			TTL:               time.Hour,
+		}}
+		p, report, err := Compose(ComposeIntersect, org, agent)
+		require.NoError(t, err)
+		assert.Equal(t, &Escalation{
+			Thresholds:   []ApprovalThreshold{{Quote: "USD", Amount: big.NewInt(1000)}, {Token: usdc, Amount: big.NewInt(50)}},
+			NewContracts: true,
+			Approvers:    []common.Address{payee, drainer},
+			TTL:          time.Hour,
+		}, p.Escalation)
+		assert.Equal(t, "approval for 2 thresholds, new contracts, 2 approvers", report.Field(FieldEscalation).Detail)
+
+		p, _, err = Compose(ComposeIntersect, org, &Policy{})
+		require.NoError(t, err)
+		assert.False(t, p.Escalation.NewContracts)
+		assert.Len(t, p.Escalation.Thresholds, 1)
+	})
+}
+
+func TestComposeUnion(t *testing.T) {
+	p, report, err := Compose(ComposeUnion, orgPolicy(), agentPolicy())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[common.Address]bool{usdc: true, weth: true, router: true}, p.ContractAllowlist.Contracts)
+	require.Len(t, p.SpendingLimits, 1)
+	assert.Equal(t, big.NewInt(500), p.SpendingLimits[0].MaxAmount)
+	assert.Equal(t, big.NewInt(1000), p.MaxValuePerTx)
+	assert.Equal(t, []WeeklyWindow{{Days: allWeekdays(), Start: 9 * time.Hour, End: 20 * time.Hour}}, p.TimeWindow.Windows)
+	assert.Equal(t, uint64(10), p.RateLimit.MaxCalls)
+	assert.Nil(t, p.Deny)
+
+	assert.Equal(t, &FieldDerivation{Field: FieldSpendingLimits, Sources: []string{"agent"}, Detail: "highest rate for 1 tokens limited by every policy"}, report.Field(FieldSpendingLimits))
+	assert.Equal(t, &FieldDerivation{Field: FieldDeny, Detail: notSetByEvery}, report.Field(FieldDeny))
+	assert.Equal(t, &FieldDerivation{Field: FieldMaxValuePerTx, Sources: []string{"org"}, Detail: "highest maximum: 1000"}, report.Field(FieldMaxValuePerTx))
+
+	t.Run("unset allowlist is unrestricted", func(t *testing.T) {
+		p, _, err := Compose(ComposeUnion, &Policy{ContractAllowlist: NewContractAllowlist([]common.Address{usdc})}, &Policy{})
+		require.NoError(t, err)
+		assert.Nil(t, p.ContractAllowlist)
+	})
+
+	t.Run("deny entries shared by every policy", func(t *testing.T) {
+		p, _, err := Compose(ComposeUnion,
+			&Policy{Deny: &DenyList{Contracts: []common.Address{drainer, weth}, Selectors: []string{"0xA9059CBB"}}},
+			&Policy{Deny: &DenyList{Contracts: []common.Address{drainer}, Selectors: []string{Selector(transferSig)}}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []common.Address{drainer}, p.Deny.Contracts)
+		assert.Equal(t, []string{"a9059cbb"}, p.Deny.Selectors)
+	})
+
+	t.Run("quote limits are not native limits", func(t *testing.T) {
+		p, _, err := Compose(ComposeUnion,
+			&Policy{SpendingLimits: []SpendingLimit{{Quote: "USD", MaxAmount: big.NewInt(100), Period: time.Hour}}},
+			&Policy{SpendingLimits: []SpendingLimit{
+				{MaxAmount: big.NewInt(5), Period: time.Hour},
+				{Quote: "USD", MaxAmount: big.NewInt(200), Period: time.Hour},
+			}},
+		)
+		require.NoError(t, err)
+		require.Len(t, p.SpendingLimits, 1)
+		assert.Equal(t, "USD", p.SpendingLimits[0].Quote)
+		assert.Equal(t, big.NewInt(200), p.SpendingLimits[0].MaxAmount)
+	})
+
+	t.Run("time zones", func(t *testing.T) {
+		_, _, err := Compose(ComposeUnion,
+			&Policy{TimeWindow: &TimeWindow{Location: "Europe/Berlin", Hours: [2]int{9, 17}}},
+			&Policy{TimeWindow: &TimeWindow{Location: "Asia/Tokyo", Hours: [2]int{9, 17}}},
+		)
+		assert.EqualError(t, err, "cannot union time windows in different time zones")
+	})
+
+	t.Run("escalation", func(t *testing.T) {
+		p, report, err := Compose(ComposeUnion,
+			&Policy{Escalation: &Escalation{
+				Thresholds: []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(50)}, {Quote: "USD", Amount: big.NewInt(1000)}},
+				Approvers:  []common.Address{payee},
+			}},
+			&Policy{Escalation: &Escalation{
+				Thresholds:   []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(80)}},
+				NewContracts: true,
+				Approvers:    []common.Address{payee},
+			}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(80)}}, p.Escalation.Thresholds)
+		assert.True(t, p.Escalation.NewContracts)
+		assert.Equal(t, []string{"policy[0]", "policy[1]"}, report.Field(FieldEscalation).Sources)
+
+		p, report, err = Compose(ComposeUnion, &Policy{Escalation: &Escalation{Thresholds: []ApprovalThreshold{{Token: usdc, Amount: big.NewInt(50)}}, Approvers: []common.Address{payee}}}, &Policy{})
+		require.NoError(t, err)
+		assert.Nil(t, p.Escalation)
+		assert.Equal(t, "no escalation rule survives composition", report.Field(FieldEscalation).Detail)
+	})
+}
+
+func TestComposeOverride(t *testing.T) {
+	org := orgPolicy()
+	org.Priority = 0
+	agent := agentPolicy()
+	agent.TimeWindow = nil
+
+	p, report, err := Compose(ComposeOverride, org, agent)
+	require.NoError(t, err)
+
+	assert.Equal(t, agent.ContractAllowlist, p.ContractAllowlist)
+	assert.Equal(t, agent.SpendingLimits, p.SpendingLimits)
+	assert.Equal(t, agent.MaxValuePerTx, p.MaxValuePerTx)
+	assert.Equal(t, org.TimeWindow.Windows, p.TimeWindow.Windows)
+	assert.Equal(t, org.Deny, p.Deny)
+
+	assert.Equal(t, &FieldDerivation{Field: FieldContractAllowlist, Sources: []string{"agent"}, Detail: "priority 10, overrides org"}, report.Field(FieldContractAllowlist))
+	assert.Equal(t, &FieldDerivation{Field: FieldTimeWindow, Sources: []string{"org"}, Detail: "priority 0"}, report.Field(FieldTimeWindow))
+
+	t.Run("equal priority keeps argument order", func(t *testing.T) {
+		a := &Policy{RateLimit: &RateLimit{MaxCalls: 1, Period: time.Hour}}
+		b := &Policy{RateLimit: &RateLimit{MaxCalls: 2, Period: time.Hour}}
+		p, report, err := Compose(ComposeOverride, a, b)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), p.RateLimit.MaxCalls)
+		assert.Equal(t, []string{"policy[0]"}, report.Field(FieldRateLimit).Sources)
+	})
+}
+
+func TestComposeErrors(t *testing.T) {
+	_, _, err := Compose("merge", orgPolicy())
+	assert.EqualError(t, err, "unsupported compose strategy")
+}
+
+func TestMeetPermission(t *testing.T) {
+	transfer := Selector(transferSig)
+	tests := []struct {
+		name string
+		a, b Permission
+		want Permission
+		ok   bool
+	}{
+		{"wildcards", Permit(AnyContract, AnySelector), Permit(usdc, transferSig), Permit(usdc, transferSig), true},
+		{"contract and selector", Permit(usdc, AnySelector), Permit(AnyContract, transferSig), Permission{Contract: usdc, Selector: transfer}, true},
+		{"different contracts", Permit(usdc, AnySelector), Permit(weth, AnySelector), Permission{}, false},
+		{"different selectors", Permit(usdc, transferSig), Permit(usdc, "approve(address,uint256)"), Permission{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := meetPermission(tt.a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func allWeekdays() []time.Weekday {
+	return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+}
@@ -0,0 +1,218 @@
+package policy
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/x402"
+)
+
+type TemplateName string
+
+const (
+	TemplateX402Payments TemplateName = "x402-payments"
+	TemplateUniswapSwaps TemplateName = "uniswap-swaps"
+	TemplateAaveSupply   TemplateName = "aave-supply-repay"
+	TemplateReadOnly     TemplateName = "read-only"
+)
+
+type Template struct {
+	Name        TemplateName
+	Description string
+	Policy      *Policy
+	OnChain     *Policy
+}
+
+type TemplateParams struct {
+	Chain      chain.SupportedChain
+	Wallet     common.Address
+	Tokens     []common.Address
+	DailyLimit *big.Int
+}
+
+var usdcToken = Token{Symbol: "USDC", Decimals: 6}
+
+var tokenApprovalSignatures = []string{
+	erc20TransferSignature,
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+}
+
+func NewTemplate(name TemplateName, params TemplateParams) (*Template, error) {
+	switch name {
+	case TemplateX402Payments:
+		return X402PaymentsTemplate(params.Chain, params.DailyLimit)
+	case TemplateUniswapSwaps:
+		return UniswapSwapsTemplate(params.Chain, params.Wallet, params.Tokens...)
+	case TemplateAaveSupply:
+		return AaveSupplyTemplate(params.Chain, params.Wallet, params.Tokens...)
+	case TemplateReadOnly:
+		return ReadOnlyTemplate(), nil
+	default:
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+}
+
+func X402PaymentsTemplate(c chain.SupportedChain, dailyLimit *big.Int) (*Template, error) {
+	cfg, err := templateChain(c)
+	if err != nil {
+		return nil, err
+	}
+	if dailyLimit == nil || dailyLimit.Sign() <= 0 {
+		return nil, errors.New("invalid daily limit")
+	}
+
+	deny := &DenyList{}
+	for _, sig := range tokenApprovalSignatures {
+		deny.Selectors = append(deny.Selectors, Selector(sig))
+	}
+	return &Template{
+		Name:        TemplateX402Payments,
+		Description: fmt.Sprintf("x402 payments only: up to %s per day to any payee on %s; on-chain only the USDC contract is enforced, with any function and amount", formatUnits(usdcToken, dailyLimit), cfg.Name),
+		Policy: &Policy{
+			SpendingLimits: []SpendingLimit{
+				{Token: cfg.USDC, MaxAmount: new(big.Int).Set(dailyLimit), Period: Day, Window: SpendingCalendarWindow},
+			},
+			MaxValuePerTx:     new(big.Int),
+			ContractAllowlist: NewContractAllowlist([]common.Address{cfg.USDC}),
+			FunctionAllowlist: selectorAllowlist(x402.TransferWithAuthorizationSelector),
+			Deny:              deny,
+		},
+		OnChain: &Policy{
+			MaxValuePerTx:     new(big.Int),
+			ContractAllowlist: NewContractAllowlist([]common.Address{cfg.USDC}),
+		},
+	}, nil
+}
+
+func UniswapSwapsTemplate(c chain.SupportedChain, wallet common.Address, tokens ...common.Address) (*Template, error) {
+	cfg, err := templateChain(c)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.UniswapV2Router == (common.Address{}) {
+		return nil, fmt.Errorf("no Uniswap router on %s", cfg.Name)
+	}
+	if wallet == (common.Address{}) {
+		return nil, errors.New("wallet address required")
+	}
+	if len(tokens) < 2 {
+		return nil, errors.New("at least two tokens required")
+	}
+
+	return &Template{
+		Name:        TemplateUniswapSwaps,
+		Description: fmt.Sprintf("Uniswap swaps only: exact-input swaps between %s through the router on %s; on-chain only the router is enforced, with any function, token and recipient", formatAddresses(tokens), cfg.Name),
+		Policy: &Policy{
+			MaxValuePerTx:     new(big.Int),
+			ContractAllowlist: NewContractAllowlist([]common.Address{cfg.UniswapV2Router}),
+			FunctionAllowlist: selectorAllowlist(defi.SwapExactTokensForTokensSelector),
+			CalldataConstraints: []CalldataConstraint{
+				{Contract: cfg.UniswapV2Router, Signature: defi.SwapExactTokensForTokensSignature, Args: []ArgConstraint{
+					{Index: 2, Op: ArgIn, Values: addressValues(tokens)},
+					{Index: 3, Op: ArgEqual, Values: []string{wallet.Hex()}},
+				}},
+			},
+		},
+		OnChain: &Policy{
+			MaxValuePerTx:     new(big.Int),
+			ContractAllowlist: NewContractAllowlist([]common.Address{cfg.UniswapV2Router}),
+		},
+	}, nil
+}
+
+func AaveSupplyTemplate(c chain.SupportedChain, wallet common.Address, tokens ...common.Address) (*Template, error) {
+	cfg, err := templateChain(c)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AavePool == (common.Address{}) {
+		return nil, fmt.Errorf("no Aave pool on %s", cfg.Name)
+	}
+	if wallet == (common.Address{}) {
+		return nil, errors.New("wallet address required")
+	}
+
+	p := &Policy{
+		MaxValuePerTx:     new(big.Int),
+		ContractAllowlist: NewContractAllowlist([]common.Address{cfg.AavePool}),
+		FunctionAllowlist: selectorAllowlist(defi.SupplySelector, defi.RepaySelector),
+		Deny:              &DenyList{Selectors: []string{hex.EncodeToString(defi.BorrowSelector[:])}},
+	}
+	assets := "any asset"
+	if len(tokens) > 0 {
+		assets = formatAddresses(tokens)
+	}
+	for _, call := range []struct {
+		sig        string
+		onBehalfOf int
+	}{{defi.SupplySignature, 2}, {defi.RepaySignature, 3}} {
+		var args []ArgConstraint
+		if len(tokens) > 0 {
+			args = append(args, ArgConstraint{Index: 0, Op: ArgIn, Values: addressValues(tokens)})
+		}
+		args = append(args, ArgConstraint{Index: call.onBehalfOf, Op: ArgEqual, Values: []string{wallet.Hex()}})
+		p.CalldataConstraints = append(p.CalldataConstraints, CalldataConstraint{Contract: cfg.AavePool, Signature: call.sig, Args: args})
+	}
+
+	return &Template{
+		Name:        TemplateAaveSupply,
+		Description: fmt.Sprintf("Aave supply and repay only, no borrowing: %s in the pool on %s; on-chain only the pool is enforced, so borrowing is allowed", assets, cfg.Name),
+		Policy:      p,
+		OnChain: &Policy{
+			MaxValuePerTx:     new(big.Int),
+			ContractAllowlist: NewContractAllowlist([]common.Address{cfg.AavePool}),
+		},
+	}, nil
+}
+
+func ReadOnlyTemplate() *Template {
+	readOnly := func() *Policy {
+		return &Policy{Deny: &DenyList{Calls: []Permission{Permit(AnyContract, AnySelector)}}}
+	}
+	return &Template{
+		Name:        TemplateReadOnly,
+		Description: "read-only: every transaction is denied",
+		Policy:      readOnly(),
+		OnChain:     readOnly(),
+	}
+}
+
+func (t *Template) AgentPolicy() (*encoding.AgentPolicy, *AgentPolicyReport, error) {
+	return ToAgentPolicy(t.OnChain)
+}
+
+func templateChain(c chain.SupportedChain) (chain.ChainConfig, error) {
+	cfg, ok := chain.Chains[c]
+	if !ok {
+		return chain.ChainConfig{}, errors.New("unsupported chain")
+	}
+	return cfg, nil
+}
+
+func selectorAllowlist(selectors ...[4]byte) *FunctionAllowlist {
+	al := &FunctionAllowlist{Functions: make(map[string]bool, len(selectors))}
+	for _, sel := range selectors {
+		al.Functions[hex.EncodeToString(sel[:])] = true
+	}
+	return al
+}
+
+func addressValues(addrs []common.Address) []string {
+	values := make([]string, len(addrs))
+	for i, addr := range addrs {
+		values[i] = addr.Hex()
+	}
+	return values
+}
+
+func formatAddresses(addrs []common.Address) string {
+	return strings.Join(addressValues(addrs), ", ")
+}
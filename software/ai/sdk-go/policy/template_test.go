@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/x402"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	base := chain.Chains[chain.Base]
+	swapTo := func(to common.Address, path ...common.Address) []byte {
+		return mustCalldata(t, defi.SwapExactTokensForTokensSignature, big.NewInt(1000), big.NewInt(990), path, to, big.NewInt(1700000000))
+	}
+	swap := func(path ...common.Address) []byte {
+		return swapTo(agentSA, path...)
+	}
+	lendFor := func(sig string, asset, onBehalfOf common.Address) []byte {
+		switch sig {
+		case defi.SupplySignature:
+			return mustCalldata(t, sig, asset, big.NewInt(1000), onBehalfOf, uint16(0))
+		case defi.BorrowSignature:
+			return mustCalldata(t, sig, asset, big.NewInt(1000), big.NewInt(2), uint16(0), onBehalfOf)
+		default:
+			return mustCalldata(t, sig, asset, big.NewInt(1000), big.NewInt(2), onBehalfOf)
+		}
+	}
+	lend := func(sig string, asset common.Address) []byte {
+		return lendFor(sig, asset, agentSA)
+	}
+
+	x402Payments, err := NewTemplate(TemplateX402Payments, TemplateParams{Chain: chain.Base, DailyLimit: big.NewInt(10_000_000)})
+	require.NoError(t, err)
+	swaps, err := NewTemplate(TemplateUniswapSwaps, TemplateParams{Chain: chain.Base, Wallet: agentSA, Tokens: []common.Address{usdc, weth}})
+	require.NoError(t, err)
+	aave, err := NewTemplate(TemplateAaveSupply, TemplateParams{Chain: chain.Base, Wallet: agentSA, Tokens: []common.Address{usdc}})
+	require.NoError(t, err)
+	anyAsset, err := NewTemplate(TemplateAaveSupply, TemplateParams{Chain: chain.Base, Wallet: agentSA})
+	require.NoError(t, err)
+	readOnly, err := NewTemplate(TemplateReadOnly, TemplateParams{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "x402 payments only: up to 10 USDC per day to any payee on Base; on-chain only the USDC contract is enforced, with any function and amount", x402Payments.Description)
+	assert.Equal(t, "Uniswap swaps only: exact-input swaps between "+usdc.Hex()+", "+weth.Hex()+" through the router on Base; on-chain only the router is enforced, with any function, token and recipient", swaps.Description)
+	assert.Equal(t, "Aave supply and repay only, no borrowing: "+usdc.Hex()+" in the pool on Base; on-chain only the pool is enforced, so borrowing is allowed", aave.Description)
+	assert.Equal(t, "read-only: every transaction is denied", readOnly.Description)
+
+	payment := x402.TransferWithAuthorizationSelector[:]
+
+	tests := []struct {
+		name     string
+		template *Template
+		tx       *Transaction
+		allowed  bool
+	}{
+		{"x402 payment", x402Payments, &Transaction{To: x402.PaymentToken, Data: payment, Token: x402.PaymentToken, Amount: big.NewInt(5_000_000), Payee: payee, Time: evalTime}, true},
+		{"x402 over daily limit", x402Payments, &Transaction{To: x402.PaymentToken, Data: payment, Token: x402.PaymentToken, Amount: big.NewInt(11_000_000), Payee: payee, Time: evalTime}, false},
+		{"x402 other USDC function", x402Payments, &Transaction{To: base.USDC, Data: mustCalldata(t, "increaseAllowance(address,uint256)", payee, big.NewInt(1)), Time: evalTime}, false},
+		{"x402 call without selector", x402Payments, &Transaction{To: base.USDC, Time: evalTime}, false},
+		{"x402 direct transfer", x402Payments, &Transaction{To: base.USDC, Data: mustCalldata(t, transferSig, payee, big.NewInt(1)), Time: evalTime}, false},
+		{"x402 other contract", x402Payments, &Transaction{To: base.UniswapV2Router, Time: evalTime}, false},
+		{"swap listed tokens", swaps, &Transaction{To: base.UniswapV2Router, Data: swap(usdc, weth), Time: evalTime}, true},
+		{"swap unlisted token", swaps, &Transaction{To: base.UniswapV2Router, Data: swap(usdc, degen), Time: evalTime}, false},
+		{"swap to another recipient", swaps, &Transaction{To: base.UniswapV2Router, Data: swapTo(payee, usdc, weth), Time: evalTime}, false},
+		{"swap sends value", swaps, &Transaction{To: base.UniswapV2Router, Value: ether(1), Data: swap(usdc, weth), Time: evalTime}, false},
+		{"aave supply", aave, &Transaction{To: base.AavePool, Data: lend(defi.SupplySignature, usdc), Time: evalTime}, true},
+		{"aave repay", aave, &Transaction{To: base.AavePool, Data: lend(defi.RepaySignature, usdc), Time: evalTime}, true},
+		{"aave supply unlisted asset", aave, &Transaction{To: base.AavePool, Data: lend(defi.SupplySignature, weth), Time: evalTime}, false},
+		{"aave supply for another account", aave, &Transaction{To: base.AavePool, Data: lendFor(defi.SupplySignature, usdc, payee), Time: evalTime}, false},
+		{"aave repay for another account", aave, &Transaction{To: base.AavePool, Data: lendFor(defi.RepaySignature, usdc, payee), Time: evalTime}, false},
+		{"aave any asset", anyAsset, &Transaction{To: base.AavePool, Data: lend(defi.SupplySignature, weth), Time: evalTime}, true},
+		{"aave any asset for another account", anyAsset, &Transaction{To: base.AavePool, Data: lendFor(defi.RepaySignature, weth, payee), Time: evalTime}, false},
+		{"aave borrow", aave, &Transaction{To: base.AavePool, Data: lend(defi.BorrowSignature, usdc), Time: evalTime}, false},
+		{"read-only", readOnly, &Transaction{To: usdc, Time: evalTime}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, validatePolicy(tt.template.Policy))
+			d, err := Evaluate(context.Background(), tt.template.Policy, tt.tx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, d.Allowed)
+		})
+	}
+
+	for _, tmpl := range []*Template{x402Payments, swaps, aave, readOnly} {
+		t.Run(string(tmpl.Name)+" on-chain", func(t *testing.T) {
+			ap, report, err := tmpl.AgentPolicy()
+			require.NoError(t, err)
+			assert.True(t, report.Lossless(), report.String())
+			assert.Equal(t, tmpl.Name != TemplateReadOnly, ap.Active)
+		})
+	}
+
+	ap, _, err := aave.AgentPolicy()
+	require.NoError(t, err)
+	assert.Empty(t, ap.AllowedSelectors)
+	assert.Equal(t, []common.Address{base.AavePool}, ap.AllowedTargets)
+	assert.Zero(t, ap.MaxAmountPerTx.Sign())
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    TemplateName
+		params  TemplateParams
+		wantErr string
+	}{
+		{"unknown template", "yolo", TemplateParams{}, `unknown template "yolo"`},
+		{"unsupported chain", TemplateX402Payments, TemplateParams{Chain: "solana", DailyLimit: big.NewInt(1)}, "unsupported chain"},
+		{"missing daily limit", TemplateX402Payments, TemplateParams{Chain: chain.Base}, "invalid daily limit"},
+		{"one token", TemplateUniswapSwaps, TemplateParams{Chain: chain.Base, Wallet: agentSA, Tokens: []common.Address{usdc}}, "at least two tokens required"},
+		{"no router", TemplateUniswapSwaps, TemplateParams{Chain: chain.BaseSepolia, Wallet: agentSA, Tokens: []common.Address{usdc, weth}}, "no Uniswap router on Base Sepolia"},
+		{"swap without wallet", TemplateUniswapSwaps, TemplateParams{Chain: chain.Base, Tokens: []common.Address{usdc, weth}}, "wallet address required"},
+		{"no pool", TemplateAaveSupply, TemplateParams{Chain: chain.ArbitrumSepolia, Wallet: agentSA}, "no Aave pool on Arbitrum Sepolia"},
+		{"aave without wallet", TemplateAaveSupply, TemplateParams{Chain: chain.Base}, "wallet address required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTemplate(tt.tmpl, tt.params)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package policy
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	oneDay  = 24 * time.Hour
+	oneWeek = 7 * oneDay
+)
+
+type span struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func CheckTimeWindow(tw *TimeWindow, t time.Time) error {
+	if tw == nil {
+		return errors.New("nil time window")
+	}
+	if !tw.Start.IsZero() && t.Before(tw.Start) {
+		return errors.New("before time window start")
+	}
+	if !tw.End.IsZero() && t.After(tw.End) {
+		return errors.New("after time window end")
+	}
+	for _, b := range tw.Blackouts {
+		if !t.Before(b.Start) && t.Before(b.End) {
+			return errors.New("in blackout period")
+		}
+	}
+
+	if hasSchedule(tw) {
+		loc, err := loadLocation(tw.Location)
+		if err != nil {
+			return err
+		}
+		if !spansContain(schedule(tw), weekOffset(t.In(loc))) {
+			return errors.New("outside allowed schedule")
+		}
+	}
+
+	for i := range tw.Intersect {
+		if err := CheckTimeWindow(&tw.Intersect[i], t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTimeWindow(tw *TimeWindow) error {
+	if !tw.End.IsZero() && tw.Start.After(tw.End) {
+		return errors.New("time window start after end")
+	}
+	if tw.Hours[0] < 0 || tw.Hours[0] > 23 {
+		return errors.New("invalid start hour")
+	}
+	if tw.Hours[1] < 0 || tw.Hours[1] > 23 {
+		return errors.New("invalid end hour")
+	}
+	if err := validateWeekdays(tw.Days); err != nil {
+		return err
+	}
+	if _, err := loadLocation(tw.Location); err != nil {
+		return err
+	}
+
+	for _, w := range tw.Windows {
+		if w.Start < 0 || w.Start >= oneDay || w.End < 0 || w.End >= oneDay {
+			return errors.New("invalid window time")
+		}
+		if err := validateWeekdays(w.Days); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range tw.Blackouts {
+		if !b.End.After(b.Start) {
+			return errors.New("invalid blackout period")
+		}
+	}
+
+	for i := range tw.Intersect {
+		if err := validateTimeWindow(&tw.Intersect[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateWeekdays(days []time.Weekday) error {
+	for _, d := range days {
+		if d < time.Sunday || d > time.Saturday {
+			return errors.New("invalid weekday")
+		}
+	}
+	return nil
+}
+
+func mergeTimeWindows(a, b *TimeWindow) *TimeWindow {
+	merged := &TimeWindow{
+		Start:     a.Start,
+		End:       a.End,
+		Days:      a.Days,
+		Hours:     a.Hours,
+		Location:  a.Location,
+		Windows:   a.Windows,
+		Blackouts: append(append([]Blackout(nil), a.Blackouts...), b.Blackouts...),
+		Intersect: append(append([]TimeWindow(nil), a.Intersect...), b.Intersect...),
+	}
+
+	if b.Start.After(merged.Start) {
+		merged.Start = b.Start
+	}
+	if !b.End.IsZero() && (merged.End.IsZero() || b.End.Before(merged.End)) {
+		merged.End = b.End
+	}
+
+	if !hasSchedule(b) {
+		return merged
+	}
+	if !hasSchedule(a) {
+		merged.Days = b.Days
+		merged.Hours = b.Hours
+		merged.Location = b.Location
+		merged.Windows = b.Windows
+		return merged
+	}
+
+	if sameLocation(a.Location, b.Location) {
+		if spans := intersectSpans(schedule(a), schedule(b)); len(spans) > 0 {
+			merged.Days = nil
+			merged.Hours = [2]int{}
+			merged.Windows = windowsFromSpans(spans)
+			return merged
+		}
+	}
+
+	merged.Intersect = append(merged.Intersect, TimeWindow{
+		Days:     b.Days,
+		Hours:    b.Hours,
+		Location: b.Location,
+		Windows:  b.Windows,
+	})
+	return merged
+}
+
+func hasSchedule(tw *TimeWindow) bool {
+	return len(tw.Windows) > 0 || len(tw.Days) > 0 || tw.Hours != [2]int{}
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("unknown time zone")
+	}
+	return loc, nil
+}
+
+func sameLocation(a, b string) bool {
+	if a == "" {
+		a = "UTC"
+	}
+	if b == "" {
+		b = "UTC"
+	}
+	return a == b
+}
+
+func weekOffset(t time.Time) time.Duration {
+	return time.Duration(t.Weekday())*oneDay +
+		time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())
+}
+
+func schedule(tw *TimeWindow) []span {
+	var spans []span
+	if len(tw.Windows) > 0 {
+		for _, w := range tw.Windows {
+			spans = append(spans, weeklySpans(w.Days, w.Start, w.End)...)
+		}
+		spans = normalizeSpans(spans)
+	}
+
+	if len(tw.Days) > 0 || tw.Hours != [2]int{} {
+		var start, end time.Duration
+		if tw.Hours != [2]int{} {
+			start = time.Duration(tw.Hours[0]) * time.Hour
+			end = time.Duration(tw.Hours[1]+1) * time.Hour % oneDay
+		}
+		legacy := normalizeSpans(weeklySpans(tw.Days, start, end))
+		if len(tw.Windows) == 0 {
+			return legacy
+		}
+		spans = intersectSpans(spans, legacy)
+	}
+	return spans
+}
+
+func weeklySpans(days []time.Weekday, start, end time.Duration) []span {
+	if len(days) == 0 {
+		days = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	}
+	length := end - start
+	if length <= 0 {
+		length += oneDay
+	}
+
+	spans := make([]span, 0, len(days))
+	for _, d := range days {
+		s := time.Duration(d)*oneDay + start
+		e := s + length
+		if e > oneWeek {
+			spans = append(spans, span{s, oneWeek}, span{0, e - oneWeek})
+		} else {
+			spans = append(spans, span{s, e})
+		}
+	}
+	return spans
+}
+
+func normalizeSpans(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := make([]span, 0, len(spans))
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func intersectSpans(a, b []span) []span {
+	var result []span
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start := max(a[i].start, b[j].start)
+		end := min(a[i].end, b[j].end)
+		if start < end {
+			result = append(result, span{start, end})
+		}
+		if a[i].end < b[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func spansContain(spans []span, offset time.Duration) bool {
+	for _, s := range spans {
+		if offset >= s.start && offset < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+func windowsFromSpans(spans []span) []WeeklyWindow {
+	spans = append([]span(nil), spans...)
+	if n := len(spans); n > 1 && spans[0].start == 0 && spans[n-1].end == oneWeek {
+		spans[n-1].end = oneWeek + spans[0].end
+		spans = spans[1:]
+	}
+
+	var windows []WeeklyWindow
+	for _, s := range spans {
+		for start := s.start; start < s.end; start += oneDay {
+			end := min(start+oneDay, s.end)
+			weekday := time.Weekday(start / oneDay % 7)
+			w := WeeklyWindow{Start: start % oneDay, End: end % oneDay}
+
+			found := false
+			for i := range windows {
+				if windows[i].Start == w.Start && windows[i].End == w.End {
+					windows[i].Days = append(windows[i].Days, weekday)
+					found = true
+					break
+				}
+			}
+			if !found {
+				w.Days = []time.Weekday{weekday}
+				windows = append(windows, w)
+			}
+		}
+	}
+	return windows
+}
@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestCheckTimeWindow(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	businessHours := TimeWindow{
+		Location: "America/New_York",
+		Windows:  []WeeklyWindow{{Days: weekdays, Start: 9 * time.Hour, End: 17 * time.Hour}},
+	}
+	overnight := TimeWindow{
+		Windows: []WeeklyWindow{{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 6 * time.Hour}},
+	}
+	christmas := time.Date(2026, 12, 25, 0, 0, 0, 0, ny)
+
+	tests := []struct {
+		name    string
+		window  TimeWindow
+		at      time.Time
+		wantErr string
+	}{
+		{"local business hours", businessHours, time.Date(2026, 3, 4, 9, 0, 0, 0, ny), ""},
+		{"utc instant mapped to zone", businessHours, time.Date(2026, 3, 4, 14, 30, 0, 0, time.UTC), ""},
+		{"before local opening", businessHours, time.Date(2026, 3, 4, 8, 59, 0, 0, ny), "outside allowed schedule"},
+		{"end is exclusive", businessHours, time.Date(2026, 3, 4, 17, 0, 0, 0, ny), "outside allowed schedule"},
+		{"weekend", businessHours, time.Date(2026, 3, 7, 12, 0, 0, 0, ny), "outside allowed schedule"},
+		{"after dst change", businessHours, time.Date(2026, 3, 9, 13, 30, 0, 0, time.UTC), ""},
+		{"overnight start day", overnight, time.Date(2026, 3, 6, 23, 0, 0, 0, time.UTC), ""},
+		{"overnight next morning", overnight, time.Date(2026, 3, 7, 5, 59, 0, 0, time.UTC), ""},
+		{"overnight ended", overnight, time.Date(2026, 3, 7, 6, 0, 0, 0, time.UTC), "outside allowed schedule"},
+		{"overnight wrong day", overnight, time.Date(2026, 3, 5, 23, 0, 0, 0, time.UTC), "outside allowed schedule"},
+		{
+			"saturday window wraps into sunday",
+			TimeWindow{Windows: []WeeklyWindow{{Days: []time.Weekday{time.Saturday}, Start: 22 * time.Hour, End: 2 * time.Hour}}},
+			time.Date(2026, 3, 8, 1, 0, 0, 0, time.UTC),
+			"",
+		},
+		{
+			"multiple windows",
+			TimeWindow{Windows: []WeeklyWindow{
+				{Days: []time.Weekday{time.Monday}, Start: 9 * time.Hour, End: 12 * time.Hour},
+				{Days: []time.Weekday{time.Wednesday}, Start: 14 * time.Hour, End: 18 * time.Hour},
+			}},
+			time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC),
+			"",
+		},
+		{
+			"equal start and end is a full day",
+			TimeWindow{Windows: []WeeklyWindow{{Days: []time.Weekday{time.Wednesday}}}},
+			time.Date(2026, 3, 4, 23, 59, 0, 0, time.UTC),
+			"",
+		},
+		{"legacy overnight hours", TimeWindow{Hours: [2]int{22, 5}}, time.Date(2026, 3, 4, 5, 30, 0, 0, time.UTC), ""},
+		{"legacy overnight hours outside", TimeWindow{Hours: [2]int{22, 5}}, time.Date(2026, 3, 4, 6, 0, 0, 0, time.UTC), "outside allowed schedule"},
+		{
+			"legacy days narrow windows",
+			TimeWindow{
+				Days:    []time.Weekday{time.Monday},
+				Windows: []WeeklyWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}},
+			},
+			time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC),
+			"outside allowed schedule",
+		},
+		{
+			"blackout",
+			TimeWindow{Blackouts: []Blackout{{Start: christmas, End: christmas.AddDate(0, 0, 1)}}},
+			christmas.Add(12 * time.Hour),
+			"in blackout period",
+		},
+		{
+			"blackout end exclusive",
+			TimeWindow{Blackouts: []Blackout{{Start: christmas, End: christmas.AddDate(0, 0, 1)}}},
+			christmas.AddDate(0, 0, 1),
+			"",
+		},
+		{
+			"intersect",
+			TimeWindow{
+				Windows:   []WeeklyWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}},
+				Intersect: []TimeWindow{{Location: "Asia/Tokyo", Windows: []WeeklyWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}}}},
+			},
+			time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC),
+			"outside allowed schedule",
+		},
+		{"unknown zone", TimeWindow{Location: "Mars/Olympus_Mons", Hours: [2]int{9, 17}}, time.Now(), "unknown time zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := tt.window
+			err := CheckTimeWindow(&window, tt.at)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("nil window", func(t *testing.T) {
+		assert.EqualError(t, CheckTimeWindow(nil, time.Now()), "nil time window")
+	})
+}
+
+func TestValidateTimeWindow(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		window  TimeWindow
+		wantErr string
+	}{
+		{"valid", TimeWindow{Location: "Europe/Berlin", Windows: []WeeklyWindow{{Start: 22 * time.Hour, End: 6 * time.Hour}}}, ""},
+		{"unknown zone", TimeWindow{Location: "Nowhere/City"}, "unknown time zone"},
+		{"window start out of range", TimeWindow{Windows: []WeeklyWindow{{Start: 24 * time.Hour}}}, "invalid window time"},
+		{"window end negative", TimeWindow{Windows: []WeeklyWindow{{End: -time.Minute}}}, "invalid window time"},
+		{"invalid weekday", TimeWindow{Windows: []WeeklyWindow{{Days: []time.Weekday{7}}}}, "invalid weekday"},
+		{"invalid legacy weekday", TimeWindow{Days: []time.Weekday{-1}}, "invalid weekday"},
+		{"empty blackout", TimeWindow{Blackouts: []Blackout{{Start: now, End: now}}}, "invalid blackout period"},
+		{"invalid intersect", TimeWindow{Intersect: []TimeWindow{{Location: "Nowhere/City"}}}, "unknown time zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := tt.window
+			err := validateTimeWindow(&window)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWindowsFromSpans(t *testing.T) {
+	t.Run("round trips weekday schedule", func(t *testing.T) {
+		weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		spans := normalizeSpans(weeklySpans(weekdays, 9*time.Hour, 17*time.Hour))
+		assert.Equal(t, []WeeklyWindow{{Days: weekdays, Start: 9 * time.Hour, End: 17 * time.Hour}}, windowsFromSpans(spans))
+	})
+
+	t.Run("joins across week boundary", func(t *testing.T) {
+		spans := normalizeSpans(weeklySpans([]time.Weekday{time.Saturday}, 22*time.Hour, 2*time.Hour))
+		require.Len(t, spans, 2)
+		assert.Equal(t, []WeeklyWindow{{Days: []time.Weekday{time.Saturday}, Start: 22 * time.Hour, End: 2 * time.Hour}}, windowsFromSpans(spans))
+	})
+}
@@ -8,13 +8,21 @@ import (
 )
 
 type Policy struct {
-	ID                string
-	SpendingLimits    []SpendingLimit
-	ContractAllowlist *ContractAllowlist
-	FunctionAllowlist *FunctionAllowlist
-	TimeWindow        *TimeWindow
-	RateLimit         *RateLimit
-	CreatedAt         time.Time
+	ID                  string
+	Priority            int
+	SpendingLimits      []SpendingLimit
+	MaxValuePerTx       *big.Int
+	ContractAllowlist   *ContractAllowlist
+	FunctionAllowlist   *FunctionAllowlist
+	Permissions         *PermissionList
+	Deny                *DenyList
+	CalldataConstraints []CalldataConstraint
+	TimeWindow          *TimeWindow
+	RateLimit           *RateLimit
+	Escalation          *Escalation
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Revision            int
 }
 
 type SpendingLimit struct {
@@ -23,6 +31,14 @@ type SpendingLimit struct {
 	Spent       *big.Int
 	Period      time.Duration
 	ResetAt     time.Time
+	Window      SpendingWindow
+	Spends      []SpendRecord
+	Quote       string
+}
+
+type SpendRecord struct {
+	Amount *big.Int  `json:"amount"`
+	Time   time.Time `json:"time"`
 }
 
 type ContractAllowlist struct {
@@ -33,16 +49,78 @@ type FunctionAllowlist struct {
 	Functions map[string]bool
 }
 
+type PermissionList struct {
+	Permissions []Permission
+}
+
+type Permission struct {
+	Contract common.Address
+	Selector string
+}
+
+type DenyList struct {
+	Contracts []common.Address
+	Selectors []string
+	Calls     []Permission
+	Payees    []common.Address
+	Tokens    []common.Address
+}
+
+type CalldataConstraint struct {
+	Contract  common.Address
+	Signature string
+	Args      []ArgConstraint
+}
+
+type ArgConstraint struct {
+	Index  int
+	Op     ArgOp
+	Values []string
+}
+
 type TimeWindow struct {
+	Start     time.Time
+	End       time.Time
+	Days      []time.Weekday
+	Hours     [2]int
+	Location  string
+	Windows   []WeeklyWindow
+	Blackouts []Blackout
+	Intersect []TimeWindow
+}
+
+type WeeklyWindow struct {
+	Days  []time.Weekday
+	Start time.Duration
+	End   time.Duration
+}
+
+type Blackout struct {
 	Start time.Time
 	End   time.Time
-	Days  []time.Weekday
-	Hours [2]int
 }
 
 type RateLimit struct {
-	MaxCalls  uint64
-	Calls     uint64
-	Period    time.Duration
-	ResetAt   time.Time
+	MaxCalls uint64
+	Period   time.Duration
+	Mode     RateLimitMode
+
+	// Deprecated: calls are counted by the RateLimiter; Calls is ignored.
+	Calls uint64
+	// Deprecated: calls are counted by the RateLimiter; ResetAt is ignored.
+	ResetAt time.Time
+}
+
+type Escalation struct {
+	Thresholds        []ApprovalThreshold
+	NewContracts      bool
+	OutsideTimeWindow bool
+	Approvers         []common.Address
+	TTL               time.Duration
+}
+
+type ApprovalThreshold struct {
+	Token   common.Address
+	Quote   string
+	Amount  *big.Int
 }
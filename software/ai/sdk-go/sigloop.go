@@ -23,7 +23,7 @@ func NewClient(walletConfig wallet.WalletConfig, x402Policy x402.X402Policy, bud
 	return &SigloopClient{
 		WalletService: wallet.NewWalletService(walletConfig),
 		AgentService:  agent.NewAgentService(),
-		PolicyService: policy.NewPolicyService(),
+		PolicyService: policy.NewPolicyService(policy.WithSpendingTracker(policy.NewSpendingTracker())),
 		X402Service:   x402.NewBudgetTracker(x402Policy, budgetPeriod),
 		ChainService:  chainService,
 		DeFiService:   defi.NewDeFiService(chainService),
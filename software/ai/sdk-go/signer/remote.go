@@ -0,0 +1,183 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      uint64            `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("remote signer error %d: %s", e.Code, e.Message)
+}
+
+type RemoteSigner struct {
+	url        string
+	address    common.Address
+	httpClient *http.Client
+	nextID     atomic.Uint64
+}
+
+func NewRemoteSigner(ctx context.Context, endpoint string, httpClient *http.Client) (*RemoteSigner, error) {
+	if endpoint == "" {
+		return nil, errors.New("empty signer endpoint")
+	}
+
+	url := endpoint
+	if socket, ok := strings.CutPrefix(endpoint, "unix://"); ok {
+		if socket == "" {
+			return nil, errors.New("empty signer endpoint")
+		}
+		httpClient = unixSocketClient(socket, httpClient)
+		url = "http://signer/"
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	s := &RemoteSigner{
+		url:        url,
+		httpClient: httpClient,
+	}
+	if err := s.call(ctx, &s.address, "signer_address"); err != nil {
+		return nil, err
+	}
+	if s.address == (common.Address{}) {
+		return nil, errors.New("remote signer returned zero address")
+	}
+	return s, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.call(ctx, &sig, "signer_signHash", hash); err != nil {
+		return nil, err
+	}
+	return s.checkSignature(hash, sig)
+}
+
+func (s *RemoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, err := TypedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig hexutil.Bytes
+	if err := s.call(ctx, &sig, "signer_signTypedData", data); err != nil {
+		return nil, err
+	}
+	return s.checkSignature(hash, sig)
+}
+
+func (s *RemoteSigner) checkSignature(hash common.Hash, sig []byte) ([]byte, error) {
+	if err := Verify(s.address, hash, sig); err != nil {
+		return nil, err
+	}
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	return sig, nil
+}
+
+func (s *RemoteSigner) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	rawParams := make([]json.RawMessage, 0, len(params))
+	for _, p := range params {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		rawParams = append(rawParams, raw)
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      s.nextID.Add(1),
+		Method:  method,
+		Params:  rawParams,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("remote signer returned http status %d", resp.StatusCode)
+		}
+		return errors.New("invalid remote signer response")
+	}
+
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+
+	if len(rpcResp.Result) == 0 {
+		return errors.New("empty remote signer response")
+	}
+
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+func unixSocketClient(socket string, base *http.Client) *http.Client {
+	client := &http.Client{}
+	if base != nil {
+		*client = *base
+	}
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return client
+}
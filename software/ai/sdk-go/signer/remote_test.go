@@ -0,0 +1,192 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rejectingSigner struct {
+	*PrivateKeySigner
+}
+
+func (rejectingSigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	return nil, assert.AnError
+}
+
+type wrongKeySigner struct {
+	*PrivateKeySigner
+	other *PrivateKeySigner
+}
+
+func (s wrongKeySigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	return s.other.SignHash(ctx, hash)
+}
+
+func (s wrongKeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	return s.other.SignTypedData(ctx, data)
+}
+
+func newTestSigner(t *testing.T) *PrivateKeySigner {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := NewPrivateKeySigner(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local := newTestSigner(t)
+	server := httptest.NewServer(NewServer(local))
+	defer server.Close()
+
+	remote, err := NewRemoteSigner(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, local.Address(), remote.Address())
+
+	t.Run("sign hash", func(t *testing.T) {
+		hash := crypto.Keccak256Hash([]byte("hello"))
+		want, err := local.SignHash(context.Background(), hash)
+		require.NoError(t, err)
+
+		got, err := remote.SignHash(context.Background(), hash)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("sign typed data", func(t *testing.T) {
+		want, err := local.SignTypedData(context.Background(), testTypedData())
+		require.NoError(t, err)
+
+		got, err := remote.SignTypedData(context.Background(), testTypedData())
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("empty endpoint", func(t *testing.T) {
+		_, err := NewRemoteSigner(context.Background(), "", nil)
+		assert.EqualError(t, err, "empty signer endpoint")
+
+		_, err = NewRemoteSigner(context.Background(), "unix://", nil)
+		assert.EqualError(t, err, "empty signer endpoint")
+	})
+}
+
+func TestRemoteSignerUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "signer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "signer.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	local := newTestSigner(t)
+	server := &http.Server{Handler: NewServer(local)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	remote, err := NewRemoteSigner(context.Background(), "unix://"+socket, nil)
+	require.NoError(t, err)
+	assert.Equal(t, local.Address(), remote.Address())
+
+	hash := crypto.Keccak256Hash([]byte("over a socket"))
+	sig, err := remote.SignHash(context.Background(), hash)
+	require.NoError(t, err)
+	assert.NoError(t, Verify(local.Address(), hash, sig))
+}
+
+func TestRemoteSignerErrors(t *testing.T) {
+	hash := crypto.Keccak256Hash([]byte("hello"))
+
+	t.Run("signer error", func(t *testing.T) {
+		server := httptest.NewServer(NewServer(rejectingSigner{newTestSigner(t)}))
+		defer server.Close()
+
+		remote, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+
+		_, err = remote.SignHash(context.Background(), hash)
+		var rpcErr *RPCError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, -32000, rpcErr.Code)
+		assert.Equal(t, assert.AnError.Error(), rpcErr.Message)
+	})
+
+	t.Run("signature from wrong key", func(t *testing.T) {
+		server := httptest.NewServer(NewServer(wrongKeySigner{newTestSigner(t), newTestSigner(t)}))
+		defer server.Close()
+
+		remote, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+
+		_, err = remote.SignHash(context.Background(), hash)
+		assert.EqualError(t, err, "signature does not match signer")
+		_, err = remote.SignTypedData(context.Background(), testTypedData())
+		assert.EqualError(t, err, "signature does not match signer")
+	})
+
+	t.Run("v offset by 27 is normalized", func(t *testing.T) {
+		local := newTestSigner(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req rpcRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			result := `"` + local.Address().Hex() + `"`
+			if req.Method == "signer_signHash" {
+				sig, err := local.SignHash(r.Context(), hash)
+				assert.NoError(t, err)
+				sig[64] += 27
+				result = `"0x` + common.Bytes2Hex(sig) + `"`
+			}
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+		}))
+		defer server.Close()
+
+		remote, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		sig, err := remote.SignHash(context.Background(), hash)
+		require.NoError(t, err)
+		assert.Less(t, sig[64], byte(2))
+	})
+
+	t.Run("zero address", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000"}`))
+		}))
+		defer server.Close()
+
+		_, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		assert.EqualError(t, err, "remote signer returned zero address")
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		_, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		assert.EqualError(t, err, "remote signer returned http status 502")
+	})
+
+	t.Run("empty result", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1}`))
+		}))
+		defer server.Close()
+
+		_, err := NewRemoteSigner(context.Background(), server.URL, nil)
+		assert.EqualError(t, err, "empty remote signer response")
+	})
+}
@@ -0,0 +1,94 @@
+package signer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcSignerError    = -32000
+)
+
+type Server struct {
+	signer Signer
+}
+
+func NewServer(s Signer) *Server {
+	return &Server{signer: s}
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPCResponse(w, rpcResponse{JSONRPC: "2.0", Error: &RPCError{Code: rpcParseError, Message: "parse error"}})
+		return
+	}
+
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	result, rpcErr := srv.dispatch(r, &req)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: rpcSignerError, Message: err.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	writeRPCResponse(w, resp)
+}
+
+func (srv *Server) dispatch(r *http.Request, req *rpcRequest) (interface{}, *RPCError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &RPCError{Code: rpcInvalidRequest, Message: "invalid request"}
+	}
+
+	switch req.Method {
+	case "signer_address":
+		return srv.signer.Address(), nil
+
+	case "signer_signHash":
+		var hash common.Hash
+		if len(req.Params) != 1 || json.Unmarshal(req.Params[0], &hash) != nil {
+			return nil, &RPCError{Code: rpcInvalidParams, Message: "invalid params"}
+		}
+		sig, err := srv.signer.SignHash(r.Context(), hash)
+		if err != nil {
+			return nil, &RPCError{Code: rpcSignerError, Message: err.Error()}
+		}
+		return hexutil.Bytes(sig), nil
+
+	case "signer_signTypedData":
+		var data apitypes.TypedData
+		if len(req.Params) != 1 || json.Unmarshal(req.Params[0], &data) != nil {
+			return nil, &RPCError{Code: rpcInvalidParams, Message: "invalid params"}
+		}
+		sig, err := srv.signer.SignTypedData(r.Context(), data)
+		if err != nil {
+			return nil, &RPCError{Code: rpcSignerError, Message: err.Error()}
+		}
+		return hexutil.Bytes(sig), nil
+
+	default:
+		return nil, &RPCError{Code: rpcMethodNotFound, Message: "method not found"}
+	}
+}
+
+func writeRPCResponse(w http.ResponseWriter, resp rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
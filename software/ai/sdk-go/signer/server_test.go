@@ -0,0 +1,48 @@
+package signer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer(newTestSigner(t))
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "parse error", body: `{`, wantCode: -32700},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":1,"method":"signer_address","params":[]}`, wantCode: -32600},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":1,"method":"eth_sign","params":[]}`, wantCode: -32601},
+		{name: "missing hash", body: `{"jsonrpc":"2.0","id":1,"method":"signer_signHash","params":[]}`, wantCode: -32602},
+		{name: "bad hash", body: `{"jsonrpc":"2.0","id":1,"method":"signer_signHash","params":["0x01"]}`, wantCode: -32602},
+		{name: "bad typed data", body: `{"jsonrpc":"2.0","id":1,"method":"signer_signTypedData","params":[42]}`, wantCode: -32602},
+		{name: "unhashable typed data", body: `{"jsonrpc":"2.0","id":1,"method":"signer_signTypedData","params":[{"types":{},"primaryType":"Missing","domain":{},"message":{}}]}`, wantCode: -32000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+
+			var resp rpcResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotNil(t, resp.Error)
+			assert.Equal(t, tc.wantCode, resp.Error.Code)
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
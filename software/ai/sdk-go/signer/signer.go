@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+type Signer interface {
+	Address() common.Address
+	SignHash(ctx context.Context, hash common.Hash) ([]byte, error)
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+type PrivateKeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewPrivateKeySigner(key *ecdsa.PrivateKey) (*PrivateKeySigner, error) {
+	if key == nil {
+		return nil, errors.New("nil private key")
+	}
+	return &PrivateKeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}, nil
+}
+
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *PrivateKeySigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	return crypto.Sign(hash.Bytes(), s.key)
+}
+
+func (s *PrivateKeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, err := TypedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.SignHash(ctx, hash)
+}
+
+func TypedDataHash(data apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+func Verify(address common.Address, hash common.Hash, sig []byte) error {
+	if len(sig) != crypto.SignatureLength {
+		return errors.New("invalid signature length")
+	}
+	normalized := append([]byte{}, sig...)
+	if normalized[64] >= 27 {
+		normalized[64] -= 27
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), normalized)
+	if err != nil {
+		return errors.New("invalid signature")
+	}
+	if crypto.PubkeyToAddress(*pub) != address {
+		return errors.New("signature does not match signer")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTypedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+			},
+			"Mail": {
+				{Name: "to", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: apitypes.TypedDataDomain{
+			Name:    "Test",
+			ChainId: math.NewHexOrDecimal256(8453),
+		},
+		Message: apitypes.TypedDataMessage{
+			"to":     "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+			"amount": "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+		},
+	}
+}
+
+func TestPrivateKeySigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := NewPrivateKeySigner(key)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Address())
+
+	t.Run("sign hash", func(t *testing.T) {
+		hash := crypto.Keccak256Hash([]byte("hello"))
+		sig, err := s.SignHash(context.Background(), hash)
+		require.NoError(t, err)
+		assert.Len(t, sig, 65)
+		assert.Less(t, sig[64], byte(2))
+		assert.NoError(t, Verify(s.Address(), hash, sig))
+	})
+
+	t.Run("sign typed data", func(t *testing.T) {
+		data := testTypedData()
+		sig, err := s.SignTypedData(context.Background(), data)
+		require.NoError(t, err)
+
+		hash, err := TypedDataHash(data)
+		require.NoError(t, err)
+		assert.NoError(t, Verify(s.Address(), hash, sig))
+	})
+
+	t.Run("invalid typed data", func(t *testing.T) {
+		data := testTypedData()
+		data.PrimaryType = "Missing"
+		_, err := s.SignTypedData(context.Background(), data)
+		assert.Error(t, err)
+	})
+
+	t.Run("nil key", func(t *testing.T) {
+		_, err := NewPrivateKeySigner(nil)
+		assert.EqualError(t, err, "nil private key")
+	})
+}
+
+func TestTypedDataHash(t *testing.T) {
+	data := testTypedData()
+	hash, err := TypedDataHash(data)
+	require.NoError(t, err)
+
+	maxUint := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,uint256 chainId)")),
+		crypto.Keccak256([]byte("Test")),
+		common.LeftPadBytes(big.NewInt(8453).Bytes(), 32),
+	)
+	structHash := crypto.Keccak256(
+		crypto.Keccak256([]byte("Mail(address to,uint256 amount)")),
+		common.LeftPadBytes(common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Bytes(), 32),
+		maxUint.Bytes(),
+	)
+	want := crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator, structHash)
+	assert.Equal(t, want, hash)
+}
+
+func TestVerify(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	hash := crypto.Keccak256Hash([]byte("hello"))
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	require.NoError(t, err)
+
+	t.Run("raw v", func(t *testing.T) {
+		assert.NoError(t, Verify(addr, hash, sig))
+	})
+
+	t.Run("offset v", func(t *testing.T) {
+		offset := append([]byte{}, sig...)
+		offset[64] += 27
+		assert.NoError(t, Verify(addr, hash, offset))
+		assert.Equal(t, sig[64]+27, offset[64])
+	})
+
+	t.Run("wrong signer", func(t *testing.T) {
+		assert.EqualError(t, Verify(common.HexToAddress("0x01"), hash, sig), "signature does not match signer")
+	})
+
+	t.Run("bad length", func(t *testing.T) {
+		assert.EqualError(t, Verify(addr, hash, sig[:64]), "invalid signature length")
+	})
+
+	t.Run("bad recovery id", func(t *testing.T) {
+		bad := append([]byte{}, sig...)
+		bad[64] = 9
+		assert.EqualError(t, Verify(addr, hash, bad), "invalid signature")
+	})
+}
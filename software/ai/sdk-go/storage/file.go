@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const fileFormatVersion = 1
+
+type fileSnapshot struct {
+	Version int                        `json:"version"`
+	Items   map[string]json.RawMessage `json:"items"`
+}
+
+type FileStore struct {
+	path  string
+	items map[string]json.RawMessage
+	mu    sync.RWMutex
+}
+
+func OpenFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.New("empty store path")
+	}
+
+	s := &FileStore{
+		path:  path,
+		items: make(map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snap fileSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, errors.New("corrupt store file")
+	}
+	if snap.Version != fileFormatVersion {
+		return nil, errors.New("unsupported store file version")
+	}
+	if snap.Items != nil {
+		s.items = snap.Items
+	}
+	return s, nil
+}
+
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+func (s *FileStore) Get(key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	raw, ok := s.items[key]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileStore) Put(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed := s.items[key]
+	s.items[key] = raw
+	if err := s.flush(); err != nil {
+		if existed {
+			s.items[key] = prev
+		} else {
+			delete(s.items, key)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed := s.items[key]
+	if !existed {
+		return nil
+	}
+	delete(s.items, key)
+	if err := s.flush(); err != nil {
+		s.items[key] = prev
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.items))
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *FileStore) flush() error {
+	data, err := json.Marshal(fileSnapshot{
+		Version: fileFormatVersion,
+		Items:   s.items,
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(s.path, data)
+}
+
+func WriteFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
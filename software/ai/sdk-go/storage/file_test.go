@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name  string
+	Count int
+}
+
+func TestOpenFileStore(t *testing.T) {
+	t.Run("missing file starts empty", func(t *testing.T) {
+		s, err := OpenFileStore(filepath.Join(t.TempDir(), "store.json"))
+		require.NoError(t, err)
+		assert.Empty(t, s.Keys())
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		_, err := OpenFileStore("")
+		assert.EqualError(t, err, "empty store path")
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "store.json")
+		require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+		_, err := OpenFileStore(path)
+		assert.EqualError(t, err, "corrupt store file")
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "store.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"version":99,"items":{}}`), 0o600))
+		_, err := OpenFileStore(path)
+		assert.EqualError(t, err, "unsupported store file version")
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "store.json")
+
+	s, err := OpenFileStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, s.Path())
+
+	t.Run("put and get", func(t *testing.T) {
+		require.NoError(t, s.Put("b", testItem{Name: "beta", Count: 2}))
+		require.NoError(t, s.Put("a", testItem{Name: "alpha", Count: 1}))
+
+		var got testItem
+		ok, err := s.Get("a", &got)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, testItem{Name: "alpha", Count: 1}, got)
+		assert.Equal(t, []string{"a", "b"}, s.Keys())
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		var got testItem
+		ok, err := s.Get("missing", &got)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("survives reopen", func(t *testing.T) {
+		reopened, err := OpenFileStore(path)
+		require.NoError(t, err)
+
+		var got testItem
+		ok, err := reopened.Get("b", &got)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, testItem{Name: "beta", Count: 2}, got)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, s.Delete("a"))
+		require.NoError(t, s.Delete("a"))
+
+		reopened, err := OpenFileStore(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b"}, reopened.Keys())
+	})
+
+	t.Run("file permissions", func(t *testing.T) {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("unencodable value leaves store unchanged", func(t *testing.T) {
+		err := s.Put("bad", make(chan int))
+		require.Error(t, err)
+		assert.Equal(t, []string{"b"}, s.Keys())
+	})
+}
@@ -0,0 +1,167 @@
+package userop
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/agent"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/wallet"
+)
+
+type Builder struct {
+	config BuilderConfig
+}
+
+func NewBuilder(config BuilderConfig) *Builder {
+	if config.SignatureFormat == nil {
+		config.SignatureFormat = ValidatorSignature{}
+	}
+	if config.CallEncoder == nil {
+		config.CallEncoder = SimpleAccountCalls{}
+	}
+	return &Builder{
+		config: config,
+	}
+}
+
+func NewBuilderForChain(cfg *chain.ChainConfig, stages ...Stage) (*Builder, error) {
+	if cfg == nil {
+		return nil, errors.New("nil chain config")
+	}
+	return NewBuilder(BuilderConfig{
+		EntryPoint:        cfg.EntryPoint,
+		EntryPointVersion: cfg.Version(),
+		ChainID:           cfg.ChainID,
+		Stages:            stages,
+	}), nil
+}
+
+func CallFromDeFi(result *defi.DeFiResult) (Call, error) {
+	if result == nil {
+		return Call{}, errors.New("nil defi result")
+	}
+	value := result.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	return Call{
+		Target: result.To,
+		Value:  value,
+		Data:   result.Data,
+	}, nil
+}
+
+func (b *Builder) Build(ctx context.Context, w *wallet.Wallet, sk *agent.SessionKey, calls ...Call) (*encoding.UserOperation, error) {
+	if w == nil {
+		return nil, errors.New("nil wallet")
+	}
+	if sk == nil {
+		return nil, errors.New("nil session key")
+	}
+	if b.config.ChainID == nil {
+		return nil, errors.New("chain ID required")
+	}
+
+	callData, err := b.config.CallEncoder.EncodeCalls(calls)
+	if err != nil {
+		return nil, err
+	}
+
+	bc := &BuildContext{
+		Wallet:     w,
+		SessionKey: sk,
+		EntryPoint: b.config.EntryPoint,
+		ChainID:    b.config.ChainID,
+		Calls:      calls,
+		Op: &encoding.UserOperation{
+			Sender:               w.Address,
+			Nonce:                big.NewInt(0),
+			InitCode:             []byte{},
+			CallData:             callData,
+			CallGasLimit:         big.NewInt(0),
+			VerificationGasLimit: big.NewInt(0),
+			PreVerificationGas:   big.NewInt(0),
+			MaxFeePerGas:         big.NewInt(0),
+			MaxPriorityFeePerGas: big.NewInt(0),
+			PaymasterAndData:     []byte{},
+			Signature:            b.config.SignatureFormat.Dummy(sk),
+		},
+	}
+
+	for _, stage := range b.config.Stages {
+		if err := stage.Apply(ctx, bc); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.Sign(bc.Op, sk); err != nil {
+		return nil, err
+	}
+
+	return bc.Op, nil
+}
+
+func (b *Builder) Sign(op *encoding.UserOperation, sk *agent.SessionKey) error {
+	hash, err := b.Hash(op)
+	if err != nil {
+		return err
+	}
+
+	sig, err := b.config.SignatureFormat.Encode(sk, hash)
+	if err != nil {
+		return err
+	}
+
+	op.Signature = sig
+	return nil
+}
+
+func (b *Builder) Hash(op *encoding.UserOperation) (common.Hash, error) {
+	if op == nil {
+		return common.Hash{}, errors.New("nil user operation")
+	}
+	if b.config.EntryPointVersion == chain.EntryPointV07 {
+		return encoding.HashUserOpV07(op, b.config.EntryPoint, b.config.ChainID)
+	}
+	return encoding.HashUserOp(op, b.config.EntryPoint, b.config.ChainID)
+}
+
+func EncodeCalls(calls []Call) ([]byte, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("no calls")
+	}
+
+	if len(calls) == 1 {
+		return encoding.EncodeCallData(calls[0].Target, valueOrZero(calls[0].Value), nonNilData(calls[0].Data))
+	}
+
+	targets := make([]common.Address, len(calls))
+	values := make([]*big.Int, len(calls))
+	data := make([][]byte, len(calls))
+	for i, c := range calls {
+		targets[i] = c.Target
+		values[i] = valueOrZero(c.Value)
+		data[i] = nonNilData(c.Data)
+	}
+
+	return encoding.EncodeBatchCallData(targets, values, data)
+}
+
+func valueOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+	return v
+}
+
+func nonNilData(d []byte) []byte {
+	if d == nil {
+		return []byte{}
+	}
+	return d
+}
@@ -0,0 +1,347 @@
+package userop
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/agent"
+	"github.com/sigloop/sdk-go/bundler"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/defi"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/signer"
+	"github.com/sigloop/sdk-go/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubEstimator struct {
+	estimate *bundler.GasEstimate
+	err      error
+	seen     *encoding.UserOperation
+}
+
+func (s *stubEstimator) EstimateUserOperationGas(ctx context.Context, op *encoding.UserOperation) (*bundler.GasEstimate, error) {
+	copied := *op
+	s.seen = &copied
+	return s.estimate, s.err
+}
+
+type stubPaymaster struct {
+	data []byte
+}
+
+func (s stubPaymaster) PaymasterAndData(ctx context.Context, op *encoding.UserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	return s.data, nil
+}
+
+func testWallet(deployed bool) *wallet.Wallet {
+	factory := wallet.SimpleAccountFactory{Factory: common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")}
+	owner := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	initCode, _ := factory.InitCode(owner, big.NewInt(3))
+	return &wallet.Wallet{
+		Address:    common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Owner:      owner,
+		EntryPoint: chain.DefaultEntryPoint,
+		Factory:    factory.Factory,
+		Salt:       big.NewInt(3),
+		InitCode:   initCode,
+		ChainID:    big.NewInt(8453),
+		IsDeployed: deployed,
+		Nonce:      4,
+	}
+}
+
+func testSessionKey(t *testing.T) *agent.SessionKey {
+	t.Helper()
+	sk, err := agent.GenerateSessionKey(big.NewInt(8453), time.Hour)
+	require.NoError(t, err)
+	return sk
+}
+
+func testEstimator() *stubEstimator {
+	return &stubEstimator{
+		estimate: &bundler.GasEstimate{
+			PreVerificationGas:   big.NewInt(50000),
+			VerificationGasLimit: big.NewInt(150000),
+			CallGasLimit:         big.NewInt(100000),
+		},
+	}
+}
+
+func testFees() StaticFeeSource {
+	return StaticFeeSource{
+		MaxFeePerGas:         big.NewInt(2000000000),
+		MaxPriorityFeePerGas: big.NewInt(1000000),
+	}
+}
+
+func TestNewBuilderForChain(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cfg := chain.Chains[chain.Base]
+		b, err := NewBuilderForChain(&cfg, WalletNonceStage())
+		require.NoError(t, err)
+		assert.Equal(t, cfg.EntryPoint, b.config.EntryPoint)
+		assert.Equal(t, chain.EntryPointV06, b.config.EntryPointVersion)
+		assert.Equal(t, cfg.ChainID, b.config.ChainID)
+		assert.Len(t, b.config.Stages, 1)
+		assert.IsType(t, ValidatorSignature{}, b.config.SignatureFormat)
+		assert.IsType(t, SimpleAccountCalls{}, b.config.CallEncoder)
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		_, err := NewBuilderForChain(nil)
+		require.Error(t, err)
+		assert.Equal(t, "nil chain config", err.Error())
+	})
+}
+
+func TestBuild(t *testing.T) {
+	target := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+
+	t.Run("full pipeline", func(t *testing.T) {
+		est := testEstimator()
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages:     DefaultStages(&stubNonceSource{nonce: big.NewInt(7)}, est, testFees(), 1.5),
+		})
+		w := testWallet(false)
+		sk := testSessionKey(t)
+
+		op, err := b.Build(context.Background(), w, sk, Call{Target: target, Data: []byte{0x01}})
+		require.NoError(t, err)
+
+		assert.Equal(t, w.Address, op.Sender)
+		assert.Equal(t, big.NewInt(7), op.Nonce)
+		assert.Equal(t, w.Factory.Bytes(), op.InitCode[:20])
+		assert.Equal(t, big.NewInt(150000), op.CallGasLimit)
+		assert.Equal(t, big.NewInt(225000), op.VerificationGasLimit)
+		assert.Equal(t, big.NewInt(75000), op.PreVerificationGas)
+		assert.Equal(t, big.NewInt(2000000000), op.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(1000000), op.MaxPriorityFeePerGas)
+		assert.Len(t, op.Signature, 85)
+
+		require.NotNil(t, est.seen)
+		assert.Len(t, est.seen.Signature, 85)
+		assert.Equal(t, sk.Address.Bytes(), est.seen.Signature[:20])
+		assert.Equal(t, big.NewInt(2000000000), est.seen.MaxFeePerGas)
+
+		expectedCallData, err := encoding.EncodeCallData(target, big.NewInt(0), []byte{0x01})
+		require.NoError(t, err)
+		assert.Equal(t, expectedCallData, op.CallData)
+	})
+
+	t.Run("signature recovers to session key", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages:     []Stage{WalletNonceStage(), StaticGasStage(big.NewInt(1), big.NewInt(1), big.NewInt(1))},
+		})
+		sk := testSessionKey(t)
+
+		op, err := b.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		require.NoError(t, err)
+
+		hash, err := b.Hash(op)
+		require.NoError(t, err)
+
+		assert.Equal(t, sk.Address.Bytes(), op.Signature[:20])
+		ecdsaSig := append([]byte{}, op.Signature[20:]...)
+		ecdsaSig[64] -= 27
+		pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), ecdsaSig)
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, crypto.PubkeyToAddress(*pub))
+	})
+
+	t.Run("ecdsa signature uses the eth signed message hash", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{
+			EntryPoint:      chain.DefaultEntryPoint,
+			ChainID:         big.NewInt(8453),
+			SignatureFormat: ECDSASignature{},
+		})
+		sk := testSessionKey(t)
+
+		op, err := b.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		require.NoError(t, err)
+		require.Len(t, op.Signature, 65)
+
+		hash, err := b.Hash(op)
+		require.NoError(t, err)
+		ecdsaSig := append([]byte{}, op.Signature...)
+		ecdsaSig[64] -= 27
+		pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), ecdsaSig)
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, crypto.PubkeyToAddress(*pub))
+	})
+
+	t.Run("remote session key", func(t *testing.T) {
+		local := testSessionKey(t)
+		keySigner, err := signer.NewPrivateKeySigner(local.PrivateKey)
+		require.NoError(t, err)
+		server := httptest.NewServer(signer.NewServer(keySigner))
+		defer server.Close()
+
+		remote, err := signer.NewRemoteSigner(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+		sk, err := agent.NewRemoteSessionKey(remote, local.ChainID, local.ValidAfter, local.ValidUntil)
+		require.NoError(t, err)
+
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages:     []Stage{WalletNonceStage(), StaticGasStage(big.NewInt(1), big.NewInt(1), big.NewInt(1))},
+		})
+		op, err := b.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		require.NoError(t, err)
+
+		localOp := *op
+		require.NoError(t, b.Sign(&localOp, local))
+		assert.Equal(t, localOp.Signature, op.Signature)
+	})
+
+	t.Run("v0.7 hash", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{
+			EntryPoint:        chain.EntryPointV07Address,
+			EntryPointVersion: chain.EntryPointV07,
+			ChainID:           big.NewInt(8453),
+			Stages:            []Stage{WalletNonceStage(), StaticGasStage(big.NewInt(1), big.NewInt(1), big.NewInt(1))},
+		})
+		sk := testSessionKey(t)
+
+		op, err := b.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		require.NoError(t, err)
+
+		hash, err := b.Hash(op)
+		require.NoError(t, err)
+		expected, err := encoding.HashUserOpV07(op, chain.EntryPointV07Address, big.NewInt(8453))
+		require.NoError(t, err)
+		assert.Equal(t, expected, hash)
+
+		ecdsaSig := append([]byte{}, op.Signature[20:]...)
+		ecdsaSig[64] -= 27
+		pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), ecdsaSig)
+		require.NoError(t, err)
+		assert.Equal(t, sk.Address, crypto.PubkeyToAddress(*pub))
+	})
+
+	t.Run("batch calls", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{EntryPoint: chain.DefaultEntryPoint, ChainID: big.NewInt(8453)})
+		swap, err := defi.NewDeFiService(chain.NewChainService()).ExecuteSwap(defi.SwapParams{
+			TokenIn:  target,
+			TokenOut: common.HexToAddress("0x4200000000000000000000000000000000000006"),
+			AmountIn: big.NewInt(1000),
+			Router:   common.HexToAddress("0x2626664c2603336E57B271c5C0b26F421741e481"),
+		})
+		require.NoError(t, err)
+		call, err := CallFromDeFi(swap)
+		require.NoError(t, err)
+
+		op, err := b.Build(context.Background(), testWallet(true), testSessionKey(t),
+			Call{Target: target, Data: []byte{0x09}},
+			call,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, crypto.Keccak256([]byte("executeBatch(address[],uint256[],bytes[])"))[:4], op.CallData[:4])
+
+		_, err = CallFromDeFi(nil)
+		assert.EqualError(t, err, "nil defi result")
+	})
+
+	t.Run("call encoder from the account factory", func(t *testing.T) {
+		assert.IsType(t, SimpleAccountCalls{}, CallEncoderFor(wallet.SimpleAccountFactory{}))
+		assert.IsType(t, ERC7579Calls{}, CallEncoderFor(wallet.Safe7579Factory{}))
+
+		b := NewBuilder(BuilderConfig{
+			EntryPoint:  chain.DefaultEntryPoint,
+			ChainID:     big.NewInt(8453),
+			CallEncoder: CallEncoderFor(wallet.KernelFactory{}),
+		})
+		op, err := b.Build(context.Background(), testWallet(true), testSessionKey(t), Call{Target: target, Data: []byte{0x01}})
+		require.NoError(t, err)
+		expected, err := encoding.EncodeERC7579CallData(target, big.NewInt(0), []byte{0x01})
+		require.NoError(t, err)
+		assert.Equal(t, expected, op.CallData)
+
+		op, err = b.Build(context.Background(), testWallet(true), testSessionKey(t), Call{Target: target}, Call{Target: target})
+		require.NoError(t, err)
+		args, err := encoding.DecodeFunctionCall(encoding.ERC7579ExecuteSignature, op.CallData)
+		require.NoError(t, err)
+		assert.Equal(t, encoding.ERC7579BatchMode, args[0])
+	})
+
+	t.Run("paymaster stage", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages:     []Stage{PaymasterStage(stubPaymaster{data: []byte{0xaa, 0xbb}})},
+		})
+
+		op, err := b.Build(context.Background(), testWallet(true), testSessionKey(t), Call{Target: target})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xaa, 0xbb}, op.PaymasterAndData)
+	})
+
+	t.Run("custom stage", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages: []Stage{StageFunc(func(ctx context.Context, bc *BuildContext) error {
+				bc.Op.Nonce = big.NewInt(99)
+				return nil
+			})},
+		})
+
+		op, err := b.Build(context.Background(), testWallet(true), testSessionKey(t), Call{Target: target})
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(99), op.Nonce)
+	})
+
+	t.Run("stage error aborts", func(t *testing.T) {
+		est := testEstimator()
+		est.err = errors.New("estimate failed")
+		b := NewBuilder(BuilderConfig{
+			EntryPoint: chain.DefaultEntryPoint,
+			ChainID:    big.NewInt(8453),
+			Stages:     []Stage{GasStage(est, 1)},
+		})
+
+		_, err := b.Build(context.Background(), testWallet(true), testSessionKey(t), Call{Target: target})
+		require.Error(t, err)
+		assert.Equal(t, "estimate failed", err.Error())
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{EntryPoint: chain.DefaultEntryPoint, ChainID: big.NewInt(8453)})
+		sk := testSessionKey(t)
+
+		_, err := b.Build(context.Background(), nil, sk, Call{Target: target})
+		assert.EqualError(t, err, "nil wallet")
+
+		_, err = b.Build(context.Background(), testWallet(true), nil, Call{Target: target})
+		assert.EqualError(t, err, "nil session key")
+
+		_, err = b.Build(context.Background(), testWallet(true), sk)
+		assert.EqualError(t, err, "no calls")
+
+		noChain := NewBuilder(BuilderConfig{EntryPoint: chain.DefaultEntryPoint})
+		_, err = noChain.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		assert.EqualError(t, err, "chain ID required")
+	})
+
+	t.Run("expired session key", func(t *testing.T) {
+		b := NewBuilder(BuilderConfig{EntryPoint: chain.DefaultEntryPoint, ChainID: big.NewInt(8453)})
+		sk := testSessionKey(t)
+		sk.ValidUntil = big.NewInt(1)
+
+		_, err := b.Build(context.Background(), testWallet(true), sk, Call{Target: target})
+		assert.EqualError(t, err, "session key expired")
+	})
+}
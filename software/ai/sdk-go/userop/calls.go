@@ -0,0 +1,47 @@
+package userop
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/wallet"
+)
+
+type SimpleAccountCalls struct{}
+
+func (SimpleAccountCalls) EncodeCalls(calls []Call) ([]byte, error) {
+	return EncodeCalls(calls)
+}
+
+type ERC7579Calls struct{}
+
+func (ERC7579Calls) EncodeCalls(calls []Call) ([]byte, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("no calls")
+	}
+
+	if len(calls) == 1 {
+		return encoding.EncodeERC7579CallData(calls[0].Target, valueOrZero(calls[0].Value), nonNilData(calls[0].Data))
+	}
+
+	targets := make([]common.Address, len(calls))
+	values := make([]*big.Int, len(calls))
+	data := make([][]byte, len(calls))
+	for i, c := range calls {
+		targets[i] = c.Target
+		values[i] = valueOrZero(c.Value)
+		data[i] = nonNilData(c.Data)
+	}
+
+	return encoding.EncodeERC7579BatchCallData(targets, values, data)
+}
+
+func CallEncoderFor(factory wallet.AccountFactory) CallEncoder {
+	switch factory.(type) {
+	case wallet.KernelFactory, *wallet.KernelFactory, wallet.Safe7579Factory, *wallet.Safe7579Factory:
+		return ERC7579Calls{}
+	}
+	return SimpleAccountCalls{}
+}
@@ -0,0 +1,44 @@
+package userop
+
+import (
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/agent"
+)
+
+var dummyECDSASignature = common.FromHex("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+type ValidatorSignature struct{}
+
+func (ValidatorSignature) Dummy(sk *agent.SessionKey) []byte {
+	sig := make([]byte, 0, 85)
+	sig = append(sig, sk.Address.Bytes()...)
+	return append(sig, dummyECDSASignature...)
+}
+
+func (ValidatorSignature) Encode(sk *agent.SessionKey, userOpHash common.Hash) ([]byte, error) {
+	ecdsaSig, err := agent.SignWithSessionKey(sk, accounts.TextHash(userOpHash.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	ecdsaSig[64] += 27
+
+	sig := make([]byte, 0, 85)
+	sig = append(sig, sk.Address.Bytes()...)
+	return append(sig, ecdsaSig...), nil
+}
+
+type ECDSASignature struct{}
+
+func (ECDSASignature) Dummy(sk *agent.SessionKey) []byte {
+	return append([]byte{}, dummyECDSASignature...)
+}
+
+func (ECDSASignature) Encode(sk *agent.SessionKey, userOpHash common.Hash) ([]byte, error) {
+	sig, err := agent.SignWithSessionKey(sk, accounts.TextHash(userOpHash.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
@@ -0,0 +1,178 @@
+package userop
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/sigloop/sdk-go/wallet"
+)
+
+type StaticFeeSource struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+func (s StaticFeeSource) SuggestFees(ctx context.Context) (*big.Int, *big.Int, error) {
+	if s.MaxFeePerGas == nil || s.MaxPriorityFeePerGas == nil {
+		return nil, nil, errors.New("static fees not configured")
+	}
+	return new(big.Int).Set(s.MaxFeePerGas), new(big.Int).Set(s.MaxPriorityFeePerGas), nil
+}
+
+func WalletNonceStage() Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		bc.Op.Nonce = new(big.Int).SetUint64(bc.Wallet.Nonce)
+		return nil
+	})
+}
+
+func NonceStage(source NonceSource, key *big.Int) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if source == nil {
+			return errors.New("nil nonce source")
+		}
+		k := key
+		if k == nil {
+			k = big.NewInt(0)
+		}
+		nonce, err := source.GetNonce(ctx, bc.Wallet.Address, k)
+		if err != nil {
+			return err
+		}
+		bc.Op.Nonce = nonce
+		return nil
+	})
+}
+
+func InitCodeStage() Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if bc.Wallet.IsDeployed {
+			bc.Op.InitCode = []byte{}
+			return nil
+		}
+
+		initCode, err := walletInitCode(bc.Wallet)
+		if err != nil {
+			return err
+		}
+		bc.Op.InitCode = initCode
+		return nil
+	})
+}
+
+func DeploymentStage(source DeploymentSource) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if source == nil {
+			return errors.New("nil deployment source")
+		}
+
+		deployed, err := source.IsDeployed(ctx, bc.Wallet.Address)
+		if err != nil {
+			return err
+		}
+		if deployed {
+			bc.Op.InitCode = []byte{}
+			return nil
+		}
+
+		initCode, err := walletInitCode(bc.Wallet)
+		if err != nil {
+			return err
+		}
+		bc.Op.InitCode = initCode
+		return nil
+	})
+}
+
+func StaticGasStage(callGasLimit, verificationGasLimit, preVerificationGas *big.Int) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if callGasLimit == nil || verificationGasLimit == nil || preVerificationGas == nil {
+			return errors.New("static gas limits not configured")
+		}
+		bc.Op.CallGasLimit = new(big.Int).Set(callGasLimit)
+		bc.Op.VerificationGasLimit = new(big.Int).Set(verificationGasLimit)
+		bc.Op.PreVerificationGas = new(big.Int).Set(preVerificationGas)
+		return nil
+	})
+}
+
+func GasStage(estimator GasEstimator, multiplier float64) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if estimator == nil {
+			return errors.New("nil gas estimator")
+		}
+
+		est, err := estimator.EstimateUserOperationGas(ctx, bc.Op)
+		if err != nil {
+			return err
+		}
+
+		bc.Op.CallGasLimit = scaleGas(est.CallGasLimit, multiplier)
+		bc.Op.VerificationGasLimit = scaleGas(est.VerificationGasLimit, multiplier)
+		bc.Op.PreVerificationGas = scaleGas(est.PreVerificationGas, multiplier)
+		return nil
+	})
+}
+
+func FeeStage(source FeeSource) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if source == nil {
+			return errors.New("nil fee source")
+		}
+
+		maxFee, maxPriority, err := source.SuggestFees(ctx)
+		if err != nil {
+			return err
+		}
+		if maxPriority.Cmp(maxFee) > 0 {
+			return errors.New("priority fee exceeds max fee")
+		}
+
+		bc.Op.MaxFeePerGas = maxFee
+		bc.Op.MaxPriorityFeePerGas = maxPriority
+		return nil
+	})
+}
+
+func PaymasterStage(paymaster Paymaster) Stage {
+	return StageFunc(func(ctx context.Context, bc *BuildContext) error {
+		if paymaster == nil {
+			return errors.New("nil paymaster")
+		}
+
+		data, err := paymaster.PaymasterAndData(ctx, bc.Op, bc.EntryPoint, bc.ChainID)
+		if err != nil {
+			return err
+		}
+		bc.Op.PaymasterAndData = data
+		return nil
+	})
+}
+
+func DefaultStages(nonces NonceSource, estimator GasEstimator, fees FeeSource, gasMultiplier float64) []Stage {
+	return []Stage{
+		NonceStage(nonces, nil),
+		InitCodeStage(),
+		FeeStage(fees),
+		GasStage(estimator, gasMultiplier),
+	}
+}
+
+func walletInitCode(w *wallet.Wallet) ([]byte, error) {
+	if len(w.InitCode) == 0 {
+		return nil, errors.New("wallet has no init code")
+	}
+	return append([]byte{}, w.InitCode...), nil
+}
+
+func scaleGas(v *big.Int, multiplier float64) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+	if multiplier <= 1 {
+		return new(big.Int).Set(v)
+	}
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(v), big.NewFloat(multiplier)).Int(nil)
+	return scaled
+}
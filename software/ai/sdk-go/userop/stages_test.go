@@ -0,0 +1,215 @@
+package userop
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubNonceSource struct {
+	nonce  *big.Int
+	err    error
+	gotKey *big.Int
+}
+
+func (s *stubNonceSource) GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error) {
+	s.gotKey = key
+	return s.nonce, s.err
+}
+
+type nonceSourceFunc func(key *big.Int) *big.Int
+
+func (f nonceSourceFunc) GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error) {
+	return f(key), nil
+}
+
+type stubDeploymentSource struct {
+	deployed bool
+	err      error
+}
+
+func (s stubDeploymentSource) IsDeployed(ctx context.Context, account common.Address) (bool, error) {
+	return s.deployed, s.err
+}
+
+func testBuildContext(t *testing.T, deployed bool) *BuildContext {
+	return &BuildContext{
+		Wallet:     testWallet(deployed),
+		SessionKey: testSessionKey(t),
+		ChainID:    big.NewInt(8453),
+		Op: &encoding.UserOperation{
+			Nonce:    big.NewInt(0),
+			InitCode: []byte{},
+		},
+	}
+}
+
+func TestNonceStage(t *testing.T) {
+	t.Run("wallet nonce", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		require.NoError(t, WalletNonceStage().Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(4), bc.Op.Nonce)
+	})
+
+	t.Run("source with key", func(t *testing.T) {
+		src := &stubNonceSource{nonce: big.NewInt(12)}
+		bc := testBuildContext(t, true)
+		require.NoError(t, NonceStage(src, big.NewInt(5)).Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(12), bc.Op.Nonce)
+		assert.Equal(t, big.NewInt(5), src.gotKey)
+	})
+
+	t.Run("nil key defaults to zero", func(t *testing.T) {
+		src := &stubNonceSource{nonce: big.NewInt(1)}
+		bc := testBuildContext(t, true)
+		require.NoError(t, NonceStage(src, nil).Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(0), src.gotKey)
+	})
+
+	t.Run("shared stage", func(t *testing.T) {
+		stage := NonceStage(nonceSourceFunc(func(key *big.Int) *big.Int { return key }), nil)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bc := testBuildContext(t, true)
+				assert.NoError(t, stage.Apply(context.Background(), bc))
+				assert.Equal(t, big.NewInt(0), bc.Op.Nonce)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("source error", func(t *testing.T) {
+		src := &stubNonceSource{err: errors.New("rpc down")}
+		err := NonceStage(src, nil).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "rpc down")
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		err := NonceStage(nil, nil).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "nil nonce source")
+	})
+}
+
+func TestInitCodeStage(t *testing.T) {
+	t.Run("undeployed wallet", func(t *testing.T) {
+		bc := testBuildContext(t, false)
+		require.NoError(t, InitCodeStage().Apply(context.Background(), bc))
+
+		factoryData, err := encoding.EncodeFunctionCall("createAccount(address,uint256)", bc.Wallet.Owner, big.NewInt(3))
+		require.NoError(t, err)
+		assert.Equal(t, append(bc.Wallet.Factory.Bytes(), factoryData...), bc.Op.InitCode)
+		assert.Equal(t, crypto.Keccak256([]byte("createAccount(address,uint256)"))[:4], bc.Op.InitCode[20:24])
+	})
+
+	t.Run("wallet init code", func(t *testing.T) {
+		bc := testBuildContext(t, false)
+		bc.Wallet.InitCode = []byte{0x0a, 0x0b}
+		require.NoError(t, InitCodeStage().Apply(context.Background(), bc))
+		assert.Equal(t, []byte{0x0a, 0x0b}, bc.Op.InitCode)
+	})
+
+	t.Run("missing init code", func(t *testing.T) {
+		bc := testBuildContext(t, false)
+		bc.Wallet.InitCode = nil
+		assert.EqualError(t, InitCodeStage().Apply(context.Background(), bc), "wallet has no init code")
+	})
+
+	t.Run("deployed wallet", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		bc.Op.InitCode = []byte{0x01}
+		require.NoError(t, InitCodeStage().Apply(context.Background(), bc))
+		assert.Empty(t, bc.Op.InitCode)
+	})
+}
+
+func TestDeploymentStage(t *testing.T) {
+	t.Run("undeployed on chain attaches init code", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		bc.Wallet.InitCode = []byte{0x0a, 0x0b}
+		require.NoError(t, DeploymentStage(stubDeploymentSource{deployed: false}).Apply(context.Background(), bc))
+		assert.Equal(t, []byte{0x0a, 0x0b}, bc.Op.InitCode)
+	})
+
+	t.Run("deployed on chain clears init code", func(t *testing.T) {
+		bc := testBuildContext(t, false)
+		bc.Op.InitCode = []byte{0x01}
+		require.NoError(t, DeploymentStage(stubDeploymentSource{deployed: true}).Apply(context.Background(), bc))
+		assert.Empty(t, bc.Op.InitCode)
+	})
+
+	t.Run("source error", func(t *testing.T) {
+		err := DeploymentStage(stubDeploymentSource{err: errors.New("rpc down")}).Apply(context.Background(), testBuildContext(t, false))
+		assert.EqualError(t, err, "rpc down")
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		err := DeploymentStage(nil).Apply(context.Background(), testBuildContext(t, false))
+		assert.EqualError(t, err, "nil deployment source")
+	})
+}
+
+func TestFeeStage(t *testing.T) {
+	t.Run("static fees", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		require.NoError(t, FeeStage(testFees()).Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(2000000000), bc.Op.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(1000000), bc.Op.MaxPriorityFeePerGas)
+	})
+
+	t.Run("priority above max", func(t *testing.T) {
+		fees := StaticFeeSource{MaxFeePerGas: big.NewInt(1), MaxPriorityFeePerGas: big.NewInt(2)}
+		err := FeeStage(fees).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "priority fee exceeds max fee")
+	})
+
+	t.Run("unconfigured static fees", func(t *testing.T) {
+		err := FeeStage(StaticFeeSource{}).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "static fees not configured")
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		err := FeeStage(nil).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "nil fee source")
+	})
+}
+
+func TestGasStage(t *testing.T) {
+	t.Run("no multiplier", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		require.NoError(t, GasStage(testEstimator(), 0).Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(100000), bc.Op.CallGasLimit)
+		assert.Equal(t, big.NewInt(150000), bc.Op.VerificationGasLimit)
+		assert.Equal(t, big.NewInt(50000), bc.Op.PreVerificationGas)
+	})
+
+	t.Run("static gas", func(t *testing.T) {
+		bc := testBuildContext(t, true)
+		require.NoError(t, StaticGasStage(big.NewInt(1), big.NewInt(2), big.NewInt(3)).Apply(context.Background(), bc))
+		assert.Equal(t, big.NewInt(1), bc.Op.CallGasLimit)
+		assert.Equal(t, big.NewInt(2), bc.Op.VerificationGasLimit)
+		assert.Equal(t, big.NewInt(3), bc.Op.PreVerificationGas)
+	})
+
+	t.Run("nil estimator", func(t *testing.T) {
+		err := GasStage(nil, 1).Apply(context.Background(), testBuildContext(t, true))
+		assert.EqualError(t, err, "nil gas estimator")
+	})
+}
+
+func TestScaleGas(t *testing.T) {
+	assert.Equal(t, big.NewInt(0), scaleGas(nil, 2))
+	assert.Equal(t, big.NewInt(100), scaleGas(big.NewInt(100), 0.5))
+	assert.Equal(t, big.NewInt(110), scaleGas(big.NewInt(100), 1.1))
+	assert.Equal(t, big.NewInt(200), scaleGas(big.NewInt(100), 2))
+}
@@ -0,0 +1,76 @@
+package userop
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/agent"
+	"github.com/sigloop/sdk-go/bundler"
+	"github.com/sigloop/sdk-go/chain"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/sigloop/sdk-go/wallet"
+)
+
+type Call struct {
+	Target common.Address
+	Value  *big.Int
+	Data   []byte
+}
+
+type BuildContext struct {
+	Wallet     *wallet.Wallet
+	SessionKey *agent.SessionKey
+	EntryPoint common.Address
+	ChainID    *big.Int
+	Calls      []Call
+	Op         *encoding.UserOperation
+}
+
+type Stage interface {
+	Apply(ctx context.Context, bc *BuildContext) error
+}
+
+type StageFunc func(ctx context.Context, bc *BuildContext) error
+
+func (f StageFunc) Apply(ctx context.Context, bc *BuildContext) error {
+	return f(ctx, bc)
+}
+
+type NonceSource interface {
+	GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error)
+}
+
+type DeploymentSource interface {
+	IsDeployed(ctx context.Context, account common.Address) (bool, error)
+}
+
+type GasEstimator interface {
+	EstimateUserOperationGas(ctx context.Context, op *encoding.UserOperation) (*bundler.GasEstimate, error)
+}
+
+type FeeSource interface {
+	SuggestFees(ctx context.Context) (maxFeePerGas *big.Int, maxPriorityFeePerGas *big.Int, err error)
+}
+
+type Paymaster interface {
+	PaymasterAndData(ctx context.Context, op *encoding.UserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error)
+}
+
+type SignatureFormat interface {
+	Dummy(sk *agent.SessionKey) []byte
+	Encode(sk *agent.SessionKey, userOpHash common.Hash) ([]byte, error)
+}
+
+type CallEncoder interface {
+	EncodeCalls(calls []Call) ([]byte, error)
+}
+
+type BuilderConfig struct {
+	EntryPoint        common.Address
+	EntryPointVersion chain.EntryPointVersion
+	ChainID           *big.Int
+	Stages            []Stage
+	SignatureFormat   SignatureFormat
+	CallEncoder       CallEncoder
+}
@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+var DefaultSimpleAccountFactory = common.HexToAddress("0x9406Cc6185a346906296840746125a0E44976454")
+
+var ErrProxyCreationCodeRequired = errors.New("proxy creation code required")
+
+type AccountFactory interface {
+	FactoryAddress() common.Address
+	InitCode(owner common.Address, salt *big.Int) ([]byte, error)
+	AccountAddress(owner common.Address, salt *big.Int) (common.Address, error)
+}
+
+type SimpleAccountFactory struct {
+	Factory           common.Address
+	Implementation    common.Address
+	ProxyCreationCode []byte
+}
+
+func (f SimpleAccountFactory) FactoryAddress() common.Address {
+	return f.Factory
+}
+
+func (f SimpleAccountFactory) InitCode(owner common.Address, salt *big.Int) ([]byte, error) {
+	data, err := encoding.EncodeFunctionCall("createAccount(address,uint256)", owner, saltOrZero(salt))
+	if err != nil {
+		return nil, err
+	}
+	return joinInitCode(f.Factory, data), nil
+}
+
+func (f SimpleAccountFactory) AccountAddress(owner common.Address, salt *big.Int) (common.Address, error) {
+	if len(f.ProxyCreationCode) == 0 {
+		return common.Address{}, ErrProxyCreationCodeRequired
+	}
+
+	initialize, err := encoding.EncodeFunctionCall("initialize(address)", owner)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	args := abi.Arguments{{Type: mustType("address")}, {Type: mustType("bytes")}}
+	ctorArgs, err := args.Pack(f.Implementation, initialize)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	salt32, err := saltBytes(salt)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	initCodeHash := crypto.Keccak256(f.ProxyCreationCode, ctorArgs)
+	return crypto.CreateAddress2(f.Factory, salt32, initCodeHash), nil
+}
+
+type KernelFactory struct {
+	Factory        common.Address
+	Implementation common.Address
+	Validator      common.Address
+}
+
+func (f KernelFactory) FactoryAddress() common.Address {
+	return f.Factory
+}
+
+func (f KernelFactory) InitCode(owner common.Address, salt *big.Int) ([]byte, error) {
+	initData, err := f.initializeData(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	salt32, err := saltBytes(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoding.EncodeFunctionCall("createAccount(bytes,bytes32)", initData, salt32)
+	if err != nil {
+		return nil, err
+	}
+	return joinInitCode(f.Factory, data), nil
+}
+
+func (f KernelFactory) AccountAddress(owner common.Address, salt *big.Int) (common.Address, error) {
+	initData, err := f.initializeData(owner)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	salt32, err := saltBytes(salt)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var actualSalt [32]byte
+	copy(actualSalt[:], crypto.Keccak256(initData, salt32[:]))
+
+	return crypto.CreateAddress2(f.Factory, actualSalt, crypto.Keccak256(erc1967ProxyInitCode(f.Implementation))), nil
+}
+
+func (f KernelFactory) initializeData(owner common.Address) ([]byte, error) {
+	var rootValidator [21]byte
+	rootValidator[0] = 0x01
+	copy(rootValidator[1:], f.Validator.Bytes())
+
+	return encoding.EncodeFunctionCall(
+		"initialize(bytes21,address,bytes,bytes,bytes[])",
+		rootValidator,
+		common.Address{},
+		owner.Bytes(),
+		[]byte{},
+		[][]byte{},
+	)
+}
+
+type Safe7579Factory struct {
+	ProxyFactory      common.Address
+	Singleton         common.Address
+	ProxyCreationCode []byte
+	ModuleSetup       common.Address
+	Adapter           common.Address
+}
+
+func (f Safe7579Factory) FactoryAddress() common.Address {
+	return f.ProxyFactory
+}
+
+func (f Safe7579Factory) InitCode(owner common.Address, salt *big.Int) ([]byte, error) {
+	initializer, err := f.initializer(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoding.EncodeFunctionCall(
+		"createProxyWithNonce(address,bytes,uint256)",
+		f.Singleton,
+		initializer,
+		saltOrZero(salt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return joinInitCode(f.ProxyFactory, data), nil
+}
+
+func (f Safe7579Factory) AccountAddress(owner common.Address, salt *big.Int) (common.Address, error) {
+	if len(f.ProxyCreationCode) == 0 {
+		return common.Address{}, ErrProxyCreationCodeRequired
+	}
+
+	initializer, err := f.initializer(owner)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	saltNonce, err := saltBytes(salt)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var proxySalt [32]byte
+	copy(proxySalt[:], crypto.Keccak256(crypto.Keccak256(initializer), saltNonce[:]))
+
+	initCodeHash := crypto.Keccak256(f.ProxyCreationCode, common.LeftPadBytes(f.Singleton.Bytes(), 32))
+	return crypto.CreateAddress2(f.ProxyFactory, proxySalt, initCodeHash), nil
+}
+
+func (f Safe7579Factory) initializer(owner common.Address) ([]byte, error) {
+	enableModules, err := encoding.EncodeFunctionCall("enableModules(address[])", []common.Address{f.Adapter})
+	if err != nil {
+		return nil, err
+	}
+
+	return encoding.EncodeFunctionCall(
+		"setup(address[],uint256,address,bytes,address,address,uint256,address)",
+		[]common.Address{owner},
+		big.NewInt(1),
+		f.ModuleSetup,
+		enableModules,
+		f.Adapter,
+		common.Address{},
+		big.NewInt(0),
+		common.Address{},
+	)
+}
+
+func erc1967ProxyInitCode(implementation common.Address) []byte {
+	code := make([]byte, 0, 95)
+	code = append(code, common.FromHex("0x603d3d8160223d3973")...)
+	code = append(code, implementation.Bytes()...)
+	code = append(code, common.FromHex("0x60095155f3363d3d373d3d363d7f360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc545af43d6000803e6038573d6000fd5b3d6000f3")...)
+	return code
+}
+
+func joinInitCode(factory common.Address, data []byte) []byte {
+	initCode := make([]byte, 0, 20+len(data))
+	initCode = append(initCode, factory.Bytes()...)
+	return append(initCode, data...)
+}
+
+func saltOrZero(salt *big.Int) *big.Int {
+	if salt == nil {
+		return big.NewInt(0)
+	}
+	return salt
+}
+
+func saltBytes(salt *big.Int) ([32]byte, error) {
+	var out [32]byte
+	salt = saltOrZero(salt)
+	if salt.Sign() < 0 || salt.BitLen() > 256 {
+		return out, errors.New("invalid salt")
+	}
+	salt.FillBytes(out[:])
+	return out, nil
+}
+
+func mustType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
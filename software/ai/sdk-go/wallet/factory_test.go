@@ -0,0 +1,241 @@
+package wallet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFactoryOwner   = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	testProxyCode      = common.FromHex("0x608060405260405161040a38038061040a833981016040819052610022916101fe565b")
+	testImplementation = common.HexToAddress("0x1111111111111111111111111111111111111111")
+)
+
+func testKernelFactory() KernelFactory {
+	return KernelFactory{
+		Factory:        common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678"),
+		Implementation: testImplementation,
+		Validator:      common.HexToAddress("0x2222222222222222222222222222222222222222"),
+	}
+}
+
+func testSimpleAccountFactory() SimpleAccountFactory {
+	return SimpleAccountFactory{
+		Factory:           common.HexToAddress("0x9406Cc6185a346906296840746125a0E44976454"),
+		Implementation:    testImplementation,
+		ProxyCreationCode: testProxyCode,
+	}
+}
+
+func testSafe7579Factory() Safe7579Factory {
+	return Safe7579Factory{
+		ProxyFactory:      common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		Singleton:         common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		ProxyCreationCode: testProxyCode,
+		ModuleSetup:       common.HexToAddress("0x5555555555555555555555555555555555555555"),
+		Adapter:           common.HexToAddress("0x6666666666666666666666666666666666666666"),
+	}
+}
+
+func TestAccountFactoryCommon(t *testing.T) {
+	factories := []struct {
+		name    string
+		factory AccountFactory
+	}{
+		{name: "simple account", factory: testSimpleAccountFactory()},
+		{name: "kernel", factory: testKernelFactory()},
+		{name: "safe7579", factory: testSafe7579Factory()},
+	}
+
+	for _, tc := range factories {
+		t.Run(tc.name, func(t *testing.T) {
+			addr1, err := tc.factory.AccountAddress(testFactoryOwner, big.NewInt(0))
+			require.NoError(t, err)
+			addr2, err := tc.factory.AccountAddress(testFactoryOwner, big.NewInt(0))
+			require.NoError(t, err)
+			assert.Equal(t, addr1, addr2)
+
+			addr3, err := tc.factory.AccountAddress(testFactoryOwner, big.NewInt(1))
+			require.NoError(t, err)
+			assert.NotEqual(t, addr1, addr3)
+
+			addr4, err := tc.factory.AccountAddress(common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"), big.NewInt(0))
+			require.NoError(t, err)
+			assert.NotEqual(t, addr1, addr4)
+
+			nilSalt, err := tc.factory.AccountAddress(testFactoryOwner, nil)
+			require.NoError(t, err)
+			assert.Equal(t, addr1, nilSalt)
+
+			initCode, err := tc.factory.InitCode(testFactoryOwner, big.NewInt(0))
+			require.NoError(t, err)
+			assert.Equal(t, tc.factory.FactoryAddress().Bytes(), initCode[:20])
+
+			_, err = tc.factory.AccountAddress(testFactoryOwner, big.NewInt(-1))
+			assert.EqualError(t, err, "invalid salt")
+		})
+	}
+}
+
+func TestSimpleAccountFactory(t *testing.T) {
+	f := testSimpleAccountFactory()
+	salt := big.NewInt(7)
+
+	t.Run("init code", func(t *testing.T) {
+		initCode, err := f.InitCode(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		expected, err := encoding.EncodeFunctionCall("createAccount(address,uint256)", testFactoryOwner, salt)
+		require.NoError(t, err)
+		assert.Equal(t, append(f.Factory.Bytes(), expected...), initCode)
+	})
+
+	t.Run("address", func(t *testing.T) {
+		addr, err := f.AccountAddress(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		initialize, err := encoding.EncodeFunctionCall("initialize(address)", testFactoryOwner)
+		require.NoError(t, err)
+
+		var ctorArgs []byte
+		ctorArgs = append(ctorArgs, common.LeftPadBytes(f.Implementation.Bytes(), 32)...)
+		ctorArgs = append(ctorArgs, common.LeftPadBytes([]byte{0x40}, 32)...)
+		ctorArgs = append(ctorArgs, common.LeftPadBytes(big.NewInt(int64(len(initialize))).Bytes(), 32)...)
+		ctorArgs = append(ctorArgs, common.RightPadBytes(initialize, 64)...)
+
+		var salt32 [32]byte
+		salt.FillBytes(salt32[:])
+		expected := crypto.CreateAddress2(f.Factory, salt32, crypto.Keccak256(f.ProxyCreationCode, ctorArgs))
+		assert.Equal(t, expected, addr)
+	})
+
+	t.Run("missing proxy code", func(t *testing.T) {
+		f := testSimpleAccountFactory()
+		f.ProxyCreationCode = nil
+		_, err := f.AccountAddress(testFactoryOwner, salt)
+		assert.EqualError(t, err, "proxy creation code required")
+	})
+}
+
+func TestKernelFactory(t *testing.T) {
+	f := testKernelFactory()
+	salt := big.NewInt(3)
+
+	initData, err := f.initializeData(testFactoryOwner)
+	require.NoError(t, err)
+
+	t.Run("initialize data", func(t *testing.T) {
+		assert.Equal(t, crypto.Keccak256([]byte("initialize(bytes21,address,bytes,bytes,bytes[])"))[:4], initData[:4])
+		assert.Equal(t, byte(0x01), initData[4])
+		assert.Equal(t, f.Validator.Bytes(), initData[5:25])
+	})
+
+	t.Run("init code", func(t *testing.T) {
+		initCode, err := f.InitCode(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		var salt32 [32]byte
+		salt.FillBytes(salt32[:])
+		expected, err := encoding.EncodeFunctionCall("createAccount(bytes,bytes32)", initData, salt32)
+		require.NoError(t, err)
+		assert.Equal(t, append(f.Factory.Bytes(), expected...), initCode)
+	})
+
+	t.Run("address", func(t *testing.T) {
+		addr, err := f.AccountAddress(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		var salt32 [32]byte
+		salt.FillBytes(salt32[:])
+		var actualSalt [32]byte
+		copy(actualSalt[:], crypto.Keccak256(append(append([]byte{}, initData...), salt32[:]...)))
+
+		expected := crypto.CreateAddress2(f.Factory, actualSalt, crypto.Keccak256(erc1967ProxyInitCode(f.Implementation)))
+		assert.Equal(t, expected, addr)
+	})
+
+	t.Run("different validator", func(t *testing.T) {
+		other := testKernelFactory()
+		other.Validator = common.HexToAddress("0x7777777777777777777777777777777777777777")
+
+		addr1, err := f.AccountAddress(testFactoryOwner, salt)
+		require.NoError(t, err)
+		addr2, err := other.AccountAddress(testFactoryOwner, salt)
+		require.NoError(t, err)
+		assert.NotEqual(t, addr1, addr2)
+	})
+}
+
+func TestERC1967ProxyInitCode(t *testing.T) {
+	code := erc1967ProxyInitCode(testImplementation)
+	require.Len(t, code, 95)
+
+	cfg := &runtime.Config{}
+	deployed, addr, _, err := runtime.Create(code, cfg)
+	require.NoError(t, err)
+
+	implSlot := common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	assert.Equal(t, code[34:], deployed)
+	assert.Equal(t, common.BytesToHash(testImplementation.Bytes()), cfg.State.GetState(addr, implSlot))
+}
+
+func TestSafe7579Factory(t *testing.T) {
+	f := testSafe7579Factory()
+	salt := big.NewInt(11)
+
+	initializer, err := f.initializer(testFactoryOwner)
+	require.NoError(t, err)
+
+	t.Run("initializer", func(t *testing.T) {
+		enableModules, err := encoding.EncodeFunctionCall("enableModules(address[])", []common.Address{f.Adapter})
+		require.NoError(t, err)
+
+		expected, err := encoding.EncodeFunctionCall(
+			"setup(address[],uint256,address,bytes,address,address,uint256,address)",
+			[]common.Address{testFactoryOwner},
+			big.NewInt(1),
+			f.ModuleSetup,
+			enableModules,
+			f.Adapter,
+			common.Address{},
+			big.NewInt(0),
+			common.Address{},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, expected, initializer)
+	})
+
+	t.Run("init code", func(t *testing.T) {
+		initCode, err := f.InitCode(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		expected, err := encoding.EncodeFunctionCall("createProxyWithNonce(address,bytes,uint256)", f.Singleton, initializer, salt)
+		require.NoError(t, err)
+		assert.Equal(t, append(f.ProxyFactory.Bytes(), expected...), initCode)
+	})
+
+	t.Run("address", func(t *testing.T) {
+		addr, err := f.AccountAddress(testFactoryOwner, salt)
+		require.NoError(t, err)
+
+		var proxySalt [32]byte
+		copy(proxySalt[:], crypto.Keccak256(crypto.Keccak256(initializer), common.LeftPadBytes(salt.Bytes(), 32)))
+		initCodeHash := crypto.Keccak256(f.ProxyCreationCode, common.LeftPadBytes(f.Singleton.Bytes(), 32))
+
+		assert.Equal(t, crypto.CreateAddress2(f.ProxyFactory, proxySalt, initCodeHash), addr)
+	})
+
+	t.Run("missing proxy code", func(t *testing.T) {
+		f := testSafe7579Factory()
+		f.ProxyCreationCode = nil
+		_, err := f.AccountAddress(testFactoryOwner, salt)
+		assert.EqualError(t, err, "proxy creation code required")
+	})
+}
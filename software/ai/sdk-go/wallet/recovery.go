@@ -1,11 +1,15 @@
 package wallet
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
 )
 
 type RecoveryRequest struct {
@@ -14,24 +18,41 @@ type RecoveryRequest struct {
 	Approvals     map[common.Address][]byte
 	Threshold     uint8
 	Executed      bool
+	Nonce         uint64
 }
 
 type RecoveryService struct {
-	requests map[common.Address]*RecoveryRequest
-	mu       sync.Mutex
+	store RecoveryStore
+	mu    sync.Mutex
 }
 
-func NewRecoveryService() *RecoveryService {
-	return &RecoveryService{
-		requests: make(map[common.Address]*RecoveryRequest),
+type RecoveryServiceOption func(*RecoveryService)
+
+func WithRecoveryStore(store RecoveryStore) RecoveryServiceOption {
+	return func(rs *RecoveryService) {
+		rs.store = store
 	}
 }
 
+func NewRecoveryService(opts ...RecoveryServiceOption) *RecoveryService {
+	rs := &RecoveryService{}
+	for _, opt := range opts {
+		opt(rs)
+	}
+	if rs.store == nil {
+		rs.store = NewMemoryRecoveryStore()
+	}
+	return rs
+}
+
 func (s *WalletService) AddGuardian(walletAddr common.Address, guardian common.Address, threshold uint8) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	w, ok := s.wallets[walletAddr]
+	w, ok, err := s.store.Get(walletAddr)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("wallet not found")
 	}
@@ -48,14 +69,17 @@ func (s *WalletService) AddGuardian(walletAddr common.Address, guardian common.A
 		Threshold: threshold,
 	})
 
-	return nil
+	return s.store.Put(w)
 }
 
 func (s *WalletService) RemoveGuardian(walletAddr common.Address, guardian common.Address) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	w, ok := s.wallets[walletAddr]
+	w, ok, err := s.store.Get(walletAddr)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("wallet not found")
 	}
@@ -63,7 +87,7 @@ func (s *WalletService) RemoveGuardian(walletAddr common.Address, guardian commo
 	for i, g := range w.Guardians {
 		if g.Address == guardian {
 			w.Guardians = append(w.Guardians[:i], w.Guardians[i+1:]...)
-			return nil
+			return s.store.Put(w)
 		}
 	}
 
@@ -78,15 +102,23 @@ func (rs *RecoveryService) InitiateRecovery(wallet *Wallet, newOwner common.Addr
 		return nil, errors.New("no guardians configured")
 	}
 
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
 	req := &RecoveryRequest{
 		WalletAddress: wallet.Address,
 		NewOwner:      newOwner,
 		Approvals:     make(map[common.Address][]byte),
 		Threshold:     wallet.Guardians[0].Threshold,
 		Executed:      false,
+		Nonce:         binary.BigEndian.Uint64(nonce[:]),
 	}
 
-	rs.requests[wallet.Address] = req
+	if err := rs.store.Put(req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
@@ -94,21 +126,62 @@ func (rs *RecoveryService) ApproveRecovery(walletAddr common.Address, guardian c
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	req, ok := rs.requests[walletAddr]
-	if !ok {
-		return errors.New("no recovery request found")
+	req, err := rs.pendingRequest(walletAddr)
+	if err != nil {
+		return err
+	}
+	return rs.approve(req, guardian, signature)
+}
+
+func (rs *RecoveryService) ApproveRecoveryWithSigner(ctx context.Context, walletAddr common.Address, s signer.Signer) error {
+	if s == nil {
+		return errors.New("nil signer")
+	}
+
+	rs.mu.Lock()
+	req, err := rs.pendingRequest(walletAddr)
+	rs.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	newOwner, nonce := req.NewOwner, req.Nonce
+
+	sig, err := SignRecoveryApproval(ctx, s, walletAddr, newOwner)
+	if err != nil {
+		return err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	req, err = rs.pendingRequest(walletAddr)
+	if err != nil {
+		return err
+	}
+	if req.NewOwner != newOwner || req.Nonce != nonce {
+		return errors.New("recovery request changed while signing")
 	}
+	return rs.approve(req, s.Address(), sig)
+}
 
+func (rs *RecoveryService) pendingRequest(walletAddr common.Address) (*RecoveryRequest, error) {
+	req, ok, err := rs.store.Get(walletAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("no recovery request found")
+	}
 	if req.Executed {
-		return errors.New("recovery already executed")
+		return nil, errors.New("recovery already executed")
 	}
+	return req, nil
+}
 
-	recoveryHash := crypto.Keccak256(
-		walletAddr.Bytes(),
-		req.NewOwner.Bytes(),
-	)
+func (rs *RecoveryService) approve(req *RecoveryRequest, guardian common.Address, signature []byte) error {
+	recoveryHash := RecoveryHash(req.WalletAddress, req.NewOwner)
 
-	sigPublicKey, err := crypto.SigToPub(recoveryHash, signature)
+	sigPublicKey, err := crypto.SigToPub(recoveryHash.Bytes(), signature)
 	if err != nil {
 		return errors.New("invalid signature")
 	}
@@ -119,14 +192,28 @@ func (rs *RecoveryService) ApproveRecovery(walletAddr common.Address, guardian c
 	}
 
 	req.Approvals[guardian] = signature
-	return nil
+	return rs.store.Put(req)
+}
+
+func RecoveryHash(walletAddr common.Address, newOwner common.Address) common.Hash {
+	return crypto.Keccak256Hash(walletAddr.Bytes(), newOwner.Bytes())
+}
+
+func SignRecoveryApproval(ctx context.Context, s signer.Signer, walletAddr common.Address, newOwner common.Address) ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("nil signer")
+	}
+	return s.SignHash(ctx, RecoveryHash(walletAddr, newOwner))
 }
 
 func (rs *RecoveryService) ExecuteRecovery(walletAddr common.Address, walletService *WalletService) error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	req, ok := rs.requests[walletAddr]
+	req, ok, err := rs.store.Get(walletAddr)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("no recovery request found")
 	}
@@ -142,14 +229,19 @@ func (rs *RecoveryService) ExecuteRecovery(walletAddr common.Address, walletServ
 	walletService.mu.Lock()
 	defer walletService.mu.Unlock()
 
-	w, ok := walletService.wallets[walletAddr]
+	w, ok, err := walletService.store.Get(walletAddr)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("wallet not found")
 	}
 
 	w.Owner = req.NewOwner
-	req.Executed = true
-	delete(rs.requests, walletAddr)
+	if err := walletService.store.Put(w); err != nil {
+		return err
+	}
 
-	return nil
+	req.Executed = true
+	return rs.store.Delete(walletAddr)
 }
@@ -1,11 +1,14 @@
 package wallet
 
 import (
+	"context"
 	"math/big"
+	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -13,9 +16,9 @@ import (
 func setupWalletWithGuardians(t *testing.T, guardians []common.Address) (*WalletService, *Wallet) {
 	t.Helper()
 	cfg := WalletConfig{
-		EntryPoint: common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
-		Factory:    common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678"),
-		ChainID:    big.NewInt(8453),
+		EntryPoint:     common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
+		AccountFactory: testKernelFactory(),
+		ChainID:        big.NewInt(8453),
 	}
 	svc := NewWalletService(cfg)
 	w, err := svc.CreateWallet(CreateWalletParams{
@@ -31,8 +34,8 @@ func setupWalletWithGuardians(t *testing.T, guardians []common.Address) (*Wallet
 func TestNewRecoveryService(t *testing.T) {
 	rs := NewRecoveryService()
 	assert.NotNil(t, rs)
-	assert.NotNil(t, rs.requests)
-	assert.Empty(t, rs.requests)
+	assert.IsType(t, &MemoryRecoveryStore{}, rs.store)
+	assert.Empty(t, rs.store.(*MemoryRecoveryStore).requests)
 }
 
 func TestAddGuardian(t *testing.T) {
@@ -281,6 +284,108 @@ func TestApproveRecovery(t *testing.T) {
 	})
 }
 
+func TestApproveRecoveryWithSigner(t *testing.T) {
+	guardianKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	guardian, err := signer.NewPrivateKeySigner(guardianKey)
+	require.NoError(t, err)
+
+	newOwner := common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+
+	t.Run("success", func(t *testing.T) {
+		_, w := setupWalletWithGuardians(t, []common.Address{guardian.Address()})
+		rs := NewRecoveryService()
+
+		req, err := rs.InitiateRecovery(w, newOwner)
+		require.NoError(t, err)
+
+		require.NoError(t, rs.ApproveRecoveryWithSigner(context.Background(), w.Address, guardian))
+
+		want, err := crypto.Sign(RecoveryHash(w.Address, newOwner).Bytes(), guardianKey)
+		require.NoError(t, err)
+		assert.Equal(t, want, req.Approvals[guardian.Address()])
+	})
+
+	t.Run("concurrent guardians", func(t *testing.T) {
+		guardians := make([]signer.Signer, 4)
+		addrs := make([]common.Address, len(guardians))
+		for i := range guardians {
+			key, err := crypto.GenerateKey()
+			require.NoError(t, err)
+			guardians[i], err = signer.NewPrivateKeySigner(key)
+			require.NoError(t, err)
+			addrs[i] = guardians[i].Address()
+		}
+		_, w := setupWalletWithGuardians(t, addrs)
+		rs := NewRecoveryService()
+		req, err := rs.InitiateRecovery(w, newOwner)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for _, g := range guardians {
+			wg.Add(1)
+			go func(g signer.Signer) {
+				defer wg.Done()
+				assert.NoError(t, rs.ApproveRecoveryWithSigner(context.Background(), w.Address, g))
+			}(g)
+		}
+		wg.Wait()
+		assert.Len(t, req.Approvals, len(guardians))
+	})
+
+	t.Run("no recovery request", func(t *testing.T) {
+		rs := NewRecoveryService()
+		err := rs.ApproveRecoveryWithSigner(context.Background(), common.HexToAddress("0xdead"), guardian)
+		assert.EqualError(t, err, "no recovery request found")
+	})
+
+	t.Run("nil signer", func(t *testing.T) {
+		rs := NewRecoveryService()
+		err := rs.ApproveRecoveryWithSigner(context.Background(), common.HexToAddress("0xdead"), nil)
+		assert.EqualError(t, err, "nil signer")
+	})
+
+	t.Run("request replaced while signing", func(t *testing.T) {
+		_, w := setupWalletWithGuardians(t, []common.Address{guardian.Address()})
+		rs := NewRecoveryService()
+		_, err := rs.InitiateRecovery(w, newOwner)
+		require.NoError(t, err)
+
+		var replaced *RecoveryRequest
+		s := &hookSigner{Signer: guardian, onSign: func() {
+			replaced, err = rs.InitiateRecovery(w, newOwner)
+			require.NoError(t, err)
+		}}
+		err = rs.ApproveRecoveryWithSigner(context.Background(), w.Address, s)
+		assert.EqualError(t, err, "recovery request changed while signing")
+		assert.Empty(t, replaced.Approvals)
+	})
+
+	t.Run("request executed while signing", func(t *testing.T) {
+		_, w := setupWalletWithGuardians(t, []common.Address{guardian.Address()})
+		rs := NewRecoveryService()
+		req, err := rs.InitiateRecovery(w, newOwner)
+		require.NoError(t, err)
+
+		s := &hookSigner{Signer: guardian, onSign: func() {
+			req.Executed = true
+		}}
+		err = rs.ApproveRecoveryWithSigner(context.Background(), w.Address, s)
+		assert.EqualError(t, err, "recovery already executed")
+		assert.Empty(t, req.Approvals)
+	})
+}
+
+type hookSigner struct {
+	signer.Signer
+	onSign func()
+}
+
+func (s *hookSigner) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	s.onSign()
+	return s.Signer.SignHash(ctx, hash)
+}
+
 func TestExecuteRecovery(t *testing.T) {
 	guardianKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/storage"
+)
+
+type WalletStore interface {
+	Get(address common.Address) (*Wallet, bool, error)
+	Put(w *Wallet) error
+	List() ([]*Wallet, error)
+}
+
+type RecoveryStore interface {
+	Get(walletAddr common.Address) (*RecoveryRequest, bool, error)
+	Put(req *RecoveryRequest) error
+	Delete(walletAddr common.Address) error
+}
+
+type MemoryWalletStore struct {
+	wallets map[common.Address]*Wallet
+	mu      sync.RWMutex
+}
+
+func NewMemoryWalletStore() *MemoryWalletStore {
+	return &MemoryWalletStore{
+		wallets: make(map[common.Address]*Wallet),
+	}
+}
+
+func (s *MemoryWalletStore) Get(address common.Address) (*Wallet, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w, ok := s.wallets[address]
+	if !ok {
+		return nil, false, nil
+	}
+	return copyWallet(w), true, nil
+}
+
+func (s *MemoryWalletStore) Put(w *Wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wallets[w.Address] = copyWallet(w)
+	return nil
+}
+
+func (s *MemoryWalletStore) List() ([]*Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Wallet, 0, len(s.wallets))
+	for _, w := range s.wallets {
+		result = append(result, copyWallet(w))
+	}
+	return result, nil
+}
+
+func copyWallet(w *Wallet) *Wallet {
+	c := *w
+	if w.Salt != nil {
+		c.Salt = new(big.Int).Set(w.Salt)
+	}
+	if w.ChainID != nil {
+		c.ChainID = new(big.Int).Set(w.ChainID)
+	}
+	if w.InitCode != nil {
+		c.InitCode = append([]byte{}, w.InitCode...)
+	}
+	if w.Guardians != nil {
+		c.Guardians = append([]Guardian{}, w.Guardians...)
+	}
+	if w.Nonces != nil {
+		c.Nonces = make(map[string]*big.Int, len(w.Nonces))
+		for key, nonce := range w.Nonces {
+			if nonce != nil {
+				nonce = new(big.Int).Set(nonce)
+			}
+			c.Nonces[key] = nonce
+		}
+	}
+	return &c
+}
+
+type FileWalletStore struct {
+	file *storage.FileStore
+}
+
+func NewFileWalletStore(path string) (*FileWalletStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWalletStore{file: file}, nil
+}
+
+func (s *FileWalletStore) Get(address common.Address) (*Wallet, bool, error) {
+	var w Wallet
+	ok, err := s.file.Get(address.Hex(), &w)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &w, true, nil
+}
+
+func (s *FileWalletStore) Put(w *Wallet) error {
+	return s.file.Put(w.Address.Hex(), w)
+}
+
+func (s *FileWalletStore) List() ([]*Wallet, error) {
+	keys := s.file.Keys()
+	result := make([]*Wallet, 0, len(keys))
+	for _, key := range keys {
+		var w Wallet
+		ok, err := s.file.Get(key, &w)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, &w)
+		}
+	}
+	return result, nil
+}
+
+type MemoryRecoveryStore struct {
+	requests map[common.Address]*RecoveryRequest
+	mu       sync.RWMutex
+}
+
+func NewMemoryRecoveryStore() *MemoryRecoveryStore {
+	return &MemoryRecoveryStore{
+		requests: make(map[common.Address]*RecoveryRequest),
+	}
+}
+
+func (s *MemoryRecoveryStore) Get(walletAddr common.Address) (*RecoveryRequest, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	req, ok := s.requests[walletAddr]
+	return req, ok, nil
+}
+
+func (s *MemoryRecoveryStore) Put(req *RecoveryRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[req.WalletAddress] = req
+	return nil
+}
+
+func (s *MemoryRecoveryStore) Delete(walletAddr common.Address) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.requests, walletAddr)
+	return nil
+}
+
+type FileRecoveryStore struct {
+	file *storage.FileStore
+}
+
+func NewFileRecoveryStore(path string) (*FileRecoveryStore, error) {
+	file, err := storage.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRecoveryStore{file: file}, nil
+}
+
+func (s *FileRecoveryStore) Get(walletAddr common.Address) (*RecoveryRequest, bool, error) {
+	var req RecoveryRequest
+	ok, err := s.file.Get(walletAddr.Hex(), &req)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if req.Approvals == nil {
+		req.Approvals = make(map[common.Address][]byte)
+	}
+	return &req, true, nil
+}
+
+func (s *FileRecoveryStore) Put(req *RecoveryRequest) error {
+	return s.file.Put(req.WalletAddress.Hex(), req)
+}
+
+func (s *FileRecoveryStore) Delete(walletAddr common.Address) error {
+	return s.file.Delete(walletAddr.Hex())
+}
@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWalletStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallets.json")
+	owner := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	guardian := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	store, err := NewFileWalletStore(path)
+	require.NoError(t, err)
+	svc := NewWalletService(testWalletConfig(), WithWalletStore(store))
+
+	w, err := svc.CreateWallet(CreateWalletParams{Owner: owner, Salt: big.NewInt(5), Guardians: []common.Address{guardian}})
+	require.NoError(t, err)
+	require.NoError(t, svc.AddGuardian(w.Address, common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc"), 2))
+
+	t.Run("restored after restart", func(t *testing.T) {
+		reopened, err := NewFileWalletStore(path)
+		require.NoError(t, err)
+		restarted := NewWalletService(testWalletConfig(), WithWalletStore(reopened))
+
+		got, ok := restarted.GetWallet(w.Address)
+		require.True(t, ok)
+		assert.Equal(t, w.Address, got.Address)
+		assert.Equal(t, owner, got.Owner)
+		assert.Equal(t, big.NewInt(5), got.Salt)
+		assert.Equal(t, w.InitCode, got.InitCode)
+		assert.Len(t, got.Guardians, 2)
+		assert.Equal(t, uint8(2), got.Guardians[1].Threshold)
+		wallets, err := restarted.ListWallets()
+		require.NoError(t, err)
+		assert.Len(t, wallets, 1)
+	})
+
+	t.Run("returned wallets are copies", func(t *testing.T) {
+		got, ok := svc.GetWallet(w.Address)
+		require.True(t, ok)
+		got.Nonce = 99
+
+		again, ok := svc.GetWallet(w.Address)
+		require.True(t, ok)
+		assert.Equal(t, uint64(0), again.Nonce)
+	})
+}
+
+func TestMemoryWalletStore(t *testing.T) {
+	store := NewMemoryWalletStore()
+	w := &Wallet{
+		Address:   common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Salt:      big.NewInt(5),
+		Guardians: []Guardian{{Address: common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")}},
+		Nonces:    map[string]*big.Int{"0": big.NewInt(1)},
+	}
+	require.NoError(t, store.Put(w))
+	w.Nonces["0"] = big.NewInt(2)
+
+	got, ok, err := store.Get(w.Address)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, big.NewInt(1), got.Nonces["0"])
+
+	got.Salt.SetInt64(9)
+	got.Guardians[0].Threshold = 3
+	got.Nonces["7"] = big.NewInt(7)
+
+	wallets, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, big.NewInt(5), wallets[0].Salt)
+	assert.Equal(t, uint8(0), wallets[0].Guardians[0].Threshold)
+	assert.Equal(t, map[string]*big.Int{"0": big.NewInt(1)}, wallets[0].Nonces)
+}
+
+func TestFileRecoveryStore(t *testing.T) {
+	guardianKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	guardian := crypto.PubkeyToAddress(guardianKey.PublicKey)
+	newOwner := common.HexToAddress("0xdddddddddddddddddddddddddddddddddddddddd")
+
+	walletPath := filepath.Join(t.TempDir(), "wallets.json")
+	recoveryPath := filepath.Join(t.TempDir(), "recovery.json")
+
+	walletStore, err := NewFileWalletStore(walletPath)
+	require.NoError(t, err)
+	svc := NewWalletService(testWalletConfig(), WithWalletStore(walletStore))
+	w, err := svc.CreateWallet(CreateWalletParams{
+		Owner:     common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		Guardians: []common.Address{guardian},
+	})
+	require.NoError(t, err)
+
+	recoveryStore, err := NewFileRecoveryStore(recoveryPath)
+	require.NoError(t, err)
+	rs := NewRecoveryService(WithRecoveryStore(recoveryStore))
+	_, err = rs.InitiateRecovery(w, newOwner)
+	require.NoError(t, err)
+
+	reopened, err := NewFileRecoveryStore(recoveryPath)
+	require.NoError(t, err)
+	restarted := NewRecoveryService(WithRecoveryStore(reopened))
+
+	sig, err := crypto.Sign(crypto.Keccak256(w.Address.Bytes(), newOwner.Bytes()), guardianKey)
+	require.NoError(t, err)
+	require.NoError(t, restarted.ApproveRecovery(w.Address, guardian, sig))
+
+	reopened, err = NewFileRecoveryStore(recoveryPath)
+	require.NoError(t, err)
+	req, ok, err := reopened.Get(w.Address)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, sig, req.Approvals[guardian])
+
+	require.NoError(t, NewRecoveryService(WithRecoveryStore(reopened)).ExecuteRecovery(w.Address, svc))
+
+	got, ok := svc.GetWallet(w.Address)
+	require.True(t, ok)
+	assert.Equal(t, newOwner, got.Owner)
+
+	_, ok, err = reopened.Get(w.Address)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
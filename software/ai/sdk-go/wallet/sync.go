@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/encoding"
+)
+
+type ChainReader interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+var maxNonceKey = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 192), big.NewInt(1))
+
+func (s *WalletService) IsDeployed(ctx context.Context, address common.Address) (bool, error) {
+	if s.config.ChainReader == nil {
+		return false, errors.New("chain reader not configured")
+	}
+
+	code, err := s.config.ChainReader.CodeAt(ctx, address, nil)
+	if err != nil {
+		return false, err
+	}
+	return len(code) > 0, nil
+}
+
+func (s *WalletService) GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error) {
+	if s.config.ChainReader == nil {
+		return nil, errors.New("chain reader not configured")
+	}
+	if key == nil {
+		key = big.NewInt(0)
+	}
+	if key.Sign() < 0 || key.Cmp(maxNonceKey) > 0 {
+		return nil, errors.New("invalid nonce key")
+	}
+
+	data, err := encoding.EncodeFunctionCall("getNonce(address,uint192)", sender, key)
+	if err != nil {
+		return nil, err
+	}
+
+	entryPoint := s.entryPointFor(sender)
+	out, err := s.config.ChainReader.CallContract(ctx, ethereum.CallMsg{To: &entryPoint, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != 32 {
+		return nil, errors.New("invalid getNonce response")
+	}
+	return new(big.Int).SetBytes(out), nil
+}
+
+func (s *WalletService) SyncWallet(ctx context.Context, address common.Address, keys ...*big.Int) (*Wallet, error) {
+	if _, ok := s.GetWallet(address); !ok {
+		return nil, errors.New("wallet not found")
+	}
+	if len(keys) == 0 {
+		keys = []*big.Int{big.NewInt(0)}
+	}
+
+	deployed, err := s.IsDeployed(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	nonces := make(map[string]*big.Int, len(keys))
+	for _, key := range keys {
+		if key == nil {
+			key = big.NewInt(0)
+		}
+		nonce, err := s.GetNonce(ctx, address, key)
+		if err != nil {
+			return nil, err
+		}
+		if key.Sign() == 0 && !nonce.IsUint64() {
+			return nil, errors.New("nonce overflows uint64")
+		}
+		nonces[key.String()] = nonce
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok, err := s.store.Get(address)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("wallet not found")
+	}
+	w.IsDeployed = deployed
+	if w.Nonces == nil {
+		w.Nonces = make(map[string]*big.Int, len(nonces))
+	}
+	for key, nonce := range nonces {
+		w.Nonces[key] = nonce
+	}
+	if nonce, ok := nonces["0"]; ok {
+		w.Nonce = nonce.Uint64()
+	}
+	if err := s.store.Put(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *WalletService) entryPointFor(address common.Address) common.Address {
+	if w, ok := s.GetWallet(address); ok && w.EntryPoint != (common.Address{}) {
+		return w.EntryPoint
+	}
+	return s.config.EntryPoint
+}
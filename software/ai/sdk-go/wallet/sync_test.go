@@ -0,0 +1,240 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getNonce(address sender, uint192 key) returns (key << 64) | sload(sender).
+var mockEntryPointCode = common.FromHex("0x60243560401b600435541760005260206000f3")
+
+type failingReader struct{}
+
+func (failingReader) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("rpc down")
+}
+
+func (failingReader) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("rpc down")
+}
+
+func newSimulatedWalletService(t *testing.T, sequences map[common.Address]uint64, deployed ...common.Address) *WalletService {
+	t.Helper()
+
+	cfg := testWalletConfig()
+	storage := make(map[common.Hash]common.Hash)
+	for addr, seq := range sequences {
+		storage[common.BytesToHash(addr.Bytes())] = common.BigToHash(new(big.Int).SetUint64(seq))
+	}
+
+	alloc := types.GenesisAlloc{
+		cfg.EntryPoint: {Code: mockEntryPointCode, Storage: storage, Balance: big.NewInt(0)},
+	}
+	for _, addr := range deployed {
+		alloc[addr] = types.Account{Code: []byte{0x00}, Balance: big.NewInt(0)}
+	}
+
+	backend := simulated.NewBackend(alloc)
+	t.Cleanup(func() { backend.Close() })
+
+	cfg.ChainReader = backend.Client()
+	return NewWalletService(cfg)
+}
+
+func TestIsDeployed(t *testing.T) {
+	factory := testKernelFactory()
+	owner := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	deployedAddr, err := factory.AccountAddress(owner, big.NewInt(1))
+	require.NoError(t, err)
+	undeployedAddr, err := factory.AccountAddress(owner, big.NewInt(2))
+	require.NoError(t, err)
+
+	svc := newSimulatedWalletService(t, nil, deployedAddr)
+
+	t.Run("deployed", func(t *testing.T) {
+		deployed, err := svc.IsDeployed(context.Background(), deployedAddr)
+		require.NoError(t, err)
+		assert.True(t, deployed)
+	})
+
+	t.Run("undeployed", func(t *testing.T) {
+		deployed, err := svc.IsDeployed(context.Background(), undeployedAddr)
+		require.NoError(t, err)
+		assert.False(t, deployed)
+	})
+
+	t.Run("no chain reader", func(t *testing.T) {
+		_, err := NewWalletService(testWalletConfig()).IsDeployed(context.Background(), deployedAddr)
+		assert.EqualError(t, err, "chain reader not configured")
+	})
+}
+
+func TestGetNonce(t *testing.T) {
+	sender := common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+	svc := newSimulatedWalletService(t, map[common.Address]uint64{sender: 9})
+
+	tests := []struct {
+		name    string
+		key     *big.Int
+		want    *big.Int
+		wantErr string
+	}{
+		{
+			name: "default key",
+			key:  nil,
+			want: big.NewInt(9),
+		},
+		{
+			name: "2d key",
+			key:  big.NewInt(5),
+			want: new(big.Int).Or(new(big.Int).Lsh(big.NewInt(5), 64), big.NewInt(9)),
+		},
+		{
+			name: "max key",
+			key:  maxNonceKey,
+			want: new(big.Int).Or(new(big.Int).Lsh(maxNonceKey, 64), big.NewInt(9)),
+		},
+		{
+			name:    "key too large",
+			key:     new(big.Int).Lsh(big.NewInt(1), 192),
+			wantErr: "invalid nonce key",
+		},
+		{
+			name:    "negative key",
+			key:     big.NewInt(-1),
+			wantErr: "invalid nonce key",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nonce, err := svc.GetNonce(context.Background(), sender, tc.key)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, nonce)
+		})
+	}
+
+	t.Run("unknown sender has zero sequence", func(t *testing.T) {
+		nonce, err := svc.GetNonce(context.Background(), common.HexToAddress("0xdddddddddddddddddddddddddddddddddddddddd"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), nonce.Int64())
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		cfg := testWalletConfig()
+		cfg.ChainReader = failingReader{}
+		_, err := NewWalletService(cfg).GetNonce(context.Background(), sender, nil)
+		assert.EqualError(t, err, "rpc down")
+	})
+}
+
+func TestSyncWallet(t *testing.T) {
+	factory := testKernelFactory()
+	owner := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	deployedAddr, err := factory.AccountAddress(owner, big.NewInt(1))
+	require.NoError(t, err)
+
+	svc := newSimulatedWalletService(t, map[common.Address]uint64{deployedAddr: 12}, deployedAddr)
+
+	t.Run("deployed wallet", func(t *testing.T) {
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner, Salt: big.NewInt(1)})
+		require.NoError(t, err)
+		require.Equal(t, deployedAddr, w.Address)
+		assert.False(t, w.IsDeployed)
+
+		synced, err := svc.SyncWallet(context.Background(), w.Address)
+		require.NoError(t, err)
+		assert.True(t, synced.IsDeployed)
+		assert.Equal(t, uint64(12), synced.Nonce)
+
+		stored, ok := svc.GetWallet(w.Address)
+		require.True(t, ok)
+		assert.True(t, stored.IsDeployed)
+		assert.Equal(t, uint64(12), stored.Nonce)
+		assert.Equal(t, map[string]*big.Int{"0": big.NewInt(12)}, stored.Nonces)
+	})
+
+	t.Run("concurrent readers", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, err := svc.SyncWallet(context.Background(), deployedAddr)
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				w, ok := svc.GetWallet(deployedAddr)
+				if assert.True(t, ok) {
+					for key := range w.Nonces {
+						_ = w.Nonces[key].Sign()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("nonce keys", func(t *testing.T) {
+		w, ok := svc.GetWallet(deployedAddr)
+		require.True(t, ok)
+		key := big.NewInt(7)
+
+		synced, err := svc.SyncWallet(context.Background(), w.Address, key)
+		require.NoError(t, err)
+		want := new(big.Int).Or(new(big.Int).Lsh(key, 64), big.NewInt(12))
+		assert.Equal(t, want, synced.Nonces["7"])
+		assert.Equal(t, big.NewInt(12), synced.Nonces["0"])
+		assert.Equal(t, uint64(12), synced.Nonce)
+
+		synced, err = svc.SyncWallet(context.Background(), w.Address, nil, maxNonceKey)
+		require.NoError(t, err)
+		assert.Len(t, synced.Nonces, 3)
+		assert.Equal(t, new(big.Int).Or(new(big.Int).Lsh(maxNonceKey, 64), big.NewInt(12)), synced.Nonces[maxNonceKey.String()])
+
+		_, err = svc.SyncWallet(context.Background(), w.Address, big.NewInt(-1))
+		assert.EqualError(t, err, "invalid nonce key")
+	})
+
+	t.Run("undeployed wallet", func(t *testing.T) {
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner, Salt: big.NewInt(2)})
+		require.NoError(t, err)
+
+		synced, err := svc.SyncWallet(context.Background(), w.Address)
+		require.NoError(t, err)
+		assert.False(t, synced.IsDeployed)
+		assert.Equal(t, uint64(0), synced.Nonce)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := svc.SyncWallet(context.Background(), common.HexToAddress("0xdead"))
+		assert.EqualError(t, err, "wallet not found")
+	})
+
+	t.Run("reader error leaves wallet unchanged", func(t *testing.T) {
+		cfg := testWalletConfig()
+		cfg.ChainReader = failingReader{}
+		svc := NewWalletService(cfg)
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner})
+		require.NoError(t, err)
+
+		_, err = svc.SyncWallet(context.Background(), w.Address)
+		assert.EqualError(t, err, "rpc down")
+		assert.False(t, w.IsDeployed)
+	})
+}
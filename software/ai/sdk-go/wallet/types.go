@@ -12,18 +12,25 @@ type Wallet struct {
 	EntryPoint    common.Address
 	Factory       common.Address
 	Salt          *big.Int
+	InitCode      []byte
 	ChainID       *big.Int
 	Guardians     []Guardian
 	IsDeployed    bool
 	Nonce         uint64
+	Nonces        map[string]*big.Int
 }
 
 type WalletConfig struct {
-	EntryPoint    common.Address
-	Factory       common.Address
-	ChainID       *big.Int
-	BundlerURL    string
-	RPCURL        string
+	EntryPoint     common.Address
+	AccountFactory AccountFactory
+	ChainReader    ChainReader
+	ChainID        *big.Int
+	BundlerURL     string
+	RPCURL         string
+
+	// Deprecated: set AccountFactory. A non-zero Factory with no
+	// AccountFactory is used as SimpleAccountFactory{Factory: Factory}.
+	Factory common.Address
 }
 
 type CreateWalletParams struct {
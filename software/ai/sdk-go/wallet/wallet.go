@@ -1,25 +1,43 @@
 package wallet
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"sync"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
 )
 
 type WalletService struct {
-	config  WalletConfig
-	wallets map[common.Address]*Wallet
-	mu      sync.RWMutex
+	config WalletConfig
+	store  WalletStore
+	mu     sync.RWMutex
 }
 
-func NewWalletService(config WalletConfig) *WalletService {
-	return &WalletService{
-		config:  config,
-		wallets: make(map[common.Address]*Wallet),
+type WalletServiceOption func(*WalletService)
+
+func WithWalletStore(store WalletStore) WalletServiceOption {
+	return func(s *WalletService) {
+		s.store = store
+	}
+}
+
+func NewWalletService(config WalletConfig, opts ...WalletServiceOption) *WalletService {
+	s := &WalletService{
+		config: config,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		s.store = NewMemoryWalletStore()
 	}
+	return s
 }
 
 func (s *WalletService) CreateWallet(params CreateWalletParams) (*Wallet, error) {
@@ -31,7 +49,19 @@ func (s *WalletService) CreateWallet(params CreateWalletParams) (*Wallet, error)
 		salt = big.NewInt(0)
 	}
 
-	address := computeCounterfactualAddress(params.Owner, params.Config.Factory, salt)
+	factory := s.accountFactory(params.Config)
+	address, err := factory.AccountAddress(params.Owner, salt)
+	if simple, ok := factory.(SimpleAccountFactory); ok && errors.Is(err, ErrProxyCreationCodeRequired) && s.config.ChainReader != nil {
+		address, err = s.simpleAccountAddress(context.Background(), simple, params.Owner, salt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	initCode, err := factory.InitCode(params.Owner, salt)
+	if err != nil {
+		return nil, err
+	}
 
 	guardians := make([]Guardian, len(params.Guardians))
 	for i, g := range params.Guardians {
@@ -46,44 +76,64 @@ func (s *WalletService) CreateWallet(params CreateWalletParams) (*Wallet, error)
 		Address:    address,
 		Owner:      params.Owner,
 		EntryPoint: params.Config.EntryPoint,
-		Factory:    params.Config.Factory,
+		Factory:    factory.FactoryAddress(),
 		Salt:       salt,
+		InitCode:   initCode,
 		ChainID:    params.Config.ChainID,
 		Guardians:  guardians,
 		IsDeployed: false,
 		Nonce:      0,
 	}
 
-	s.wallets[address] = w
+	if err := s.store.Put(w); err != nil {
+		return nil, err
+	}
 	return w, nil
 }
 
+func (s *WalletService) accountFactory(config WalletConfig) AccountFactory {
+	switch {
+	case config.AccountFactory != nil:
+		return config.AccountFactory
+	case config.Factory != (common.Address{}):
+		return SimpleAccountFactory{Factory: config.Factory}
+	case s.config.AccountFactory != nil:
+		return s.config.AccountFactory
+	case s.config.Factory != (common.Address{}):
+		return SimpleAccountFactory{Factory: s.config.Factory}
+	}
+	return SimpleAccountFactory{Factory: DefaultSimpleAccountFactory}
+}
+
+func (s *WalletService) simpleAccountAddress(ctx context.Context, f SimpleAccountFactory, owner common.Address, salt *big.Int) (common.Address, error) {
+	data, err := encoding.EncodeFunctionCall("getAddress(address,uint256)", owner, saltOrZero(salt))
+	if err != nil {
+		return common.Address{}, err
+	}
+	out, err := s.config.ChainReader.CallContract(ctx, ethereum.CallMsg{To: &f.Factory, Data: data}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) != 32 {
+		return common.Address{}, errors.New("invalid getAddress response")
+	}
+	return common.BytesToAddress(out), nil
+}
+
 func (s *WalletService) GetWallet(address common.Address) (*Wallet, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	w, ok := s.wallets[address]
+	w, ok, err := s.store.Get(address)
+	if err != nil {
+		return nil, false
+	}
 	return w, ok
 }
 
-func (s *WalletService) ListWallets() []*Wallet {
+func (s *WalletService) ListWallets() ([]*Wallet, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	result := make([]*Wallet, 0, len(s.wallets))
-	for _, w := range s.wallets {
-		result = append(result, w)
-	}
-	return result
-}
-
-func computeCounterfactualAddress(owner common.Address, factory common.Address, salt *big.Int) common.Address {
-	initCodeHash := crypto.Keccak256(owner.Bytes())
-	data := make([]byte, 0, 85)
-	data = append(data, 0xff)
-	data = append(data, factory.Bytes()...)
-	data = append(data, common.LeftPadBytes(salt.Bytes(), 32)...)
-	data = append(data, initCodeHash...)
-	hash := crypto.Keccak256(data)
-	return common.BytesToAddress(hash[12:])
+	return s.store.List()
 }
 
 func AddressFromPrivateKey(key *ecdsa.PrivateKey) common.Address {
@@ -1,23 +1,27 @@
 package wallet
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"sync"
 	"testing"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/encoding"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func testWalletConfig() WalletConfig {
 	return WalletConfig{
-		EntryPoint: common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
-		Factory:    common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678"),
-		ChainID:    big.NewInt(8453),
-		BundlerURL: "https://bundler.base.org",
-		RPCURL:     "https://mainnet.base.org",
+		EntryPoint:     common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"),
+		AccountFactory: testKernelFactory(),
+		ChainID:        big.NewInt(8453),
+		BundlerURL:     "https://bundler.base.org",
+		RPCURL:         "https://mainnet.base.org",
 	}
 }
 
@@ -26,9 +30,11 @@ func TestNewWalletService(t *testing.T) {
 	svc := NewWalletService(cfg)
 
 	assert.NotNil(t, svc)
-	assert.NotNil(t, svc.wallets)
+	assert.IsType(t, &MemoryWalletStore{}, svc.store)
 	assert.Equal(t, cfg, svc.config)
-	assert.Empty(t, svc.wallets)
+	wallets, err := svc.ListWallets()
+	require.NoError(t, err)
+	assert.Empty(t, wallets)
 }
 
 func TestCreateWallet(t *testing.T) {
@@ -97,7 +103,7 @@ func TestCreateWallet(t *testing.T) {
 			assert.Equal(t, tc.params.Owner, w.Owner)
 			assert.Equal(t, tc.checkSalt, w.Salt)
 			assert.Equal(t, cfg.EntryPoint, w.EntryPoint)
-			assert.Equal(t, cfg.Factory, w.Factory)
+			assert.Equal(t, cfg.AccountFactory.FactoryAddress(), w.Factory)
 			assert.False(t, w.IsDeployed)
 			assert.Equal(t, uint64(0), w.Nonce)
 			assert.Len(t, w.Guardians, len(tc.params.Guardians))
@@ -108,9 +114,14 @@ func TestCreateWallet(t *testing.T) {
 				assert.Equal(t, uint8(1), g.Threshold)
 			}
 
-			expectedAddr := computeCounterfactualAddress(tc.params.Owner, cfg.Factory, tc.checkSalt)
+			expectedAddr, err := cfg.AccountFactory.AccountAddress(tc.params.Owner, tc.checkSalt)
+			require.NoError(t, err)
 			assert.Equal(t, expectedAddr, w.Address)
 
+			expectedInitCode, err := cfg.AccountFactory.InitCode(tc.params.Owner, tc.checkSalt)
+			require.NoError(t, err)
+			assert.Equal(t, expectedInitCode, w.InitCode)
+
 			stored, ok := svc.GetWallet(w.Address)
 			assert.True(t, ok)
 			assert.Equal(t, w, stored)
@@ -118,6 +129,65 @@ func TestCreateWallet(t *testing.T) {
 	}
 }
 
+func TestCreateWalletFactoryErrors(t *testing.T) {
+	owner := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+
+	t.Run("falls back to service factory", func(t *testing.T) {
+		cfg := testWalletConfig()
+		svc := NewWalletService(cfg)
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner})
+		require.NoError(t, err)
+
+		expected, err := cfg.AccountFactory.AccountAddress(owner, big.NewInt(0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, w.Address)
+	})
+
+	t.Run("defaults to SimpleAccount", func(t *testing.T) {
+		reader := &getAddressReader{account: common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc")}
+		svc := NewWalletService(WalletConfig{ChainReader: reader})
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner, Salt: big.NewInt(7)})
+		require.NoError(t, err)
+
+		assert.Equal(t, reader.account, w.Address)
+		assert.Equal(t, DefaultSimpleAccountFactory, w.Factory)
+		assert.Equal(t, DefaultSimpleAccountFactory, *reader.call.To)
+		expectedCall, err := encoding.EncodeFunctionCall("getAddress(address,uint256)", owner, big.NewInt(7))
+		require.NoError(t, err)
+		assert.Equal(t, expectedCall, reader.call.Data)
+		expectedInitCode, err := SimpleAccountFactory{Factory: DefaultSimpleAccountFactory}.InitCode(owner, big.NewInt(7))
+		require.NoError(t, err)
+		assert.Equal(t, expectedInitCode, w.InitCode)
+	})
+
+	t.Run("deprecated factory address", func(t *testing.T) {
+		legacy := common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
+		reader := &getAddressReader{account: common.HexToAddress("0xdddddddddddddddddddddddddddddddddddddddd")}
+		svc := NewWalletService(WalletConfig{Factory: legacy, ChainReader: reader})
+		w, err := svc.CreateWallet(CreateWalletParams{Owner: owner})
+		require.NoError(t, err)
+		assert.Equal(t, reader.account, w.Address)
+		assert.Equal(t, legacy, w.Factory)
+		assert.Equal(t, legacy, *reader.call.To)
+	})
+
+	t.Run("default factory without a chain reader", func(t *testing.T) {
+		svc := NewWalletService(WalletConfig{})
+		_, err := svc.CreateWallet(CreateWalletParams{Owner: owner})
+		assert.ErrorIs(t, err, ErrProxyCreationCodeRequired)
+		wallets, err := svc.ListWallets()
+		require.NoError(t, err)
+		assert.Empty(t, wallets)
+	})
+
+	t.Run("factory error", func(t *testing.T) {
+		svc := NewWalletService(WalletConfig{AccountFactory: SimpleAccountFactory{}})
+		_, err := svc.CreateWallet(CreateWalletParams{Owner: owner})
+		require.Error(t, err)
+		assert.Equal(t, "proxy creation code required", err.Error())
+	})
+}
+
 func TestGetWallet(t *testing.T) {
 	cfg := testWalletConfig()
 	svc := NewWalletService(cfg)
@@ -147,7 +217,8 @@ func TestListWallets(t *testing.T) {
 
 	t.Run("empty service", func(t *testing.T) {
 		svc := NewWalletService(cfg)
-		result := svc.ListWallets()
+		result, err := svc.ListWallets()
+		require.NoError(t, err)
 		assert.Empty(t, result)
 	})
 
@@ -164,40 +235,24 @@ func TestListWallets(t *testing.T) {
 			require.NoError(t, err)
 		}
 
-		result := svc.ListWallets()
+		result, err := svc.ListWallets()
+		require.NoError(t, err)
 		assert.Len(t, result, 5)
 	})
-}
-
-func TestComputeCounterfactualAddress(t *testing.T) {
-	owner := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
-	factory := common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
-
-	t.Run("deterministic", func(t *testing.T) {
-		addr1 := computeCounterfactualAddress(owner, factory, big.NewInt(0))
-		addr2 := computeCounterfactualAddress(owner, factory, big.NewInt(0))
-		assert.Equal(t, addr1, addr2)
-	})
 
-	t.Run("different salts produce different addresses", func(t *testing.T) {
-		addr1 := computeCounterfactualAddress(owner, factory, big.NewInt(0))
-		addr2 := computeCounterfactualAddress(owner, factory, big.NewInt(1))
-		assert.NotEqual(t, addr1, addr2)
+	t.Run("store error", func(t *testing.T) {
+		svc := NewWalletService(cfg, WithWalletStore(failingWalletStore{NewMemoryWalletStore()}))
+		_, err := svc.ListWallets()
+		assert.EqualError(t, err, "store unavailable")
 	})
+}
 
-	t.Run("different owners produce different addresses", func(t *testing.T) {
-		owner2 := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
-		addr1 := computeCounterfactualAddress(owner, factory, big.NewInt(0))
-		addr2 := computeCounterfactualAddress(owner2, factory, big.NewInt(0))
-		assert.NotEqual(t, addr1, addr2)
-	})
+type failingWalletStore struct {
+	*MemoryWalletStore
+}
 
-	t.Run("different factories produce different addresses", func(t *testing.T) {
-		factory2 := common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")
-		addr1 := computeCounterfactualAddress(owner, factory, big.NewInt(0))
-		addr2 := computeCounterfactualAddress(owner, factory2, big.NewInt(0))
-		assert.NotEqual(t, addr1, addr2)
-	})
+func (failingWalletStore) List() ([]*Wallet, error) {
+	return nil, errors.New("store unavailable")
 }
 
 func TestAddressFromPrivateKey(t *testing.T) {
@@ -231,10 +286,26 @@ func TestWalletServiceConcurrency(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_ = svc.ListWallets()
+			_, _ = svc.ListWallets()
 		}()
 	}
 
 	wg.Wait()
-	assert.Len(t, svc.ListWallets(), 50)
+	result, err := svc.ListWallets()
+	require.NoError(t, err)
+	assert.Len(t, result, 50)
+}
+
+type getAddressReader struct {
+	account common.Address
+	call    ethereum.CallMsg
+}
+
+func (r *getAddressReader) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *getAddressReader) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	r.call = call
+	return common.LeftPadBytes(r.account.Bytes(), 32), nil
 }
@@ -1,22 +1,50 @@
 package x402
 
 import (
+	"context"
 	"errors"
 	"math/big"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/oracle"
+)
+
+type BudgetWindow string
+
+const (
+	BudgetFixedWindow         BudgetWindow = "fixed_window"
+	BudgetSlidingWindow       BudgetWindow = "sliding_window"
+	BudgetCalendarWindow      BudgetWindow = "calendar"
+	BudgetCalendarMonthWindow BudgetWindow = "calendar_month"
 )
 
 type BudgetTracker struct {
 	state  BudgetState
 	policy X402Policy
+	oracle oracle.PriceOracle
+	now    func() time.Time
+	held   *big.Int
 	mu     sync.Mutex
 }
 
-func NewBudgetTracker(policy X402Policy, periodDuration uint64) *BudgetTracker {
-	return &BudgetTracker{
+type BudgetReservation struct {
+	tracker *BudgetTracker
+	record  PaymentRecord
+	settled bool
+}
+
+type BudgetOption func(*BudgetTracker)
+
+func WithPriceOracle(o oracle.PriceOracle) BudgetOption {
+	return func(bt *BudgetTracker) {
+		bt.oracle = o
+	}
+}
+
+func NewBudgetTracker(policy X402Policy, periodDuration uint64, opts ...BudgetOption) *BudgetTracker {
+	bt := &BudgetTracker{
 		state: BudgetState{
 			TotalSpent:     big.NewInt(0),
 			PeriodSpent:    big.NewInt(0),
@@ -25,54 +53,108 @@ func NewBudgetTracker(policy X402Policy, periodDuration uint64) *BudgetTracker {
 			Records:        make([]PaymentRecord, 0),
 		},
 		policy: policy,
+		now:    time.Now,
+		held:   big.NewInt(0),
+	}
+	for _, opt := range opts {
+		opt(bt)
 	}
+	bt.resetPeriodIfNeeded()
+	return bt
 }
 
 func (bt *BudgetTracker) Track(record PaymentRecord) error {
+	value, err := bt.value(record.Amount)
+	if err != nil {
+		return err
+	}
+
 	bt.mu.Lock()
 	defer bt.mu.Unlock()
 
 	bt.resetPeriodIfNeeded()
 
-	if bt.policy.AllowedPayees != nil && len(bt.policy.AllowedPayees) > 0 {
-		if !bt.policy.AllowedPayees[record.PayTo] {
-			return errors.New("payee not in allowlist")
-		}
+	if err := bt.check(value, record.PayTo); err != nil {
+		return err
 	}
 
-	if bt.policy.MaxPerRequest != nil && record.Amount.Cmp(bt.policy.MaxPerRequest) > 0 {
-		return errors.New("amount exceeds per-request limit")
-	}
+	record.Value = value
+	bt.record(record)
+	return nil
+}
 
-	newPeriodTotal := new(big.Int).Add(bt.state.PeriodSpent, record.Amount)
-	if bt.policy.MaxPerPeriod != nil && newPeriodTotal.Cmp(bt.policy.MaxPerPeriod) > 0 {
-		return errors.New("amount exceeds period budget")
+func (bt *BudgetTracker) Check(amount *big.Int, payTo common.Address) error {
+	value, err := bt.value(amount)
+	if err != nil {
+		return err
 	}
 
-	bt.state.TotalSpent = new(big.Int).Add(bt.state.TotalSpent, record.Amount)
-	bt.state.PeriodSpent = newPeriodTotal
-	bt.state.Records = append(bt.state.Records, record)
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
 
-	return nil
+	bt.resetPeriodIfNeeded()
+
+	return bt.check(value, payTo)
 }
 
-func (bt *BudgetTracker) Check(amount *big.Int, payTo common.Address) error {
+func (bt *BudgetTracker) Reserve(record PaymentRecord) (*BudgetReservation, error) {
+	value, err := bt.value(record.Amount)
+	if err != nil {
+		return nil, err
+	}
+
 	bt.mu.Lock()
 	defer bt.mu.Unlock()
 
 	bt.resetPeriodIfNeeded()
 
+	if err := bt.check(value, record.PayTo); err != nil {
+		return nil, err
+	}
+
+	record.Value = value
+	bt.held = new(big.Int).Add(bt.held, value)
+	return &BudgetReservation{tracker: bt, record: record}, nil
+}
+
+func (r *BudgetReservation) Commit() error {
+	return r.settle(true)
+}
+
+func (r *BudgetReservation) Release() error {
+	return r.settle(false)
+}
+
+func (r *BudgetReservation) settle(commit bool) error {
+	bt := r.tracker
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	if r.settled {
+		return errors.New("reservation already settled")
+	}
+	r.settled = true
+	bt.held = new(big.Int).Sub(bt.held, r.record.Value)
+	if commit {
+		bt.resetPeriodIfNeeded()
+		bt.record(r.record)
+	}
+	return nil
+}
+
+func (bt *BudgetTracker) check(value *big.Int, payTo common.Address) error {
 	if bt.policy.AllowedPayees != nil && len(bt.policy.AllowedPayees) > 0 {
 		if !bt.policy.AllowedPayees[payTo] {
 			return errors.New("payee not in allowlist")
 		}
 	}
 
-	if bt.policy.MaxPerRequest != nil && amount.Cmp(bt.policy.MaxPerRequest) > 0 {
+	if bt.policy.MaxPerRequest != nil && value.Cmp(bt.policy.MaxPerRequest) > 0 {
 		return errors.New("amount exceeds per-request limit")
 	}
 
-	newPeriodTotal := new(big.Int).Add(bt.state.PeriodSpent, amount)
+	newPeriodTotal := new(big.Int).Add(bt.state.PeriodSpent, bt.held)
+	newPeriodTotal.Add(newPeriodTotal, value)
 	if bt.policy.MaxPerPeriod != nil && newPeriodTotal.Cmp(bt.policy.MaxPerPeriod) > 0 {
 		return errors.New("amount exceeds period budget")
 	}
@@ -80,6 +162,15 @@ func (bt *BudgetTracker) Check(amount *big.Int, payTo common.Address) error {
 	return nil
 }
 
+func (bt *BudgetTracker) record(record PaymentRecord) {
+	if record.Timestamp == 0 {
+		record.Timestamp = uint64(bt.now().Unix())
+	}
+	bt.state.TotalSpent = new(big.Int).Add(bt.state.TotalSpent, record.Amount)
+	bt.state.PeriodSpent = new(big.Int).Add(bt.state.PeriodSpent, record.Value)
+	bt.state.Records = append(bt.state.Records, record)
+}
+
 func (bt *BudgetTracker) Remaining() *big.Int {
 	bt.mu.Lock()
 	defer bt.mu.Unlock()
@@ -111,9 +202,77 @@ func (bt *BudgetTracker) IsExhausted() bool {
 }
 
 func (bt *BudgetTracker) resetPeriodIfNeeded() {
-	now := uint64(time.Now().Unix())
-	if bt.state.PeriodDuration > 0 && now >= bt.state.PeriodStart+bt.state.PeriodDuration {
-		bt.state.PeriodSpent = big.NewInt(0)
-		bt.state.PeriodStart = now
+	now := uint64(bt.now().Unix())
+	if bt.policy.Window == BudgetCalendarMonthWindow {
+		if start := calendarMonthStart(now); start != bt.state.PeriodStart {
+			bt.state.PeriodSpent = big.NewInt(0)
+			bt.state.PeriodStart = start
+		}
+		return
+	}
+
+	duration := bt.state.PeriodDuration
+	if duration == 0 {
+		return
 	}
+
+	switch bt.policy.Window {
+	case BudgetSlidingWindow:
+		var start uint64
+		if now > duration {
+			start = now - duration
+		}
+		spent := big.NewInt(0)
+		for _, r := range bt.state.Records {
+			if r.Timestamp > start {
+				spent.Add(spent, recordValue(r))
+			}
+		}
+		bt.state.PeriodStart = start
+		bt.state.PeriodSpent = spent
+	case BudgetCalendarWindow:
+		if start := now / duration * duration; start != bt.state.PeriodStart {
+			bt.state.PeriodSpent = big.NewInt(0)
+			bt.state.PeriodStart = start
+		}
+	default:
+		if now >= bt.state.PeriodStart+duration {
+			bt.state.PeriodSpent = big.NewInt(0)
+			bt.state.PeriodStart += (now - bt.state.PeriodStart) / duration * duration
+		}
+	}
+}
+
+func (bt *BudgetTracker) value(amount *big.Int) (*big.Int, error) {
+	return quoteValue(context.Background(), bt.oracle, &bt.policy, amount)
+}
+
+func quoteValue(ctx context.Context, o oracle.PriceOracle, policy *X402Policy, amount *big.Int) (*big.Int, error) {
+	if policy.Quote == "" {
+		return amount, nil
+	}
+	if o == nil {
+		return nil, errors.New("price oracle not configured")
+	}
+	asset := policy.Asset
+	if asset == (common.Address{}) {
+		asset = PaymentToken
+	}
+	p, err := o.Price(ctx, asset, policy.Quote)
+	if err != nil {
+		return nil, err
+	}
+	return p.Value(amount), nil
+}
+
+func recordValue(r PaymentRecord) *big.Int {
+	if r.Value != nil {
+		return r.Value
+	}
+	return r.Amount
+}
+
+func calendarMonthStart(now uint64) uint64 {
+	y, m, _ := time.Unix(int64(now), 0).UTC().Date()
+	return uint64(time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Unix())
 }
@@ -3,8 +3,10 @@ package x402
 import (
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/sigloop/sdk-go/oracle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -170,6 +172,50 @@ func TestBudgetTrackerCheck(t *testing.T) {
 	})
 }
 
+func TestBudgetTrackerReserve(t *testing.T) {
+	payee := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	t.Run("holds count against the period", func(t *testing.T) {
+		bt := testBudgetTracker(100, 150, nil)
+		r, err := bt.Reserve(PaymentRecord{Amount: big.NewInt(100), PayTo: payee})
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(0), bt.state.PeriodSpent)
+
+		_, err = bt.Reserve(PaymentRecord{Amount: big.NewInt(60), PayTo: payee})
+		assert.EqualError(t, err, "amount exceeds period budget")
+		assert.EqualError(t, bt.Check(big.NewInt(60), payee), "amount exceeds period budget")
+
+		require.NoError(t, r.Release())
+		require.NoError(t, bt.Check(big.NewInt(60), payee))
+		assert.EqualError(t, r.Commit(), "reservation already settled")
+	})
+
+	t.Run("commit records the payment", func(t *testing.T) {
+		bt := testBudgetTracker(100, 150, nil)
+		r, err := bt.Reserve(PaymentRecord{Resource: "https://api.example.com", Amount: big.NewInt(100), PayTo: payee})
+		require.NoError(t, err)
+		require.NoError(t, r.Commit())
+
+		assert.Equal(t, big.NewInt(100), bt.state.PeriodSpent)
+		assert.Equal(t, big.NewInt(50), bt.Remaining())
+		require.Len(t, bt.state.Records, 1)
+		assert.Equal(t, "https://api.example.com", bt.state.Records[0].Resource)
+		assert.NotZero(t, bt.state.Records[0].Timestamp)
+		assert.EqualError(t, r.Release(), "reservation already settled")
+	})
+
+	t.Run("commit does not recheck the limits", func(t *testing.T) {
+		bt := testBudgetTracker(100, 150, nil)
+		r, err := bt.Reserve(PaymentRecord{Amount: big.NewInt(100), PayTo: payee})
+		require.NoError(t, err)
+		bt.policy.MaxPerPeriod = big.NewInt(50)
+
+		require.NoError(t, r.Commit())
+		assert.Equal(t, big.NewInt(100), bt.state.PeriodSpent)
+		assert.True(t, bt.IsExhausted())
+	})
+}
+
 func TestBudgetTrackerRemaining(t *testing.T) {
 	t.Run("no spending", func(t *testing.T) {
 		bt := testBudgetTracker(100, 1000, nil)
@@ -228,3 +274,103 @@ func TestBudgetTrackerIsExhausted(t *testing.T) {
 		assert.False(t, bt.IsExhausted())
 	})
 }
+
+func TestBudgetTrackerWindows(t *testing.T) {
+	payee := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	start := time.Date(2026, 10, 14, 22, 0, 0, 0, time.UTC)
+	newTracker := func(window BudgetWindow, duration uint64) (*BudgetTracker, *time.Time) {
+		now := start
+		bt := NewBudgetTracker(X402Policy{MaxPerPeriod: big.NewInt(100), Window: window}, duration)
+		bt.now = func() time.Time { return now }
+		bt.state.PeriodStart = uint64(now.Unix())
+		bt.resetPeriodIfNeeded()
+		return bt, &now
+	}
+	pay := func(bt *BudgetTracker, amount int64) error {
+		return bt.Track(PaymentRecord{Amount: big.NewInt(amount), PayTo: payee})
+	}
+
+	t.Run("fixed window keeps its anchor", func(t *testing.T) {
+		bt, now := newTracker(BudgetFixedWindow, 3600)
+		require.NoError(t, pay(bt, 100))
+		*now = now.Add(150 * time.Minute)
+		assert.Equal(t, big.NewInt(100), bt.Remaining())
+		assert.Equal(t, uint64(start.Add(2*time.Hour).Unix()), bt.state.PeriodStart)
+	})
+
+	t.Run("calendar window resets at midnight UTC", func(t *testing.T) {
+		bt, now := newTracker(BudgetCalendarWindow, 24*60*60)
+		assert.Equal(t, uint64(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC).Unix()), bt.state.PeriodStart)
+		require.NoError(t, pay(bt, 100))
+		*now = now.Add(time.Hour + 59*time.Minute)
+		assert.True(t, bt.IsExhausted())
+		*now = now.Add(time.Minute)
+		assert.False(t, bt.IsExhausted())
+	})
+
+	t.Run("calendar month", func(t *testing.T) {
+		bt, now := newTracker(BudgetCalendarMonthWindow, 0)
+		assert.Equal(t, uint64(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC).Unix()), bt.state.PeriodStart)
+		require.NoError(t, pay(bt, 100))
+		*now = time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC)
+		assert.True(t, bt.IsExhausted())
+		*now = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, big.NewInt(100), bt.Remaining())
+	})
+
+	t.Run("calendar window of 30 days is not a month", func(t *testing.T) {
+		bt, _ := newTracker(BudgetCalendarWindow, 30*24*60*60)
+		assert.Equal(t, uint64(time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC).Unix()), bt.state.PeriodStart)
+	})
+
+	t.Run("sliding window counts the last period of payments", func(t *testing.T) {
+		bt, now := newTracker(BudgetSlidingWindow, 24*60*60)
+		require.NoError(t, pay(bt, 60))
+		*now = now.Add(12 * time.Hour)
+		require.NoError(t, pay(bt, 40))
+		*now = now.Add(11 * time.Hour)
+		assert.EqualError(t, bt.Check(big.NewInt(1), payee), "amount exceeds period budget")
+
+		*now = now.Add(time.Hour)
+		assert.Equal(t, big.NewInt(60), bt.Remaining())
+		assert.Equal(t, uint64(start.Add(12*time.Hour).Unix()), bt.state.Records[1].Timestamp)
+		assert.Equal(t, big.NewInt(100), bt.state.TotalSpent)
+	})
+}
+
+func TestBudgetTrackerQuote(t *testing.T) {
+	payee := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	policy := X402Policy{
+		MaxPerRequest: big.NewInt(200_000_000),
+		MaxPerPeriod:  big.NewInt(300_000_000),
+		Quote:         "USD",
+	}
+
+	t.Run("budget in quote currency", func(t *testing.T) {
+		o := oracle.NewStaticOracle()
+		require.NoError(t, o.Set(PaymentToken, "USD", 6, "0.5"))
+		bt := NewBudgetTracker(policy, 3600, WithPriceOracle(o))
+
+		require.NoError(t, bt.Check(big.NewInt(4_000_000), payee))
+		assert.EqualError(t, bt.Check(big.NewInt(4_000_001), payee), "amount exceeds per-request limit")
+
+		require.NoError(t, bt.Track(PaymentRecord{Amount: big.NewInt(4_000_000), PayTo: payee}))
+		assert.Equal(t, big.NewInt(200_000_000), bt.state.Records[0].Value)
+		assert.Equal(t, big.NewInt(4_000_000), bt.state.TotalSpent)
+		assert.Equal(t, big.NewInt(100_000_000), bt.Remaining())
+
+		require.NoError(t, o.Set(PaymentToken, "USD", 6, "1"))
+		assert.NoError(t, bt.Check(big.NewInt(1_000_000), payee))
+		assert.EqualError(t, bt.Check(big.NewInt(1_000_001), payee), "amount exceeds period budget")
+	})
+
+	t.Run("price errors reject payments", func(t *testing.T) {
+		bt := NewBudgetTracker(policy, 3600)
+		assert.EqualError(t, bt.Check(big.NewInt(1), payee), "price oracle not configured")
+
+		bt = NewBudgetTracker(policy, 3600, WithPriceOracle(oracle.NewStaticOracle()))
+		err := bt.Track(PaymentRecord{Amount: big.NewInt(1), PayTo: payee})
+		assert.ErrorIs(t, err, oracle.ErrNoFeed)
+		assert.Empty(t, bt.state.Records)
+	})
+}
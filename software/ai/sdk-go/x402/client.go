@@ -1,13 +1,14 @@
 package x402
 
 import (
-	"crypto/ecdsa"
 	"math/big"
 	"net/http"
+
+	"github.com/sigloop/sdk-go/signer"
 )
 
 func NewX402Client(
-	privateKey *ecdsa.PrivateKey,
+	s signer.Signer,
 	chainID *big.Int,
 	budget *BudgetTracker,
 	policy *X402Policy,
@@ -15,7 +16,7 @@ func NewX402Client(
 ) *http.Client {
 	transport := NewX402Transport(
 		http.DefaultTransport,
-		privateKey,
+		s,
 		chainID,
 		budget,
 		policy,
@@ -1,28 +1,38 @@
 package x402
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"io"
 	"math/big"
 	"net/http"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/oracle"
+	"github.com/sigloop/sdk-go/signer"
 )
 
+type PaymentReservation interface {
+	Commit() error
+	Release() error
+}
+
+type PaymentAuthorizer func(ctx context.Context, req *http.Request, payment *PaymentRequirement, amount *big.Int) (PaymentReservation, error)
+
 type X402Transport struct {
-	Base       http.RoundTripper
-	PrivateKey *ecdsa.PrivateKey
-	From       common.Address
-	ChainID    *big.Int
-	Budget     *BudgetTracker
-	Policy     *X402Policy
-	Config     X402Config
+	Base      http.RoundTripper
+	Signer    signer.Signer
+	From      common.Address
+	ChainID   *big.Int
+	Budget    *BudgetTracker
+	Policy    *X402Policy
+	Oracle    oracle.PriceOracle
+	Config    X402Config
+	Authorize PaymentAuthorizer
 }
 
 func NewX402Transport(
 	base http.RoundTripper,
-	privateKey *ecdsa.PrivateKey,
+	s signer.Signer,
 	chainID *big.Int,
 	budget *BudgetTracker,
 	policy *X402Policy,
@@ -31,15 +41,23 @@ func NewX402Transport(
 	if base == nil {
 		base = http.DefaultTransport
 	}
-	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	var from common.Address
+	if s != nil {
+		from = s.Address()
+	}
+	var o oracle.PriceOracle
+	if budget != nil {
+		o = budget.oracle
+	}
 	return &X402Transport{
-		Base:       base,
-		PrivateKey: privateKey,
-		From:       from,
-		ChainID:    chainID,
-		Budget:     budget,
-		Policy:     policy,
-		Config:     config,
+		Base:    base,
+		Signer:  s,
+		From:    from,
+		ChainID: chainID,
+		Budget:  budget,
+		Policy:  policy,
+		Oracle:  o,
+		Config:  config,
 	}
 }
 
@@ -83,8 +101,11 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 
 	if t.Policy != nil {
-		if t.Policy.MaxPerRequest != nil && amount.Cmp(t.Policy.MaxPerRequest) > 0 {
-			return resp, nil
+		if t.Policy.MaxPerRequest != nil {
+			value, err := quoteValue(req.Context(), t.Oracle, t.Policy, amount)
+			if err != nil || value.Cmp(t.Policy.MaxPerRequest) > 0 {
+				return resp, nil
+			}
 		}
 		if t.Policy.AllowedPayees != nil && len(t.Policy.AllowedPayees) > 0 {
 			if !t.Policy.AllowedPayees[payReq.PayTo] {
@@ -98,15 +119,71 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 
+	var reservations []PaymentReservation
 	if t.Budget != nil {
-		if err := t.Budget.Check(amount, payReq.PayTo); err != nil {
+		held, err := t.Budget.Reserve(PaymentRecord{
+			Resource: req.URL.String(),
+			Amount:   amount,
+			PayTo:    payReq.PayTo,
+			Network:  payReq.Network,
+		})
+		if err != nil {
+			return resp, nil
+		}
+		reservations = append(reservations, held)
+	}
+
+	if t.Authorize != nil {
+		reservation, err := t.Authorize(req.Context(), req, payReq, amount)
+		if err != nil {
+			if err := release(reservations); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+		if reservation != nil {
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	retryResp, err := t.pay(req, payReq)
+	if err != nil || retryResp.StatusCode < 200 || retryResp.StatusCode >= 300 {
+		if err := release(reservations); err != nil {
+			if retryResp != nil {
+				retryResp.Body.Close()
+			}
+			return nil, err
+		}
+		if err != nil {
 			return resp, nil
 		}
+		return retryResp, nil
+	}
+
+	for _, r := range reservations {
+		if err := r.Commit(); err != nil {
+			retryResp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return retryResp, nil
+}
+
+func release(reservations []PaymentReservation) error {
+	var first error
+	for _, r := range reservations {
+		if err := r.Release(); err != nil && first == nil {
+			first = err
+		}
 	}
+	return first
+}
 
-	paymentHeader, err := BuildPaymentHeader(t.PrivateKey, payReq, t.From, t.ChainID)
+func (t *X402Transport) pay(req *http.Request, payReq *PaymentRequirement) (*http.Response, error) {
+	paymentHeader, err := BuildPaymentHeader(req.Context(), t.Signer, payReq, t.ChainID)
 	if err != nil {
-		return resp, nil
+		return nil, err
 	}
 
 	retryReq := req.Clone(req.Context())
@@ -116,29 +193,13 @@ func (t *X402Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		if req.GetBody != nil {
 			newBody, err := req.GetBody()
 			if err != nil {
-				return resp, nil
+				return nil, err
 			}
 			retryReq.Body = newBody
 		}
 	}
 
-	retryResp, err := t.Base.RoundTrip(retryReq)
-	if err != nil {
-		return resp, nil
-	}
-
-	if retryResp.StatusCode >= 200 && retryResp.StatusCode < 300 && t.Budget != nil {
-		record := PaymentRecord{
-			Resource:  req.URL.String(),
-			Amount:    amount,
-			PayTo:     payReq.PayTo,
-			Timestamp: uint64(0),
-			Network:   payReq.Network,
-		}
-		t.Budget.Track(record)
-	}
-
-	return retryResp, nil
+	return t.Base.RoundTrip(retryReq)
 }
 
 func (t *X402Transport) selectRequirement(requirements []PaymentRequirement) *PaymentRequirement {
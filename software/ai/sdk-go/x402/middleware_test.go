@@ -1,10 +1,20 @@
 package x402
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/oracle"
+	"github.com/sigloop/sdk-go/signer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -12,10 +22,12 @@ import (
 func TestNewX402Transport(t *testing.T) {
 	privateKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
 	chainID := big.NewInt(8453)
 
 	t.Run("with base transport", func(t *testing.T) {
-		transport := NewX402Transport(nil, privateKey, chainID, nil, nil, X402Config{})
+		transport := NewX402Transport(nil, s, chainID, nil, nil, X402Config{})
 		assert.NotNil(t, transport)
 		assert.NotNil(t, transport.Base)
 		assert.Equal(t, chainID, transport.ChainID)
@@ -26,6 +38,8 @@ func TestNewX402Transport(t *testing.T) {
 func TestSelectRequirement(t *testing.T) {
 	privateKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
 
 	requirements := []PaymentRequirement{
 		{Scheme: "exact", Network: "base"},
@@ -34,14 +48,14 @@ func TestSelectRequirement(t *testing.T) {
 	}
 
 	t.Run("no scheme filter returns first", func(t *testing.T) {
-		transport := NewX402Transport(nil, privateKey, big.NewInt(1), nil, nil, X402Config{})
+		transport := NewX402Transport(nil, s, big.NewInt(1), nil, nil, X402Config{})
 		result := transport.selectRequirement(requirements)
 		require.NotNil(t, result)
 		assert.Equal(t, "base", result.Network)
 	})
 
 	t.Run("matching scheme", func(t *testing.T) {
-		transport := NewX402Transport(nil, privateKey, big.NewInt(1), nil, nil, X402Config{
+		transport := NewX402Transport(nil, s, big.NewInt(1), nil, nil, X402Config{
 			AllowedSchemes: []string{"upto"},
 		})
 		result := transport.selectRequirement(requirements)
@@ -51,7 +65,7 @@ func TestSelectRequirement(t *testing.T) {
 	})
 
 	t.Run("no matching scheme", func(t *testing.T) {
-		transport := NewX402Transport(nil, privateKey, big.NewInt(1), nil, nil, X402Config{
+		transport := NewX402Transport(nil, s, big.NewInt(1), nil, nil, X402Config{
 			AllowedSchemes: []string{"stream"},
 		})
 		result := transport.selectRequirement(requirements)
@@ -59,7 +73,7 @@ func TestSelectRequirement(t *testing.T) {
 	})
 
 	t.Run("first matching scheme when multiple match", func(t *testing.T) {
-		transport := NewX402Transport(nil, privateKey, big.NewInt(1), nil, nil, X402Config{
+		transport := NewX402Transport(nil, s, big.NewInt(1), nil, nil, X402Config{
 			AllowedSchemes: []string{"exact"},
 		})
 		result := transport.selectRequirement(requirements)
@@ -71,12 +85,14 @@ func TestSelectRequirement(t *testing.T) {
 func TestNewX402Client(t *testing.T) {
 	privateKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
 
 	policy := &X402Policy{MaxPerRequest: big.NewInt(100)}
 	budget := NewBudgetTracker(X402Policy{}, 3600)
 	config := X402Config{AutoPay: true}
 
-	client := NewX402Client(privateKey, big.NewInt(8453), budget, policy, config)
+	client := NewX402Client(s, big.NewInt(8453), budget, policy, config)
 	require.NotNil(t, client)
 	assert.NotNil(t, client.Transport)
 
@@ -85,3 +101,234 @@ func TestNewX402Client(t *testing.T) {
 	assert.Equal(t, big.NewInt(8453), transport.ChainID)
 	assert.True(t, transport.Config.AutoPay)
 }
+
+type fakeReservation struct {
+	committed, released int
+}
+
+func (r *fakeReservation) Commit() error {
+	r.committed++
+	return nil
+}
+
+func (r *fakeReservation) Release() error {
+	r.released++
+	return nil
+}
+
+func TestX402TransportAuthorize(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
+
+	payTo := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	paidStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			w.WriteHeader(paidStatus)
+			return
+		}
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode([]PaymentRequirement{{
+			Scheme:            "exact",
+			Network:           "base",
+			MaxAmountRequired: "1000",
+			PayTo:             payTo,
+			RequiredDeadline:  "9999999999",
+		}})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		authorize    error
+		paidStatus   int
+		wantStatus   int
+		wantErr      string
+		wantCommit   int
+		wantReleased int
+	}{
+		{"allowed", nil, http.StatusOK, http.StatusOK, "", 1, 0},
+		{"denied", errors.New("policy denied"), http.StatusOK, 0, "policy denied", 0, 0},
+		{"paid request fails", nil, http.StatusInternalServerError, http.StatusInternalServerError, "", 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paidStatus = tt.paidStatus
+			var gotPayee common.Address
+			var gotAmount *big.Int
+			reservation := &fakeReservation{}
+			transport := NewX402Transport(nil, s, big.NewInt(8453), nil, nil, X402Config{AutoPay: true})
+			transport.Authorize = func(ctx context.Context, req *http.Request, payment *PaymentRequirement, amount *big.Int) (PaymentReservation, error) {
+				gotPayee, gotAmount = payment.PayTo, amount
+				if tt.authorize != nil {
+					return nil, tt.authorize
+				}
+				return reservation, nil
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			assert.Equal(t, payTo, gotPayee)
+			assert.Equal(t, big.NewInt(1000), gotAmount)
+			assert.Equal(t, tt.wantCommit, reservation.committed)
+			assert.Equal(t, tt.wantReleased, reservation.released)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestX402TransportQuote(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode([]PaymentRequirement{{
+			Scheme:            "exact",
+			Network:           "base",
+			MaxAmountRequired: "4000000",
+			PayTo:             common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"),
+			RequiredDeadline:  "9999999999",
+		}})
+	}))
+	defer server.Close()
+
+	o := oracle.NewStaticOracle()
+	require.NoError(t, o.Set(PaymentToken, "USD", 6, "0.5"))
+
+	tests := []struct {
+		name          string
+		maxPerRequest int64
+		oracle        oracle.PriceOracle
+		wantStatus    int
+	}{
+		{"quoted amount within limit", 200_000_000, o, http.StatusOK},
+		{"quoted amount above limit", 199_999_999, o, http.StatusPaymentRequired},
+		{"no oracle", 400_000_000, nil, http.StatusPaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &X402Policy{MaxPerRequest: big.NewInt(tt.maxPerRequest), Quote: "USD"}
+			transport := NewX402Transport(nil, s, big.NewInt(8453), nil, policy, X402Config{AutoPay: true})
+			transport.Oracle = tt.oracle
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+
+	t.Run("oracle from budget", func(t *testing.T) {
+		policy := X402Policy{MaxPerRequest: big.NewInt(200_000_000), Quote: "USD"}
+		budget := NewBudgetTracker(policy, 3600, WithPriceOracle(o))
+		transport := NewX402Transport(nil, s, big.NewInt(8453), budget, &policy, X402Config{AutoPay: true})
+		assert.Equal(t, o, transport.Oracle)
+	})
+}
+
+func TestX402TransportBudget(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
+
+	payTo := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	paidStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PAYMENT") != "" {
+			w.WriteHeader(paidStatus)
+			return
+		}
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode([]PaymentRequirement{{
+			Scheme:            "exact",
+			Network:           "base",
+			MaxAmountRequired: "1000000",
+			PayTo:             payTo,
+			RequiredDeadline:  "9999999999",
+		}})
+	}))
+	defer server.Close()
+
+	t.Run("concurrent payments stay within the period budget", func(t *testing.T) {
+		budget := NewBudgetTracker(X402Policy{MaxPerPeriod: big.NewInt(5_000_000)}, 3600)
+		client := &http.Client{Transport: NewX402Transport(nil, s, big.NewInt(8453), budget, nil, X402Config{AutoPay: true})}
+
+		var wg sync.WaitGroup
+		var paid atomic.Int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					return
+				}
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					paid.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(5), paid.Load())
+		assert.Zero(t, budget.Remaining().Sign())
+	})
+
+	t.Run("failed payment releases the hold", func(t *testing.T) {
+		paidStatus = http.StatusInternalServerError
+		defer func() { paidStatus = http.StatusOK }()
+		budget := NewBudgetTracker(X402Policy{MaxPerPeriod: big.NewInt(1_000_000)}, 3600)
+		transport := NewX402Transport(nil, s, big.NewInt(8453), budget, nil, X402Config{AutoPay: true})
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.NoError(t, budget.Check(big.NewInt(1_000_000), payTo))
+	})
+
+	t.Run("payment is priced once before signing", func(t *testing.T) {
+		static := oracle.NewStaticOracle()
+		require.NoError(t, static.Set(PaymentToken, "USD", 6, "1"))
+		budget := NewBudgetTracker(X402Policy{MaxPerPeriod: big.NewInt(500_000_000), Quote: "USD"}, 3600, WithPriceOracle(&failingAfterOracle{PriceOracle: static, calls: 1}))
+		transport := NewX402Transport(nil, s, big.NewInt(8453), budget, nil, X402Config{AutoPay: true})
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, len(budget.state.Records))
+		assert.Equal(t, big.NewInt(1_000_000), budget.state.Records[0].Amount)
+	})
+}
+
+type failingAfterOracle struct {
+	oracle.PriceOracle
+	calls int
+}
+
+func (o *failingAfterOracle) Price(ctx context.Context, token common.Address, quote string) (*oracle.Price, error) {
+	if o.calls == 0 {
+		return nil, errors.New("oracle down")
+	}
+	o.calls--
+	return o.PriceOracle.Price(ctx, token, quote)
+}
@@ -1,18 +1,76 @@
 package x402
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"encoding/json"
 	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/sigloop/sdk-go/signer"
 )
 
+var PaymentToken = common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+
+const TransferWithAuthorizationSignature = "transferWithAuthorization(address,address,uint256,uint256,uint256,bytes32,uint8,bytes32,bytes32)"
+
+var TransferWithAuthorizationSelector = [4]byte{0xe3, 0xee, 0x16, 0x0e}
+
+func EIP3009TypedData(
+	tokenAddress common.Address,
+	from common.Address,
+	to common.Address,
+	value *big.Int,
+	validAfter *big.Int,
+	validBefore *big.Int,
+	nonce [32]byte,
+	chainID *big.Int,
+) (apitypes.TypedData, error) {
+	if value == nil || validAfter == nil || validBefore == nil || chainID == nil {
+		return apitypes.TypedData{}, errors.New("missing authorization field")
+	}
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"TransferWithAuthorization": {
+				{Name: "from", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "validBefore", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "TransferWithAuthorization",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "USD Coin",
+			Version:           "2",
+			ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(chainID)),
+			VerifyingContract: tokenAddress.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":        from.Hex(),
+			"to":          to.Hex(),
+			"value":       value.String(),
+			"validAfter":  validAfter.String(),
+			"validBefore": validBefore.String(),
+			"nonce":       hexutil.Encode(nonce[:]),
+		},
+	}, nil
+}
+
 func SignEIP3009Authorization(
-	privateKey *ecdsa.PrivateKey,
+	ctx context.Context,
+	s signer.Signer,
 	tokenAddress common.Address,
 	from common.Address,
 	to common.Address,
@@ -22,29 +80,17 @@ func SignEIP3009Authorization(
 	nonce [32]byte,
 	chainID *big.Int,
 ) ([]byte, error) {
-	domainSeparator := buildDomainSeparator(tokenAddress, chainID)
-
-	transferTypeHash := crypto.Keccak256([]byte(
-		"TransferWithAuthorization(address from,address to,uint256 value,uint256 validAfter,uint256 validBefore,bytes32 nonce)",
-	))
-
-	structHash := crypto.Keccak256(
-		transferTypeHash,
-		common.LeftPadBytes(from.Bytes(), 32),
-		common.LeftPadBytes(to.Bytes(), 32),
-		math.U256Bytes(value),
-		math.U256Bytes(validAfter),
-		math.U256Bytes(validBefore),
-		nonce[:],
-	)
-
-	digestInput := make([]byte, 0, 66)
-	digestInput = append(digestInput, 0x19, 0x01)
-	digestInput = append(digestInput, domainSeparator...)
-	digestInput = append(digestInput, structHash...)
-	digest := crypto.Keccak256(digestInput)
-
-	sig, err := crypto.Sign(digest, privateKey)
+	if s == nil {
+		return nil, errors.New("nil signer")
+	}
+	if s.Address() != from {
+		return nil, errors.New("signer does not match from address")
+	}
+	data, err := EIP3009TypedData(tokenAddress, from, to, value, validAfter, validBefore, nonce, chainID)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.SignTypedData(ctx, data)
 	if err != nil {
 		return nil, err
 	}
@@ -56,29 +102,15 @@ func SignEIP3009Authorization(
 	return sig, nil
 }
 
-func buildDomainSeparator(tokenAddress common.Address, chainID *big.Int) []byte {
-	domainTypeHash := crypto.Keccak256([]byte(
-		"EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)",
-	))
-
-	nameHash := crypto.Keccak256([]byte("USD Coin"))
-	versionHash := crypto.Keccak256([]byte("2"))
-
-	return crypto.Keccak256(
-		domainTypeHash,
-		nameHash,
-		versionHash,
-		math.U256Bytes(chainID),
-		common.LeftPadBytes(tokenAddress.Bytes(), 32),
-	)
-}
-
 func BuildPaymentHeader(
-	privateKey *ecdsa.PrivateKey,
+	ctx context.Context,
+	s signer.Signer,
 	req *PaymentRequirement,
-	from common.Address,
 	chainID *big.Int,
 ) (string, error) {
+	if s == nil {
+		return "", errors.New("nil signer")
+	}
 	if req == nil {
 		return "", errors.New("nil payment requirement")
 	}
@@ -93,6 +125,8 @@ func BuildPaymentHeader(
 		deadline = big.NewInt(0)
 	}
 
+	from := s.Address()
+
 	var nonce [32]byte
 	nonceHash := crypto.Keccak256(
 		from.Bytes(),
@@ -101,11 +135,10 @@ func BuildPaymentHeader(
 	)
 	copy(nonce[:], nonceHash[:32])
 
-	tokenAddress := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
-
 	sig, err := SignEIP3009Authorization(
-		privateKey,
-		tokenAddress,
+		ctx,
+		s,
+		PaymentToken,
 		from,
 		req.PayTo,
 		amount,
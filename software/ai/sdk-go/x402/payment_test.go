@@ -1,12 +1,15 @@
 package x402
 
 import (
+	"context"
 	"encoding/json"
 	"math/big"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sigloop/sdk-go/signer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -14,6 +17,8 @@ import (
 func TestSignEIP3009Authorization(t *testing.T) {
 	privateKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
 
 	tokenAddr := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
 	from := crypto.PubkeyToAddress(privateKey.PublicKey)
@@ -26,7 +31,7 @@ func TestSignEIP3009Authorization(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		sig, err := SignEIP3009Authorization(
-			privateKey, tokenAddr, from, to, value,
+			context.Background(), s, tokenAddr, from, to, value,
 			validAfter, validBefore, nonce, chainID,
 		)
 		require.NoError(t, err)
@@ -36,13 +41,13 @@ func TestSignEIP3009Authorization(t *testing.T) {
 
 	t.Run("deterministic", func(t *testing.T) {
 		sig1, err := SignEIP3009Authorization(
-			privateKey, tokenAddr, from, to, value,
+			context.Background(), s, tokenAddr, from, to, value,
 			validAfter, validBefore, nonce, chainID,
 		)
 		require.NoError(t, err)
 
 		sig2, err := SignEIP3009Authorization(
-			privateKey, tokenAddr, from, to, value,
+			context.Background(), s, tokenAddr, from, to, value,
 			validAfter, validBefore, nonce, chainID,
 		)
 		require.NoError(t, err)
@@ -52,24 +57,112 @@ func TestSignEIP3009Authorization(t *testing.T) {
 
 	t.Run("different values produce different signatures", func(t *testing.T) {
 		sig1, err := SignEIP3009Authorization(
-			privateKey, tokenAddr, from, to, value,
+			context.Background(), s, tokenAddr, from, to, value,
 			validAfter, validBefore, nonce, chainID,
 		)
 		require.NoError(t, err)
 
 		sig2, err := SignEIP3009Authorization(
-			privateKey, tokenAddr, from, to, big.NewInt(2000000),
+			context.Background(), s, tokenAddr, from, to, big.NewInt(2000000),
 			validAfter, validBefore, nonce, chainID,
 		)
 		require.NoError(t, err)
 
 		assert.NotEqual(t, sig1, sig2)
 	})
+
+	t.Run("matches EIP-712 digest", func(t *testing.T) {
+		sig, err := SignEIP3009Authorization(
+			context.Background(), s, tokenAddr, from, to, value,
+			validAfter, validBefore, nonce, chainID,
+		)
+		require.NoError(t, err)
+
+		domainSeparator := crypto.Keccak256(
+			crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+			crypto.Keccak256([]byte("USD Coin")),
+			crypto.Keccak256([]byte("2")),
+			common.LeftPadBytes(chainID.Bytes(), 32),
+			common.LeftPadBytes(tokenAddr.Bytes(), 32),
+		)
+		structHash := crypto.Keccak256(
+			crypto.Keccak256([]byte("TransferWithAuthorization(address from,address to,uint256 value,uint256 validAfter,uint256 validBefore,bytes32 nonce)")),
+			common.LeftPadBytes(from.Bytes(), 32),
+			common.LeftPadBytes(to.Bytes(), 32),
+			common.LeftPadBytes(value.Bytes(), 32),
+			common.LeftPadBytes(validAfter.Bytes(), 32),
+			common.LeftPadBytes(validBefore.Bytes(), 32),
+			nonce[:],
+		)
+		digest := crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+
+		rawSig := append([]byte{}, sig...)
+		rawSig[64] -= 27
+		pub, err := crypto.SigToPub(digest, rawSig)
+		require.NoError(t, err)
+		assert.Equal(t, from, crypto.PubkeyToAddress(*pub))
+	})
+
+	t.Run("remote signer", func(t *testing.T) {
+		server := httptest.NewServer(signer.NewServer(s))
+		defer server.Close()
+
+		remote, err := signer.NewRemoteSigner(context.Background(), server.URL, nil)
+		require.NoError(t, err)
+
+		local, err := SignEIP3009Authorization(
+			context.Background(), s, tokenAddr, from, to, value,
+			validAfter, validBefore, nonce, chainID,
+		)
+		require.NoError(t, err)
+		got, err := SignEIP3009Authorization(
+			context.Background(), remote, tokenAddr, from, to, value,
+			validAfter, validBefore, nonce, chainID,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, local, got)
+	})
+
+	t.Run("signer does not match from", func(t *testing.T) {
+		_, err := SignEIP3009Authorization(
+			context.Background(), s, tokenAddr, to, to, value,
+			validAfter, validBefore, nonce, chainID,
+		)
+		assert.EqualError(t, err, "signer does not match from address")
+	})
+
+	t.Run("nil signer", func(t *testing.T) {
+		_, err := SignEIP3009Authorization(
+			context.Background(), nil, tokenAddr, from, to, value,
+			validAfter, validBefore, nonce, chainID,
+		)
+		assert.EqualError(t, err, "nil signer")
+	})
+}
+
+func TestEIP3009TypedData(t *testing.T) {
+	tokenAddr := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+	from := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	to := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	data, err := EIP3009TypedData(tokenAddr, from, to, big.NewInt(1000000), big.NewInt(0), big.NewInt(9999999999), [32]byte{}, big.NewInt(8453))
+	require.NoError(t, err)
+	assert.Equal(t, "TransferWithAuthorization", data.PrimaryType)
+	assert.Equal(t, "1000000", data.Message["value"])
+
+	_, err = EIP3009TypedData(tokenAddr, from, to, big.NewInt(1000000), big.NewInt(0), big.NewInt(9999999999), [32]byte{}, nil)
+	assert.EqualError(t, err, "missing authorization field")
+}
+
+func TestTransferWithAuthorizationSelector(t *testing.T) {
+	assert.Equal(t, crypto.Keccak256([]byte(TransferWithAuthorizationSignature))[:4], TransferWithAuthorizationSelector[:])
 }
 
 func TestBuildPaymentHeader(t *testing.T) {
 	privateKey, err := crypto.GenerateKey()
 	require.NoError(t, err)
+	s, err := signer.NewPrivateKeySigner(privateKey)
+	require.NoError(t, err)
 	from := crypto.PubkeyToAddress(privateKey.PublicKey)
 	chainID := big.NewInt(8453)
 
@@ -82,7 +175,7 @@ func TestBuildPaymentHeader(t *testing.T) {
 			RequiredDeadline:  "9999999999",
 		}
 
-		header, err := BuildPaymentHeader(privateKey, req, from, chainID)
+		header, err := BuildPaymentHeader(context.Background(), s, req, chainID)
 		require.NoError(t, err)
 		assert.NotEmpty(t, header)
 
@@ -104,7 +197,7 @@ func TestBuildPaymentHeader(t *testing.T) {
 	})
 
 	t.Run("nil requirement", func(t *testing.T) {
-		_, err := BuildPaymentHeader(privateKey, nil, from, chainID)
+		_, err := BuildPaymentHeader(context.Background(), s, nil, chainID)
 		require.Error(t, err)
 		assert.Equal(t, "nil payment requirement", err.Error())
 	})
@@ -114,7 +207,7 @@ func TestBuildPaymentHeader(t *testing.T) {
 			MaxAmountRequired: "not-a-number",
 			PayTo:             common.HexToAddress("0xbbbb"),
 		}
-		_, err := BuildPaymentHeader(privateKey, req, from, chainID)
+		_, err := BuildPaymentHeader(context.Background(), s, req, chainID)
 		require.Error(t, err)
 		assert.Equal(t, "invalid amount", err.Error())
 	})
@@ -127,7 +220,7 @@ func TestBuildPaymentHeader(t *testing.T) {
 			PayTo:             common.HexToAddress("0xbbbb"),
 			RequiredDeadline:  "not-a-number",
 		}
-		header, err := BuildPaymentHeader(privateKey, req, from, chainID)
+		header, err := BuildPaymentHeader(context.Background(), s, req, chainID)
 		require.NoError(t, err)
 		assert.NotEmpty(t, header)
 	})
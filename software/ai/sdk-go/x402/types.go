@@ -32,6 +32,7 @@ type PaymentRecord struct {
 	Timestamp      uint64
 	TxHash         common.Hash
 	Network        string
+	Value          *big.Int
 }
 
 type X402Policy struct {
@@ -39,6 +40,9 @@ type X402Policy struct {
 	MaxPerPeriod   *big.Int
 	AllowedPayees  map[common.Address]bool
 	AllowedDomains map[string]bool
+	Window         BudgetWindow
+	Quote          string
+	Asset          common.Address
 }
 
 type BudgetState struct {